@@ -0,0 +1,267 @@
+// Package bundle exports the metadata of a library to a portable bundle, and imports it back,
+// in the same or another instance. Items are matched to files by path, then by content hash.
+//
+// Bundles are either a single JSON document, or a zip archive holding that document along with
+// the artwork it references.
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/meteorae/meteorae-server/database"
+)
+
+// Version of the bundle format, increased on breaking changes.
+const Version = 1
+
+const (
+	metadataFileName = "metadata.json"
+	artworkDirectory = "artwork/"
+	// The local file header signature, which zip archives start with.
+	zipSignature = "PK\x03\x04"
+)
+
+var (
+	errInvalidFormat      = errors.New("invalid bundle format")
+	errUnsupportedVersion = errors.New("unsupported bundle version")
+	errMissingMetadata    = errors.New("bundle has no " + metadataFileName)
+)
+
+// Matches the hashes of images in the image cache, as opposed to remote URLs.
+var imageHashRegexp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+type Format string
+
+const (
+	// A single JSON document, without artwork.
+	JSONFormat Format = "json"
+	// A zip archive holding the JSON document and artwork.
+	ZipFormat Format = "zip"
+)
+
+func (f Format) String() string {
+	return string(f)
+}
+
+func (f *Format) UnmarshalText(text []byte) error {
+	switch format := Format(text); format {
+	case JSONFormat, ZipFormat:
+		*f = format
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", errInvalidFormat, text)
+}
+
+type Bundle struct {
+	Version     int          `json:"version"`
+	ExportedAt  time.Time    `json:"exportedAt"`
+	Library     Library      `json:"library"`
+	Items       []Item       `json:"items"`
+	Collections []Collection `json:"collections"`
+	// Artwork, keyed by image hash. Only filled for zip bundles.
+	Artwork map[string]ArtworkOpener `json:"-"`
+}
+
+// Opens an image of a bundle, so artwork is streamed instead of being held in memory.
+type ArtworkOpener func() (io.ReadCloser, error)
+
+type Library struct {
+	Name     string               `json:"name"`
+	Type     database.LibraryType `json:"type"`
+	Language string               `json:"language"`
+}
+
+// The metadata of an item, keyed by the path and content hash of its file.
+type Item struct {
+	// Path of the file, relative to the library location holding it, with forward slashes.
+	Path string `json:"path"`
+	// SHA-256 hash of the contents of the file, empty for directories.
+	Hash          string                `json:"hash"`
+	Size          int64                 `json:"size"`
+	Type          database.ItemType     `json:"type"`
+	Title         string                `json:"title"`
+	SortTitle     string                `json:"sortTitle"`
+	OriginalTitle string                `json:"originalTitle"`
+	Tagline       string                `json:"tagline"`
+	Summary       string                `json:"summary"`
+	ReleaseDate   time.Time             `json:"releaseDate"`
+	Thumb         string                `json:"thumb"`
+	Art           string                `json:"art"`
+	MatchProvider string                `json:"matchProvider"`
+	MatchID       string                `json:"matchId"`
+	LockedFields  []string              `json:"lockedFields"`
+	ExternalIDs   []ExternalIdentifier  `json:"externalIds"`
+	Tags          []Tag                 `json:"tags"`
+	Collections   []CollectionReference `json:"collections"`
+}
+
+type ExternalIdentifier struct {
+	Type       database.IdentifierType `json:"type"`
+	Identifier string                  `json:"identifier"`
+}
+
+type Tag struct {
+	// Names of the tag and its ancestors, root first.
+	Path        []string `json:"path"`
+	UserDefined bool     `json:"userDefined"`
+}
+
+type CollectionReference struct {
+	// Key of the collection in the bundle.
+	Key   string `json:"key"`
+	Index int    `json:"index"`
+}
+
+type Collection struct {
+	Key           string                       `json:"key"`
+	Title         string                       `json:"title"`
+	SortTitle     string                       `json:"sortTitle"`
+	Summary       string                       `json:"summary"`
+	Thumb         string                       `json:"thumb"`
+	Art           string                       `json:"art"`
+	MatchProvider string                       `json:"matchProvider"`
+	MatchID       string                       `json:"matchId"`
+	SortOrder     database.CollectionSortOrder `json:"sortOrder"`
+	ExternalIDs   []ExternalIdentifier         `json:"externalIds"`
+}
+
+// Writes a bundle in the given format.
+func Write(bundle *Bundle, format Format, writer io.Writer) error {
+	if format == JSONFormat {
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(bundle); err != nil {
+			return fmt.Errorf("failed to encode bundle: %w", err)
+		}
+
+		return nil
+	}
+
+	archive := zip.NewWriter(writer)
+
+	metadataFile, err := archive.Create(metadataFileName)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", metadataFileName, err)
+	}
+
+	if err := json.NewEncoder(metadataFile).Encode(bundle); err != nil {
+		return fmt.Errorf("failed to encode bundle: %w", err)
+	}
+
+	for hash, open := range bundle.Artwork {
+		if err := writeArtwork(archive, hash, open); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	return nil
+}
+
+func writeArtwork(archive *zip.Writer, hash string, open ArtworkOpener) error {
+	image, err := open()
+	if err != nil {
+		return fmt.Errorf("failed to open artwork %s: %w", hash, err)
+	}
+	defer image.Close()
+
+	// Images are already compressed
+	imageFile, err := archive.CreateHeader(&zip.FileHeader{Name: artworkDirectory + hash, Method: zip.Store})
+	if err != nil {
+		return fmt.Errorf("failed to create artwork %s: %w", hash, err)
+	}
+
+	if _, err := io.Copy(imageFile, image); err != nil {
+		return fmt.Errorf("failed to write artwork %s: %w", hash, err)
+	}
+
+	return nil
+}
+
+// Reads a bundle in any format. Artwork is read from the reader when opened,
+// so it must stay available until the bundle is imported.
+func Read(reader io.ReaderAt, size int64) (*Bundle, error) {
+	var bundle Bundle
+
+	header := make([]byte, len(zipSignature))
+	if _, err := reader.ReadAt(header, 0); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+
+	if !bytes.Equal(header, []byte(zipSignature)) {
+		if err := json.NewDecoder(io.NewSectionReader(reader, 0, size)).Decode(&bundle); err != nil {
+			return nil, fmt.Errorf("failed to decode bundle: %w", err)
+		}
+
+		return checkVersion(&bundle)
+	}
+
+	archive, err := zip.NewReader(reader, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+
+	bundle.Artwork = make(map[string]ArtworkOpener)
+	hasMetadata := false
+
+	for _, file := range archive.File {
+		switch {
+		case file.Name == metadataFileName:
+			if err := readJSONFile(file, &bundle); err != nil {
+				return nil, err
+			}
+
+			hasMetadata = true
+		case strings.HasPrefix(file.Name, artworkDirectory):
+			hash := strings.TrimPrefix(file.Name, artworkDirectory)
+			// Anything else could escape the image cache
+			if !imageHashRegexp.MatchString(hash) {
+				continue
+			}
+
+			bundle.Artwork[hash] = file.Open
+		}
+	}
+
+	if !hasMetadata {
+		return nil, errMissingMetadata
+	}
+
+	return checkVersion(&bundle)
+}
+
+func checkVersion(bundle *Bundle) (*Bundle, error) {
+	if bundle.Version < 1 || bundle.Version > Version {
+		return nil, fmt.Errorf("%w: %d", errUnsupportedVersion, bundle.Version)
+	}
+
+	return bundle, nil
+}
+
+func readJSONFile(file *zip.File, value interface{}) error {
+	reader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", file.Name, err)
+	}
+	defer reader.Close()
+
+	if err := json.NewDecoder(reader).Decode(value); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", file.Name, err)
+	}
+
+	return nil
+}
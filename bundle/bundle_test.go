@@ -0,0 +1,135 @@
+package bundle_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/meteorae/meteorae-server/bundle"
+	"github.com/meteorae/meteorae-server/database"
+)
+
+var imageHash = strings.Repeat("ab", 32)
+
+func newTestBundle() *bundle.Bundle {
+	return &bundle.Bundle{
+		Version: bundle.Version,
+		Library: bundle.Library{Name: "Movies", Type: database.MovieLibrary},
+		Items: []bundle.Item{{
+			Path:         "Movies/Alien (1979).mkv",
+			Hash:         "0123",
+			Title:        "Alien",
+			Thumb:        imageHash,
+			LockedFields: []string{"title"},
+			Tags:         []bundle.Tag{{Path: []string{"Genres", "Horror"}}},
+		}},
+		Artwork: map[string]bundle.ArtworkOpener{imageHash: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("image")), nil
+		}},
+	}
+}
+
+func TestZipRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	var buffer bytes.Buffer
+
+	if err := bundle.Write(newTestBundle(), bundle.ZipFormat, &buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := bundle.Read(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(read.Items) != 1 || read.Items[0].Title != "Alien" || read.Items[0].Tags[0].Path[1] != "Horror" {
+		t.Errorf("unexpected items: %+v", read.Items)
+	}
+
+	open, ok := read.Artwork[imageHash]
+	if !ok {
+		t.Fatalf("missing artwork: %v", read.Artwork)
+	}
+
+	image, err := open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer image.Close()
+
+	if data, err := io.ReadAll(image); err != nil || string(data) != "image" {
+		t.Errorf("unexpected artwork: %q, %v", data, err)
+	}
+}
+
+func TestJSONRoundTripHasNoArtwork(t *testing.T) {
+	t.Parallel()
+
+	var buffer bytes.Buffer
+
+	if err := bundle.Write(newTestBundle(), bundle.JSONFormat, &buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := bundle.Read(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if read.Items[0].Thumb != imageHash || len(read.Artwork) != 0 {
+		t.Errorf("unexpected bundle: %+v", read)
+	}
+}
+
+func TestReadIgnoresInvalidArtworkNames(t *testing.T) {
+	t.Parallel()
+
+	var buffer bytes.Buffer
+
+	archive := zip.NewWriter(&buffer)
+
+	for name, content := range map[string]string{
+		"metadata.json":      `{"version": 1}`,
+		"artwork/../../evil": "evil",
+	} {
+		file, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := file.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := bundle.Read(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(read.Artwork) != 0 {
+		t.Errorf("expected no artwork, got %v", read.Artwork)
+	}
+}
+
+func TestReadRejectsUnsupportedVersions(t *testing.T) {
+	t.Parallel()
+
+	data := `{"version": 99}`
+
+	if _, err := bundle.Read(strings.NewReader(data), int64(len(data))); err == nil {
+		t.Error("expected an error")
+	}
+
+	var format bundle.Format
+	if err := format.UnmarshalText([]byte("tar")); err == nil {
+		t.Error("expected an invalid format error")
+	}
+}
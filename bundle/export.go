@@ -0,0 +1,260 @@
+package bundle
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/helpers"
+	"github.com/meteorae/meteorae-server/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// Builds the bundle of a library, which must have its locations loaded.
+// Artwork is only included when withArtwork is set.
+func Export(library *database.Library, withArtwork bool) (*Bundle, error) {
+	items, err := database.GetItemsWithMediaPartFromLibrary(library.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get library items: %w", err)
+	}
+
+	exporter := exporter{
+		bundle: &Bundle{
+			Version:    Version,
+			ExportedAt: time.Now(),
+			Library: Library{
+				Name:     library.Name,
+				Type:     library.Type,
+				Language: library.Language,
+			},
+			Items:       make([]Item, 0, len(items)),
+			Collections: []Collection{},
+		},
+		library:     library,
+		withArtwork: withArtwork,
+		collections: make(map[uint64]bool),
+		tagPaths:    make(map[uint64][]string),
+	}
+
+	if withArtwork {
+		exporter.bundle.Artwork = make(map[string]ArtworkOpener)
+	}
+
+	for _, item := range items {
+		if err := exporter.addItem(item); err != nil {
+			return nil, err
+		}
+	}
+
+	return exporter.bundle, nil
+}
+
+type exporter struct {
+	bundle      *Bundle
+	library     *database.Library
+	withArtwork bool
+	// The collections already added to the bundle.
+	collections map[uint64]bool
+	tagPaths    map[uint64][]string
+}
+
+func (e *exporter) addItem(item *database.ItemMetadata) error {
+	identifiers, err := database.GetExternalIdentifiersFromItem(strconv.FormatUint(item.ID, 10)) //nolint:gomnd
+	if err != nil {
+		return fmt.Errorf("failed to get external identifiers of item %d: %w", item.ID, err)
+	}
+
+	tags, err := e.getTags(item.ID)
+	if err != nil {
+		return err
+	}
+
+	collections, err := e.getCollections(item.ID)
+	if err != nil {
+		return err
+	}
+
+	hash, size := getContentHash(item.MediaPart.FilePath)
+
+	e.bundle.Items = append(e.bundle.Items, Item{
+		Path:          e.getRelativePath(item.MediaPart.FilePath),
+		Hash:          hash,
+		Size:          size,
+		Type:          item.Type,
+		Title:         item.Title,
+		SortTitle:     item.SortTitle,
+		OriginalTitle: item.OriginalTitle,
+		Tagline:       item.Tagline,
+		Summary:       item.Summary,
+		ReleaseDate:   item.ReleaseDate,
+		Thumb:         e.addArtwork(item.Thumb),
+		Art:           e.addArtwork(item.Art),
+		MatchProvider: item.MatchProvider,
+		MatchID:       item.MatchID,
+		LockedFields:  item.GetLockedFields(),
+		ExternalIDs:   getExternalIdentifiers(identifiers),
+		Tags:          tags,
+		Collections:   collections,
+	})
+
+	return nil
+}
+
+// Returns the content hash and size of a file, so it can be found after being moved or renamed.
+// Returns an empty hash for directories, and files which can't be read.
+func getContentHash(path string) (string, int64) {
+	fileInfo, err := os.Stat(path)
+	if err != nil || fileInfo.IsDir() {
+		return "", 0
+	}
+
+	hash, err := utils.HashFileContents(path)
+	if err != nil {
+		log.Err(err).Msgf("Failed to hash %s", path)
+
+		return "", 0
+	}
+
+	return hex.EncodeToString(hash), fileInfo.Size()
+}
+
+// Returns the path of a file relative to the library location holding it,
+// or the full path when it isn't in any location.
+func (e *exporter) getRelativePath(path string) string {
+	for _, location := range e.library.LibraryLocations {
+		relativePath, err := filepath.Rel(location.RootPath, path)
+		if err == nil && relativePath != ".." && !strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+			return filepath.ToSlash(relativePath)
+		}
+	}
+
+	return filepath.ToSlash(path)
+}
+
+func (e *exporter) getTags(itemID uint64) ([]Tag, error) {
+	itemTags, err := database.GetItemTagsFromItem(itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags of item %d: %w", itemID, err)
+	}
+
+	tags := make([]Tag, 0, len(itemTags))
+
+	for index := range itemTags {
+		path, ok := e.tagPaths[itemTags[index].TagID]
+		if !ok {
+			tagPath, err := database.GetTagPath(&itemTags[index].Tag)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get path of tag %d: %w", itemTags[index].TagID, err)
+			}
+
+			for _, tag := range tagPath {
+				path = append(path, tag.Name)
+			}
+
+			e.tagPaths[itemTags[index].TagID] = path
+		}
+
+		tags = append(tags, Tag{Path: path, UserDefined: itemTags[index].UserDefined})
+	}
+
+	return tags, nil
+}
+
+func (e *exporter) getCollections(itemID uint64) ([]CollectionReference, error) {
+	members, err := database.GetCollectionMembersFromItem(itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collections of item %d: %w", itemID, err)
+	}
+
+	references := make([]CollectionReference, 0, len(members))
+
+	for index := range members {
+		key := strconv.FormatUint(members[index].CollectionID, 10) //nolint:gomnd
+
+		if !e.collections[members[index].CollectionID] {
+			if err := e.addCollection(key, &members[index].Collection); err != nil {
+				return nil, err
+			}
+
+			e.collections[members[index].CollectionID] = true
+		}
+
+		references = append(references, CollectionReference{Key: key, Index: members[index].Index})
+	}
+
+	return references, nil
+}
+
+func (e *exporter) addCollection(key string, collection *database.ItemMetadata) error {
+	identifiers, err := database.GetExternalIdentifiersFromItem(key)
+	if err != nil {
+		return fmt.Errorf("failed to get external identifiers of collection %s: %w", key, err)
+	}
+
+	e.bundle.Collections = append(e.bundle.Collections, Collection{
+		Key:           key,
+		Title:         collection.Title,
+		SortTitle:     collection.SortTitle,
+		Summary:       collection.Summary,
+		Thumb:         e.addArtwork(collection.Thumb),
+		Art:           e.addArtwork(collection.Art),
+		MatchProvider: collection.MatchProvider,
+		MatchID:       collection.MatchID,
+		SortOrder:     database.GetCollectionSortOrder(collection),
+		ExternalIDs:   getExternalIdentifiers(identifiers),
+	})
+
+	return nil
+}
+
+// Adds a cached image to the bundle, when artwork is included. Returns the image reference unchanged.
+func (e *exporter) addArtwork(image string) string {
+	if !e.withArtwork || !imageHashRegexp.MatchString(image) {
+		return image
+	}
+
+	if _, ok := e.bundle.Artwork[image]; ok {
+		return image
+	}
+
+	imagePath, err := helpers.GetCachedImagePath(image)
+	if err != nil {
+		log.Err(err).Msgf("Failed to get path of image %s", image)
+
+		return image
+	}
+
+	if _, err := os.Stat(imagePath); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Err(err).Msgf("Failed to read image %s", imagePath)
+		}
+
+		return image
+	}
+
+	e.bundle.Artwork[image] = func() (io.ReadCloser, error) {
+		return os.Open(imagePath)
+	}
+
+	return image
+}
+
+func getExternalIdentifiers(identifiers []*database.ExternalIdentifier) []ExternalIdentifier {
+	externalIdentifiers := make([]ExternalIdentifier, 0, len(identifiers))
+
+	for _, identifier := range identifiers {
+		externalIdentifiers = append(externalIdentifiers, ExternalIdentifier{
+			Type:       identifier.IdentifierType,
+			Identifier: identifier.Identifier,
+		})
+	}
+
+	return externalIdentifiers
+}
@@ -0,0 +1,376 @@
+package bundle
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/helpers"
+	"github.com/meteorae/meteorae-server/utils"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+type ImportResult struct {
+	// Number of items whose metadata was imported.
+	Matched int `json:"matched"`
+	// Paths of the items without a matching file in the library.
+	Unmatched []string `json:"unmatched"`
+}
+
+// Imports a bundle into a library, which must have its locations loaded.
+// Bundle items are matched to the library items by the path of their file, then by its content hash.
+func Import(bundle *Bundle, library *database.Library) (*ImportResult, error) {
+	importer := importer{
+		library:       library,
+		collections:   make(map[string]*Collection, len(bundle.Collections)),
+		collectionIDs: make(map[string]uint64),
+		tagIDs:        make(map[string]uint64),
+		contentHashes: make(map[string]string),
+		images:        make(map[string]string, len(bundle.Artwork)),
+	}
+
+	for hash, open := range bundle.Artwork {
+		if isImageCached(hash) {
+			importer.images[hash] = hash
+
+			continue
+		}
+
+		cachedHash, err := importArtwork(hash, open)
+		if err != nil {
+			return nil, err
+		}
+
+		importer.images[hash] = cachedHash
+	}
+
+	for index := range bundle.Collections {
+		importer.collections[bundle.Collections[index].Key] = &bundle.Collections[index]
+	}
+
+	result := ImportResult{Unmatched: []string{}}
+
+	for index := range bundle.Items {
+		matched, err := importer.importItem(&bundle.Items[index])
+		if err != nil {
+			return nil, err
+		}
+
+		if matched {
+			result.Matched++
+		} else {
+			result.Unmatched = append(result.Unmatched, bundle.Items[index].Path)
+		}
+	}
+
+	return &result, nil
+}
+
+type importer struct {
+	library     *database.Library
+	collections map[string]*Collection
+	// The identifiers of the collections already imported, by bundle key.
+	collectionIDs map[string]uint64
+	// The identifiers of the tags already imported, by path.
+	tagIDs map[string]uint64
+	// The content hashes of the library files already hashed, by path.
+	contentHashes map[string]string
+	// The hashes of the cached images, by bundle reference, empty for the references which can't be used.
+	images map[string]string
+}
+
+// Imports the metadata of an item. Returns whether a matching item was found.
+func (i *importer) importItem(bundleItem *Item) (bool, error) {
+	item, err := i.findItem(bundleItem)
+	if err != nil {
+		return false, err
+	}
+
+	// A different type means the file was resolved as something else, and the metadata wouldn't fit
+	if item == nil || item.Type != bundleItem.Type {
+		return false, nil
+	}
+
+	item.Title = bundleItem.Title
+	item.SortTitle = bundleItem.SortTitle
+	item.OriginalTitle = bundleItem.OriginalTitle
+	item.Tagline = bundleItem.Tagline
+	item.Summary = bundleItem.Summary
+	item.ReleaseDate = bundleItem.ReleaseDate
+	item.MatchProvider = bundleItem.MatchProvider
+	item.MatchID = bundleItem.MatchID
+	item.SetLockedFields(bundleItem.LockedFields)
+
+	if thumb := i.getImage(bundleItem.Thumb); thumb != "" {
+		item.Thumb = thumb
+	}
+
+	if art := i.getImage(bundleItem.Art); art != "" {
+		item.Art = art
+	}
+
+	item.ExternalIdentifiers = getDatabaseIdentifiers(bundleItem.ExternalIDs)
+
+	itemTags := make([]database.ItemTag, 0, len(bundleItem.Tags))
+
+	for _, tag := range bundleItem.Tags {
+		tagID, err := i.getTagID(tag.Path)
+		if err != nil {
+			return false, err
+		}
+
+		if tagID != 0 {
+			itemTags = append(itemTags, database.ItemTag{TagID: tagID, UserDefined: tag.UserDefined})
+		}
+	}
+
+	members := make([]database.CollectionMember, 0, len(bundleItem.Collections))
+
+	for _, reference := range bundleItem.Collections {
+		collectionID, err := i.getCollectionID(reference.Key)
+		if err != nil {
+			return false, err
+		}
+
+		if collectionID != 0 {
+			members = append(members, database.CollectionMember{CollectionID: collectionID, Index: reference.Index})
+		}
+	}
+
+	if err := database.ImportItem(item, itemTags, members); err != nil {
+		return false, fmt.Errorf("failed to import %s: %w", bundleItem.Path, err)
+	}
+
+	return true, nil
+}
+
+// Returns the library item for a bundle item, or nil when there is none.
+func (i *importer) findItem(bundleItem *Item) (*database.ItemMetadata, error) {
+	item, err := i.findItemByPath(bundleItem.Path)
+	if item != nil || err != nil {
+		return item, err
+	}
+
+	return i.findItemByContentHash(bundleItem)
+}
+
+func (i *importer) findItemByPath(bundlePath string) (*database.ItemMetadata, error) {
+	path := filepath.FromSlash(bundlePath)
+	paths := []string{path}
+
+	if !filepath.IsAbs(path) {
+		paths = make([]string, 0, len(i.library.LibraryLocations))
+
+		for _, location := range i.library.LibraryLocations {
+			paths = append(paths, filepath.Join(location.RootPath, path))
+		}
+	}
+
+	for _, path := range paths {
+		item, err := database.GetItemByMediaPartPath(path)
+		if err == nil && item.LibraryID == i.library.ID {
+			return item, nil
+		}
+
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to find item by path: %w", err)
+		}
+	}
+
+	return nil, nil
+}
+
+// Finds a file which was moved or renamed. Hashing reads whole files, so only the files
+// of the same size are hashed, once per import.
+func (i *importer) findItemByContentHash(bundleItem *Item) (*database.ItemMetadata, error) {
+	if bundleItem.Hash == "" {
+		return nil, nil
+	}
+
+	candidates, err := database.GetItemsByMediaPartSize(i.library.ID, bundleItem.Size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find item by hash: %w", err)
+	}
+
+	for _, candidate := range candidates {
+		path := candidate.MediaPart.FilePath
+
+		hash, ok := i.contentHashes[path]
+		if !ok {
+			contentHash, err := utils.HashFileContents(path)
+			if err != nil {
+				log.Warn().Err(err).Msgf("Failed to hash %s", path)
+			} else {
+				hash = hex.EncodeToString(contentHash)
+			}
+
+			i.contentHashes[path] = hash
+		}
+
+		if hash == bundleItem.Hash {
+			return candidate, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Returns the identifier of the tag with the given path, creating the missing tags.
+func (i *importer) getTagID(path []string) (uint64, error) {
+	var tagID uint64
+
+	for depth, name := range path {
+		key := fmt.Sprintf("%q", path[:depth+1])
+
+		if id, ok := i.tagIDs[key]; ok {
+			tagID = id
+
+			continue
+		}
+
+		tag, err := database.GetOrCreateTag(name, tagID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to import tag: %w", err)
+		}
+
+		tagID = tag.ID
+		i.tagIDs[key] = tagID
+	}
+
+	return tagID, nil
+}
+
+// Returns the identifier of the collection with the given bundle key, importing it if needed.
+// Provider collections are matched by external identifiers, user collections by title.
+// Returns 0 when the bundle has no such collection.
+func (i *importer) getCollectionID(key string) (uint64, error) {
+	if id, ok := i.collectionIDs[key]; ok {
+		return id, nil
+	}
+
+	bundleCollection, ok := i.collections[key]
+	if !ok {
+		log.Warn().Msgf("Collection %s is missing from the bundle", key)
+
+		return 0, nil
+	}
+
+	collection := &database.ItemMetadata{
+		Title:               bundleCollection.Title,
+		SortTitle:           bundleCollection.SortTitle,
+		Summary:             bundleCollection.Summary,
+		MatchProvider:       bundleCollection.MatchProvider,
+		MatchID:             bundleCollection.MatchID,
+		ExternalIdentifiers: getDatabaseIdentifiers(bundleCollection.ExternalIDs),
+	}
+
+	collection.Thumb = i.getImage(bundleCollection.Thumb)
+	collection.Art = i.getImage(bundleCollection.Art)
+
+	if err := database.SetCollectionSortOrder(collection, bundleCollection.SortOrder); err != nil {
+		return 0, err
+	}
+
+	collection, err := i.getOrCreateCollection(collection)
+	if err != nil {
+		return 0, fmt.Errorf("failed to import collection %s: %w", bundleCollection.Title, err)
+	}
+
+	i.collectionIDs[key] = collection.ID
+
+	return collection.ID, nil
+}
+
+func (i *importer) getOrCreateCollection(collection *database.ItemMetadata) (*database.ItemMetadata, error) {
+	if !database.IsUserCollection(collection) {
+		return database.GetOrCreateCollection(collection)
+	}
+
+	existing, err := database.GetUserCollectionByTitle(collection.Title)
+	if err == nil {
+		return existing, nil
+	}
+
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if err := database.CreateCollection(collection); err != nil {
+		return nil, err
+	}
+
+	return collection, nil
+}
+
+// Returns the hash of the cached image for an image reference of the bundle, or an empty string when it can't be used.
+// Hashes must be those of images imported from the bundle or already in the cache, and remote URLs are downloaded
+// to the cache, as for images given by users.
+func (i *importer) getImage(image string) string {
+	if image == "" {
+		return ""
+	}
+
+	if hash, ok := i.images[image]; ok {
+		return hash
+	}
+
+	hash, err := helpers.SaveUserImageToCache(image)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Skipping image %s", image)
+	}
+
+	i.images[image] = hash
+
+	return hash
+}
+
+// Saves an image of the bundle to the image cache. The bundle is only trusted for the reference, so the image is
+// cached under the hash of its contents, which is returned.
+func importArtwork(hash string, open ArtworkOpener) (string, error) {
+	reader, err := open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open artwork %s: %w", hash, err)
+	}
+	defer reader.Close()
+
+	image, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to read artwork %s: %w", hash, err)
+	}
+
+	cachedHash, err := helpers.SaveImageDataToCache(image)
+	if err != nil {
+		return "", fmt.Errorf("failed to import artwork %s: %w", hash, err)
+	}
+
+	return cachedHash, nil
+}
+
+func getDatabaseIdentifiers(identifiers []ExternalIdentifier) []database.ExternalIdentifier {
+	externalIdentifiers := make([]database.ExternalIdentifier, 0, len(identifiers))
+
+	for _, identifier := range identifiers {
+		externalIdentifiers = append(externalIdentifiers, database.ExternalIdentifier{
+			IdentifierType: identifier.Type,
+			Identifier:     identifier.Identifier,
+		})
+	}
+
+	return externalIdentifiers
+}
+
+func isImageCached(hash string) bool {
+	imagePath, err := helpers.GetCachedImagePath(hash)
+	if err != nil {
+		return false
+	}
+
+	_, err = os.Stat(imagePath)
+
+	return err == nil
+}
@@ -0,0 +1,254 @@
+package bundle_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/adrg/xdg"
+	"github.com/meteorae/meteorae-server/bundle"
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/helpers"
+	"github.com/meteorae/meteorae-server/internal/databasetest"
+)
+
+// Creates a movie library with a movie for each of the given files, relative to the library location.
+func setupLibrary(t *testing.T, files map[string]string) (*database.Library, map[string]uint64) {
+	t.Helper()
+
+	databasetest.Setup(t)
+
+	root := t.TempDir()
+
+	library, _, err := database.CreateLibrary("Movies", "en", "movie", []string{root}, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make(map[string]uint64, len(files))
+
+	for name, content := range files {
+		path := filepath.Join(root, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		movie := database.ItemMetadata{
+			Title:     name,
+			Type:      database.MovieItem,
+			LibraryID: library.ID,
+			MediaPart: database.MediaPart{FilePath: path, Size: int64(len(content))},
+		}
+
+		if err := database.CreateMovie(&movie); err != nil {
+			t.Fatal(err)
+		}
+
+		ids[name] = movie.ID
+	}
+
+	library, err = database.GetLibraryWithLocations(strconv.FormatUint(library.ID, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return library, ids
+}
+
+func hashContent(content string) string {
+	hash := sha256.Sum256([]byte(content))
+
+	return hex.EncodeToString(hash[:])
+}
+
+func getItem(t *testing.T, id uint64) *database.ItemMetadata {
+	t.Helper()
+
+	item, err := database.GetItemByID(strconv.FormatUint(id, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return item
+}
+
+func getTitle(t *testing.T, id uint64) string {
+	t.Helper()
+
+	return getItem(t, id).Title
+}
+
+// Points the image cache to a temporary directory.
+func setupImageCache(t *testing.T) {
+	t.Helper()
+
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+}
+
+func isImageCached(t *testing.T, hash string) bool {
+	t.Helper()
+
+	imagePath, err := helpers.GetCachedImagePath(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = os.Stat(imagePath)
+
+	return err == nil
+}
+
+func TestExportHashesFileContents(t *testing.T) {
+	library, _ := setupLibrary(t, map[string]string{"Alien (1979)/Alien.mkv": "alien"})
+
+	exported, err := bundle.Export(library, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(exported.Items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(exported.Items))
+	}
+
+	item := exported.Items[0]
+	if item.Path != "Alien (1979)/Alien.mkv" || item.Hash != hashContent("alien") || item.Size != 5 {
+		t.Errorf("unexpected item: %+v", item)
+	}
+}
+
+func TestImportMatchesItems(t *testing.T) {
+	library, ids := setupLibrary(t, map[string]string{
+		"Alien.mkv":       "alien",
+		"Renamed/Big.mkv": "the big lebowski",
+		"Same size.mkv":   "the big lebowsky",
+	})
+
+	result, err := bundle.Import(&bundle.Bundle{
+		Version: bundle.Version,
+		Items: []bundle.Item{
+			// Matched by path, even though the contents changed
+			{Path: "Alien.mkv", Hash: hashContent("remuxed"), Size: 7, Type: database.MovieItem, Title: "Alien"},
+			// Moved, and matched by content hash instead of the file of the same size
+			{
+				Path:  "The Big Lebowski.mkv",
+				Hash:  hashContent("the big lebowski"),
+				Size:  16,
+				Type:  database.MovieItem,
+				Title: "The Big Lebowski",
+			},
+			{Path: "Missing.mkv", Hash: hashContent("missing"), Size: 7, Type: database.MovieItem, Title: "Missing"},
+		},
+	}, library)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Matched != 2 || len(result.Unmatched) != 1 || result.Unmatched[0] != "Missing.mkv" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	for name, title := range map[string]string{
+		"Alien.mkv":       "Alien",
+		"Renamed/Big.mkv": "The Big Lebowski",
+		"Same size.mkv":   "Same size.mkv",
+	} {
+		if got := getTitle(t, ids[name]); got != title {
+			t.Errorf("expected %s to be titled %q, got %q", name, title, got)
+		}
+	}
+}
+
+func TestImportSkipsItemsOfAnotherType(t *testing.T) {
+	library, ids := setupLibrary(t, map[string]string{"Alien.mkv": "alien"})
+
+	result, err := bundle.Import(&bundle.Bundle{
+		Version: bundle.Version,
+		Items:   []bundle.Item{{Path: "Alien.mkv", Type: database.ImageItem, Title: "Alien"}},
+	}, library)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Matched != 0 || getTitle(t, ids["Alien.mkv"]) != "Alien.mkv" {
+		t.Errorf("expected the item to be skipped, got %+v", result)
+	}
+}
+
+func TestImportCachesArtworkUnderItsHash(t *testing.T) {
+	setupImageCache(t)
+
+	library, ids := setupLibrary(t, map[string]string{"Alien.mkv": "alien"})
+
+	var artwork bytes.Buffer
+	if err := png.Encode(&artwork, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+
+	// The bundle claims the artwork is another image
+	claimedHash := hashContent("another image")
+
+	_, err := bundle.Import(&bundle.Bundle{
+		Version: bundle.Version,
+		Items:   []bundle.Item{{Path: "Alien.mkv", Type: database.MovieItem, Title: "Alien", Thumb: claimedHash}},
+		Artwork: map[string]bundle.ArtworkOpener{claimedHash: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(artwork.Bytes())), nil
+		}},
+	}, library)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantHash := hashContent(artwork.String())
+
+	if thumb := getItem(t, ids["Alien.mkv"]).Thumb; thumb != wantHash {
+		t.Errorf("expected the thumb to be %s, got %s", wantHash, thumb)
+	}
+
+	if !isImageCached(t, wantHash) || isImageCached(t, claimedHash) {
+		t.Errorf("expected the artwork to be cached under %s only", wantHash)
+	}
+}
+
+func TestImportSkipsUnavailableImages(t *testing.T) {
+	setupImageCache(t)
+
+	library, ids := setupLibrary(t, map[string]string{
+		"Alien.mkv":   "alien",
+		"Aliens.mkv":  "aliens",
+		"Alien 3.mkv": "alien 3",
+	})
+
+	_, err := bundle.Import(&bundle.Bundle{
+		Version: bundle.Version,
+		Items: []bundle.Item{
+			{Path: "Alien.mkv", Type: database.MovieItem, Title: "Alien", Thumb: "a", Art: "../../../etc/passwd"},
+			// Hashes of images which are neither in the bundle nor in the cache
+			{Path: "Aliens.mkv", Type: database.MovieItem, Title: "Aliens", Thumb: hashContent("aliens")},
+			// Remote images are only downloaded from public addresses
+			{Path: "Alien 3.mkv", Type: database.MovieItem, Title: "Alien 3", Art: "http://127.0.0.1/alien3.jpg"},
+		},
+	}, library)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, id := range ids {
+		if item := getItem(t, id); item.Thumb != "" || item.Art != "" {
+			t.Errorf("expected %s to have no images, got %q and %q", name, item.Thumb, item.Art)
+		}
+	}
+}
@@ -28,6 +28,76 @@ func init() {
 	// The reason is that it's anonimized, and helps us a lot
 	// to get feedback users might not submit or even know about.
 	viper.SetDefault("crash_reporting", true)
+	// Location of the SQLite database, which can be changed to run several instances side by side
+	viper.SetDefault("database.path", filepath.Join(xdg.DataHome, "meteorae/meteorae.db"))
+	// Language used for metadata missing in the library's language
+	viper.SetDefault("providers.fallback_language", "en-US")
+	// Metadata older than this is refreshed in the background
+	viper.SetDefault("providers.refresh.max_age", "720h")
+	// Metadata missing a poster or summary is refreshed more often
+	viper.SetDefault("providers.refresh.missing_max_age", "24h")
+	// How often to look for outdated metadata
+	viper.SetDefault("providers.refresh.check_interval", "1h")
+	// Minimum delay between two refreshes, to stay under provider rate limits
+	viper.SetDefault("providers.refresh.delay", "1s")
+	// Outgoing requests to metadata providers
+	viper.SetDefault("providers.http.timeout", "30s")
+	viper.SetDefault("providers.http.requests_per_second", 10) //nolint:gomnd
+	viper.SetDefault("providers.http.max_retries", 3)          //nolint:gomnd
+	viper.SetDefault("providers.http.retry_delay", "1s")
+	// Longest delay requested by providers through Retry-After headers that is honored
+	viper.SetDefault("providers.http.max_retry_after", "1m")
+	// Larger responses are rejected, in bytes
+	viper.SetDefault("providers.http.max_response_size", 64<<20) //nolint:gomnd
+	viper.SetDefault("providers.http.cache_ttl", "24h")
+	viper.SetDefault("providers.http.cache_dir", filepath.Join(xdg.CacheHome, "meteorae/http"))
+	viper.SetDefault("providers.http.user_agent", "Meteorae ( https://github.com/meteorae/meteorae-server )")
+	// The Movie Database API, used for movie metadata
+	viper.SetDefault("providers.tmdb.url", "https://api.themoviedb.org/3")
+	viper.SetDefault("providers.tmdb.image_url", "https://image.tmdb.org/t/p/original")
+	viper.SetDefault("providers.tmdb.api_key", "c9ae218044f9b20a4fcbba36d543a730") //#nosec
+	// AcoustID lookup API, or a compatible server, used to identify music from audio fingerprints.
+	// The AcoustID service needs an application key, see https://acoustid.org/new-application
+	viper.SetDefault("providers.acoustid.url", "https://api.acoustid.org/v2")
+	viper.SetDefault("providers.acoustid.api_key", "")
+	// Minimum score, from 0 to 1, of the recordings identified from fingerprints
+	viper.SetDefault("providers.acoustid.min_score", 0.8) //nolint:gomnd
+	// MusicBrainz API, or a mirror, used for music metadata
+	viper.SetDefault("providers.musicbrainz.url", "https://musicbrainz.org/ws/2")
+	// Cover Art Archive API, used for album covers
+	viper.SetDefault("providers.coverartarchive.url", "https://coverartarchive.org")
+	// AniDB HTTP API, used for anime metadata. It needs a registered client, see https://anidb.net/software/add
+	viper.SetDefault("providers.anidb.url", "http://api.anidb.net:9001/httpapi")
+	viper.SetDefault("providers.anidb.image_url", "https://cdn.anidb.net/images/main")
+	viper.SetDefault("providers.anidb.client", "")
+	viper.SetDefault("providers.anidb.client_version", 1)
+	// AniDB UDP API, used to identify anime files from their ED2K hash. It also needs an AniDB account
+	viper.SetDefault("providers.anidb.udp_address", "api.anidb.net:9000")
+	viper.SetDefault("providers.anidb.username", "")
+	viper.SetDefault("providers.anidb.password", "")
+	// AniDB bans clients sending more than a packet every 2 seconds
+	viper.SetDefault("providers.anidb.udp_packet_interval", "2s")
+	// AniDB anime title dump, imported periodically so that anime can be matched by name locally.
+	// AniDB bans clients downloading it more than once a day
+	viper.SetDefault("providers.anidb.titles_url", "https://anidb.net/api/anime-titles.dat.gz")
+	viper.SetDefault("providers.anidb.titles_import_interval", "168h")
+	// OpenSubtitles API, used to download subtitles. It needs an API key, see https://www.opensubtitles.com/consumers.
+	// An account is optional, and raises the number of daily downloads
+	viper.SetDefault("providers.opensubtitles.url", "https://api.opensubtitles.com/api/v1")
+	viper.SetDefault("providers.opensubtitles.api_key", "")
+	viper.SetDefault("providers.opensubtitles.username", "")
+	viper.SetDefault("providers.opensubtitles.password", "")
+	// Where downloaded subtitles are stored, either "media" to write them next to the media files,
+	// or "data" to keep them in the data directory. Subtitles go to the data directory when media is read-only
+	viper.SetDefault("subtitles.storage", "media")
+	viper.SetDefault("subtitles.data_dir", filepath.Join(xdg.DataHome, "meteorae/subtitles"))
+	// Maximum number of differing bits, out of 64, between the perceptual hashes of near-duplicates
+	viper.SetDefault("duplicates.max_distance", 10) //nolint:gomnd
+	// GeoNames cities dump used to find where photos were taken, instead of the bundled cities.
+	// The bundled cities have no regions, so photos only get one when this is set
+	viper.SetDefault("geocoding.geonames_path", "")
+	// Maximum size of the bundles imported into libraries, in bytes
+	viper.SetDefault("bundles.max_import_size", 4<<30) //nolint:gomnd
 
 	if err := viper.ReadInConfig(); err != nil {
 		var configFileNotFound viper.ConfigFileNotFoundError
@@ -0,0 +1,204 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// Number of titles saved per statement when importing the title dump.
+const animeTitleBatchSize = 1000
+
+// Types of anime titles, with the same values as in the AniDB title dump.
+type AnimeTitleType int8
+
+const (
+	MainAnimeTitle     AnimeTitleType = 1
+	SynonymAnimeTitle  AnimeTitleType = 2
+	ShortAnimeTitle    AnimeTitleType = 3
+	OfficialAnimeTitle AnimeTitleType = 4
+)
+
+// A title of an anime, from the AniDB title dump. Anime have a main title, and any number of
+// official titles, synonyms and short titles, in various languages.
+// Titles are stored locally, so anime can be matched by name without calling AniDB.
+type AnimeTitle struct {
+	ID       uint64         `gorm:"primary_key" json:"id"`
+	AnimeID  int64          `gorm:"not null;index" json:"animeId"`
+	Type     AnimeTitleType `gorm:"not null" json:"type"`
+	Language string         `json:"language"`
+	Title    string         `gorm:"not null;index" json:"title"`
+	// When the dump holding the title was imported.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Replaces all the anime titles with the ones from a new title dump.
+func ReplaceAnimeTitles(titles []AnimeTitle) error {
+	err := db.Transaction(func(transaction *gorm.DB) error {
+		if result := transaction.Where("1 = 1").Delete(&AnimeTitle{}); result.Error != nil {
+			return result.Error
+		}
+
+		if len(titles) == 0 {
+			return nil
+		}
+
+		return transaction.CreateInBatches(&titles, animeTitleBatchSize).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to replace anime titles: %w", err)
+	}
+
+	return nil
+}
+
+// Returns when the anime titles were last imported, or the zero time if they never were.
+func GetAnimeTitlesImportedAt() (time.Time, error) {
+	var title AnimeTitle
+
+	result := db.Order("created_at DESC").Limit(1).Find(&title)
+	if result.Error != nil {
+		return time.Time{}, fmt.Errorf("failed to get anime titles import date: %w", result.Error)
+	}
+
+	return title.CreatedAt, nil
+}
+
+// Words shorter than this only select candidates when the title has no longer word, since most titles hold them.
+const minSearchWordLength = 3
+
+// Returns the titles sharing a word with the given one, best first as ranked by the given similarity function,
+// so that differences in punctuation or word order don't matter. Every title sharing a word is scored, and
+// only the best ones are kept.
+func SearchAnimeTitles(title string, similarity func(string) float64, limit int) ([]AnimeTitle, error) {
+	words := strings.FieldsFunc(title, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	longWords := make([]string, 0, len(words))
+
+	for _, word := range words {
+		if utf8.RuneCountInString(word) >= minSearchWordLength {
+			longWords = append(longWords, word)
+		}
+	}
+
+	if len(longWords) > 0 {
+		words = longWords
+	}
+
+	titles := []AnimeTitle{}
+
+	if len(words) == 0 {
+		return titles, nil
+	}
+
+	// Words only hold letters and numbers, so they can't hold LIKE wildcards
+	condition := db.Where("title LIKE ?", "%"+words[0]+"%")
+	for _, word := range words[1:] {
+		condition = condition.Or("title LIKE ?", "%"+word+"%")
+	}
+
+	rows, err := db.Model(&AnimeTitle{}).Where(condition).Rows()
+	if err != nil {
+		return nil, fmt.Errorf("failed to search anime titles: %w", err)
+	}
+	defer rows.Close()
+
+	scores := make(map[uint64]float64)
+
+	for rows.Next() {
+		var candidate AnimeTitle
+		if err := db.ScanRows(rows, &candidate); err != nil {
+			return nil, fmt.Errorf("failed to read anime title: %w", err)
+		}
+
+		scores[candidate.ID] = similarity(candidate.Title)
+		titles = append(titles, candidate)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search anime titles: %w", err)
+	}
+
+	sort.SliceStable(titles, func(i, j int) bool {
+		if scores[titles[i].ID] != scores[titles[j].ID] {
+			return scores[titles[i].ID] > scores[titles[j].ID]
+		}
+
+		if titles[i].AnimeID != titles[j].AnimeID {
+			return titles[i].AnimeID < titles[j].AnimeID
+		}
+
+		return titles[i].Type < titles[j].Type
+	})
+
+	if len(titles) > limit {
+		titles = titles[:limit]
+	}
+
+	return titles, nil
+}
+
+// Returns all the titles of the given anime, main title first.
+func GetAnimeTitles(animeID int64) ([]AnimeTitle, error) {
+	var titles []AnimeTitle
+
+	result := db.Where("anime_id = ?", animeID).Order("type, id").Find(&titles)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get anime titles: %w", result.Error)
+	}
+
+	return titles, nil
+}
+
+// Returns the show with the given title in a library, creating it if it doesn't exist yet.
+func GetOrCreateAnimeShow(library Library, title, sortTitle string) (*ItemMetadata, error) {
+	var show ItemMetadata
+
+	result := db.
+		Where("library_id = ? AND type = ? AND title = ? COLLATE NOCASE", library.ID, AnimeShowItem, title).
+		First(&show)
+	if result.Error == nil {
+		return &show, nil
+	}
+
+	if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get anime show: %w", result.Error)
+	}
+
+	show = ItemMetadata{
+		Title:     title,
+		SortTitle: sortTitle,
+		Type:      AnimeShowItem,
+		LibraryID: library.ID,
+		Library:   library,
+	}
+
+	// Episodes of a show are usually scanned at once, so the key keeps them from creating the show twice
+	key := fmt.Sprintf("animeShow:%d:%s", library.ID, strings.ToLower(title))
+
+	if _, err := createUniqueItem(db, &show, key); err != nil {
+		return nil, fmt.Errorf("failed to create anime show: %w", err)
+	}
+
+	return &show, nil
+}
+
+func CreateAnime(animeInfo *ItemMetadata) error {
+	if result := db.Create(animeInfo); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func UpdateAnime(animeInfo *ItemMetadata) error {
+	return UpdateItem(animeInfo)
+}
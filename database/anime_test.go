@@ -0,0 +1,112 @@
+package database_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/internal/databasetest"
+)
+
+func TestSearchAnimeTitles(t *testing.T) {
+	databasetest.Setup(t)
+
+	// Many titles share the longest word, while the searched anime doesn't hold it and comes last
+	titles := make([]database.AnimeTitle, 0, 601)
+	for animeID := 1; animeID <= 600; animeID++ {
+		titles = append(titles, database.AnimeTitle{
+			AnimeID: int64(animeID),
+			Type:    database.MainAnimeTitle,
+			Title:   fmt.Sprintf("Mobile Suit Gundam %d", animeID),
+		})
+	}
+
+	titles = append(titles, database.AnimeTitle{
+		AnimeID: 9999,
+		Type:    database.SynonymAnimeTitle,
+		Title:   "Gundam: The Witch from Mercury",
+	})
+
+	if err := database.ReplaceAnimeTitles(titles); err != nil {
+		t.Fatal(err)
+	}
+
+	query := "Mobile Suit Gundam: Witch"
+	similarity := func(title string) float64 {
+		if strings.Contains(title, "Witch") {
+			return 1
+		}
+
+		return 0.5
+	}
+
+	found, err := database.SearchAnimeTitles(query, similarity, 10)
+	if err != nil {
+		t.Fatalf("SearchAnimeTitles() error = %v", err)
+	}
+
+	if len(found) != 10 || found[0].AnimeID != 9999 {
+		t.Fatalf("SearchAnimeTitles() = %d titles starting with %v, want 10 starting with anime 9999", len(found), found[0])
+	}
+
+	// Ties are sorted by anime
+	if found[1].AnimeID != 1 || found[2].AnimeID != 2 {
+		t.Errorf("SearchAnimeTitles() ranked anime %d and %d after the best match, want 1 and 2", found[1].AnimeID, found[2].AnimeID)
+	}
+
+	none, err := database.SearchAnimeTitles("Macross", similarity, 10)
+	if err != nil || len(none) != 0 {
+		t.Errorf("SearchAnimeTitles() = %v, %v, want no titles", none, err)
+	}
+}
+
+func TestGetOrCreateAnimeShowConcurrently(t *testing.T) {
+	databasetest.Setup(t)
+
+	library, _, err := database.CreateLibrary("Anime", "en", "animeTV", []string{t.TempDir()}, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const workers = 8
+
+	var waitGroup sync.WaitGroup
+
+	ids := make([]uint64, workers)
+	errs := make([]error, workers)
+
+	for worker := 0; worker < workers; worker++ {
+		waitGroup.Add(1)
+
+		go func(worker int) {
+			defer waitGroup.Done()
+
+			// Release groups don't agree on the case of titles
+			title := "Cowboy Bebop"
+			if worker%2 == 1 {
+				title = "cowboy bebop"
+			}
+
+			show, err := database.GetOrCreateAnimeShow(*library, title, title)
+			if err == nil {
+				ids[worker] = show.ID
+			}
+
+			errs[worker] = err
+		}(worker)
+	}
+
+	waitGroup.Wait()
+
+	for worker := range ids {
+		if errs[worker] != nil {
+			t.Fatalf("GetOrCreateAnimeShow() error = %v", errs[worker])
+		}
+
+		if ids[worker] != ids[0] {
+			t.Errorf("GetOrCreateAnimeShow() returned shows %d and %d, want a single show", ids[0], ids[worker])
+		}
+	}
+}
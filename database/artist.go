@@ -0,0 +1,166 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Links an item, such as a music video or an album, to one of its artists.
+// Artists are stored as PersonItem or GroupItem metadata, and are shared across libraries.
+type ItemArtist struct {
+	ID             uint64    `gorm:"primary_key" json:"id"`
+	ItemMetadataID uint64    `gorm:"not null;uniqueIndex:idx_item_artist"`
+	ArtistID       uint64    `gorm:"not null;uniqueIndex:idx_item_artist;index"`
+	Index          int       `gorm:"not null" json:"index"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// Returns the artist with the given name, creating it if it doesn't exist yet.
+// New artists are created as people, since we can't tell bands apart from a name alone.
+func GetOrCreateArtist(name, sortTitle string) (*ItemMetadata, error) {
+	var artist ItemMetadata
+
+	result := db.
+		Where("type IN ? AND title = ? COLLATE NOCASE", []ItemType{PersonItem, GroupItem}, name).
+		First(&artist)
+	if result.Error == nil {
+		return &artist, nil
+	}
+
+	if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get artist: %w", result.Error)
+	}
+
+	artist = ItemMetadata{
+		Title:     name,
+		SortTitle: sortTitle,
+		Type:      PersonItem,
+	}
+
+	if _, err := createUniqueItem(db, &artist, "artist:"+strings.ToLower(name)); err != nil {
+		return nil, fmt.Errorf("failed to create artist: %w", err)
+	}
+
+	return &artist, nil
+}
+
+// Returns the album with the given title by the given artist in a library, creating it if it doesn't exist yet.
+// New albums are linked to the artist, unless it is 0 for albums without artist.
+func GetOrCreateMusicAlbum(library Library, title, sortTitle string, artistID uint64) (*ItemMetadata, error) {
+	var album ItemMetadata
+
+	query := db.Where("item_metadata.library_id = ? AND item_metadata.type = ? AND item_metadata.title = ? COLLATE NOCASE",
+		library.ID, MusicAlbumItem, title)
+
+	if artistID != 0 {
+		query = query.
+			Joins("JOIN item_artists ON item_artists.item_metadata_id = item_metadata.id").
+			Where("item_artists.artist_id = ?", artistID)
+	}
+
+	result := query.First(&album)
+	if result.Error == nil {
+		return &album, nil
+	}
+
+	if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get album: %w", result.Error)
+	}
+
+	album = ItemMetadata{
+		Title:     title,
+		SortTitle: sortTitle,
+		Type:      MusicAlbumItem,
+		LibraryID: library.ID,
+		Library:   library,
+	}
+
+	key := fmt.Sprintf("musicAlbum:%d:%d:%s", library.ID, artistID, strings.ToLower(title))
+
+	created, err := createUniqueItem(db, &album, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create album: %w", err)
+	}
+
+	if created && artistID != 0 {
+		if err := AddItemArtist(album.ID, artistID, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	return &album, nil
+}
+
+// Links the given item to an artist. Linking the same artist twice is a no-op.
+func AddItemArtist(itemID, artistID uint64, index int) error {
+	itemArtist := ItemArtist{
+		ItemMetadataID: itemID,
+		ArtistID:       artistID,
+		Index:          index,
+	}
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&itemArtist)
+	if result.Error != nil {
+		return fmt.Errorf("failed to link artist: %w", result.Error)
+	}
+
+	return nil
+}
+
+// Returns the artists of the given item, in credit order.
+func GetArtistsFromItem(itemID string) ([]*ItemMetadata, error) {
+	var artists []*ItemMetadata
+
+	result := db.
+		Joins("JOIN item_artists ON item_artists.artist_id = item_metadata.id").
+		Where("item_artists.item_metadata_id = ?", itemID).
+		Order("item_artists.`index`").
+		Find(&artists)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return artists, nil
+}
+
+// Returns the items of the given type linked to an artist.
+func GetItemsFromArtist(artistID string, itemType ItemType, limit, offset *int64) ([]*ItemMetadata, error) {
+	var items []*ItemMetadata
+
+	result := db.
+		Limit(int(*limit)).
+		Offset(int(*offset)).
+		Joins("JOIN item_artists ON item_artists.item_metadata_id = item_metadata.id").
+		Where("item_artists.artist_id = ? AND item_metadata.type = ? AND item_metadata.hidden = ?",
+			artistID, itemType, false).
+		Order("item_metadata.release_date, item_metadata.sort_title").
+		Find(&items)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return items, nil
+}
+
+// Returns the number of items of the given type linked to an artist.
+func GetItemsCountFromArtist(artistID string, itemType ItemType) (*int64, error) {
+	var count int64
+
+	result := db.
+		Model(&ItemMetadata{}).
+		Joins("JOIN item_artists ON item_artists.item_metadata_id = item_metadata.id").
+		Where("item_artists.artist_id = ? AND item_metadata.type = ? AND item_metadata.hidden = ?",
+			artistID, itemType, false).
+		Count(&count)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &count, nil
+}
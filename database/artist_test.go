@@ -0,0 +1,186 @@
+package database_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/internal/databasetest"
+	"gorm.io/gorm"
+)
+
+func TestGetOrCreateArtistConcurrently(t *testing.T) {
+	databasetest.Setup(t)
+
+	const workers = 8
+
+	var waitGroup sync.WaitGroup
+
+	ids := make([]uint64, workers)
+	errs := make([]error, workers)
+
+	for worker := 0; worker < workers; worker++ {
+		waitGroup.Add(1)
+
+		go func(worker int) {
+			defer waitGroup.Done()
+
+			artist, err := database.GetOrCreateArtist("Daft Punk", "Daft Punk")
+			if err == nil {
+				ids[worker] = artist.ID
+			}
+
+			errs[worker] = err
+		}(worker)
+	}
+
+	waitGroup.Wait()
+
+	for worker := range ids {
+		if errs[worker] != nil {
+			t.Fatalf("GetOrCreateArtist() error = %v", errs[worker])
+		}
+
+		if ids[worker] != ids[0] {
+			t.Errorf("GetOrCreateArtist() returned artists %d and %d, want a single artist", ids[0], ids[worker])
+		}
+	}
+
+	artist, err := database.GetOrCreateArtist("daft punk", "daft punk")
+	if err != nil || artist.ID != ids[0] {
+		t.Errorf("GetOrCreateArtist() = %+v, %v, want artist %d whatever the case", artist, err, ids[0])
+	}
+}
+
+func TestGetOrCreateMusicAlbum(t *testing.T) {
+	databasetest.Setup(t)
+
+	library := database.Library{Name: "Music", Type: database.MusicLibrary}
+
+	artist, err := database.GetOrCreateArtist("Daft Punk", "Daft Punk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := database.GetOrCreateArtist("Justice", "Justice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	album, err := database.GetOrCreateMusicAlbum(library, "Discovery", "Discovery", artist.ID)
+	if err != nil {
+		t.Fatalf("GetOrCreateMusicAlbum() error = %v", err)
+	}
+
+	again, err := database.GetOrCreateMusicAlbum(library, "discovery", "discovery", artist.ID)
+	if err != nil || again.ID != album.ID {
+		t.Errorf("GetOrCreateMusicAlbum() = %+v, %v, want album %d", again, err, album.ID)
+	}
+
+	// Albums are told apart by artist, like self-titled albums
+	homonym, err := database.GetOrCreateMusicAlbum(library, "Discovery", "Discovery", other.ID)
+	if err != nil || homonym.ID == album.ID {
+		t.Errorf("GetOrCreateMusicAlbum() = %+v, %v, want another album for another artist", homonym, err)
+	}
+
+	limit, offset := int64(10), int64(0)
+
+	albums, err := database.GetItemsFromArtist(fmtID(artist.ID), database.MusicAlbumItem, &limit, &offset)
+	if err != nil || len(albums) != 1 || albums[0].ID != album.ID {
+		t.Errorf("GetItemsFromArtist() = %+v, %v, want album %d", albums, err, album.ID)
+	}
+}
+
+func createMusicVideoLibrary(t *testing.T, name string) *database.Library {
+	t.Helper()
+
+	library, _, err := database.CreateLibrary(name, "en", "musicVideo", []string{t.TempDir()}, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return library
+}
+
+func createMusicVideo(t *testing.T, library *database.Library, title string) *database.ItemMetadata {
+	t.Helper()
+
+	musicVideo := database.ItemMetadata{
+		Title:     title,
+		Type:      database.MusicVideoItem,
+		LibraryID: library.ID,
+		MediaPart: database.MediaPart{FilePath: "/" + title + ".mp4"},
+	}
+
+	if err := database.CreateMusicVideo(&musicVideo); err != nil {
+		t.Fatal(err)
+	}
+
+	return &musicVideo
+}
+
+func TestGetSharedItemLibrary(t *testing.T) {
+	databasetest.Setup(t)
+
+	first := createMusicVideoLibrary(t, "Music Videos")
+	second := createMusicVideoLibrary(t, "Concerts")
+
+	artist, err := database.GetOrCreateArtist("Daft Punk", "Daft Punk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := database.GetSharedItemLibrary(artist.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("GetSharedItemLibrary() error = %v, want %v before any link", err, gorm.ErrRecordNotFound)
+	}
+
+	for _, musicVideo := range []*database.ItemMetadata{
+		createMusicVideo(t, first, "Around the World"),
+		createMusicVideo(t, second, "Alive 2007"),
+	} {
+		if err := database.AddItemArtist(musicVideo.ID, artist.ID, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	library, err := database.GetSharedItemLibrary(artist.ID)
+	if err != nil || library.ID != first.ID {
+		t.Errorf("GetSharedItemLibrary() = %+v, %v, want the library of the first music video", library, err)
+	}
+}
+
+func TestGetArtistsFromItem(t *testing.T) {
+	databasetest.Setup(t)
+
+	musicVideo := createMusicVideo(t, createMusicVideoLibrary(t, "Music Videos"), "Around the World")
+
+	artist, err := database.GetOrCreateArtist("Daft Punk", "Daft Punk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Linking the same artist twice is a no-op
+	for i := 0; i < 2; i++ {
+		if err := database.AddItemArtist(musicVideo.ID, artist.ID, 0); err != nil {
+			t.Fatalf("AddItemArtist() error = %v", err)
+		}
+	}
+
+	artists, err := database.GetArtistsFromItem(fmtID(musicVideo.ID))
+	if err != nil || len(artists) != 1 || artists[0].ID != artist.ID {
+		t.Errorf("GetArtistsFromItem() = %+v, %v, want only %s", artists, err, artist.Title)
+	}
+
+	limit, offset := int64(10), int64(0)
+
+	items, err := database.GetItemsFromArtist(fmtID(artist.ID), database.MusicVideoItem, &limit, &offset)
+	if err != nil || len(items) != 1 || items[0].ID != musicVideo.ID {
+		t.Errorf("GetItemsFromArtist() = %+v, %v, want only %s", items, err, musicVideo.Title)
+	}
+
+	count, err := database.GetItemsCountFromArtist(fmtID(artist.ID), database.MusicVideoItem)
+	if err != nil || *count != 1 {
+		t.Errorf("GetItemsCountFromArtist() = %v, %v, want 1", count, err)
+	}
+}
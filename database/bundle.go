@@ -0,0 +1,151 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Returns the items of a library backed by a file or directory, with their media part.
+func GetItemsWithMediaPartFromLibrary(libraryID uint64) ([]*ItemMetadata, error) {
+	var items []*ItemMetadata
+
+	result := db.
+		Preload("MediaPart").
+		Where("library_id = ?", libraryID).
+		Where("id IN (?)", db.Model(&MediaPart{}).Select("item_metadata_id")).
+		Order("id").
+		Find(&items)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return items, nil
+}
+
+// Returns the items of a library backed by a file of the given size, with their media part.
+func GetItemsByMediaPartSize(libraryID uint64, size int64) ([]*ItemMetadata, error) {
+	var items []*ItemMetadata
+
+	result := db.
+		Preload("MediaPart").
+		Where("library_id = ?", libraryID).
+		Where("id IN (?)", db.Model(&MediaPart{}).Select("item_metadata_id").Where("size = ?", size)).
+		Order("id").
+		Find(&items)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return items, nil
+}
+
+// Returns the tag assignments of an item, with their tag.
+func GetItemTagsFromItem(itemID uint64) ([]ItemTag, error) {
+	var itemTags []ItemTag
+
+	if result := db.Preload("Tag").Where("item_metadata_id = ?", itemID).Find(&itemTags); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return itemTags, nil
+}
+
+// Returns the collection memberships of an item, with their collection.
+func GetCollectionMembersFromItem(itemID uint64) ([]CollectionMember, error) {
+	var members []CollectionMember
+
+	result := db.Preload("Collection").Where("item_metadata_id = ?", itemID).Find(&members)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return members, nil
+}
+
+// Returns the user collection with the given title.
+func GetUserCollectionByTitle(title string) (*ItemMetadata, error) {
+	var collection ItemMetadata
+
+	result := db.
+		Where("type = ? AND match_provider = ? AND title = ?", CollectionItem, "", title).
+		Order("id").
+		First(&collection)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &collection, nil
+}
+
+// Saves an item imported from a metadata bundle, including its locked fields.
+// Its external identifiers and tags replace the saved ones, unless they are nil,
+// and it is added to the given collections, at the given positions.
+func ImportItem(item *ItemMetadata, itemTags []ItemTag, members []CollectionMember) error {
+	err := db.Transaction(func(transaction *gorm.DB) error {
+		if result := transaction.Omit(clause.Associations).Save(item); result.Error != nil {
+			return result.Error
+		}
+
+		if item.ExternalIdentifiers != nil {
+			if err := setExternalIdentifiers(transaction, item.ID, item.ExternalIdentifiers); err != nil {
+				return err
+			}
+		}
+
+		if itemTags != nil {
+			if err := setItemTags(transaction, item.ID, itemTags); err != nil {
+				return err
+			}
+		}
+
+		if len(members) == 0 {
+			return nil
+		}
+
+		for index := range members {
+			members[index].ID = 0
+			members[index].ItemMetadataID = item.ID
+		}
+
+		return transaction.
+			Omit(clause.Associations).
+			Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "collection_id"}, {Name: "item_metadata_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"index"}),
+			}).
+			Create(&members).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to import item: %w", err)
+	}
+
+	return nil
+}
+
+// Replaces all the tags of an item, keeping whether they were assigned by users. Tags must already exist.
+func setItemTags(transaction *gorm.DB, itemID uint64, itemTags []ItemTag) error {
+	if result := transaction.Where("item_metadata_id = ?", itemID).Delete(&ItemTag{}); result.Error != nil {
+		return fmt.Errorf("failed to delete tags: %w", result.Error)
+	}
+
+	if len(itemTags) == 0 {
+		return nil
+	}
+
+	for index := range itemTags {
+		itemTags[index].ID = 0
+		itemTags[index].ItemMetadataID = itemID
+	}
+
+	result := transaction.
+		Omit(clause.Associations).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&itemTags)
+	if result.Error != nil {
+		return fmt.Errorf("failed to create tags: %w", result.Error)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// A chapter of a long-form item, like an audiobook or a podcast episode.
+// Timestamps are in milliseconds, relative to the start of the item, even when it spans multiple files.
+type Chapter struct {
+	ID             uint64    `gorm:"primary_key" json:"id"`
+	ItemMetadataID uint64    `gorm:"not null;index"`
+	Index          int64     `gorm:"not null" json:"index"`
+	Title          string    `json:"title"`
+	StartTime      int64     `gorm:"not null" json:"startTime"`
+	EndTime        int64     `gorm:"not null" json:"endTime"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// Replaces the chapters of the given item.
+func SetChapters(itemID uint64, chapters []Chapter) error {
+	err := db.Transaction(func(transaction *gorm.DB) error {
+		if result := transaction.Where("item_metadata_id = ?", itemID).Delete(&Chapter{}); result.Error != nil {
+			return result.Error
+		}
+
+		if len(chapters) == 0 {
+			return nil
+		}
+
+		for index := range chapters {
+			chapters[index].ItemMetadataID = itemID
+		}
+
+		if result := transaction.Create(&chapters); result.Error != nil {
+			return result.Error
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set chapters: %w", err)
+	}
+
+	return nil
+}
+
+// Returns the chapters of the given item, in order.
+func GetChaptersFromItem(itemID string) ([]*Chapter, error) {
+	var chapters []*Chapter
+
+	result := db.Where("item_metadata_id = ?", itemID).Order("`index`").Find(&chapters)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return chapters, nil
+}
@@ -0,0 +1,53 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/internal/databasetest"
+)
+
+func TestSetChapters(t *testing.T) {
+	databasetest.Setup(t)
+
+	book := database.ItemMetadata{Title: "Dune", Type: database.AudiobookItem}
+	if err := database.CreateAudiobook(&book); err != nil {
+		t.Fatal(err)
+	}
+
+	err := database.SetChapters(book.ID, []database.Chapter{
+		{Index: 1, Title: "Part Two", StartTime: 1000, EndTime: 2000},
+		{Index: 0, Title: "Part One", StartTime: 0, EndTime: 1000},
+	})
+	if err != nil {
+		t.Fatalf("SetChapters() error = %v", err)
+	}
+
+	chapters, err := database.GetChaptersFromItem(fmtID(book.ID))
+	if err != nil {
+		t.Fatalf("GetChaptersFromItem() error = %v", err)
+	}
+
+	if len(chapters) != 2 || chapters[0].Title != "Part One" || chapters[1].Title != "Part Two" {
+		t.Fatalf("GetChaptersFromItem() = %+v, want Part One then Part Two", chapters)
+	}
+
+	// Chapters are replaced, rather than added to the previous ones
+	if err := database.SetChapters(book.ID, []database.Chapter{{Title: "Whole Book", EndTime: 2000}}); err != nil {
+		t.Fatalf("SetChapters() error = %v", err)
+	}
+
+	chapters, err = database.GetChaptersFromItem(fmtID(book.ID))
+	if err != nil || len(chapters) != 1 || chapters[0].Title != "Whole Book" {
+		t.Errorf("GetChaptersFromItem() = %+v, %v, want only Whole Book", chapters, err)
+	}
+
+	if err := database.SetChapters(book.ID, nil); err != nil {
+		t.Fatalf("SetChapters() error = %v", err)
+	}
+
+	chapters, err = database.GetChaptersFromItem(fmtID(book.ID))
+	if err != nil || len(chapters) != 0 {
+		t.Errorf("GetChaptersFromItem() = %+v, %v, want no chapters", chapters, err)
+	}
+}
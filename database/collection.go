@@ -0,0 +1,426 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errInvalidSortOrder = errors.New("invalid sort order")
+
+// Describes how the items of a collection are sorted.
+type CollectionSortOrder string
+
+const (
+	// Items are sorted in the order chosen by users.
+	ManualSortOrder CollectionSortOrder = "manual"
+	// Items are sorted by release date, oldest first.
+	ReleaseDateSortOrder CollectionSortOrder = "releaseDate"
+	// Items are sorted by sort title.
+	TitleSortOrder CollectionSortOrder = "title"
+)
+
+func (s CollectionSortOrder) String() string {
+	return string(s)
+}
+
+func (s *CollectionSortOrder) UnmarshalText(text []byte) error {
+	switch sortOrder := CollectionSortOrder(text); sortOrder {
+	case ManualSortOrder, ReleaseDateSortOrder, TitleSortOrder:
+		*s = sortOrder
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", errInvalidSortOrder, text)
+}
+
+type CollectionExtraInfo struct {
+	SortOrder CollectionSortOrder `json:"sortOrder"`
+}
+
+// Adds an item to a collection. Collections are items too, and can hold items from any library.
+type CollectionMember struct {
+	ID           uint64 `gorm:"primary_key" json:"id"`
+	CollectionID uint64 `gorm:"not null;uniqueIndex:idx_collection_member"`
+	// Only used to pass collections from providers, see Provider.GetMetadata.
+	Collection     ItemMetadata `gorm:"foreignKey:CollectionID" json:"-"`
+	ItemMetadataID uint64       `gorm:"not null;uniqueIndex:idx_collection_member;index"`
+	// Position of the item in collections sorted manually.
+	Index     int       `gorm:"not null" json:"index"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Returns the sort order of a collection, defaulting to manual.
+func GetCollectionSortOrder(collection *ItemMetadata) CollectionSortOrder {
+	var extraInfo CollectionExtraInfo
+
+	if len(collection.ExtraInfo) > 0 {
+		if err := json.Unmarshal(collection.ExtraInfo, &extraInfo); err != nil {
+			return ManualSortOrder
+		}
+	}
+
+	if extraInfo.SortOrder == "" {
+		return ManualSortOrder
+	}
+
+	return extraInfo.SortOrder
+}
+
+// Sets the sort order of a collection. The collection isn't saved.
+func SetCollectionSortOrder(collection *ItemMetadata, sortOrder CollectionSortOrder) error {
+	extraInfo, err := json.Marshal(CollectionExtraInfo{SortOrder: sortOrder})
+	if err != nil {
+		return fmt.Errorf("failed to encode collection information: %w", err)
+	}
+
+	collection.ExtraInfo = extraInfo
+
+	return nil
+}
+
+// Returns whether a collection was created by a user, rather than imported from a metadata provider.
+func IsUserCollection(collection *ItemMetadata) bool {
+	return collection.MatchProvider == ""
+}
+
+func CreateCollection(collection *ItemMetadata) error {
+	collection.Type = CollectionItem
+
+	if result := db.Create(collection); result.Error != nil {
+		return fmt.Errorf("failed to create collection: %w", result.Error)
+	}
+
+	return nil
+}
+
+// Returns the collection matching any of the given external identifiers, creating it if it doesn't exist yet.
+func GetOrCreateCollection(collection *ItemMetadata) (*ItemMetadata, error) {
+	for _, identifier := range collection.ExternalIdentifiers {
+		var existing ItemMetadata
+
+		result := db.
+			Joins("JOIN external_identifiers ON external_identifiers.item_metadata_id = item_metadata.id").
+			Where("item_metadata.type = ?", CollectionItem).
+			Where("external_identifiers.identifier_type = ? AND external_identifiers.identifier = ?",
+				identifier.IdentifierType, identifier.Identifier).
+			First(&existing)
+		if result.Error == nil {
+			return &existing, nil
+		}
+
+		if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to get collection: %w", result.Error)
+		}
+	}
+
+	created := ItemMetadata{
+		Title:         collection.Title,
+		SortTitle:     collection.SortTitle,
+		Summary:       collection.Summary,
+		Thumb:         collection.Thumb,
+		Art:           collection.Art,
+		Type:          CollectionItem,
+		MatchProvider: collection.MatchProvider,
+		MatchID:       collection.MatchID,
+		ExtraInfo:     collection.ExtraInfo,
+	}
+
+	err := db.Transaction(func(transaction *gorm.DB) error {
+		if len(collection.ExternalIdentifiers) == 0 {
+			return transaction.Create(&created).Error
+		}
+
+		// Concurrent scans can find the same collection at once, so the first identifier keeps it from being
+		// created twice
+		identifier := collection.ExternalIdentifiers[0]
+		key := fmt.Sprintf("collection:%d:%s", identifier.IdentifierType, identifier.Identifier)
+
+		isCreated, err := createUniqueItem(transaction, &created, key)
+		if err != nil || !isCreated {
+			return err
+		}
+
+		return setExternalIdentifiers(transaction, created.ID, collection.ExternalIdentifiers)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create collection: %w", err)
+	}
+
+	return &created, nil
+}
+
+// Replaces the provider collections of an item with the given ones. Collections must already exist.
+// Memberships in user collections are kept.
+func setProviderCollections(transaction *gorm.DB, itemID uint64, members []CollectionMember) error {
+	providerCollections := transaction.
+		Model(&ItemMetadata{}).
+		Select("id").
+		Where("type = ? AND match_provider != ?", CollectionItem, "")
+
+	result := transaction.
+		Where("item_metadata_id = ? AND collection_id IN (?)", itemID, providerCollections).
+		Delete(&CollectionMember{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete collection members: %w", result.Error)
+	}
+
+	if len(members) == 0 {
+		return nil
+	}
+
+	for index := range members {
+		members[index].ID = 0
+		members[index].ItemMetadataID = itemID
+	}
+
+	result = transaction.
+		Omit(clause.Associations).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&members)
+	if result.Error != nil {
+		return fmt.Errorf("failed to create collection members: %w", result.Error)
+	}
+
+	return nil
+}
+
+// Appends the given items to a collection. Items already in the collection are skipped.
+func AddItemsToCollection(collectionID uint64, itemIDs []uint64) error {
+	err := db.Transaction(func(transaction *gorm.DB) error {
+		var lastIndex int
+
+		result := transaction.
+			Model(&CollectionMember{}).
+			Select("COALESCE(MAX(`index`), -1)").
+			Where("collection_id = ?", collectionID).
+			Scan(&lastIndex)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		for _, itemID := range itemIDs {
+			lastIndex++
+
+			result := transaction.Clauses(clause.OnConflict{DoNothing: true}).Create(&CollectionMember{
+				CollectionID:   collectionID,
+				ItemMetadataID: itemID,
+				Index:          lastIndex,
+			})
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add items to collection: %w", err)
+	}
+
+	return nil
+}
+
+// Removes the given items from a collection.
+func RemoveItemsFromCollection(collectionID uint64, itemIDs []uint64) error {
+	result := db.
+		Where("collection_id = ? AND item_metadata_id IN ?", collectionID, itemIDs).
+		Delete(&CollectionMember{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to remove items from collection: %w", result.Error)
+	}
+
+	return nil
+}
+
+// Moves an item of a collection to the given position, shifting the items after it.
+func MoveCollectionItem(collectionID, itemID uint64, index int) error {
+	err := db.Transaction(func(transaction *gorm.DB) error {
+		var members []CollectionMember
+
+		result := transaction.
+			Where("collection_id = ?", collectionID).
+			Order("`index`, id").
+			Find(&members)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		position := -1
+
+		for i, member := range members {
+			if member.ItemMetadataID == itemID {
+				position = i
+			}
+		}
+
+		if position < 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		moved := members[position]
+		members = append(members[:position], members[position+1:]...)
+
+		if index < 0 {
+			index = 0
+		}
+
+		if index > len(members) {
+			index = len(members)
+		}
+
+		members = append(members[:index], append([]CollectionMember{moved}, members[index:]...)...)
+
+		for i, member := range members {
+			result := transaction.Model(&CollectionMember{ID: member.ID}).UpdateColumn("index", i)
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to move collection item: %w", err)
+	}
+
+	return nil
+}
+
+// Deletes a collection, along with its memberships and relations. The items themselves are kept.
+func DeleteCollection(collectionID uint64) error {
+	err := db.Transaction(func(transaction *gorm.DB) error {
+		if result := transaction.Where("collection_id = ?", collectionID).Delete(&CollectionMember{}); result.Error != nil {
+			return result.Error
+		}
+
+		result := transaction.
+			Where("source_id = ? OR target_id = ?", collectionID, collectionID).
+			Delete(&ItemRelation{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if err := setExternalIdentifiers(transaction, collectionID, nil); err != nil {
+			return err
+		}
+
+		return transaction.Delete(&ItemMetadata{}, collectionID).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+
+	return nil
+}
+
+// Returns the items of a collection matching the rating filter, in the given order, or in the sort order of
+// the collection when it is empty. The index of items is their position in the collection.
+func GetCollectionItems(
+	collectionID string,
+	collectionSortOrder CollectionSortOrder,
+	sortOrder ChildSortOrder,
+	ratingFilter RatingFilter,
+	limit, offset *int64,
+) ([]*ItemMetadata, error) {
+	var items []*ItemMetadata
+
+	query := db.
+		Preload("Library").
+		Joins("JOIN collection_members ON collection_members.item_metadata_id = item_metadata.id").
+		Where("collection_members.collection_id = ? AND item_metadata.hidden = ?", collectionID, false)
+	query = filterOnUserRating(query, ratingFilter)
+
+	switch sortOrder {
+	case "":
+		query = sortCollectionItems(query, collectionSortOrder)
+	case IndexChildSortOrder:
+		query = sortCollectionItems(query, ManualSortOrder)
+	case TitleChildSortOrder, CaptureDateChildSortOrder, UserRatingChildSortOrder:
+		query = sortItems(query, sortOrder)
+	}
+
+	result := query.
+		Limit(int(*limit)).
+		Offset(int(*offset)).
+		Find(&items)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return items, nil
+}
+
+func sortCollectionItems(query *gorm.DB, sortOrder CollectionSortOrder) *gorm.DB {
+	switch sortOrder {
+	case ReleaseDateSortOrder:
+		return query.Order("item_metadata.release_date, item_metadata.sort_title")
+	case TitleSortOrder:
+		return query.Order("item_metadata.sort_title, item_metadata.release_date")
+	case ManualSortOrder:
+	}
+
+	return query.Order("collection_members.`index`, item_metadata.id")
+}
+
+// Returns the number of items of a collection matching the rating filter.
+func GetCollectionItemsCount(collectionID string, ratingFilter RatingFilter) (*int64, error) {
+	var count int64
+
+	query := db.Model(&CollectionMember{}).
+		Joins("JOIN item_metadata ON item_metadata.id = collection_members.item_metadata_id").
+		Where("collection_members.collection_id = ? AND item_metadata.hidden = ?", collectionID, false)
+
+	if result := filterOnUserRating(query, ratingFilter).Count(&count); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &count, nil
+}
+
+// Returns all the collections, sorted by title.
+// When userDefined is set, only the user or provider collections are returned.
+func GetCollections(userDefined *bool, limit, offset *int64) ([]*ItemMetadata, error) {
+	var collections []*ItemMetadata
+
+	result := filterCollections(userDefined).
+		Order("sort_title").
+		Limit(int(*limit)).
+		Offset(int(*offset)).
+		Find(&collections)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return collections, nil
+}
+
+func GetCollectionsCount(userDefined *bool) (*int64, error) {
+	var count int64
+
+	if result := filterCollections(userDefined).Count(&count); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &count, nil
+}
+
+func filterCollections(userDefined *bool) *gorm.DB {
+	query := db.Model(&ItemMetadata{}).Where("type = ?", CollectionItem)
+
+	if userDefined != nil {
+		if *userDefined {
+			query = query.Where("match_provider = ?", "")
+		} else {
+			query = query.Where("match_provider != ?", "")
+		}
+	}
+
+	return query
+}
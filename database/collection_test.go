@@ -0,0 +1,166 @@
+package database_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/internal/databasetest"
+)
+
+func TestGetOrCreateCollectionConcurrently(t *testing.T) {
+	databasetest.Setup(t)
+
+	const workers = 8
+
+	var waitGroup sync.WaitGroup
+
+	ids := make([]uint64, workers)
+	errs := make([]error, workers)
+
+	for worker := 0; worker < workers; worker++ {
+		waitGroup.Add(1)
+
+		go func(worker int) {
+			defer waitGroup.Done()
+
+			collection, err := database.GetOrCreateCollection(&database.ItemMetadata{
+				Title: "The Matrix Collection",
+				ExternalIdentifiers: []database.ExternalIdentifier{
+					{IdentifierType: database.TmdbIdentifier, Identifier: "2344"},
+				},
+			})
+			if err == nil {
+				ids[worker] = collection.ID
+			}
+
+			errs[worker] = err
+		}(worker)
+	}
+
+	waitGroup.Wait()
+
+	for worker := range ids {
+		if errs[worker] != nil {
+			t.Fatalf("GetOrCreateCollection() error = %v", errs[worker])
+		}
+
+		if ids[worker] != ids[0] {
+			t.Errorf("GetOrCreateCollection() returned collections %d and %d, want a single one", ids[0], ids[worker])
+		}
+	}
+}
+
+func createReleasedMovie(t *testing.T, title string, year int) *database.ItemMetadata {
+	t.Helper()
+
+	movie := database.ItemMetadata{
+		Title:       title,
+		SortTitle:   title,
+		Type:        database.MovieItem,
+		ReleaseDate: time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	if err := database.CreateMovie(&movie); err != nil {
+		t.Fatal(err)
+	}
+
+	return &movie
+}
+
+func TestUpdateItemCollections(t *testing.T) {
+	databasetest.Setup(t)
+
+	goldfinger := createReleasedMovie(t, "Goldfinger", 1964)
+	drNo := createReleasedMovie(t, "Dr. No", 1962)
+
+	collection := database.ItemMetadata{
+		Title:               "James Bond Collection",
+		MatchProvider:       "TMDb",
+		MatchID:             "645",
+		ExternalIdentifiers: []database.ExternalIdentifier{{IdentifierType: database.TmdbIdentifier, Identifier: "645"}},
+	}
+
+	if err := database.SetCollectionSortOrder(&collection, database.ReleaseDateSortOrder); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := database.GetOrCreateCollection(&collection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, movie := range []*database.ItemMetadata{goldfinger, drNo} {
+		movie.Collections = []database.CollectionMember{{CollectionID: saved.ID}}
+
+		if err := database.UpdateItem(movie); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	limit, offset := int64(10), int64(0)
+
+	items, err := database.GetCollectionItems(
+		fmtID(saved.ID), database.ReleaseDateSortOrder, "", database.RatingFilter{}, &limit, &offset)
+	if err != nil || len(items) != 2 || items[0].ID != drNo.ID {
+		t.Errorf("GetCollectionItems() = %v, %v, want both movies, oldest first", getTitles(items), err)
+	}
+
+	// An empty list removes the item from its collections
+	goldfinger.Collections = []database.CollectionMember{}
+
+	if err := database.UpdateItem(goldfinger); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err = database.GetCollectionItems(
+		fmtID(saved.ID), database.ReleaseDateSortOrder, "", database.RatingFilter{}, &limit, &offset)
+	if err != nil || len(items) != 1 || items[0].ID != drNo.ID {
+		t.Errorf("GetCollectionItems() = %v, %v, want only %s", getTitles(items), err, drNo.Title)
+	}
+}
+
+func TestUserCollections(t *testing.T) {
+	databasetest.Setup(t)
+
+	goldfinger := createReleasedMovie(t, "Goldfinger", 1964)
+	drNo := createReleasedMovie(t, "Dr. No", 1962)
+
+	// Adding an item twice is a no-op
+	collection := createUserCollection(t, "Favorites", goldfinger.ID, drNo.ID)
+	if err := database.AddItemsToCollection(collection.ID, []uint64{goldfinger.ID}); err != nil {
+		t.Fatalf("AddItemsToCollection() error = %v", err)
+	}
+
+	if ids := getCollectionItemIDs(t, collection); len(ids) != 2 || ids[0] != goldfinger.ID {
+		t.Errorf("collection holds %v, want both movies in the order they were added", ids)
+	}
+
+	if err := database.MoveCollectionItem(collection.ID, drNo.ID, 0); err != nil {
+		t.Fatalf("MoveCollectionItem() error = %v", err)
+	}
+
+	if ids := getCollectionItemIDs(t, collection); len(ids) != 2 || ids[0] != drNo.ID {
+		t.Errorf("collection holds %v, want %s first", ids, drNo.Title)
+	}
+
+	provider := database.ItemMetadata{Title: "James Bond Collection", MatchProvider: "TMDb", MatchID: "645"}
+	if _, err := database.GetOrCreateCollection(&provider); err != nil {
+		t.Fatal(err)
+	}
+
+	userDefined := true
+
+	if count, err := database.GetCollectionsCount(&userDefined); err != nil || *count != 1 {
+		t.Errorf("GetCollectionsCount(true) = %v, %v, want 1", count, err)
+	}
+
+	if err := database.DeleteCollection(collection.ID); err != nil {
+		t.Fatalf("DeleteCollection() error = %v", err)
+	}
+
+	if count, err := database.GetCollectionsCount(nil); err != nil || *count != 1 {
+		t.Errorf("GetCollectionsCount() = %v, %v, want only the provider collection", count, err)
+	}
+}
@@ -0,0 +1,232 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// Credits for actors and voice actors.
+	CastRole = "cast"
+	// Credits for everyone else, like directors or costume designers.
+	CrewRole = "crew"
+)
+
+// Credits a person for their work on an item, like an actor playing a character in a movie.
+type Credit struct {
+	ID             uint64 `gorm:"primary_key" json:"id"`
+	ItemMetadataID uint64 `gorm:"not null;index"`
+	PersonID       uint64 `gorm:"not null;index"`
+	// Only used to pass people from providers, see Provider.GetMetadata.
+	Person     ItemMetadata `gorm:"foreignKey:PersonID" json:"-"`
+	Role       string       `gorm:"not null" json:"role"`
+	Character  string       `json:"character"`
+	Department string       `json:"department"`
+	Job        string       `json:"job"`
+	Index      int          `gorm:"not null" json:"index"`
+	CreatedAt  time.Time    `json:"createdAt"`
+	UpdatedAt  time.Time    `json:"updatedAt"`
+}
+
+// Replaces the credits of an item with the given ones. People must already exist.
+func setCredits(transaction *gorm.DB, itemID uint64, credits []Credit) error {
+	result := transaction.Where("item_metadata_id = ?", itemID).Delete(&Credit{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete credits: %w", result.Error)
+	}
+
+	if err := setCreditedOnRelations(transaction, itemID, credits); err != nil {
+		return err
+	}
+
+	if len(credits) == 0 {
+		return nil
+	}
+
+	for index := range credits {
+		credits[index].ID = 0
+		credits[index].ItemMetadataID = itemID
+	}
+
+	if result := transaction.Omit(clause.Associations).Create(&credits); result.Error != nil {
+		return fmt.Errorf("failed to create credits: %w", result.Error)
+	}
+
+	return nil
+}
+
+// Returns the person matching any of the given external identifiers, creating it if it doesn't exist yet.
+// Otherwise, people with the same name and no identifier from the same providers, like artists read from file
+// tags, are matched and get the identifiers. People without identifiers are matched by name instead, like artists.
+func GetOrCreatePerson(person *ItemMetadata) (*ItemMetadata, error) {
+	if len(person.ExternalIdentifiers) == 0 {
+		return GetOrCreateArtist(person.Title, person.SortTitle)
+	}
+
+	for _, identifier := range person.ExternalIdentifiers {
+		var existing ItemMetadata
+
+		result := db.
+			Joins("JOIN external_identifiers ON external_identifiers.item_metadata_id = item_metadata.id").
+			Where("item_metadata.type IN ?", []ItemType{PersonItem, GroupItem}).
+			Where("external_identifiers.identifier_type = ? AND external_identifiers.identifier = ?",
+				identifier.IdentifierType, identifier.Identifier).
+			First(&existing)
+		if result.Error == nil {
+			return &existing, nil
+		}
+
+		if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to get person: %w", result.Error)
+		}
+	}
+
+	// Artists read from file tags are created by name, before a provider identifies them
+	existing, err := identifyPersonByName(person)
+	if err != nil || existing != nil {
+		return existing, err
+	}
+
+	created := ItemMetadata{
+		Title:     person.Title,
+		SortTitle: person.SortTitle,
+		Type:      PersonItem,
+	}
+
+	// Some providers, like MusicBrainz, can tell bands apart from people
+	if person.Type == GroupItem {
+		created.Type = GroupItem
+	}
+
+	// Concurrent scans can credit the same person at once, so the first identifier keeps them from being
+	// created twice
+	identifier := person.ExternalIdentifiers[0]
+	key := fmt.Sprintf("person:%d:%s", identifier.IdentifierType, identifier.Identifier)
+
+	err = db.Transaction(func(transaction *gorm.DB) error {
+		isCreated, err := createUniqueItem(transaction, &created, key)
+		if err != nil || !isCreated {
+			return err
+		}
+
+		return setExternalIdentifiers(transaction, created.ID, person.ExternalIdentifiers)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create person: %w", err)
+	}
+
+	return &created, nil
+}
+
+// Returns the person or group with the name or sort title of the given person, which has no identifier of the
+// same types yet, after adding the identifiers of the given person to it. Homonyms identified by the same
+// providers are different people, so they aren't matched. Returns nil when no such person exists.
+func identifyPersonByName(person *ItemMetadata) (*ItemMetadata, error) {
+	identifierTypes := make([]IdentifierType, 0, len(person.ExternalIdentifiers))
+	for _, identifier := range person.ExternalIdentifiers {
+		identifierTypes = append(identifierTypes, identifier.IdentifierType)
+	}
+
+	var existing ItemMetadata
+
+	result := db.
+		Where("type IN ?", []ItemType{PersonItem, GroupItem}).
+		Where("(title = ? COLLATE NOCASE OR (sort_title <> '' AND sort_title = ?))", person.Title, person.SortTitle).
+		Where(`NOT EXISTS (SELECT 1 FROM external_identifiers
+			WHERE external_identifiers.item_metadata_id = item_metadata.id AND external_identifiers.identifier_type IN ?)`,
+			identifierTypes).
+		First(&existing)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get person: %w", result.Error)
+	}
+
+	err := db.Transaction(func(transaction *gorm.DB) error {
+		// Artists created by name are people, until a provider tells they are a band
+		if person.Type == GroupItem && existing.Type != GroupItem {
+			existing.Type = GroupItem
+
+			if result := transaction.Model(&existing).UpdateColumn("type", GroupItem); result.Error != nil {
+				return result.Error
+			}
+		}
+
+		for _, identifier := range person.ExternalIdentifiers {
+			result := transaction.Clauses(clause.OnConflict{DoNothing: true}).Create(&ExternalIdentifier{
+				IdentifierType: identifier.IdentifierType,
+				Identifier:     identifier.Identifier,
+				ItemMetadataID: existing.ID,
+			})
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to identify person: %w", err)
+	}
+
+	return &existing, nil
+}
+
+// Sets the thumbnail of a person, unless it was locked by a manual edit.
+func SetPersonThumb(personID uint64, thumb string) error {
+	result := db.
+		Model(&ItemMetadata{ID: personID}).
+		Where("(',' || COALESCE(locked_fields, '') || ',') NOT LIKE ?", "%,"+ThumbField+",%").
+		UpdateColumn("thumb", thumb)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// Returns the credits of the given item, cast first.
+func GetCreditsFromItem(itemID string) ([]*Credit, error) {
+	var credits []*Credit
+
+	result := db.
+		Where("item_metadata_id = ?", itemID).
+		Order("role, `index`").
+		Find(&credits)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return credits, nil
+}
+
+// Returns the credits of the given person, newest items first.
+// The role and item types are optional filters, ignored when empty.
+func GetCreditsFromPerson(personID, role string, itemTypes []ItemType) ([]*Credit, error) {
+	var credits []*Credit
+
+	query := db.
+		Joins("JOIN item_metadata ON item_metadata.id = credits.item_metadata_id").
+		Where("credits.person_id = ?", personID)
+
+	if role != "" {
+		query = query.Where("credits.role = ?", role)
+	}
+
+	if len(itemTypes) > 0 {
+		query = query.Where("item_metadata.type IN ?", itemTypes)
+	}
+
+	result := query.Order("item_metadata.release_date DESC, credits.`index`").Find(&credits)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return credits, nil
+}
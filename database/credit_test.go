@@ -0,0 +1,176 @@
+package database_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/internal/databasetest"
+)
+
+func TestGetOrCreatePersonConcurrently(t *testing.T) {
+	databasetest.Setup(t)
+
+	const workers = 8
+
+	var waitGroup sync.WaitGroup
+
+	ids := make([]uint64, workers)
+	errs := make([]error, workers)
+
+	for worker := 0; worker < workers; worker++ {
+		waitGroup.Add(1)
+
+		go func(worker int) {
+			defer waitGroup.Done()
+
+			person, err := database.GetOrCreatePerson(&database.ItemMetadata{
+				Title:     "Keanu Reeves",
+				SortTitle: "Reeves, Keanu",
+				ExternalIdentifiers: []database.ExternalIdentifier{
+					{IdentifierType: database.TmdbIdentifier, Identifier: "6384"},
+				},
+			})
+			if err == nil {
+				ids[worker] = person.ID
+			}
+
+			errs[worker] = err
+		}(worker)
+	}
+
+	waitGroup.Wait()
+
+	for worker := range ids {
+		if errs[worker] != nil {
+			t.Fatalf("GetOrCreatePerson() error = %v", errs[worker])
+		}
+
+		if ids[worker] != ids[0] {
+			t.Errorf("GetOrCreatePerson() returned people %d and %d, want a single person", ids[0], ids[worker])
+		}
+	}
+}
+
+func TestGetOrCreatePersonIdentifiesArtists(t *testing.T) {
+	databasetest.Setup(t)
+
+	artist, err := database.GetOrCreateArtist("The Beatles", "Beatles, The")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	band := database.ItemMetadata{
+		Title:     "the beatles",
+		SortTitle: "Beatles, The",
+		Type:      database.GroupItem,
+		ExternalIdentifiers: []database.ExternalIdentifier{
+			{IdentifierType: database.MusicbrainzIdentifier, Identifier: "b10bbbfc-cf9e-42e0-be17-e2c3e1d2600d"},
+		},
+	}
+
+	identified, err := database.GetOrCreatePerson(&band)
+	if err != nil {
+		t.Fatalf("GetOrCreatePerson() error = %v", err)
+	}
+
+	if identified.ID != artist.ID || identified.Type != database.GroupItem {
+		t.Errorf("GetOrCreatePerson() = %d of type %d, want group %d", identified.ID, identified.Type, artist.ID)
+	}
+
+	identifiers, err := database.GetExternalIdentifiersFromItem(fmtID(artist.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(identifiers) != 1 || identifiers[0].Identifier != band.ExternalIdentifiers[0].Identifier {
+		t.Errorf("artist identifiers = %v, want the MusicBrainz identifier", identifiers)
+	}
+
+	// Once identified, the artist is matched by identifier whatever its name
+	band.Title = "Beatles"
+
+	renamed, err := database.GetOrCreatePerson(&band)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if renamed.ID != artist.ID {
+		t.Errorf("GetOrCreatePerson() = %d, want %d", renamed.ID, artist.ID)
+	}
+}
+
+func TestGetOrCreatePersonKeepsHomonymsApart(t *testing.T) {
+	databasetest.Setup(t)
+
+	composer, err := database.GetOrCreatePerson(&database.ItemMetadata{
+		Title: "John Williams",
+		ExternalIdentifiers: []database.ExternalIdentifier{
+			{IdentifierType: database.TmdbIdentifier, Identifier: "491"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	guitarist, err := database.GetOrCreatePerson(&database.ItemMetadata{
+		Title: "John Williams",
+		ExternalIdentifiers: []database.ExternalIdentifier{
+			{IdentifierType: database.TmdbIdentifier, Identifier: "1263389"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("GetOrCreatePerson() error = %v", err)
+	}
+
+	if guitarist.ID == composer.ID {
+		t.Error("GetOrCreatePerson() matched a person identified by the same provider")
+	}
+}
+
+func TestUpdateItemCredits(t *testing.T) {
+	databasetest.Setup(t)
+
+	movie := database.ItemMetadata{Title: "The Matrix", Type: database.MovieItem}
+	if err := database.CreateMovie(&movie); err != nil {
+		t.Fatal(err)
+	}
+
+	person, err := database.GetOrCreatePerson(&database.ItemMetadata{Title: "Kym Barrett"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	movie.Credits = []database.Credit{
+		{PersonID: person.ID, Role: database.CrewRole, Department: "Costume & Make-Up", Job: "Costume Design"},
+		{PersonID: person.ID, Role: database.CastRole, Character: "Extra"},
+	}
+
+	// Saving the credits again replaces them
+	for i := 0; i < 2; i++ {
+		if err := database.UpdateItem(&movie); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	credits, err := database.GetCreditsFromItem(fmtID(movie.ID))
+	if err != nil || len(credits) != 2 || credits[0].Role != database.CastRole {
+		t.Errorf("GetCreditsFromItem() = %+v, %v, want 2 credits, cast first", credits, err)
+	}
+
+	for _, filter := range []struct {
+		role      string
+		itemTypes []database.ItemType
+		want      int
+	}{
+		{want: 2},
+		{role: database.CrewRole, itemTypes: []database.ItemType{database.MovieItem}, want: 1},
+		{itemTypes: []database.ItemType{database.ImageItem}, want: 0},
+	} {
+		credits, err := database.GetCreditsFromPerson(fmtID(person.ID), filter.role, filter.itemTypes)
+		if err != nil || len(credits) != filter.want {
+			t.Errorf("GetCreditsFromPerson(%q, %v) = %+v, %v, want %d credits",
+				filter.role, filter.itemTypes, credits, err, filter.want)
+		}
+	}
+}
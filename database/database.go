@@ -1,19 +1,22 @@
 package database
 
 import (
-	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"time"
 
-	"github.com/adrg/xdg"
 	"github.com/go-gormigrate/gormigrate/v2"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+const databaseDirectoryMode = 0o755
+
 var db *gorm.DB //nolint:varnamelen
 
 func NewDatabase(zerologger zerolog.Logger) error {
@@ -27,9 +30,10 @@ func NewDatabase(zerologger zerolog.Logger) error {
 		},
 	)
 
-	databaseLocation, dataFileErr := xdg.DataFile("meteorae/meteorae.db")
-	if dataFileErr != nil {
-		return fmt.Errorf("could not get path for database: %w", dataFileErr)
+	databaseLocation := viper.GetString("database.path")
+
+	if mkdirErr := os.MkdirAll(filepath.Dir(databaseLocation), databaseDirectoryMode); mkdirErr != nil {
+		return fmt.Errorf("could not create database directory: %w", mkdirErr)
 	}
 
 	var err error // Linters complain if we initilize this on the next line
@@ -44,13 +48,41 @@ func NewDatabase(zerologger zerolog.Logger) error {
 	log.Info().Msg("Checking for database migrations…")
 
 	err = migrateSchema()
-	if errors.Is(err, nil) {
+	if err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
 	return nil
 }
 
+// Replaces the database with an empty one at the given path, returning a function which restores the previous one.
+// The full-text search tables are left out, since they need SQLite built with ICU.
+// Meant for tests, which should use databasetest.Setup.
+func ReplaceWithEmptyDatabase(path string) (func(), error) {
+	emptyDB, err := gorm.Open(&sqlite.Dialector{
+		DriverName: "sqlite3",
+		DSN:        path + "?_busy_timeout=5000",
+	}, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if err := emptyDB.AutoMigrate(allModels...); err != nil {
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	previous := db
+	db = emptyDB
+
+	return func() {
+		db = previous
+
+		if sqlDB, err := emptyDB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}, nil
+}
+
 var allModels = []interface{}{
 	&User{},
 	&ExternalIdentifier{},
@@ -59,6 +91,20 @@ var allModels = []interface{}{
 	&MediaPart{},
 	&ItemMetadata{},
 	&MediaStream{},
+	&ItemArtist{},
+	&Chapter{},
+	&Credit{},
+	&ItemRelation{},
+	&CollectionMember{},
+	&Tag{},
+	&ItemTag{},
+	&ImageExif{},
+	&DuplicateCandidate{},
+	&VideoFingerprint{},
+	&FaceRegion{},
+	&AnimeTitle{},
+	&UserRating{},
+	&CommunityRating{},
 }
 
 func initSchema(transaction *gorm.DB) error {
@@ -126,14 +172,33 @@ func initSchema(transaction *gorm.DB) error {
 	return nil
 }
 
+// Migrations for changes AutoMigrate can't handle on its own, like renamed columns.
+// New databases are created from the current models, and skip these entirely.
+var allMigrations = []*gormigrate.Migration{
+	{
+		// External identifiers used to only apply to movies
+		ID: "202210190001",
+		Migrate: func(transaction *gorm.DB) error {
+			return transaction.Migrator().RenameColumn(&ExternalIdentifier{}, "movie_id", "item_metadata_id")
+		},
+		Rollback: func(transaction *gorm.DB) error {
+			return transaction.Migrator().RenameColumn(&ExternalIdentifier{}, "item_metadata_id", "movie_id")
+		},
+	},
+}
+
 func migrateSchema() error {
-	migrations := gormigrate.New(db, gormigrate.DefaultOptions, []*gormigrate.Migration{})
+	migrations := gormigrate.New(db, gormigrate.DefaultOptions, allMigrations)
 
 	migrations.InitSchema(initSchema)
 
-	if err := migrations.Migrate(); errors.Is(err, nil) {
+	if err := migrations.Migrate(); err != nil {
 		return fmt.Errorf("could not migrate: %w", err)
 	}
 
-	return fmt.Errorf("failed to run automatic migrations: %w", db.AutoMigrate(allModels...))
+	if err := db.AutoMigrate(allModels...); err != nil {
+		return fmt.Errorf("failed to run automatic migrations: %w", err)
+	}
+
+	return nil
 }
@@ -0,0 +1,290 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errDuplicateNotHidden = errors.New("the duplicate wasn't hidden")
+	errNotDuplicateItem   = errors.New("item isn't part of the duplicate candidate")
+)
+
+type DuplicateStatus string
+
+const (
+	// The candidate is waiting for review.
+	PendingDuplicateStatus DuplicateStatus = "pending"
+	// Both items were kept, as they aren't duplicates.
+	KeptDuplicateStatus DuplicateStatus = "kept"
+	// The user tags and collections of the duplicate were moved to the item, and the duplicate was hidden.
+	MergedDuplicateStatus DuplicateStatus = "merged"
+	// The duplicate was hidden.
+	HiddenDuplicateStatus DuplicateStatus = "hidden"
+)
+
+// A pair of items which look alike, found by comparing their perceptual hashes, or the hashes of
+// the frames sampled from videos.
+type DuplicateCandidate struct {
+	ID uint64 `gorm:"primary_key" json:"id"`
+	// The item found first, or the kept one once merged.
+	ItemMetadataID uint64 `gorm:"not null;uniqueIndex:idx_duplicate_candidate" json:"itemMetadataId"`
+	// The item looking like it, hidden once merged.
+	DuplicateID uint64 `gorm:"not null;uniqueIndex:idx_duplicate_candidate;index" json:"duplicateId"`
+	// Number of differing bits between the hashes of the items, averaged over the matching frames for videos.
+	Distance  int             `gorm:"not null" json:"distance"`
+	Status    DuplicateStatus `gorm:"not null;default:pending;index" json:"status"`
+	CreatedAt time.Time       `json:"createdAt"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+}
+
+// The perceptual hash of an item.
+type PerceptualHash struct {
+	ID             uint64
+	PerceptualHash string
+}
+
+// Returns the perceptual hashes of the visible items of the given type.
+func GetPerceptualHashes(itemType ItemType) ([]PerceptualHash, error) {
+	var hashes []PerceptualHash
+
+	result := db.Model(&ItemMetadata{}).
+		Select("id, perceptual_hash").
+		Where("type = ? AND perceptual_hash != '' AND hidden = ?", itemType, false).
+		Scan(&hashes)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return hashes, nil
+}
+
+// Queues a pair of items for review, unless they were already compared.
+func AddDuplicateCandidate(itemID, duplicateID uint64, distance int) error {
+	var count int64
+
+	result := db.Model(&DuplicateCandidate{}).
+		Where("(item_metadata_id = ? AND duplicate_id = ?) OR (item_metadata_id = ? AND duplicate_id = ?)",
+			itemID, duplicateID, duplicateID, itemID).
+		Count(&count)
+	if result.Error != nil {
+		return fmt.Errorf("failed to find duplicate candidate: %w", result.Error)
+	}
+
+	if count > 0 {
+		return nil
+	}
+
+	result = db.Clauses(clause.OnConflict{DoNothing: true}).Create(&DuplicateCandidate{
+		ItemMetadataID: itemID,
+		DuplicateID:    duplicateID,
+		Distance:       distance,
+		Status:         PendingDuplicateStatus,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to create duplicate candidate: %w", result.Error)
+	}
+
+	return nil
+}
+
+func GetDuplicateCandidate(id string) (*DuplicateCandidate, error) {
+	var candidate DuplicateCandidate
+
+	if result := db.First(&candidate, id); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &candidate, nil
+}
+
+// Returns the candidates waiting for review, most similar first.
+// Candidates with a hidden item are left out. Candidates from all libraries are returned when libraryID is nil.
+func GetPendingDuplicateCandidates(libraryID *string, limit, offset *int64) ([]*DuplicateCandidate, error) {
+	var candidates []*DuplicateCandidate
+
+	result := filterPendingDuplicateCandidates(libraryID).
+		Order("distance, id").
+		Limit(int(*limit)).
+		Offset(int(*offset)).
+		Find(&candidates)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return candidates, nil
+}
+
+// Returns all the candidates waiting for review, to group them.
+func GetAllPendingDuplicateCandidates(libraryID *string) ([]*DuplicateCandidate, error) {
+	var candidates []*DuplicateCandidate
+
+	if result := filterPendingDuplicateCandidates(libraryID).Order("distance, id").Find(&candidates); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return candidates, nil
+}
+
+func GetPendingDuplicateCandidatesCount(libraryID *string) (*int64, error) {
+	var count int64
+
+	if result := filterPendingDuplicateCandidates(libraryID).Count(&count); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &count, nil
+}
+
+func filterPendingDuplicateCandidates(libraryID *string) *gorm.DB {
+	visibleItems := db.Model(&ItemMetadata{}).Select("id").Where("hidden = ?", false)
+	if libraryID != nil {
+		visibleItems = visibleItems.Where("library_id = ?", *libraryID)
+	}
+
+	return db.Model(&DuplicateCandidate{}).
+		Where("status = ?", PendingDuplicateStatus).
+		Where("item_metadata_id IN (?) AND duplicate_id IN (?)", visibleItems, visibleItems)
+}
+
+// Closes the review of a candidate, keeping both items.
+func KeepDuplicateCandidate(candidate *DuplicateCandidate) error {
+	candidate.Status = KeptDuplicateStatus
+
+	if result := db.Save(candidate); result.Error != nil {
+		return fmt.Errorf("failed to keep duplicate: %w", result.Error)
+	}
+
+	return nil
+}
+
+// Closes the review of a candidate, hiding the duplicate.
+func HideDuplicateCandidate(candidate *DuplicateCandidate) error {
+	err := db.Transaction(func(transaction *gorm.DB) error {
+		return closeDuplicateCandidate(transaction, candidate, HiddenDuplicateStatus)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to hide duplicate: %w", err)
+	}
+
+	return nil
+}
+
+// Closes the review of a candidate, moving the tags assigned by users and the user collections of the duplicate
+// to the item, and hiding the duplicate. The kept item is either of the pair; when it's the duplicate, the items
+// of the candidate are swapped first, so that the hidden one is always the duplicate.
+func MergeDuplicateCandidate(candidate *DuplicateCandidate, keptID uint64) error {
+	switch keptID {
+	case candidate.ItemMetadataID:
+	case candidate.DuplicateID:
+		candidate.ItemMetadataID, candidate.DuplicateID = candidate.DuplicateID, candidate.ItemMetadataID
+	default:
+		return fmt.Errorf("%w: %d", errNotDuplicateItem, keptID)
+	}
+
+	err := db.Transaction(func(transaction *gorm.DB) error {
+		var userTags []ItemTag
+
+		result := transaction.
+			Where("item_metadata_id = ? AND user_defined = ?", candidate.DuplicateID, true).
+			Find(&userTags)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		for index := range userTags {
+			userTags[index].ID = 0
+			userTags[index].ItemMetadataID = candidate.ItemMetadataID
+		}
+
+		if len(userTags) > 0 {
+			result = transaction.
+				Omit(clause.Associations).
+				Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "tag_id"}, {Name: "item_metadata_id"}},
+					DoUpdates: clause.Assignments(map[string]interface{}{"user_defined": true}),
+				}).
+				Create(&userTags)
+			if result.Error != nil {
+				return result.Error
+			}
+
+			result = transaction.
+				Where("item_metadata_id = ? AND user_defined = ?", candidate.DuplicateID, true).
+				Delete(&ItemTag{})
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+
+		// The item takes the place of the duplicate in the user collections it isn't in yet
+		userCollections := transaction.Model(&ItemMetadata{}).
+			Select("id").
+			Where("type = ? AND match_provider = ''", CollectionItem)
+		itemCollections := transaction.Model(&CollectionMember{}).
+			Select("collection_id").
+			Where("item_metadata_id = ?", candidate.ItemMetadataID)
+
+		result = transaction.Model(&CollectionMember{}).
+			Where("item_metadata_id = ?", candidate.DuplicateID).
+			Where("collection_id IN (?) AND collection_id NOT IN (?)", userCollections, itemCollections).
+			Update("item_metadata_id", candidate.ItemMetadataID)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		// And leaves the ones the item was already in
+		result = transaction.
+			Where("item_metadata_id = ? AND collection_id IN (?)", candidate.DuplicateID, userCollections).
+			Delete(&CollectionMember{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		return closeDuplicateCandidate(transaction, candidate, MergedDuplicateStatus)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to merge duplicate: %w", err)
+	}
+
+	return nil
+}
+
+// Shows a duplicate hidden by HideDuplicateCandidate or MergeDuplicateCandidate again, keeping both items.
+// What a merge moved to the item stays there.
+func UnhideDuplicateCandidate(candidate *DuplicateCandidate) error {
+	if candidate.Status != HiddenDuplicateStatus && candidate.Status != MergedDuplicateStatus {
+		return fmt.Errorf("%w: %s", errDuplicateNotHidden, candidate.Status)
+	}
+
+	err := db.Transaction(func(transaction *gorm.DB) error {
+		result := transaction.Model(&ItemMetadata{}).Where("id = ?", candidate.DuplicateID).Update("hidden", false)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		candidate.Status = KeptDuplicateStatus
+
+		return transaction.Save(candidate).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unhide duplicate: %w", err)
+	}
+
+	return nil
+}
+
+func closeDuplicateCandidate(transaction *gorm.DB, candidate *DuplicateCandidate, status DuplicateStatus) error {
+	result := transaction.Model(&ItemMetadata{}).Where("id = ?", candidate.DuplicateID).Update("hidden", true)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	candidate.Status = status
+
+	return transaction.Save(candidate).Error
+}
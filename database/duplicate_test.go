@@ -0,0 +1,338 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/internal/databasetest"
+)
+
+type duplicateFixture struct {
+	libraryID       string
+	item, duplicate *database.ItemMetadata
+	candidate       *database.DuplicateCandidate
+	// A provider tag of both items, and a tag assigned to the duplicate by users.
+	beach, favorite *database.Tag
+	// User collections holding only the duplicate, and both items.
+	albumOfDuplicate, albumOfBoth *database.ItemMetadata
+}
+
+func createUserCollection(t *testing.T, title string, itemIDs ...uint64) *database.ItemMetadata {
+	t.Helper()
+
+	collection := database.ItemMetadata{Title: title, SortTitle: title}
+	if err := database.CreateCollection(&collection); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.AddItemsToCollection(collection.ID, itemIDs); err != nil {
+		t.Fatal(err)
+	}
+
+	return &collection
+}
+
+// Creates two photos of the same beach, tagged and in user collections, queued for review.
+func setupDuplicates(t *testing.T) *duplicateFixture {
+	t.Helper()
+
+	databasetest.Setup(t)
+
+	id := createPhotoLibrary(t)
+	fixture := duplicateFixture{
+		libraryID: fmtID(id),
+		item:      createPhoto(t, id, "Beach", 43.6961, 7.2718),
+		duplicate: createPhoto(t, id, "Beach (copy)", 43.6961, 7.2718),
+		beach:     createTagPath(t, "Places", "Beach"),
+		favorite:  createTagPath(t, "Favorite"),
+	}
+
+	for _, photo := range []*database.ItemMetadata{fixture.item, fixture.duplicate} {
+		photo.PerceptualHash = "ffffffffffffffff"
+		photo.Tags = []database.ItemTag{{TagID: fixture.beach.ID}}
+
+		if err := database.UpdateItem(photo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := database.AssignTags([]uint64{fixture.duplicate.ID}, []uint64{fixture.favorite.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	fixture.albumOfDuplicate = createUserCollection(t, "Holidays", fixture.duplicate.ID)
+	fixture.albumOfBoth = createUserCollection(t, "Summer", fixture.item.ID, fixture.duplicate.ID)
+
+	if err := database.AddDuplicateCandidate(fixture.item.ID, fixture.duplicate.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	candidates, err := database.GetAllPendingDuplicateCandidates(nil)
+	if err != nil || len(candidates) != 1 {
+		t.Fatalf("expected a duplicate candidate, got %v, %v", candidates, err)
+	}
+
+	fixture.candidate = candidates[0]
+
+	return &fixture
+}
+
+func getTagNames(t *testing.T, itemID uint64) []string {
+	t.Helper()
+
+	tags, err := database.GetTagsFromItem(fmtID(itemID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+
+	return names
+}
+
+func getCollectionItemIDs(t *testing.T, collection *database.ItemMetadata) []uint64 {
+	t.Helper()
+
+	limit, offset := int64(10), int64(0)
+
+	items, err := database.GetCollectionItems(
+		fmtID(collection.ID), database.ManualSortOrder, "", database.RatingFilter{}, &limit, &offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := database.GetCollectionItemsCount(fmtID(collection.ID), database.RatingFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *count != int64(len(items)) {
+		t.Errorf("GetCollectionItemsCount() = %d, want %d", *count, len(items))
+	}
+
+	ids := make([]uint64, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+
+	return ids
+}
+
+// Checks that the duplicate is left out of every library, tag and map listing, or in all of them.
+func assertListed(t *testing.T, fixture *duplicateFixture, wantListed bool) {
+	t.Helper()
+
+	limit, offset := int64(10), int64(0)
+	want := 1
+
+	if wantListed {
+		want = 2
+	}
+
+	bounds := database.Bounds{North: 44, South: 43, East: 8, West: 7}
+
+	for name, list := range map[string]func() ([]*database.ItemMetadata, error){
+		"library": func() ([]*database.ItemMetadata, error) {
+			return database.GetItemsFromLibrary(fixture.libraryID, "", database.RatingFilter{}, &limit, &offset)
+		},
+		"tag": func() ([]*database.ItemMetadata, error) {
+			return database.GetItemsFromTag(fixture.beach.ID, false, &limit, &offset)
+		},
+		"tag with descendants": func() ([]*database.ItemMetadata, error) {
+			return database.GetItemsFromTag(fixture.beach.ParentID, true, &limit, &offset)
+		},
+		"bounds": func() ([]*database.ItemMetadata, error) {
+			return database.GetItemsInBounds(fixture.libraryID, bounds, &limit, &offset)
+		},
+	} {
+		items, err := list()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if len(items) != want {
+			t.Errorf("%s lists %d items, want %d", name, len(items), want)
+		}
+	}
+
+	for name, count := range map[string]func() (*int64, error){
+		"library": func() (*int64, error) {
+			return database.GetItemsCountFromLibrary(fixture.libraryID, database.RatingFilter{})
+		},
+		"tag": func() (*int64, error) {
+			return database.GetItemsCountFromTag(fixture.beach.ID, false)
+		},
+		"bounds": func() (*int64, error) {
+			return database.GetItemsCountInBounds(fixture.libraryID, bounds)
+		},
+	} {
+		total, err := count()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if *total != int64(want) {
+			t.Errorf("%s counts %d items, want %d", name, *total, want)
+		}
+	}
+
+	places, err := database.GetPlaces(fixture.libraryID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(places) != 0 && places[0].ItemCount != int64(want) {
+		t.Errorf("places count %d items, want %d", places[0].ItemCount, want)
+	}
+
+	hashes, err := database.GetPerceptualHashes(database.ImageItem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hashes) != want {
+		t.Errorf("GetPerceptualHashes() = %d hashes, want %d", len(hashes), want)
+	}
+}
+
+func TestMergeDuplicateCandidate(t *testing.T) {
+	fixture := setupDuplicates(t)
+
+	assertListed(t, fixture, true)
+
+	if err := database.MergeDuplicateCandidate(fixture.candidate, fixture.item.ID); err != nil {
+		t.Fatalf("MergeDuplicateCandidate() error = %v", err)
+	}
+
+	if fixture.candidate.Status != database.MergedDuplicateStatus {
+		t.Errorf("MergeDuplicateCandidate() status = %s", fixture.candidate.Status)
+	}
+
+	// User tags are moved, while provider tags stay with the file they came from
+	if names := getTagNames(t, fixture.item.ID); len(names) != 2 || names[0] != "Beach" || names[1] != "Favorite" {
+		t.Errorf("item tags = %v, want Beach and Favorite", names)
+	}
+
+	if names := getTagNames(t, fixture.duplicate.ID); len(names) != 1 || names[0] != "Beach" {
+		t.Errorf("duplicate tags = %v, want only Beach", names)
+	}
+
+	for _, collection := range []*database.ItemMetadata{fixture.albumOfDuplicate, fixture.albumOfBoth} {
+		if ids := getCollectionItemIDs(t, collection); len(ids) != 1 || ids[0] != fixture.item.ID {
+			t.Errorf("%s holds %v, want only the item", collection.Title, ids)
+		}
+	}
+
+	assertListed(t, fixture, false)
+
+	pending, err := database.GetPendingDuplicateCandidatesCount(nil)
+	if err != nil || *pending != 0 {
+		t.Errorf("GetPendingDuplicateCandidatesCount() = %v, %v, want none", pending, err)
+	}
+
+	if err := database.UnhideDuplicateCandidate(fixture.candidate); err != nil {
+		t.Fatalf("UnhideDuplicateCandidate() error = %v", err)
+	}
+
+	if fixture.candidate.Status != database.KeptDuplicateStatus {
+		t.Errorf("UnhideDuplicateCandidate() status = %s, want kept", fixture.candidate.Status)
+	}
+
+	assertListed(t, fixture, true)
+
+	// The memberships moved on merge stay with the item
+	if ids := getCollectionItemIDs(t, fixture.albumOfBoth); len(ids) != 1 {
+		t.Errorf("%s holds %v, want only the item", fixture.albumOfBoth.Title, ids)
+	}
+
+	if err := database.UnhideDuplicateCandidate(fixture.candidate); err == nil {
+		t.Error("UnhideDuplicateCandidate() should fail for duplicates which aren't hidden")
+	}
+}
+
+func TestMergeDuplicateCandidateKeepingDuplicate(t *testing.T) {
+	fixture := setupDuplicates(t)
+
+	const missingID = 1000
+
+	if err := database.MergeDuplicateCandidate(fixture.candidate, missingID); err == nil {
+		t.Error("MergeDuplicateCandidate() should fail for items outside of the candidate")
+	}
+
+	if err := database.MergeDuplicateCandidate(fixture.candidate, fixture.duplicate.ID); err != nil {
+		t.Fatalf("MergeDuplicateCandidate() error = %v", err)
+	}
+
+	if fixture.candidate.ItemMetadataID != fixture.duplicate.ID || fixture.candidate.DuplicateID != fixture.item.ID {
+		t.Errorf("MergeDuplicateCandidate() = %+v, want the items swapped", fixture.candidate)
+	}
+
+	// The saved candidate is swapped too, so that unhiding shows the hidden item again
+	candidate, err := database.GetDuplicateCandidate(fmtID(fixture.candidate.ID))
+	if err != nil || candidate.ItemMetadataID != fixture.duplicate.ID || candidate.Status != database.MergedDuplicateStatus {
+		t.Errorf("GetDuplicateCandidate() = %+v, %v, want the duplicate kept", candidate, err)
+	}
+
+	limit, offset := int64(10), int64(0)
+
+	items, err := database.GetItemsFromLibrary(fixture.libraryID, "", database.RatingFilter{}, &limit, &offset)
+	if err != nil || len(items) != 1 || items[0].ID != fixture.duplicate.ID {
+		t.Errorf("GetItemsFromLibrary() = %v, %v, want only the duplicate", getTitles(items), err)
+	}
+
+	if names := getTagNames(t, fixture.duplicate.ID); len(names) != 2 {
+		t.Errorf("duplicate tags = %v, want Beach and Favorite", names)
+	}
+
+	for _, collection := range []*database.ItemMetadata{fixture.albumOfDuplicate, fixture.albumOfBoth} {
+		if ids := getCollectionItemIDs(t, collection); len(ids) != 1 || ids[0] != fixture.duplicate.ID {
+			t.Errorf("%s holds %v, want only the duplicate", collection.Title, ids)
+		}
+	}
+
+	if err := database.UnhideDuplicateCandidate(fixture.candidate); err != nil {
+		t.Fatalf("UnhideDuplicateCandidate() error = %v", err)
+	}
+
+	assertListed(t, fixture, true)
+}
+
+func TestHideDuplicateCandidate(t *testing.T) {
+	fixture := setupDuplicates(t)
+
+	if err := database.HideDuplicateCandidate(fixture.candidate); err != nil {
+		t.Fatalf("HideDuplicateCandidate() error = %v", err)
+	}
+
+	// Hiding leaves the tags and collections of the duplicate alone
+	if names := getTagNames(t, fixture.duplicate.ID); len(names) != 2 {
+		t.Errorf("duplicate tags = %v, want Beach and Favorite", names)
+	}
+
+	if names := getTagNames(t, fixture.item.ID); len(names) != 1 {
+		t.Errorf("item tags = %v, want only Beach", names)
+	}
+
+	assertListed(t, fixture, false)
+
+	if ids := getCollectionItemIDs(t, fixture.albumOfDuplicate); len(ids) != 0 {
+		t.Errorf("%s lists %v, want the hidden duplicate left out", fixture.albumOfDuplicate.Title, ids)
+	}
+
+	if ids := getCollectionItemIDs(t, fixture.albumOfBoth); len(ids) != 1 || ids[0] != fixture.item.ID {
+		t.Errorf("%s lists %v, want only the item", fixture.albumOfBoth.Title, ids)
+	}
+
+	if err := database.UnhideDuplicateCandidate(fixture.candidate); err != nil {
+		t.Fatalf("UnhideDuplicateCandidate() error = %v", err)
+	}
+
+	assertListed(t, fixture, true)
+
+	if ids := getCollectionItemIDs(t, fixture.albumOfBoth); len(ids) != 2 {
+		t.Errorf("%s lists %v, want both items back", fixture.albumOfBoth.Title, ids)
+	}
+}
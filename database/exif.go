@@ -0,0 +1,71 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The EXIF data of an image. Fields missing from the image are left empty.
+type ImageExif struct {
+	ID             uint64 `gorm:"primary_key" json:"id"`
+	ItemMetadataID uint64 `gorm:"not null;uniqueIndex" json:"itemMetadataId"`
+	// When the photo was taken, in the camera's time zone.
+	DateTaken   *time.Time `gorm:"index" json:"dateTaken"`
+	CameraMake  string     `json:"cameraMake"`
+	CameraModel string     `json:"cameraModel"`
+	Lens        string     `json:"lens"`
+	// Exposure time in seconds, as a fraction like "1/250".
+	ExposureTime string   `json:"exposureTime"`
+	Aperture     *float64 `json:"aperture"`
+	ISO          *int64   `json:"iso"`
+	// Focal length in millimeters.
+	FocalLength *float64 `json:"focalLength"`
+	// EXIF orientation, from 1 to 8. Width and height are the stored dimensions, before rotation.
+	Orientation *int64   `json:"orientation"`
+	Width       *int64   `json:"width"`
+	Height      *int64   `json:"height"`
+	Latitude    *float64 `gorm:"index:idx_image_exif_location" json:"latitude"`
+	Longitude   *float64 `gorm:"index:idx_image_exif_location" json:"longitude"`
+	Altitude    *float64 `json:"altitude"`
+	// Place the photo was taken at, found from its coordinates. Region is empty with the bundled dataset.
+	Country   string    `gorm:"index:idx_image_exif_place" json:"country"`
+	Region    string    `gorm:"index:idx_image_exif_place" json:"region"`
+	City      string    `gorm:"index:idx_image_exif_place" json:"city"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Replaces the EXIF data of an item.
+func setImageExif(transaction *gorm.DB, itemID uint64, exif *ImageExif) error {
+	if result := transaction.Where("item_metadata_id = ?", itemID).Delete(&ImageExif{}); result.Error != nil {
+		return fmt.Errorf("failed to delete EXIF data: %w", result.Error)
+	}
+
+	exif.ID = 0
+	exif.ItemMetadataID = itemID
+
+	if result := transaction.Create(exif); result.Error != nil {
+		return fmt.Errorf("failed to create EXIF data: %w", result.Error)
+	}
+
+	return nil
+}
+
+// Returns the EXIF data of the given item, or nil if it has none.
+func GetImageExif(itemID string) (*ImageExif, error) {
+	var exif ImageExif
+
+	result := db.Where("item_metadata_id = ?", itemID).First(&exif)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &exif, nil
+}
@@ -0,0 +1,66 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/internal/databasetest"
+)
+
+func TestSortChildrenByCaptureDate(t *testing.T) {
+	databasetest.Setup(t)
+
+	album := database.ItemMetadata{Title: "Holidays", Type: database.ImageAlbumItem}
+	if err := database.CreateImage(&album); err != nil {
+		t.Fatal(err)
+	}
+
+	dates := map[string]*time.Time{
+		"A": nil,
+		"B": timePointer(time.Date(2020, time.July, 14, 12, 0, 0, 0, time.UTC)),
+		"C": timePointer(time.Date(2019, time.August, 1, 9, 0, 0, 0, time.UTC)),
+	}
+
+	for title, date := range dates {
+		image := database.ItemMetadata{Title: title, SortTitle: title, Type: database.ImageItem, ParentID: album.ID}
+		if err := database.CreateImage(&image); err != nil {
+			t.Fatal(err)
+		}
+
+		if date == nil {
+			continue
+		}
+
+		// Saving the EXIF data again replaces it
+		for _, cameraMake := range []string{"Canon", "Nikon"} {
+			image.Exif = &database.ImageExif{DateTaken: date, CameraMake: cameraMake}
+
+			if err := database.UpdateItem(&image); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		exif, err := database.GetImageExif(fmtID(image.ID))
+		if err != nil || exif.CameraMake != "Nikon" {
+			t.Errorf("GetImageExif() = %+v, %v, want the last camera make", exif, err)
+		}
+	}
+
+	limit, offset := int64(10), int64(0)
+
+	// Images without capture date come last
+	items, err := database.GetChildrenFromItem(
+		fmtID(album.ID), database.CaptureDateChildSortOrder, database.RatingFilter{}, &limit, &offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if titles := getOrderedTitles(items); titles != "C,B,A" {
+		t.Errorf("GetChildrenFromItem() = %s, want C,B,A", titles)
+	}
+}
+
+func timePointer(value time.Time) *time.Time {
+	return &value
+}
@@ -0,0 +1,54 @@
+package database
+
+import "testing"
+
+// Clears when an item was last refreshed, like for items created before it was recorded.
+func ClearItemRefreshedAt(t *testing.T, id uint64) {
+	t.Helper()
+
+	if result := db.Model(&ItemMetadata{ID: id}).UpdateColumn("refreshed_at", nil); result.Error != nil {
+		t.Fatal(result.Error)
+	}
+}
+
+// Replaces the external identifiers with those from before they applied to any item, as IMDb identifiers of the
+// given movies, and migrates the database like on startup.
+func MigrateLegacyExternalIdentifiers(t *testing.T, imdbIDs map[uint64]string) {
+	t.Helper()
+
+	type legacyExternalIdentifier struct {
+		ID             uint64         `gorm:"primary_key"`
+		IdentifierType IdentifierType `gorm:"not null"`
+		Identifier     string         `gorm:"not null"`
+		MovieID        uint64         `gorm:"not null"`
+	}
+
+	if err := db.Migrator().DropTable(&ExternalIdentifier{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Table("external_identifiers").AutoMigrate(&legacyExternalIdentifier{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for movieID, imdbID := range imdbIDs {
+		identifier := legacyExternalIdentifier{IdentifierType: ImdbIdentifier, Identifier: imdbID, MovieID: movieID}
+		if result := db.Table("external_identifiers").Create(&identifier); result.Error != nil {
+			t.Fatal(result.Error)
+		}
+	}
+
+	// The schema was initialized before the migration, so only the migration runs
+	for _, statement := range []string{
+		"CREATE TABLE migrations (id VARCHAR(255) PRIMARY KEY)",
+		"INSERT INTO migrations VALUES ('SCHEMA_INIT')",
+	} {
+		if result := db.Exec(statement); result.Error != nil {
+			t.Fatal(result.Error)
+		}
+	}
+
+	if err := migrateSchema(); err != nil {
+		t.Fatal(err)
+	}
+}
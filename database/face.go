@@ -0,0 +1,216 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var errInvalidFaceRegion = errors.New("face regions must fit inside the image")
+
+type FaceRegionSource string
+
+const (
+	// Read from the MWG regions of the XMP data embedded in the image or in its sidecars.
+	XMPFaceRegionSource FaceRegionSource = "xmp"
+	// Read from the .picasa.ini file of the image folder.
+	PicasaFaceRegionSource FaceRegionSource = "picasa"
+	// Added or edited by a user. These are kept when the metadata is refreshed.
+	UserFaceRegionSource FaceRegionSource = "user"
+)
+
+// A face in an image, optionally linked to the person it shows.
+// The box is normalized to the image size, from 0 to 1, with its origin at the top left corner
+// of the image as stored, before EXIF orientation is applied.
+type FaceRegion struct {
+	ID             uint64  `gorm:"primary_key" json:"id"`
+	ItemMetadataID uint64  `gorm:"not null;index" json:"itemMetadataId"`
+	X              float64 `gorm:"not null" json:"x"`
+	Y              float64 `gorm:"not null" json:"y"`
+	Width          float64 `gorm:"not null" json:"width"`
+	Height         float64 `gorm:"not null" json:"height"`
+	// The person shown, if known.
+	PersonID *uint64 `gorm:"index" json:"personId"`
+	// Only used to pass the person names read from files, see Provider.GetMetadata.
+	Person *ItemMetadata    `gorm:"foreignKey:PersonID" json:"-"`
+	Source FaceRegionSource `gorm:"not null" json:"source"`
+	// Confidence of the detection, from 0 to 1, when the region was found by a face detector.
+	Confidence *float64  `json:"confidence"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Returns whether the box fits inside the image.
+func (f *FaceRegion) isValid() bool {
+	return f.X >= 0 && f.Y >= 0 && f.Width > 0 && f.Height > 0 && f.X+f.Width <= 1 && f.Y+f.Height <= 1
+}
+
+// Returns how much two boxes overlap, as the ratio of their intersection to their union.
+func (f *FaceRegion) overlap(other *FaceRegion) float64 {
+	width := math.Min(f.X+f.Width, other.X+other.Width) - math.Max(f.X, other.X)
+	height := math.Min(f.Y+f.Height, other.Y+other.Height) - math.Max(f.Y, other.Y)
+
+	if width <= 0 || height <= 0 {
+		return 0
+	}
+
+	intersection := width * height
+
+	return intersection / (f.Width*f.Height + other.Width*other.Height - intersection)
+}
+
+// Replaces the face regions imported from files. Regions added or edited by users are kept,
+// and imported regions overlapping them are skipped, since they show the same face.
+func setImportedFaceRegions(transaction *gorm.DB, itemID uint64, regions []FaceRegion) error {
+	result := transaction.
+		Where("item_metadata_id = ? AND source != ?", itemID, UserFaceRegionSource).
+		Delete(&FaceRegion{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete face regions: %w", result.Error)
+	}
+
+	var userRegions []FaceRegion
+
+	result = transaction.Where("item_metadata_id = ? AND source = ?", itemID, UserFaceRegionSource).Find(&userRegions)
+	if result.Error != nil {
+		return fmt.Errorf("failed to get face regions: %w", result.Error)
+	}
+
+	importedRegions := make([]FaceRegion, 0, len(regions))
+
+	for _, region := range regions {
+		if !region.isValid() || overlapsAny(&region, userRegions) {
+			continue
+		}
+
+		region.ID = 0
+		region.ItemMetadataID = itemID
+
+		if region.Person != nil {
+			region.PersonID = &region.Person.ID
+			region.Person = nil
+		}
+
+		importedRegions = append(importedRegions, region)
+	}
+
+	if len(importedRegions) == 0 {
+		return nil
+	}
+
+	if result := transaction.Create(&importedRegions); result.Error != nil {
+		return fmt.Errorf("failed to create face regions: %w", result.Error)
+	}
+
+	return nil
+}
+
+func overlapsAny(region *FaceRegion, others []FaceRegion) bool {
+	const minOverlap = 0.5
+
+	for index := range others {
+		if region.overlap(&others[index]) >= minOverlap {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the face regions of the given image, from left to right.
+func GetFaceRegionsFromItem(itemID string) ([]*FaceRegion, error) {
+	var regions []*FaceRegion
+
+	if result := db.Where("item_metadata_id = ?", itemID).Order("x, y").Find(&regions); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return regions, nil
+}
+
+func GetFaceRegion(id string) (*FaceRegion, error) {
+	var region FaceRegion
+
+	if result := db.First(&region, id); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &region, nil
+}
+
+// Adds a face region to an image.
+func CreateFaceRegion(region *FaceRegion) error {
+	if !region.isValid() {
+		return errInvalidFaceRegion
+	}
+
+	region.Source = UserFaceRegionSource
+
+	if result := db.Omit("Person").Create(region); result.Error != nil {
+		return fmt.Errorf("failed to create face region: %w", result.Error)
+	}
+
+	return nil
+}
+
+// Saves the box and person of a face region. Edited regions are kept when the metadata is refreshed.
+func UpdateFaceRegion(region *FaceRegion) error {
+	if !region.isValid() {
+		return errInvalidFaceRegion
+	}
+
+	region.Source = UserFaceRegionSource
+
+	if result := db.Omit("Person").Save(region); result.Error != nil {
+		return fmt.Errorf("failed to update face region: %w", result.Error)
+	}
+
+	return nil
+}
+
+// Deletes a face region. Regions imported from files come back when the metadata is refreshed,
+// unless they are removed from the files too.
+func DeleteFaceRegion(id string) error {
+	if result := db.Delete(&FaceRegion{}, id); result.Error != nil {
+		return fmt.Errorf("failed to delete face region: %w", result.Error)
+	}
+
+	return nil
+}
+
+// Returns the photos showing the given person, by capture date.
+func GetPhotosFromPerson(personID string, limit, offset *int64) ([]*ItemMetadata, error) {
+	var items []*ItemMetadata
+
+	result := filterPhotosFromPerson(personID).
+		Preload("Library").
+		Order("image_exifs.date_taken IS NULL, image_exifs.date_taken, item_metadata.sort_title").
+		Limit(int(*limit)).
+		Offset(int(*offset)).
+		Find(&items)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return items, nil
+}
+
+func GetPhotosCountFromPerson(personID string) (*int64, error) {
+	var count int64
+
+	if result := filterPhotosFromPerson(personID).Count(&count); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &count, nil
+}
+
+func filterPhotosFromPerson(personID string) *gorm.DB {
+	return db.Model(&ItemMetadata{}).
+		Joins("LEFT JOIN image_exifs ON image_exifs.item_metadata_id = item_metadata.id").
+		Where("item_metadata.id IN (?)", db.Model(&FaceRegion{}).Select("item_metadata_id").Where("person_id = ?", personID)).
+		Where("item_metadata.hidden = ?", false)
+}
@@ -0,0 +1,141 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/internal/databasetest"
+)
+
+func syncFaceRegions(t *testing.T, image *database.ItemMetadata, regions []database.FaceRegion) {
+	t.Helper()
+
+	image.FaceRegions = regions
+
+	if err := database.UpdateItem(image); err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+}
+
+func getFaceRegions(t *testing.T, image *database.ItemMetadata) []*database.FaceRegion {
+	t.Helper()
+
+	regions, err := database.GetFaceRegionsFromItem(fmtID(image.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return regions
+}
+
+func importedRegion(x, y float64) database.FaceRegion {
+	return database.FaceRegion{X: x, Y: y, Width: 0.2, Height: 0.2, Source: database.XMPFaceRegionSource}
+}
+
+func TestUpdateItemReplacesImportedFaceRegions(t *testing.T) {
+	databasetest.Setup(t)
+
+	image := createImage(t)
+
+	syncFaceRegions(t, image, []database.FaceRegion{importedRegion(0.1, 0.1), importedRegion(0.6, 0.1)})
+
+	regions := getFaceRegions(t, image)
+	if len(regions) != 2 {
+		t.Fatalf("expected 2 imported regions, got %+v", regions)
+	}
+
+	// Users move the second region a little, and add a face the file doesn't know about
+	edited := *regions[1]
+	edited.X = 0.62
+
+	if err := database.UpdateFaceRegion(&edited); err != nil {
+		t.Fatal(err)
+	}
+
+	added := database.FaceRegion{ItemMetadataID: image.ID, X: 0.1, Y: 0.6, Width: 0.2, Height: 0.2}
+	if err := database.CreateFaceRegion(&added); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first region moved in the file, the second is still there, and a new one was added
+	syncFaceRegions(t, image, []database.FaceRegion{
+		importedRegion(0.15, 0.1),
+		importedRegion(0.6, 0.1),
+		importedRegion(0.6, 0.6),
+	})
+
+	want := []database.FaceRegion{
+		{X: 0.1, Y: 0.6, Source: database.UserFaceRegionSource},
+		{X: 0.15, Y: 0.1, Source: database.XMPFaceRegionSource},
+		{X: 0.6, Y: 0.6, Source: database.XMPFaceRegionSource},
+		{X: 0.62, Y: 0.1, Source: database.UserFaceRegionSource},
+	}
+
+	regions = getFaceRegions(t, image)
+	if len(regions) != len(want) {
+		t.Fatalf("UpdateItem() kept %d regions, want %d: %+v", len(regions), len(want), regions)
+	}
+
+	for index, region := range regions {
+		if region.X != want[index].X || region.Y != want[index].Y || region.Source != want[index].Source {
+			t.Errorf("region %d = %+v, want %+v", index, region, want[index])
+		}
+	}
+
+	// Faces removed from the file are removed, unless users edited them
+	syncFaceRegions(t, image, []database.FaceRegion{})
+
+	regions = getFaceRegions(t, image)
+	if len(regions) != 2 || regions[0].ID != added.ID || regions[1].ID != edited.ID {
+		t.Errorf("UpdateItem() without regions kept %+v, want the user regions", regions)
+	}
+
+	// Unknown regions are left untouched
+	syncFaceRegions(t, image, nil)
+
+	if regions := getFaceRegions(t, image); len(regions) != 2 {
+		t.Errorf("UpdateItem() with unknown regions kept %+v, want the user regions", regions)
+	}
+}
+
+func TestGetPhotosFromPerson(t *testing.T) {
+	databasetest.Setup(t)
+
+	person, err := database.GetOrCreatePerson(&database.ItemMetadata{Title: "Alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	portrait := createImage(t)
+	tagged := importedRegion(0.1, 0.1)
+	tagged.Person = person
+	syncFaceRegions(t, portrait, []database.FaceRegion{tagged, importedRegion(0.6, 0.1)})
+
+	// Faces nobody was assigned to don't make a photo show the person
+	crowd := database.ItemMetadata{Title: "Crowd", Type: database.ImageItem}
+	if err := database.CreateImage(&crowd); err != nil {
+		t.Fatal(err)
+	}
+
+	syncFaceRegions(t, &crowd, []database.FaceRegion{importedRegion(0.1, 0.1)})
+
+	limit, offset := int64(10), int64(0)
+
+	photos, err := database.GetPhotosFromPerson(fmtID(person.ID), &limit, &offset)
+	if err != nil {
+		t.Fatalf("GetPhotosFromPerson() error = %v", err)
+	}
+
+	if len(photos) != 1 || photos[0].ID != portrait.ID {
+		t.Errorf("GetPhotosFromPerson() = %+v, want only the portrait", photos)
+	}
+
+	count, err := database.GetPhotosCountFromPerson(fmtID(person.ID))
+	if err != nil {
+		t.Fatalf("GetPhotosCountFromPerson() error = %v", err)
+	}
+
+	if *count != 1 {
+		t.Errorf("GetPhotosCountFromPerson() = %d, want 1", *count)
+	}
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// The perceptual hashes of frames sampled at fixed relative positions of a video file,
+// used to find copies of the same video.
+type VideoFingerprint struct {
+	ID          uint64 `gorm:"primary_key" json:"id"`
+	MediaPartID uint64 `gorm:"not null;uniqueIndex" json:"mediaPartId"`
+	// Duration of the file, in milliseconds.
+	Duration int64 `gorm:"not null" json:"duration"`
+	// Comma-separated hashes of the frames, in order. Frames which couldn't be extracted are stored as zero.
+	FrameHashes string    `gorm:"not null" json:"frameHashes"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// The fingerprint of the file of an item.
+type ItemFingerprint struct {
+	ItemMetadataID uint64
+	Duration       int64
+	FrameHashes    string
+}
+
+// The resolution and bitrate of the video stream of an item, to tell which of its copies is the best one.
+type VideoQuality struct {
+	Width  int64 `json:"width"`
+	Height int64 `json:"height"`
+	// Bitrate of the stream, in bits per second, when the container reports it.
+	Bitrate int64 `json:"bitrate"`
+}
+
+// Creates or replaces the fingerprint of a media part.
+func SetVideoFingerprint(fingerprint *VideoFingerprint) error {
+	result := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "media_part_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"duration", "frame_hashes", "updated_at"}),
+	}).Create(fingerprint)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save video fingerprint: %w", result.Error)
+	}
+
+	return nil
+}
+
+// Returns the fingerprints of the visible items, from all libraries.
+func GetVideoFingerprints() ([]ItemFingerprint, error) {
+	var fingerprints []ItemFingerprint
+
+	result := db.Model(&VideoFingerprint{}).
+		Select("media_parts.item_metadata_id, video_fingerprints.duration, video_fingerprints.frame_hashes").
+		Joins("JOIN media_parts ON media_parts.id = video_fingerprints.media_part_id").
+		Joins("JOIN item_metadata ON item_metadata.id = media_parts.item_metadata_id").
+		Where("media_parts.deleted_at IS NULL AND item_metadata.hidden = ?", false).
+		Scan(&fingerprints)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return fingerprints, nil
+}
+
+// Returns the video quality of the given items, keyed by item. Items without a video stream are left out.
+func GetVideoQualities(itemIDs []uint64) (map[uint64]VideoQuality, error) {
+	var streams []struct {
+		ItemMetadataID  uint64
+		MediaStreamInfo []byte
+	}
+
+	result := db.Model(&MediaStream{}).
+		Select("media_parts.item_metadata_id, media_streams.media_stream_info").
+		Joins("JOIN media_parts ON media_parts.id = media_streams.media_part_id").
+		Where("media_parts.item_metadata_id IN ? AND media_streams.stream_type = ?", itemIDs, VideoStream).
+		Order("media_streams.`index`").
+		Scan(&streams)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	qualities := make(map[uint64]VideoQuality, len(itemIDs))
+
+	for _, stream := range streams {
+		// Only the first video stream counts, others are usually cover art
+		if _, ok := qualities[stream.ItemMetadataID]; ok {
+			continue
+		}
+
+		var info MediaStreamInfo
+		if err := json.Unmarshal(stream.MediaStreamInfo, &info); err != nil {
+			return nil, fmt.Errorf("failed to parse media stream info: %w", err)
+		}
+
+		// Unknown bitrates are left at zero
+		bitrate, _ := strconv.ParseInt(info.BitRate, 10, 64)
+
+		qualities[stream.ItemMetadataID] = VideoQuality{
+			Width:   int64(info.Width),
+			Height:  int64(info.Height),
+			Bitrate: bitrate,
+		}
+	}
+
+	return qualities, nil
+}
+
+// Returns the media parts of the visible items of the given types which weren't fingerprinted yet.
+func GetMediaPartsWithoutFingerprint(itemTypes []ItemType) ([]MediaPart, error) {
+	var mediaParts []MediaPart
+
+	result := db.Model(&MediaPart{}).
+		Select("media_parts.*").
+		Joins("JOIN item_metadata ON item_metadata.id = media_parts.item_metadata_id").
+		Joins("LEFT JOIN video_fingerprints ON video_fingerprints.media_part_id = media_parts.id").
+		Where("video_fingerprints.id IS NULL AND item_metadata.type IN ? AND item_metadata.hidden = ?", itemTypes, false).
+		Find(&mediaParts)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get media parts without fingerprint: %w", result.Error)
+	}
+
+	return mediaParts, nil
+}
@@ -0,0 +1,152 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/internal/databasetest"
+)
+
+func createMovie(t *testing.T, filePath string) *database.ItemMetadata {
+	t.Helper()
+
+	movie := database.ItemMetadata{
+		Title:     filePath,
+		Type:      database.MovieItem,
+		MediaPart: database.MediaPart{FilePath: filePath, Hash: filePath},
+	}
+	if err := database.CreateMovie(&movie); err != nil {
+		t.Fatal(err)
+	}
+
+	return &movie
+}
+
+func TestGetMediaPartsWithoutFingerprint(t *testing.T) {
+	databasetest.Setup(t)
+
+	pending := createMovie(t, "/movies/Pending.mkv")
+	fingerprinted := createMovie(t, "/movies/Fingerprinted.mkv")
+	createImage(t)
+
+	err := database.SetVideoFingerprint(&database.VideoFingerprint{
+		MediaPartID: fingerprinted.MediaPart.ID,
+		Duration:    1000,
+		FrameHashes: "1,2,3",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mediaParts, err := database.GetMediaPartsWithoutFingerprint([]database.ItemType{database.MovieItem})
+	if err != nil {
+		t.Fatalf("GetMediaPartsWithoutFingerprint() error = %v", err)
+	}
+
+	if len(mediaParts) != 1 || mediaParts[0].ID != pending.MediaPart.ID || mediaParts[0].FilePath != "/movies/Pending.mkv" {
+		t.Errorf("GetMediaPartsWithoutFingerprint() = %v, want only %s", mediaParts, pending.MediaPart.FilePath)
+	}
+}
+
+func TestSetVideoFingerprint(t *testing.T) {
+	databasetest.Setup(t)
+
+	movie := createMovie(t, "/movies/Alien.mkv")
+	hidden := createMovie(t, "/movies/Alien (copy).mkv")
+
+	// Saving a fingerprint again replaces it
+	for _, fingerprint := range []*database.VideoFingerprint{
+		{MediaPartID: movie.MediaPart.ID, Duration: 1000, FrameHashes: "1,2,3"},
+		{MediaPartID: movie.MediaPart.ID, Duration: 2000, FrameHashes: "4,5,6"},
+		{MediaPartID: hidden.MediaPart.ID, Duration: 2000, FrameHashes: "4,5,6"},
+	} {
+		if err := database.SetVideoFingerprint(fingerprint); err != nil {
+			t.Fatalf("SetVideoFingerprint() error = %v", err)
+		}
+	}
+
+	if err := database.AddDuplicateCandidate(movie.ID, hidden.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	candidates, err := database.GetAllPendingDuplicateCandidates(nil)
+	if err != nil || len(candidates) != 1 {
+		t.Fatalf("GetAllPendingDuplicateCandidates() = %v, %v, want a candidate", candidates, err)
+	}
+
+	if err := database.HideDuplicateCandidate(candidates[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	// Hidden items are left out
+	fingerprints, err := database.GetVideoFingerprints()
+	if err != nil || len(fingerprints) != 1 {
+		t.Fatalf("GetVideoFingerprints() = %+v, %v, want a single fingerprint", fingerprints, err)
+	}
+
+	if fingerprint := fingerprints[0]; fingerprint.ItemMetadataID != movie.ID || fingerprint.Duration != 2000 ||
+		fingerprint.FrameHashes != "4,5,6" {
+		t.Errorf("GetVideoFingerprints() = %+v, want the last fingerprint of %s", fingerprint, movie.Title)
+	}
+}
+
+func TestGetVideoQualities(t *testing.T) {
+	databasetest.Setup(t)
+
+	movie := createMovie(t, "/movies/Alien.mkv")
+	withoutVideo := createMovie(t, "/movies/Alien (commentary).mka")
+
+	// Only the first video stream counts, like the preview track of some files
+	for _, stream := range []struct {
+		mediaPartID uint64
+		streamType  database.StreamType
+		index       int
+		info        string
+	}{
+		{movie.MediaPart.ID, database.AudioStream, 0, `{"bitrate":"448000"}`},
+		{movie.MediaPart.ID, database.VideoStream, 1, `{"width":1920,"height":1080,"bitrate":"8000000"}`},
+		{movie.MediaPart.ID, database.VideoStream, 2, `{"width":320,"height":180}`},
+		{withoutVideo.MediaPart.ID, database.AudioStream, 0, `{"bitrate":"192000"}`},
+	} {
+		err := database.CreateMediaStream("", stream.streamType, "", stream.index, []byte(stream.info), stream.mediaPartID)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	qualities, err := database.GetVideoQualities([]uint64{movie.ID, withoutVideo.ID})
+	if err != nil {
+		t.Fatalf("GetVideoQualities() error = %v", err)
+	}
+
+	want := database.VideoQuality{Width: 1920, Height: 1080, Bitrate: 8000000}
+	if len(qualities) != 1 || qualities[movie.ID] != want {
+		t.Errorf("GetVideoQualities() = %+v, want %+v for %s only", qualities, want, movie.Title)
+	}
+}
+
+func TestSetAcoustID(t *testing.T) {
+	databasetest.Setup(t)
+
+	track := database.ItemMetadata{
+		Title:     "Windowlicker",
+		Type:      database.MusicTrackItem,
+		MediaPart: database.MediaPart{FilePath: "/music/windowlicker.flac"},
+	}
+	if err := database.CreateMusicTrack(&track); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.SetAcoustID(&track.MediaPart, "AQADtEmUaEkSRZEG"); err != nil {
+		t.Fatalf("SetAcoustID() error = %v", err)
+	}
+
+	mediaPart, err := database.GetMediaPartFromItem(fmtID(track.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mediaPart.AcoustID != "AQADtEmUaEkSRZEG" || mediaPart.FilePath != "/music/windowlicker.flac" {
+		t.Errorf("SetAcoustID() saved %+v", mediaPart)
+	}
+}
@@ -0,0 +1,7 @@
+package database_test
+
+import "strconv"
+
+func fmtID(id uint64) string {
+	return strconv.FormatUint(id, 10) //nolint:gomnd
+}
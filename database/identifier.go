@@ -0,0 +1,69 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Replaces the external identifiers of an item with the given ones.
+func setExternalIdentifiers(transaction *gorm.DB, itemID uint64, identifiers []ExternalIdentifier) error {
+	result := transaction.Where("item_metadata_id = ?", itemID).Delete(&ExternalIdentifier{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete external identifiers: %w", result.Error)
+	}
+
+	if len(identifiers) == 0 {
+		return nil
+	}
+
+	for index := range identifiers {
+		identifiers[index].ID = 0
+		identifiers[index].ItemMetadataID = itemID
+	}
+
+	if result := transaction.Create(&identifiers); result.Error != nil {
+		return fmt.Errorf("failed to create external identifiers: %w", result.Error)
+	}
+
+	return nil
+}
+
+// Adds an external identifier to an item, unless it already has it.
+func AddExternalIdentifier(itemID uint64, identifierType IdentifierType, identifier string) error {
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&ExternalIdentifier{
+		IdentifierType: identifierType,
+		Identifier:     identifier,
+		ItemMetadataID: itemID,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to create external identifier: %w", result.Error)
+	}
+
+	return nil
+}
+
+// Returns the external identifiers of the given item.
+func GetExternalIdentifiersFromItem(itemID string) ([]*ExternalIdentifier, error) {
+	var identifiers []*ExternalIdentifier
+
+	result := db.Where("item_metadata_id = ?", itemID).Order("identifier_type").Find(&identifiers)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return identifiers, nil
+}
+
+// Returns the item with the given external identifier, like its IMDb ID.
+func GetItemByExternalIdentifier(identifierType IdentifierType, identifier string) (*ItemMetadata, error) {
+	var externalIdentifier ExternalIdentifier
+
+	result := db.Where("identifier_type = ? AND identifier = ?", identifierType, identifier).First(&externalIdentifier)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return GetItemByID(fmt.Sprint(externalIdentifier.ItemMetadataID))
+}
@@ -0,0 +1,101 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/internal/databasetest"
+)
+
+func TestMigrateLegacyExternalIdentifiers(t *testing.T) {
+	databasetest.Setup(t)
+
+	movie := database.ItemMetadata{Title: "The Matrix", Type: database.MovieItem}
+	if err := database.CreateMovie(&movie); err != nil {
+		t.Fatal(err)
+	}
+
+	database.MigrateLegacyExternalIdentifiers(t, map[uint64]string{movie.ID: "tt0133093"})
+
+	item, err := database.GetItemByExternalIdentifier(database.ImdbIdentifier, "tt0133093")
+	if err != nil || item.ID != movie.ID {
+		t.Errorf("GetItemByExternalIdentifier() = %+v, %v, want %s", item, err, movie.Title)
+	}
+}
+
+func TestUpdateItemExternalIdentifiers(t *testing.T) {
+	databasetest.Setup(t)
+
+	movie := database.ItemMetadata{Title: "The Matrix", Type: database.MovieItem}
+	if err := database.CreateMovie(&movie); err != nil {
+		t.Fatal(err)
+	}
+
+	movie.ExternalIdentifiers = []database.ExternalIdentifier{
+		{IdentifierType: database.TmdbIdentifier, Identifier: "603"},
+		{IdentifierType: database.ImdbIdentifier, Identifier: "tt0133093"},
+	}
+
+	if err := database.UpdateItem(&movie); err != nil {
+		t.Fatal(err)
+	}
+
+	// Updates without identifiers keep the saved ones
+	movie.ExternalIdentifiers = nil
+	movie.Title = "Matrix"
+
+	if err := database.UpdateItem(&movie); err != nil {
+		t.Fatal(err)
+	}
+
+	identifiers, err := database.GetExternalIdentifiersFromItem(fmtID(movie.ID))
+	if err != nil || len(identifiers) != 2 {
+		t.Fatalf("GetExternalIdentifiersFromItem() = %+v, %v, want 2 identifiers", identifiers, err)
+	}
+
+	if identifiers[0].IdentifierType != database.ImdbIdentifier || identifiers[1].IdentifierType != database.TmdbIdentifier {
+		t.Errorf("GetExternalIdentifiersFromItem() = %+v, want them sorted by type", identifiers)
+	}
+
+	item, err := database.GetItemByExternalIdentifier(database.TmdbIdentifier, "603")
+	if err != nil || item.Title != "Matrix" {
+		t.Errorf("GetItemByExternalIdentifier() = %+v, %v, want %s", item, err, movie.Title)
+	}
+}
+
+func TestIdentifierTypeUnmarshalText(t *testing.T) {
+	t.Parallel()
+
+	var identifierType database.IdentifierType
+
+	if err := identifierType.UnmarshalText([]byte("IMDb")); err != nil || identifierType != database.ImdbIdentifier {
+		t.Errorf("UnmarshalText(IMDb) = %v, %v, want %v", identifierType, err, database.ImdbIdentifier)
+	}
+
+	if err := identifierType.UnmarshalText([]byte("Letterboxd")); err == nil {
+		t.Error("UnmarshalText() should fail for unknown identifier types")
+	}
+}
+
+func TestAddExternalIdentifierIgnoresDuplicates(t *testing.T) {
+	databasetest.Setup(t)
+
+	track := database.ItemMetadata{Title: "Windowlicker", Type: database.MusicTrackItem}
+	if err := database.CreateMusicTrack(&track); err != nil {
+		t.Fatal(err)
+	}
+
+	const recording = "0e1e5c6d-7ed9-4e2b-8c3b-1d3e06c1ad5a"
+
+	// Adding the same identifier again is a no-op
+	for i := 0; i < 2; i++ {
+		if err := database.AddExternalIdentifier(track.ID, database.MusicbrainzIdentifier, recording); err != nil {
+			t.Fatalf("AddExternalIdentifier() error = %v", err)
+		}
+	}
+
+	identifiers, err := database.GetExternalIdentifiersFromItem(fmtID(track.ID))
+	if err != nil || len(identifiers) != 1 {
+		t.Errorf("GetExternalIdentifiersFromItem() = %+v, %v, want 1 identifier", identifiers, err)
+	}
+}
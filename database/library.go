@@ -1,12 +1,20 @@
 package database
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
+
+	"golang.org/x/text/language"
+	"gorm.io/gorm"
 )
 
-var errInvalidLibraryType = errors.New("invalid library type")
+var (
+	errInvalidLibraryType = errors.New("invalid library type")
+	errInvalidLanguage    = errors.New("invalid language")
+)
 
 type LibraryType string
 
@@ -17,6 +25,9 @@ const (
 	AnimeTVLibrary    LibraryType = "animeTV"
 	MusicLibrary      LibraryType = "music"
 	ImageLibrary      LibraryType = "image"
+	MusicVideoLibrary LibraryType = "musicVideo"
+	AudiobookLibrary  LibraryType = "audiobook"
+	PodcastLibrary    LibraryType = "podcast"
 )
 
 func (l LibraryType) String() string {
@@ -65,6 +76,18 @@ func (l *LibraryType) UnmarshalText(text []byte) error {
 	case "image":
 		*l = ImageLibrary
 
+		return nil
+	case "musicVideo":
+		*l = MusicVideoLibrary
+
+		return nil
+	case "audiobook":
+		*l = AudiobookLibrary
+
+		return nil
+	case "podcast":
+		*l = PodcastLibrary
+
 		return nil
 	}
 
@@ -76,16 +99,51 @@ type Library struct {
 	Name             string            `json:"name"`
 	Type             LibraryType       `json:"type"`
 	Language         string            `json:"language"`
+	IncludeAdult     bool              `gorm:"not null;default:false" json:"includeAdult"`
 	LibraryLocations []LibraryLocation `gorm:"not null" json:"libraryLocations"`
 	CreatedAt        time.Time         `json:"createdAt"`
 	UpdatedAt        time.Time         `json:"updatedAt"`
 	ScannedAt        time.Time         `json:"scannedAt"`
+	// Languages subtitles are downloaded in, as comma-separated BCP 47 tags like "en,pt-BR".
+	SubtitleLanguages string `json:"subtitleLanguages"`
 }
 
 func (Library) TableName() string {
 	return "libraries"
 }
 
+// Returns the languages subtitles are downloaded in for the library, if any.
+func (l *Library) GetSubtitleLanguages() []string {
+	if l.SubtitleLanguages == "" {
+		return []string{}
+	}
+
+	return strings.Split(l.SubtitleLanguages, ",")
+}
+
+// Sets the languages subtitles are downloaded in for the library, skipping duplicates.
+// Languages are BCP 47 tags, like "en" or "pt-BR", and are stored in their canonical form.
+func (l *Library) SetSubtitleLanguages(languages []string) error {
+	subtitleLanguages := make([]string, 0, len(languages))
+	seen := make(map[string]bool, len(languages))
+
+	for _, subtitleLanguage := range languages {
+		tag, err := language.Parse(subtitleLanguage)
+		if err != nil {
+			return fmt.Errorf("%w: %s", errInvalidLanguage, subtitleLanguage)
+		}
+
+		if !seen[tag.String()] {
+			seen[tag.String()] = true
+			subtitleLanguages = append(subtitleLanguages, tag.String())
+		}
+	}
+
+	l.SubtitleLanguages = strings.Join(subtitleLanguages, ",")
+
+	return nil
+}
+
 type LibraryLocation struct {
 	ID        uint64    `gorm:"primary_key" json:"id"`
 	LibraryID uint64    `gorm:"not null"`
@@ -96,7 +154,12 @@ type LibraryLocation struct {
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
-func CreateLibrary(name, language, typeArg string, locations []string) (*Library, []LibraryLocation, error) {
+func CreateLibrary(
+	name, language, typeArg string,
+	locations []string,
+	includeAdult bool,
+	subtitleLanguages []string,
+) (*Library, []LibraryLocation, error) {
 	var libraryLocations []LibraryLocation //nolint:prealloc
 	for _, location := range locations {
 		libraryLocations = append(libraryLocations, LibraryLocation{
@@ -114,9 +177,14 @@ func CreateLibrary(name, language, typeArg string, locations []string) (*Library
 		Name:             name,
 		Type:             libraryType,
 		Language:         language,
+		IncludeAdult:     includeAdult,
 		LibraryLocations: libraryLocations,
 	}
 
+	if err := library.SetSubtitleLanguages(subtitleLanguages); err != nil {
+		return nil, nil, err
+	}
+
 	if result := db.Create(&library); result.Error != nil {
 		return nil, nil, fmt.Errorf("failed to create library: %w", result.Error)
 	}
@@ -133,6 +201,32 @@ func GetLibrary(id string) Library {
 	return library
 }
 
+// Returns the library of a shared item, like an artist or a collection, which doesn't belong to a library of its own.
+// That's the library of the first item it was linked to, as an artist, a credited person, a person shown in a photo,
+// or a collection holding the item.
+func GetSharedItemLibrary(itemID uint64) (*Library, error) {
+	var library Library
+
+	result := db.Raw( /* sql */ `SELECT libraries.* FROM libraries
+		JOIN item_metadata ON item_metadata.library_id = libraries.id
+		WHERE item_metadata.id IN (
+			SELECT item_metadata_id FROM item_artists WHERE artist_id = @id
+			UNION SELECT item_metadata_id FROM credits WHERE person_id = @id
+			UNION SELECT item_metadata_id FROM face_regions WHERE person_id = @id
+			UNION SELECT item_metadata_id FROM collection_members WHERE collection_id = @id)
+		ORDER BY item_metadata.id
+		LIMIT 1`, sql.Named("id", itemID)).Scan(&library)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &library, nil
+}
+
 // Returns the requested fields for all libraries.
 func GetLibraries() []*Library {
 	var libraries []*Library
@@ -150,3 +244,32 @@ func GetLibrariesCount() int64 {
 
 	return count
 }
+
+// Returns the specified library, with its locations.
+func GetLibraryWithLocations(id string) (*Library, error) {
+	var library Library
+
+	if result := db.Preload("LibraryLocations").First(&library, id); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &library, nil
+}
+
+// Replaces the languages subtitles are downloaded in for the specified library.
+func SetLibrarySubtitleLanguages(id string, languages []string) (*Library, error) {
+	library, err := GetLibraryWithLocations(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := library.SetSubtitleLanguages(languages); err != nil {
+		return nil, err
+	}
+
+	if result := db.Model(library).UpdateColumn("subtitle_languages", library.SubtitleLanguages); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return library, nil
+}
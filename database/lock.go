@@ -0,0 +1,115 @@
+package database
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Fields of an item that can be locked, so refreshes and rescans don't overwrite manual edits.
+const (
+	TitleField         = "title"
+	SortTitleField     = "sortTitle"
+	OriginalTitleField = "originalTitle"
+	SummaryField       = "summary"
+	TaglineField       = "tagline"
+	ReleaseDateField   = "releaseDate"
+	ThumbField         = "thumb"
+	ArtField           = "art"
+)
+
+var lockableFields = []string{
+	TitleField,
+	SortTitleField,
+	OriginalTitleField,
+	SummaryField,
+	TaglineField,
+	ReleaseDateField,
+	ThumbField,
+	ArtField,
+}
+
+// Returns whether the given field can be locked.
+func IsLockableField(field string) bool {
+	for _, lockableField := range lockableFields {
+		if field == lockableField {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the locked fields of the item.
+func (i *ItemMetadata) GetLockedFields() []string {
+	if i.LockedFields == "" {
+		return []string{}
+	}
+
+	return strings.Split(i.LockedFields, ",")
+}
+
+// Returns whether the given field of the item is locked.
+func (i *ItemMetadata) IsFieldLocked(field string) bool {
+	for _, lockedField := range i.GetLockedFields() {
+		if lockedField == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Replaces the locked fields of the item. The item isn't saved.
+func (i *ItemMetadata) SetLockedFields(fields []string) {
+	var locked []string
+
+	// Keep a stable order, without duplicates
+	for _, field := range lockableFields {
+		for _, lockedField := range fields {
+			if lockedField == field {
+				locked = append(locked, field)
+
+				break
+			}
+		}
+	}
+
+	i.LockedFields = strings.Join(locked, ",")
+}
+
+// Puts back the saved values of the locked fields of an item, which only EditItem can change.
+func restoreLockedFields(transaction *gorm.DB, item *ItemMetadata) error {
+	var saved ItemMetadata
+
+	if result := transaction.First(&saved, item.ID); result.Error != nil {
+		return result.Error
+	}
+
+	item.LockedFields = saved.LockedFields
+
+	for _, field := range saved.GetLockedFields() {
+		switch field {
+		// The sort title goes with the title, so an edited title isn't sorted like the provider's
+		case TitleField:
+			item.Title = saved.Title
+			item.SortTitle = saved.SortTitle
+		case SortTitleField:
+			item.SortTitle = saved.SortTitle
+		case OriginalTitleField:
+			item.OriginalTitle = saved.OriginalTitle
+		case SummaryField:
+			item.Summary = saved.Summary
+		case TaglineField:
+			item.Tagline = saved.Tagline
+		case ReleaseDateField:
+			item.ReleaseDate = saved.ReleaseDate
+		case ThumbField:
+			item.Thumb = saved.Thumb
+		case ArtField:
+			item.Art = saved.Art
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/internal/databasetest"
+)
+
+func TestUpdateItemKeepsLockedFields(t *testing.T) {
+	databasetest.Setup(t)
+
+	movie := database.ItemMetadata{Title: "Matrix", SortTitle: "Matrix", Type: database.MovieItem}
+	if err := database.CreateMovie(&movie); err != nil {
+		t.Fatal(err)
+	}
+
+	movie.Title = "The Matrix (Director's Cut)"
+	movie.SortTitle = "Matrix (Director's Cut)"
+	movie.Thumb = "edited"
+	movie.SetLockedFields([]string{database.TitleField, database.ThumbField})
+
+	if err := database.EditItem(&movie); err != nil {
+		t.Fatal(err)
+	}
+
+	// A refresh overwriting every field
+	refreshed := movie
+	refreshed.Title = "The Matrix"
+	refreshed.SortTitle = "Matrix, The"
+	refreshed.Thumb = "provider"
+	refreshed.Summary = "A computer hacker learns about the true nature of reality."
+	refreshed.LockedFields = ""
+
+	if err := database.UpdateItem(&refreshed); err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+
+	saved, err := database.GetItemByID(fmtID(movie.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if saved.Title != movie.Title || saved.SortTitle != movie.SortTitle || saved.Thumb != "edited" {
+		t.Errorf("UpdateItem() saved %q, %q, %q, want the locked title, sort title and thumb",
+			saved.Title, saved.SortTitle, saved.Thumb)
+	}
+
+	if saved.Summary != refreshed.Summary {
+		t.Errorf("UpdateItem() saved summary %q, want %q", saved.Summary, refreshed.Summary)
+	}
+
+	if saved.LockedFields != "title,thumb" {
+		t.Errorf("UpdateItem() saved locked fields %q, want the saved ones", saved.LockedFields)
+	}
+}
+
+func TestSetPersonThumbKeepsLockedThumb(t *testing.T) {
+	databasetest.Setup(t)
+
+	person, err := database.GetOrCreatePerson(&database.ItemMetadata{
+		Title: "Keanu Reeves",
+		ExternalIdentifiers: []database.ExternalIdentifier{
+			{IdentifierType: database.TmdbIdentifier, Identifier: "6384"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.SetPersonThumb(person.ID, "provider"); err != nil {
+		t.Fatalf("SetPersonThumb() error = %v", err)
+	}
+
+	if saved, _ := database.GetItemByID(fmtID(person.ID)); saved.Thumb != "provider" {
+		t.Errorf("SetPersonThumb() saved %q, want provider", saved.Thumb)
+	}
+
+	person.Thumb = ""
+	person.SetLockedFields([]string{database.ThumbField})
+
+	if err := database.EditItem(person); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.SetPersonThumb(person.ID, "other"); err != nil {
+		t.Fatalf("SetPersonThumb() error = %v", err)
+	}
+
+	if saved, _ := database.GetItemByID(fmtID(person.ID)); saved.Thumb != "" {
+		t.Errorf("SetPersonThumb() saved %q over a locked thumb", saved.Thumb)
+	}
+}
+
+func TestSetLockedFields(t *testing.T) {
+	t.Parallel()
+
+	var item database.ItemMetadata
+
+	item.SetLockedFields([]string{database.ThumbField, "bogus", database.TitleField, database.ThumbField})
+
+	// Fields are kept once, in a stable order, and unknown ones are dropped
+	if item.LockedFields != "title,thumb" {
+		t.Errorf("SetLockedFields() = %q, want %q", item.LockedFields, "title,thumb")
+	}
+
+	if !item.IsFieldLocked(database.TitleField) || item.IsFieldLocked("bogus") {
+		t.Errorf("IsFieldLocked() = %v, want only the known fields locked", item.GetLockedFields())
+	}
+}
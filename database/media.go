@@ -1,7 +1,9 @@
 package database
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -11,19 +13,27 @@ import (
 	"gorm.io/gorm/clause"
 )
 
+var errInvalidIdentifierType = errors.New("invalid identifier type")
+
 type MediaPart struct {
-	ID               uint64 `gorm:"primary_key" json:"id"`
-	Hash             string `gorm:"not null"`
+	ID   uint64 `gorm:"primary_key" json:"id"`
+	Hash string `gorm:"not null"`
+	// The OpenSubtitles hash of videos, identifying them on subtitle providers along with their size.
 	OpenSubtitleHash string
-	AniDBCRC         string
-	AcoustID         string
-	FilePath         string `gorm:"index;unique;not null"`
-	Size             int64  `gorm:"not null"`
-	ItemMetadataID   uint64
-	MediaStreams     []MediaStream  `json:"mediaStreams"`
-	CreatedAt        time.Time      `json:"createdAt"`
-	UpdatedAt        time.Time      `json:"updatedAt"`
-	DeletedAt        gorm.DeletedAt `gorm:"index"`
+	// The CRC32 of the file, as fansub groups write it in file names, in upper case.
+	AniDBCRC string
+	// The ED2K hash of the file, identifying it on AniDB along with its size.
+	ED2KHash string
+	// Whether the CRC32 of the file differs from the one in its name, which hints at a corrupted file.
+	CRCMismatch    bool
+	AcoustID       string
+	FilePath       string `gorm:"index;unique;not null"`
+	Size           int64  `gorm:"not null"`
+	ItemMetadataID uint64
+	MediaStreams   []MediaStream  `json:"mediaStreams"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+	DeletedAt      gorm.DeletedAt `gorm:"index"`
 }
 
 type IdentifierType int8
@@ -52,11 +62,45 @@ func (d IdentifierType) String() string {
 	}[d]
 }
 
+var identifierTypeKeys = map[IdentifierType]string{
+	ImdbIdentifier:        "imdb",
+	TmdbIdentifier:        "tmdb",
+	AnidbIdentifier:       "anidb",
+	TvdbIdentifier:        "tvdb",
+	MusicbrainzIdentifier: "musicbrainz",
+	FacebookIdentifier:    "facebook",
+	TwitterIdentifier:     "twitter",
+	InstagramIdentifier:   "instagram",
+}
+
+// Returns the short key of the identifier type, like "imdb", as used in the API.
+func (d IdentifierType) MarshalText() ([]byte, error) {
+	key, ok := identifierTypeKeys[d]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", errInvalidIdentifierType, d)
+	}
+
+	return []byte(key), nil
+}
+
+func (d *IdentifierType) UnmarshalText(text []byte) error {
+	for identifierType, key := range identifierTypeKeys {
+		if strings.EqualFold(key, string(text)) {
+			*d = identifierType
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", errInvalidIdentifierType, text)
+}
+
+// Links an item to its identifier in an external database, like IMDb or TMDb.
 type ExternalIdentifier struct {
 	ID             uint64         `gorm:"primary_key" json:"id"`
-	IdentifierType IdentifierType `gorm:"not null"`
-	Identifier     string         `gorm:"not null"`
-	MovieID        uint64         `gorm:"not null"`
+	IdentifierType IdentifierType `gorm:"not null;uniqueIndex:idx_external_identifier"`
+	Identifier     string         `gorm:"not null;uniqueIndex:idx_external_identifier"`
+	ItemMetadataID uint64         `gorm:"not null;uniqueIndex:idx_external_identifier;index"`
 }
 
 type StreamType int8
@@ -82,6 +126,9 @@ type MediaStream struct {
 	MediaPartID     uint64         `gorm:"not null"`
 	CreatedAt       time.Time      `json:"createdAt"`
 	UpdatedAt       time.Time      `json:"updatedAt"`
+	// Path of the file holding the stream, for subtitles stored outside of the media file, like downloaded ones.
+	// Empty for the streams of the media file.
+	FilePath string `json:"filePath"`
 }
 
 // This is stored in DB as a JSON blob, since we never need to filter on it.
@@ -121,10 +168,35 @@ type MediaStreamInfo struct {
 	BitsPerSample      int                       `json:"bitsPerSample"`
 }
 
-func SetAcoustID(mediaPart *MediaPart, acoustID string) {
+// Sets the Chromaprint fingerprint of a media part.
+func SetAcoustID(mediaPart *MediaPart, acoustID string) error {
 	mediaPart.AcoustID = acoustID
 
-	db.Save(&mediaPart)
+	if result := db.Model(mediaPart).UpdateColumn("acoust_id", acoustID); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// Saves the AniDB hashes of a media part, and whether its CRC32 differs from the one in its name.
+// They are also set on the given part.
+func SetAniDBHashes(mediaPart *MediaPart, ed2kHash, crc string, crcMismatch bool) error {
+	mediaPart.ED2KHash = ed2kHash
+	mediaPart.AniDBCRC = crc
+	mediaPart.CRCMismatch = crcMismatch
+
+	// Select the fields, so that a mismatch is cleared even though false is a zero value
+	result := db.Model(mediaPart).Select("ED2KHash", "AniDBCRC", "CRCMismatch").UpdateColumns(MediaPart{
+		ED2KHash:    ed2kHash,
+		AniDBCRC:    crc,
+		CRCMismatch: crcMismatch,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
 }
 
 func CreateMediaStream(
@@ -154,6 +226,35 @@ func CreateMediaStream(
 	return nil
 }
 
+// Adds a subtitle stored outside of the media file to a media part.
+func CreateExternalSubtitleStream(mediaPartID uint64, title, language, filePath string) error {
+	mediaStream := MediaStream{
+		Title:       title,
+		StreamType:  SubtitleStream,
+		Language:    language,
+		MediaPartID: mediaPartID,
+		FilePath:    filePath,
+	}
+
+	if result := db.Create(&mediaStream); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// Returns the subtitle streams of a media part, both from the media file and external ones.
+func GetSubtitleStreams(mediaPartID uint64) ([]MediaStream, error) {
+	var mediaStreams []MediaStream
+
+	result := db.Where("media_part_id = ? AND stream_type = ?", mediaPartID, SubtitleStream).Find(&mediaStreams)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return mediaStreams, nil
+}
+
 func CreateMediaPart(mediaPart MediaPart) (*MediaPart, error) {
 	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&mediaPart)
 	// TODO: Check for the actual error type
@@ -165,6 +266,38 @@ func CreateMediaPart(mediaPart MediaPart) (*MediaPart, error) {
 	return &mediaPart, nil
 }
 
+// Returns which of the given media parts exist, as a set.
+func GetExistingMediaPartIDs(ids []uint64) (map[uint64]bool, error) {
+	var existingIDs []uint64
+
+	if result := db.Model(&MediaPart{}).Where("id IN ?", ids).Pluck("id", &existingIDs); result.Error != nil {
+		return nil, fmt.Errorf("failed to get media parts: %w", result.Error)
+	}
+
+	existing := make(map[uint64]bool, len(existingIDs))
+	for _, id := range existingIDs {
+		existing[id] = true
+	}
+
+	return existing, nil
+}
+
+// Returns the file of the given item, or nil if it has none, like shows or albums.
+func GetMediaPartFromItem(itemID string) (*MediaPart, error) {
+	var mediaPart MediaPart
+
+	result := db.Where("item_metadata_id = ?", itemID).Limit(1).Find(&mediaPart)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get media part: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &mediaPart, nil
+}
+
 func GetMediaPart(metadataID, mediaPartID string) (*MediaPart, error) {
 	var mediaPart MediaPart
 
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ItemType uint
@@ -26,17 +28,21 @@ const (
 	PersonItem
 	GroupItem
 	CollectionItem
+	MusicVideoItem
+	AudiobookItem
+	AudiobookPartItem
+	PodcastItem
+	PodcastEpisodeItem
 )
 
 type ItemMetadata struct {
-	ID            uint64   `gorm:"primary_key" json:"id"`
-	Title         string   `gorm:"type:VARCHAR(255)" json:"title"`
-	SortTitle     string   `gorm:"type:VARCHAR(255) COLLATE NOCASE" json:"sortTitle"`
-	OriginalTitle string   `gorm:"type:VARCHAR(255)" json:"originalTitle"`
-	Tagline       string   `gorm:"type:VARCHAR(255)" json:"tagline"`
-	Summary       string   `json:"summary"`
-	Type          ItemType `gorm:"not null;type:INT" json:"type"`
-	// ExternalID []ExternalIdentifier
+	ID               uint64         `gorm:"primary_key" json:"id"`
+	Title            string         `gorm:"type:VARCHAR(255)" json:"title"`
+	SortTitle        string         `gorm:"type:VARCHAR(255) COLLATE NOCASE" json:"sortTitle"`
+	OriginalTitle    string         `gorm:"type:VARCHAR(255)" json:"originalTitle"`
+	Tagline          string         `gorm:"type:VARCHAR(255)" json:"tagline"`
+	Summary          string         `json:"summary"`
+	Type             ItemType       `gorm:"not null;type:INT" json:"type"`
 	ReleaseDate      time.Time      `json:"releaseDate"`
 	Popularity       float32        `json:"popularity"`
 	ParentID         uint64         `json:"parentId"`
@@ -47,38 +53,132 @@ type ItemMetadata struct {
 	Thumb            string         `json:"thumb"`
 	Art              string         `json:"art"`
 	ExtraInfo        datatypes.JSON `json:"extraInfo"`
+	MatchProvider    string         `json:"matchProvider"`
+	MatchID          string         `json:"matchId"`
+	RefreshedAt      time.Time      `json:"refreshedAt"`
 	MediaPart        MediaPart      `json:"mediaPart"`
 	LibraryID        uint64
 	Library          Library   `gorm:"not null" json:"library"`
 	CreatedAt        time.Time `json:"createdAt"`
 	UpdatedAt        time.Time `json:"updatedAt"`
 	DeleteAt         time.Time `json:"deleteAt"`
+	// Only saved by UpdateItem, where nil leaves the saved identifiers, credits, collections and tags untouched.
+	ExternalIdentifiers []ExternalIdentifier `json:"externalIdentifiers"`
+	Credits             []Credit             `gorm:"foreignKey:ItemMetadataID" json:"credits"`
+	// The collections imported from providers. User collections are managed separately.
+	Collections []CollectionMember `gorm:"foreignKey:ItemMetadataID" json:"collections"`
+	// The tags assigned by providers, like genres. User tags are managed separately.
+	Tags []ItemTag `gorm:"foreignKey:ItemMetadataID" json:"tags"`
+	// Comma-separated fields edited by users, which UpdateItem leaves untouched. See EditItem.
+	LockedFields string `json:"lockedFields"`
+	// The EXIF data of images. Only saved by UpdateItem, where nil leaves the saved data untouched.
+	Exif *ImageExif `gorm:"foreignKey:ItemMetadataID" json:"exif"`
+	// The face regions of images imported from files. Only saved by UpdateItem, where nil leaves the saved
+	// regions untouched. Regions edited by users are managed separately.
+	FaceRegions []FaceRegion `gorm:"foreignKey:ItemMetadataID" json:"faceRegions"`
+	// The community ratings imported from providers, one per source. Only saved by UpdateItem,
+	// where nil leaves the saved ratings untouched.
+	Ratings []CommunityRating `gorm:"foreignKey:ItemMetadataID" json:"ratings"`
+	// Perceptual hash of images, as hexadecimal, used to find near-duplicates.
+	PerceptualHash string `gorm:"index" json:"perceptualHash"`
+	// Set when a user removed the match of the item, so that it isn't matched again automatically,
+	// until they fix its match by hand.
+	Unmatched bool `gorm:"not null;default:false" json:"unmatched"`
+	// Hidden items, like duplicates, are left out of libraries.
+	Hidden bool `gorm:"not null;default:false" json:"hidden"`
+	// Identifies items shared by several files, like artists or albums, so that concurrent scans
+	// don't create them twice. Nil for other items. See createUniqueItem.
+	UniqueKey *string `gorm:"uniqueIndex" json:"-"`
+}
+
+// Describes how the children of an item are sorted.
+type ChildSortOrder string
+
+const (
+	// Children are sorted by index, like tracks or episodes, then by title.
+	IndexChildSortOrder ChildSortOrder = "index"
+	// Children are sorted by sort title.
+	TitleChildSortOrder ChildSortOrder = "title"
+	// Children are sorted by the date their photo was taken, oldest first.
+	// Children without a capture date come last.
+	CaptureDateChildSortOrder ChildSortOrder = "captureDate"
+	// Children are sorted by the rating of the user, best first, then by sort title.
+	// Unrated children come last.
+	UserRatingChildSortOrder ChildSortOrder = "userRating"
+)
+
+func (s ChildSortOrder) String() string {
+	return string(s)
+}
+
+func (s *ChildSortOrder) UnmarshalText(text []byte) error {
+	switch sortOrder := ChildSortOrder(text); sortOrder {
+	case IndexChildSortOrder, TitleChildSortOrder, CaptureDateChildSortOrder, UserRatingChildSortOrder:
+		*s = sortOrder
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", errInvalidSortOrder, text)
 }
 
 type MovieExtraInfo struct {
 	Type string `json:"type"`
 }
 
+type ImageExtraInfo struct {
+	// Star rating embedded in the file, from 1 to 5, or -1 for rejected photos. 0 means unrated.
+	Rating int `json:"rating"`
+}
+
+type MusicTrackExtraInfo struct {
+	Album            string `json:"album"`
+	AlbumArtist      string `json:"albumArtist"`
+	ReleaseID        string `json:"releaseId"`
+	ReleaseGroupID   string `json:"releaseGroupId"`
+	ReleaseGroupType string `json:"releaseGroupType"`
+	Label            string `json:"label"`
+	CatalogNumber    string `json:"catalogNumber"`
+	Country          string `json:"country"`
+	DiscNumber       int    `json:"discNumber"`
+	TrackNumber      int    `json:"trackNumber"`
+}
+
+type AudiobookExtraInfo struct {
+	Author      string `json:"author"`
+	Narrator    string `json:"narrator"`
+	Series      string `json:"series"`
+	SeriesIndex string `json:"seriesIndex"`
+}
+
 // Returns the requested fields from the specified item.
 func GetItemByID(id string) (*ItemMetadata, error) {
 	// TODO: Figure out a way to only request specific fields for this
 	var item ItemMetadata
 
-	if result := db.First(&item, id); result.Error != nil {
+	if result := db.Preload("MediaPart").Preload("Library").First(&item, id); result.Error != nil {
 		return nil, result.Error
 	}
 
 	return &item, nil
 }
 
-// Returns all the top-level items from the specified library.
-func GetItemsFromLibrary(libraryID string, limit, offset *int64) ([]*ItemMetadata, error) {
+// Returns the top-level items from the specified library matching the rating filter, in the given order.
+// Items are left unsorted when the order is empty.
+func GetItemsFromLibrary(
+	libraryID string,
+	sortOrder ChildSortOrder,
+	ratingFilter RatingFilter,
+	limit, offset *int64,
+) ([]*ItemMetadata, error) {
 	var items []*ItemMetadata
 
-	result := db.
+	query := db.Where(
+		"item_metadata.library_id = ? AND item_metadata.parent_id = 0 AND item_metadata.hidden = ?", libraryID, false)
+
+	result := sortItems(filterOnUserRating(query, ratingFilter), sortOrder).
 		Limit(int(*limit)).
 		Offset(int(*offset)).
-		Where("library_id = ? AND parent_id = 0", libraryID).
 		Find(&items)
 	if result.Error != nil {
 		return nil, result.Error
@@ -87,25 +187,34 @@ func GetItemsFromLibrary(libraryID string, limit, offset *int64) ([]*ItemMetadat
 	return items, nil
 }
 
-func GetItemsCountFromLibrary(libraryID string) (*int64, error) {
+// Returns the number of top-level items from the specified library matching the rating filter.
+func GetItemsCountFromLibrary(libraryID string, ratingFilter RatingFilter) (*int64, error) {
 	var count int64
 
-	result := db.Model(&ItemMetadata{}).Where("library_id = ? AND parent_id = 0", libraryID).Count(&count)
-	if result.Error != nil {
+	query := db.Model(&ItemMetadata{}).Where(
+		"item_metadata.library_id = ? AND item_metadata.parent_id = 0 AND item_metadata.hidden = ?", libraryID, false)
+
+	if result := filterOnUserRating(query, ratingFilter).Count(&count); result.Error != nil {
 		return nil, result.Error
 	}
 
 	return &count, nil
 }
 
-// Returns all the children of a given item.
-func GetChildrenFromItem(parentItemID string, limit, offset *int64) ([]*ItemMetadata, error) {
+// Returns the children of a given item matching the rating filter, in the given order.
+func GetChildrenFromItem(
+	parentItemID string,
+	sortOrder ChildSortOrder,
+	ratingFilter RatingFilter,
+	limit, offset *int64,
+) ([]*ItemMetadata, error) {
 	var children []*ItemMetadata
 
-	result := db.
+	query := db.Where("item_metadata.parent_id = ? AND item_metadata.hidden = ?", parentItemID, false)
+
+	result := sortItems(filterOnUserRating(query, ratingFilter), sortOrder).
 		Limit(int(*limit)).
 		Offset(int(*offset)).
-		Where("parent_id = ?", parentItemID).
 		Find(&children)
 	if result.Error != nil {
 		return nil, result.Error
@@ -114,24 +223,43 @@ func GetChildrenFromItem(parentItemID string, limit, offset *int64) ([]*ItemMeta
 	return children, nil
 }
 
-// Returns the number of children for a given item.
-func GetChildrenCountFromItem(id string) (*int64, error) {
+// Returns the number of children for a given item matching the rating filter.
+func GetChildrenCountFromItem(id string, ratingFilter RatingFilter) (*int64, error) {
 	var count int64
 
-	result := db.Model(&ItemMetadata{}).Where("parent_id = ?", id).Count(&count)
-	if result.Error != nil {
+	query := db.Model(&ItemMetadata{}).Where("item_metadata.parent_id = ? AND item_metadata.hidden = ?", id, false)
+
+	if result := filterOnUserRating(query, ratingFilter).Count(&count); result.Error != nil {
 		return nil, result.Error
 	}
 
 	return &count, nil
 }
 
+// Sorts a query on item metadata. Sorting by user rating needs the joins of filterOnUserRating.
+func sortItems(query *gorm.DB, sortOrder ChildSortOrder) *gorm.DB {
+	switch sortOrder {
+	case TitleChildSortOrder:
+		query = query.Order("item_metadata.sort_title")
+	case CaptureDateChildSortOrder:
+		query = query.
+			Joins("LEFT JOIN image_exifs ON image_exifs.item_metadata_id = item_metadata.id").
+			Order("image_exifs.date_taken IS NULL, image_exifs.date_taken, item_metadata.sort_title")
+	case IndexChildSortOrder:
+		query = query.Order("item_metadata.`index`, item_metadata.sort_title")
+	case UserRatingChildSortOrder:
+		query = query.Order(userRatingColumn + " IS NULL, " + userRatingColumn + " DESC, item_metadata.sort_title")
+	}
+
+	return query
+}
+
 func GetLatestItemsFromLibrary(libraryID uint64, limit int) ([]*ItemMetadata, error) {
 	var items []*ItemMetadata
 
 	itemsResult := db.
 		Limit(limit).
-		Where("library_id = ? AND parent_id = 0", libraryID).
+		Where("library_id = ? AND parent_id = 0 AND hidden = ?", libraryID, false).
 		Order("created_at desc").
 		Find(&items)
 	if itemsResult.Error != nil {
@@ -141,22 +269,168 @@ func GetLatestItemsFromLibrary(libraryID uint64, limit int) ([]*ItemMetadata, er
 	return items, nil
 }
 
-func CreateMovie(movieInfo *ItemMetadata) error {
-	if result := db.Create(movieInfo); result.Error != nil {
+// Creates an item identified by the given unique key, unless another one was created with the same key
+// first, in which case item is replaced with it. Returns whether the item was created.
+func createUniqueItem(transaction *gorm.DB, item *ItemMetadata, key string) (bool, error) {
+	item.UniqueKey = &key
+
+	result := transaction.
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "unique_key"}}, DoNothing: true}).
+		Create(item)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	var existing ItemMetadata
+
+	if result := transaction.Where("unique_key = ?", key).First(&existing); result.Error != nil {
+		return false, result.Error
+	}
+
+	*item = existing
+
+	return false, nil
+}
+
+// Saves the given item, whatever its type.
+// Its external identifiers, credits, provider collections, provider tags and community ratings replace
+// the saved ones, unless they are nil. Locked fields keep their saved value.
+// The rating embedded in images becomes their file rating, see FileRatingUserID.
+func UpdateItem(item *ItemMetadata) error {
+	err := db.Transaction(func(transaction *gorm.DB) error {
+		if item.ID != 0 {
+			if err := restoreLockedFields(transaction, item); err != nil {
+				return err
+			}
+		}
+
+		result := transaction.
+			Omit("ExternalIdentifiers", "Credits", "Collections", "Tags", "Exif", "FaceRegions", "Ratings").
+			Save(item)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if item.ExternalIdentifiers != nil {
+			if err := setExternalIdentifiers(transaction, item.ID, item.ExternalIdentifiers); err != nil {
+				return err
+			}
+		}
+
+		if item.Credits != nil {
+			if err := setCredits(transaction, item.ID, item.Credits); err != nil {
+				return err
+			}
+		}
+
+		if item.Collections != nil {
+			if err := setProviderCollections(transaction, item.ID, item.Collections); err != nil {
+				return err
+			}
+		}
+
+		if item.Tags != nil {
+			if err := setProviderTags(transaction, item.ID, item.Tags); err != nil {
+				return err
+			}
+		}
+
+		if item.Exif != nil {
+			if err := setImageExif(transaction, item.ID, item.Exif); err != nil {
+				return err
+			}
+		}
+
+		if item.FaceRegions != nil {
+			if err := setImportedFaceRegions(transaction, item.ID, item.FaceRegions); err != nil {
+				return err
+			}
+		}
+
+		if item.Ratings != nil {
+			if err := setCommunityRatings(transaction, item.ID, item.Ratings); err != nil {
+				return err
+			}
+		}
+
+		if item.Type == ImageItem && len(item.ExtraInfo) > 0 {
+			return setImageFileRating(transaction, item)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update item: %w", err)
+	}
+
+	return nil
+}
+
+// Saves the manual edits of an item, including its locked fields.
+// Unlike UpdateItem, locked fields are overwritten, and associations are left untouched.
+func EditItem(item *ItemMetadata) error {
+	result := db.Omit(clause.Associations).Save(item)
+	if result.Error != nil {
+		return fmt.Errorf("failed to edit item: %w", result.Error)
+	}
+
+	return nil
+}
+
+// Returns the identifiers of the items of the given types needing a metadata refresh.
+// Items need a refresh when their metadata is older than staleBefore, or when they are missing
+// a poster or summary and their metadata is older than missingBefore.
+// Items which were never refreshed always need one, while items unmatched by hand never do.
+// Items from all libraries are returned when libraryID is 0.
+func GetItemIDsToRefresh(
+	libraryID uint64,
+	itemTypes []ItemType,
+	staleBefore, missingBefore time.Time,
+) ([]uint64, error) {
+	var ids []uint64
+
+	query := db.
+		Model(&ItemMetadata{}).
+		Where("type IN ? AND unmatched = ?", itemTypes, false).
+		Where("refreshed_at IS NULL OR refreshed_at < ? OR ((thumb = '' OR summary = '') AND refreshed_at < ?)",
+			staleBefore, missingBefore)
+
+	if libraryID != 0 {
+		query = query.Where("library_id = ?", libraryID)
+	}
+
+	if result := query.Order("refreshed_at").Pluck("id", &ids); result.Error != nil {
+		return nil, fmt.Errorf("failed to get items to refresh: %w", result.Error)
+	}
+
+	return ids, nil
+}
+
+// Records when the metadata of an item was last refreshed, without changing anything else.
+func SetItemRefreshedAt(id uint64, refreshedAt time.Time) error {
+	if result := db.Model(&ItemMetadata{ID: id}).UpdateColumn("refreshed_at", refreshedAt); result.Error != nil {
 		return result.Error
 	}
 
 	return nil
 }
 
-func UpdateMovie(movieInfo *ItemMetadata) error {
-	if result := db.Save(movieInfo); result.Error != nil {
+func CreateMovie(movieInfo *ItemMetadata) error {
+	if result := db.Create(movieInfo); result.Error != nil {
 		return result.Error
 	}
 
 	return nil
 }
 
+func UpdateMovie(movieInfo *ItemMetadata) error {
+	return UpdateItem(movieInfo)
+}
+
 func CreateImage(imageInfo *ItemMetadata) error {
 	if result := db.Create(imageInfo); result.Error != nil {
 		return result.Error
@@ -166,13 +440,72 @@ func CreateImage(imageInfo *ItemMetadata) error {
 }
 
 func UpdateImage(imageInfo *ItemMetadata) error {
-	if result := db.Save(imageInfo); result.Error != nil {
+	return UpdateItem(imageInfo)
+}
+
+func CreateMusicVideo(musicVideoInfo *ItemMetadata) error {
+	if result := db.Create(musicVideoInfo); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func CreateMusicTrack(musicTrackInfo *ItemMetadata) error {
+	if result := db.Create(musicTrackInfo); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func UpdateMusicTrack(musicTrackInfo *ItemMetadata) error {
+	return UpdateItem(musicTrackInfo)
+}
+
+// Returns the item owning the media part at the given path.
+func GetItemByMediaPartPath(path string) (*ItemMetadata, error) {
+	var mediaPart MediaPart
+
+	var item ItemMetadata
+
+	result := db.Where("file_path = ?", path).First(&mediaPart)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	result = db.Preload("MediaPart").First(&item, mediaPart.ItemMetadataID)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &item, nil
+}
+
+func CreateAudiobook(audiobookInfo *ItemMetadata) error {
+	if result := db.Create(audiobookInfo); result.Error != nil {
 		return result.Error
 	}
 
 	return nil
 }
 
+func UpdateAudiobook(audiobookInfo *ItemMetadata) error {
+	return UpdateItem(audiobookInfo)
+}
+
+func CreatePodcast(podcastInfo *ItemMetadata) error {
+	if result := db.Create(podcastInfo); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func UpdatePodcast(podcastInfo *ItemMetadata) error {
+	return UpdateItem(podcastInfo)
+}
+
 func GetImageAlbum(id uint64) (*ItemMetadata, error) {
 	var imageAlbum ItemMetadata
 
@@ -194,7 +527,7 @@ func GetImageAlbumByPath(path string) (*ItemMetadata, error) {
 		return nil, result.Error
 	}
 
-	result = db.Where("id = ?", imageAlbumPart.ID).First(&imageAlbum)
+	result = db.Where("id = ?", imageAlbumPart.ItemMetadataID).First(&imageAlbum)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -211,9 +544,5 @@ func CreateImageAlbum(imageAlbumInfo *ItemMetadata) error {
 }
 
 func UpdateImageAlbum(imageAlbumInfo *ItemMetadata) error {
-	if result := db.Save(imageAlbumInfo); result.Error != nil {
-		return result.Error
-	}
-
-	return nil
+	return UpdateItem(imageAlbumInfo)
 }
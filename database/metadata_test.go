@@ -0,0 +1,114 @@
+package database_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/internal/databasetest"
+)
+
+func TestGetItemIDsToRefresh(t *testing.T) {
+	databasetest.Setup(t)
+
+	now := time.Now()
+	staleBefore := now.Add(-30 * 24 * time.Hour)
+	missingBefore := now.Add(-24 * time.Hour)
+
+	movies := map[string]*database.ItemMetadata{
+		"fresh":     {Thumb: "thumb", Summary: "summary", RefreshedAt: now},
+		"stale":     {Thumb: "thumb", Summary: "summary", RefreshedAt: now.Add(-60 * 24 * time.Hour)},
+		"missing":   {Summary: "summary", RefreshedAt: now.Add(-48 * time.Hour)},
+		"never":     {Thumb: "thumb", Summary: "summary"},
+		"unmatched": {Unmatched: true, RefreshedAt: now.Add(-60 * 24 * time.Hour)},
+	}
+
+	for title, movie := range movies {
+		movie.Title = title
+		movie.Type = database.MovieItem
+
+		if err := database.CreateMovie(movie); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	database.ClearItemRefreshedAt(t, movies["never"].ID)
+
+	ids, err := database.GetItemIDsToRefresh(0, []database.ItemType{database.MovieItem}, staleBefore, missingBefore)
+	if err != nil {
+		t.Fatalf("GetItemIDsToRefresh() error = %v", err)
+	}
+
+	// Items never refreshed come first
+	want := []uint64{movies["never"].ID, movies["stale"].ID, movies["missing"].ID}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("GetItemIDsToRefresh() = %v, want %v", ids, want)
+	}
+
+	if err := database.SetItemRefreshedAt(movies["never"].ID, now); err != nil {
+		t.Fatalf("SetItemRefreshedAt() error = %v", err)
+	}
+
+	ids, err = database.GetItemIDsToRefresh(0, []database.ItemType{database.MovieItem}, staleBefore, missingBefore)
+	if want := []uint64{movies["stale"].ID, movies["missing"].ID}; err != nil || !reflect.DeepEqual(ids, want) {
+		t.Errorf("GetItemIDsToRefresh() = %v, %v, want %v once refreshed", ids, err, want)
+	}
+
+	// Other libraries and types are left out
+	const otherLibraryID = 1000
+
+	for _, filter := range []struct {
+		libraryID uint64
+		itemType  database.ItemType
+	}{
+		{libraryID: otherLibraryID, itemType: database.MovieItem},
+		{itemType: database.ImageItem},
+	} {
+		ids, err := database.GetItemIDsToRefresh(
+			filter.libraryID, []database.ItemType{filter.itemType}, staleBefore, missingBefore)
+		if err != nil || len(ids) != 0 {
+			t.Errorf("GetItemIDsToRefresh(%d, %v) = %v, %v, want none", filter.libraryID, filter.itemType, ids, err)
+		}
+	}
+}
+
+func TestGetItemByIDLoadsLibrary(t *testing.T) {
+	databasetest.Setup(t)
+
+	library, _, err := database.CreateLibrary("Films", "fr-FR", "movie", []string{t.TempDir()}, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	movie := database.ItemMetadata{
+		Title:     "Amélie",
+		Type:      database.MovieItem,
+		LibraryID: library.ID,
+		MediaPart: database.MediaPart{FilePath: "/films/Amélie.mkv"},
+	}
+
+	if err := database.CreateMovie(&movie); err != nil {
+		t.Fatal(err)
+	}
+
+	// Providers need the language and adult setting of the library
+	item, err := database.GetItemByID(fmtID(movie.ID))
+	if err != nil {
+		t.Fatalf("GetItemByID() error = %v", err)
+	}
+
+	if item.Library.Language != "fr-FR" || !item.Library.IncludeAdult || item.MediaPart.FilePath != movie.MediaPart.FilePath {
+		t.Errorf("GetItemByID() = %+v, want the library and media part loaded", item)
+	}
+
+	// Saving the loaded item leaves the library alone
+	item.MatchProvider = "TMDb"
+	if err := database.UpdateItem(item); err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+
+	if library := database.GetLibrary(fmtID(library.ID)); library.Language != "fr-FR" || library.Name != "Films" {
+		t.Errorf("GetLibrary() = %+v, want it unchanged", library)
+	}
+}
@@ -0,0 +1,254 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+var errInvalidPlacePath = errors.New("place paths hold a country, and optionally a region and a city")
+
+// A place photos were taken at, like a country, a region or a city.
+type Place struct {
+	Name string `json:"name"`
+	// Names of the place and of the places containing it, country first.
+	Path      []string `json:"path"`
+	ItemCount int64    `json:"itemCount"`
+	// Average coordinates of the photos taken there.
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// A geographic bounding box, in degrees. West is greater than east for boxes crossing the antimeridian.
+type Bounds struct {
+	North float64
+	South float64
+	East  float64
+	West  float64
+}
+
+// A group of nearby photos, shown as a single marker on a map.
+type MapCluster struct {
+	// Average coordinates of the photos in the cluster.
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Count     int64   `json:"count"`
+	// One of the photos of the cluster, to show on its marker.
+	ItemID uint64 `json:"itemId"`
+}
+
+type placeCount struct {
+	Country   string
+	Region    string
+	City      string
+	ItemCount int64
+	Latitude  float64
+	Longitude float64
+}
+
+// Returns the path of a place, skipping the region when the dataset has none.
+func (p placeCount) getPath() []string {
+	path := []string{p.Country}
+
+	if p.Region != "" {
+		path = append(path, p.Region)
+	}
+
+	if p.City != "" {
+		path = append(path, p.City)
+	}
+
+	return path
+}
+
+// Returns the places inside the given place, or the countries when the path is empty, sorted by name.
+func GetPlaces(libraryID string, path []string) ([]*Place, error) {
+	var counts []placeCount
+
+	result := db.Model(&ImageExif{}).
+		Select("country, region, city, COUNT(*) AS item_count, AVG(latitude) AS latitude, AVG(longitude) AS longitude").
+		Where("country != '' AND item_metadata_id IN (?)", getLibraryItemIDs(libraryID)).
+		Group("country, region, city").
+		Scan(&counts)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	placesByName := make(map[string]*Place)
+	// Sums of the longitudes as unit vectors, since places like Fiji or Russia cross the antimeridian
+	longitudeSums := make(map[string][2]float64)
+
+	for _, count := range counts {
+		countPath := count.getPath()
+		if len(countPath) <= len(path) || !hasPathPrefix(countPath, path) {
+			continue
+		}
+
+		name := countPath[len(path)]
+
+		place, ok := placesByName[name]
+		if !ok {
+			place = &Place{Name: name, Path: countPath[:len(path)+1]}
+			placesByName[name] = place
+		}
+
+		weight := float64(count.ItemCount)
+		longitude := count.Longitude * math.Pi / 180 //nolint:gomnd
+		sums := longitudeSums[name]
+
+		longitudeSums[name] = [2]float64{sums[0] + weight*math.Cos(longitude), sums[1] + weight*math.Sin(longitude)}
+		total := float64(place.ItemCount + count.ItemCount)
+		place.Latitude = (place.Latitude*float64(place.ItemCount) + count.Latitude*weight) / total
+		place.ItemCount += count.ItemCount
+	}
+
+	places := make([]*Place, 0, len(placesByName))
+
+	for name, place := range placesByName {
+		sums := longitudeSums[name]
+		place.Longitude = math.Atan2(sums[1], sums[0]) * 180 / math.Pi //nolint:gomnd
+
+		places = append(places, place)
+	}
+
+	sort.Slice(places, func(i, j int) bool {
+		return places[i].Name < places[j].Name
+	})
+
+	return places, nil
+}
+
+func hasPathPrefix(path, prefix []string) bool {
+	for index := range prefix {
+		if path[index] != prefix[index] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Returns the photos taken at the given place, by capture date.
+func GetItemsFromPlace(libraryID string, path []string, limit, offset *int64) ([]*ItemMetadata, error) {
+	query, err := filterItemsByPlace(libraryID, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return findPhotos(query, limit, offset)
+}
+
+func GetItemsCountFromPlace(libraryID string, path []string) (*int64, error) {
+	query, err := filterItemsByPlace(libraryID, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+
+	if result := query.Count(&count); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &count, nil
+}
+
+func filterItemsByPlace(libraryID string, path []string) (*gorm.DB, error) {
+	query := filterPhotos(libraryID)
+
+	switch len(path) {
+	case 1:
+		return query.Where("image_exifs.country = ?", path[0]), nil
+	case 2: //nolint:gomnd
+		// Without regions, the second level holds cities
+		return query.Where(
+			"image_exifs.country = ? AND (image_exifs.region = ? OR (image_exifs.region = '' AND image_exifs.city = ?))",
+			path[0], path[1], path[1]), nil
+	case 3: //nolint:gomnd
+		return query.Where("image_exifs.country = ? AND image_exifs.region = ? AND image_exifs.city = ?",
+			path[0], path[1], path[2]), nil
+	default:
+		return nil, errInvalidPlacePath
+	}
+}
+
+// Returns the photos taken inside the given bounds, by capture date.
+func GetItemsInBounds(libraryID string, bounds Bounds, limit, offset *int64) ([]*ItemMetadata, error) {
+	return findPhotos(filterItemsInBounds(libraryID, bounds), limit, offset)
+}
+
+func GetItemsCountInBounds(libraryID string, bounds Bounds) (*int64, error) {
+	var count int64
+
+	if result := filterItemsInBounds(libraryID, bounds).Count(&count); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &count, nil
+}
+
+func filterItemsInBounds(libraryID string, bounds Bounds) *gorm.DB {
+	return filterPhotos(libraryID).Where(getBoundsCondition(bounds, "image_exifs."))
+}
+
+// Groups the photos taken inside the given bounds into square cells of the given size, in degrees.
+func GetMapClusters(libraryID string, bounds Bounds, cellSize float64) ([]*MapCluster, error) {
+	var clusters []*MapCluster
+
+	size := strconv.FormatFloat(cellSize, 'g', -1, 64)
+
+	// Coordinates are shifted to positive values, so casting them rounds down
+	result := db.Model(&ImageExif{}).
+		Select("COUNT(*) AS count, AVG(latitude) AS latitude, AVG(longitude) AS longitude, "+
+			"MIN(item_metadata_id) AS item_id").
+		Where("item_metadata_id IN (?)", getLibraryItemIDs(libraryID)).
+		Where(getBoundsCondition(bounds, "")).
+		Group(fmt.Sprintf("CAST((latitude + 90) / %s AS INTEGER), CAST((longitude + 180) / %s AS INTEGER)", size, size)).
+		Order("count DESC").
+		Scan(&clusters)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return clusters, nil
+}
+
+func getBoundsCondition(bounds Bounds, prefix string) *gorm.DB {
+	condition := db.Where(prefix+"latitude BETWEEN ? AND ?", bounds.South, bounds.North)
+
+	if bounds.West <= bounds.East {
+		return condition.Where(prefix+"longitude BETWEEN ? AND ?", bounds.West, bounds.East)
+	}
+
+	return condition.Where(db.Where(prefix+"longitude >= ?", bounds.West).Or(prefix+"longitude <= ?", bounds.East))
+}
+
+func getLibraryItemIDs(libraryID string) *gorm.DB {
+	return db.Model(&ItemMetadata{}).Select("id").Where("library_id = ? AND hidden = ?", libraryID, false)
+}
+
+func filterPhotos(libraryID string) *gorm.DB {
+	return db.Model(&ItemMetadata{}).
+		Joins("JOIN image_exifs ON image_exifs.item_metadata_id = item_metadata.id").
+		Where("item_metadata.library_id = ? AND item_metadata.hidden = ?", libraryID, false)
+}
+
+func findPhotos(query *gorm.DB, limit, offset *int64) ([]*ItemMetadata, error) {
+	var items []*ItemMetadata
+
+	result := query.
+		Preload("Library").
+		Order("image_exifs.date_taken IS NULL, image_exifs.date_taken, item_metadata.sort_title").
+		Limit(int(*limit)).
+		Offset(int(*offset)).
+		Find(&items)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return items, nil
+}
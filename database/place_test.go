@@ -0,0 +1,219 @@
+package database_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/internal/databasetest"
+)
+
+// Creates a photo taken at the given coordinates. Photos without coordinates are created with nil ones.
+func createPhoto(t *testing.T, libraryID uint64, title string, coordinates ...float64) *database.ItemMetadata {
+	t.Helper()
+
+	photo := database.ItemMetadata{Title: title, SortTitle: title, Type: database.ImageItem, LibraryID: libraryID}
+	if err := database.CreateImage(&photo); err != nil {
+		t.Fatal(err)
+	}
+
+	photo.Exif = &database.ImageExif{}
+
+	if len(coordinates) == 2 { //nolint:gomnd
+		photo.Exif.Latitude = &coordinates[0]
+		photo.Exif.Longitude = &coordinates[1]
+	}
+
+	if err := database.UpdateItem(&photo); err != nil {
+		t.Fatal(err)
+	}
+
+	return &photo
+}
+
+func createPhotoLibrary(t *testing.T) uint64 {
+	t.Helper()
+
+	library, _, err := database.CreateLibrary("Photos", "en", "image", []string{t.TempDir()}, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return library.ID
+}
+
+func getTitles(items []*database.ItemMetadata) map[string]bool {
+	titles := make(map[string]bool, len(items))
+
+	for _, item := range items {
+		titles[item.Title] = true
+	}
+
+	return titles
+}
+
+func TestGetItemsInBounds(t *testing.T) {
+	databasetest.Setup(t)
+
+	id := createPhotoLibrary(t)
+	libraryID := fmtID(id)
+
+	createPhoto(t, id, "Notre-Dame", 48.853, 2.3499)
+	createPhoto(t, id, "Louvre", 48.8606, 2.3376)
+	createPhoto(t, id, "Big Ben", 51.5007, -0.1246)
+	createPhoto(t, id, "Suva", -18.1416, 178.4419)
+	createPhoto(t, id, "Apia", -13.8333, -171.7667)
+	createPhoto(t, id, "Scanned print")
+	createPhoto(t, id+1, "Eiffel Tower", 48.8584, 2.2945)
+
+	limit, offset := int64(10), int64(0)
+
+	for _, test := range []struct {
+		name   string
+		bounds database.Bounds
+		want   []string
+	}{
+		{"Western Europe", database.Bounds{North: 60, South: 40, East: 10, West: -10}, []string{"Big Ben", "Louvre", "Notre-Dame"}},
+		{"Paris", database.Bounds{North: 48.9, South: 48.8, East: 2.4, West: 2.3}, []string{"Louvre", "Notre-Dame"}},
+		{"across the antimeridian", database.Bounds{North: 0, South: -30, East: -170, West: 170}, []string{"Apia", "Suva"}},
+		{"empty ocean", database.Bounds{North: -30, South: -40, East: -130, West: -140}, []string{}},
+	} {
+		items, err := database.GetItemsInBounds(libraryID, test.bounds, &limit, &offset)
+		if err != nil {
+			t.Fatalf("%s: GetItemsInBounds() error = %v", test.name, err)
+		}
+
+		titles := getTitles(items)
+		if len(titles) != len(test.want) {
+			t.Errorf("%s: GetItemsInBounds() = %v, want %v", test.name, titles, test.want)
+		}
+
+		for _, title := range test.want {
+			if !titles[title] {
+				t.Errorf("%s: GetItemsInBounds() = %v, want %v", test.name, titles, test.want)
+			}
+		}
+
+		count, err := database.GetItemsCountInBounds(libraryID, test.bounds)
+		if err != nil {
+			t.Fatalf("%s: GetItemsCountInBounds() error = %v", test.name, err)
+		}
+
+		if *count != int64(len(test.want)) {
+			t.Errorf("%s: GetItemsCountInBounds() = %d, want %d", test.name, *count, len(test.want))
+		}
+	}
+}
+
+func TestGetMapClusters(t *testing.T) {
+	databasetest.Setup(t)
+
+	id := createPhotoLibrary(t)
+	libraryID := fmtID(id)
+
+	notreDame := createPhoto(t, id, "Notre-Dame", 48.853, 2.3499)
+	createPhoto(t, id, "Louvre", 48.8606, 2.3376)
+	createPhoto(t, id, "Big Ben", 51.5007, -0.1246)
+	createPhoto(t, id, "Suva", -18.1416, 178.4419)
+	createPhoto(t, id+1, "Eiffel Tower", 48.8584, 2.2945)
+
+	europe := database.Bounds{North: 60, South: 40, East: 10, West: -10}
+
+	clusters, err := database.GetMapClusters(libraryID, europe, 1)
+	if err != nil {
+		t.Fatalf("GetMapClusters() error = %v", err)
+	}
+
+	if len(clusters) != 2 {
+		t.Fatalf("GetMapClusters() = %+v, want Paris and London", clusters)
+	}
+
+	// The largest clusters come first, at the average of their photos
+	paris := clusters[0]
+	if paris.Count != 2 || paris.ItemID != notreDame.ID ||
+		math.Abs(paris.Latitude-48.8568) > 0.001 || math.Abs(paris.Longitude-2.34375) > 0.001 {
+		t.Errorf("GetMapClusters() Paris = %+v", paris)
+	}
+
+	if clusters[1].Count != 1 || clusters[1].Latitude != 51.5007 {
+		t.Errorf("GetMapClusters() London = %+v", clusters[1])
+	}
+
+	// Larger cells merge nearby cities, and these hold both Paris and London
+	clusters, err = database.GetMapClusters(libraryID, europe, 11)
+	if err != nil {
+		t.Fatalf("GetMapClusters() error = %v", err)
+	}
+
+	if len(clusters) != 1 || clusters[0].Count != 3 {
+		t.Errorf("GetMapClusters() with large cells = %+v, want a single cluster", clusters)
+	}
+
+	// Negative coordinates are grouped like positive ones
+	clusters, err = database.GetMapClusters(libraryID, database.Bounds{North: 0, South: -30, East: -170, West: 170}, 1)
+	if err != nil {
+		t.Fatalf("GetMapClusters() error = %v", err)
+	}
+
+	if len(clusters) != 1 || clusters[0].Count != 1 || clusters[0].Longitude != 178.4419 {
+		t.Errorf("GetMapClusters() across the antimeridian = %+v, want Suva", clusters)
+	}
+}
+
+// Creates a photo reverse geocoded to the given place.
+func createPlacedPhoto(t *testing.T, libraryID uint64, title string, place []string, coordinates ...float64) {
+	t.Helper()
+
+	photo := createPhoto(t, libraryID, title, coordinates...)
+	photo.Exif.Country, photo.Exif.Region, photo.Exif.City = place[0], place[1], place[2]
+
+	if err := database.UpdateItem(photo); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetPlaces(t *testing.T) {
+	databasetest.Setup(t)
+
+	id := createPhotoLibrary(t)
+	libraryID := fmtID(id)
+
+	// Places found with the bundled dataset have no region
+	createPlacedPhoto(t, id, "Notre-Dame", []string{"France", "", "Paris"}, 48.853, 2.350)
+	createPlacedPhoto(t, id, "Louvre", []string{"France", "", "Paris"}, 48.861, 2.336)
+	createPlacedPhoto(t, id, "Fourvière", []string{"France", "", "Lyon"}, 45.762, 4.823)
+	createPlacedPhoto(t, id, "Suva", []string{"Fiji", "", "Suva"}, -18.142, 178.442)
+	createPlacedPhoto(t, id, "Labasa", []string{"Fiji", "", "Labasa"}, -16.417, -179.383)
+	createPlacedPhoto(t, createPhotoLibrary(t), "Eiffel Tower", []string{"France", "", "Paris"}, 48.858, 2.294)
+
+	countries, err := database.GetPlaces(libraryID, nil)
+	if err != nil || len(countries) != 2 || countries[0].Name != "Fiji" || countries[1].ItemCount != 3 {
+		t.Fatalf("GetPlaces() = %+v, %v, want Fiji, then France with 3 photos", countries, err)
+	}
+
+	// Fiji crosses the antimeridian, so its average longitude is near 180 rather than 0
+	if longitude := math.Abs(countries[0].Longitude); longitude < 178 {
+		t.Errorf("GetPlaces() longitude of Fiji = %f, want it near the antimeridian", countries[0].Longitude)
+	}
+
+	cities, err := database.GetPlaces(libraryID, []string{"France"})
+	if err != nil || len(cities) != 2 || cities[1].Name != "Paris" || cities[1].ItemCount != 2 || len(cities[1].Path) != 2 {
+		t.Errorf("GetPlaces(France) = %+v, %v, want Lyon and Paris, without region", cities, err)
+	}
+
+	limit, offset := int64(10), int64(0)
+	paris := []string{"France", "Paris"}
+
+	items, err := database.GetItemsFromPlace(libraryID, paris, &limit, &offset)
+	if titles := getTitles(items); err != nil || len(titles) != 2 || !titles["Louvre"] || !titles["Notre-Dame"] {
+		t.Errorf("GetItemsFromPlace() = %v, %v, want the photos of Paris in the library", titles, err)
+	}
+
+	if count, err := database.GetItemsCountFromPlace(libraryID, paris); err != nil || *count != 2 {
+		t.Errorf("GetItemsCountFromPlace() = %v, %v, want 2", count, err)
+	}
+
+	if _, err := database.GetItemsFromPlace(libraryID, []string{}, &limit, &offset); err == nil {
+		t.Error("GetItemsFromPlace() should fail without a country")
+	}
+}
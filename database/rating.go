@@ -0,0 +1,194 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	MinRating = 1
+	MaxRating = 5
+	// The user of ratings embedded in files, like the XMP ratings of photos.
+	// They count for every user who hasn't rated the item.
+	FileRatingUserID = 0
+)
+
+// Sources of community ratings. Providers can use other sources.
+const (
+	TmdbRatingSource           = "tmdb"
+	ImdbRatingSource           = "imdb"
+	RottenTomatoesRatingSource = "rottenTomatoes"
+	MetacriticRatingSource     = "metacritic"
+)
+
+// The rating of the user when they rated the item, or the rating embedded in the file otherwise.
+const userRatingColumn = "COALESCE(own_ratings.rating, file_ratings.rating)"
+
+var errInvalidRating = errors.New("invalid rating")
+
+// A star rating given to an item by a user.
+type UserRating struct {
+	ID uint64 `gorm:"primary_key" json:"id"`
+	// The user who rated the item, or FileRatingUserID for ratings embedded in files.
+	UserID         uint64 `gorm:"not null;uniqueIndex:idx_user_rating"`
+	ItemMetadataID uint64 `gorm:"not null;uniqueIndex:idx_user_rating;index"`
+	// From MinRating to MaxRating stars.
+	Rating    int       `gorm:"not null" json:"rating"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// The rating of an item by the users of a community, like IMDb, as imported from providers.
+type CommunityRating struct {
+	ID             uint64 `gorm:"primary_key" json:"id"`
+	ItemMetadataID uint64 `gorm:"not null;uniqueIndex:idx_community_rating"`
+	// Where the rating comes from, like "imdb" or "rottenTomatoes".
+	Source string `gorm:"not null;uniqueIndex:idx_community_rating" json:"source"`
+	// The average rating, out of MaxValue, like 8.7 out of 10, or 87 out of 100 for percentages.
+	Value    float64 `gorm:"not null" json:"value"`
+	MaxValue float64 `gorm:"not null" json:"maxValue"`
+	// How many people rated the item, or 0 when unknown.
+	Votes int64 `json:"votes"`
+}
+
+// Filters items on the rating a user gave them, or the rating embedded in their file.
+type RatingFilter struct {
+	// The user whose ratings are used to filter and sort items.
+	UserID uint64
+	// The minimum rating of the items, or 0 to include unrated items.
+	MinRating int
+}
+
+// Sets the star rating of a user on an item, from MinRating to MaxRating. A rating of 0 removes it.
+func SetUserRating(userID, itemID uint64, rating int) error {
+	if rating == 0 {
+		result := db.Where("user_id = ? AND item_metadata_id = ?", userID, itemID).Delete(&UserRating{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to remove rating: %w", result.Error)
+		}
+
+		return nil
+	}
+
+	if rating < MinRating || rating > MaxRating {
+		return fmt.Errorf("%w: %d, ratings are from %d to %d", errInvalidRating, rating, MinRating, MaxRating)
+	}
+
+	result := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "item_metadata_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rating", "updated_at"}),
+	}).Create(&UserRating{UserID: userID, ItemMetadataID: itemID, Rating: rating})
+	if result.Error != nil {
+		return fmt.Errorf("failed to save rating: %w", result.Error)
+	}
+
+	return nil
+}
+
+// Returns the rating of a user on an item, or the rating embedded in its file if they didn't rate it.
+// Returns nil when the item is unrated.
+func GetUserRating(userID uint64, itemID string) (*int, error) {
+	var ratings []UserRating
+
+	result := db.
+		Where("item_metadata_id = ? AND user_id IN ?", itemID, []uint64{userID, FileRatingUserID}).
+		Order("user_id DESC").
+		Limit(1).
+		Find(&ratings)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if len(ratings) == 0 {
+		return nil, nil //nolint:nilnil
+	}
+
+	return &ratings[0].Rating, nil
+}
+
+// Returns the community ratings of an item, sorted by source.
+func GetCommunityRatingsFromItem(itemID string) ([]*CommunityRating, error) {
+	var ratings []*CommunityRating
+
+	if result := db.Where("item_metadata_id = ?", itemID).Order("source").Find(&ratings); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return ratings, nil
+}
+
+// Replaces the community ratings of an item.
+func setCommunityRatings(transaction *gorm.DB, itemID uint64, ratings []CommunityRating) error {
+	result := transaction.Where("item_metadata_id = ?", itemID).Delete(&CommunityRating{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete community ratings: %w", result.Error)
+	}
+
+	if len(ratings) == 0 {
+		return nil
+	}
+
+	for index := range ratings {
+		ratings[index].ID = 0
+		ratings[index].ItemMetadataID = itemID
+	}
+
+	if result := transaction.Create(&ratings); result.Error != nil {
+		return fmt.Errorf("failed to create community ratings: %w", result.Error)
+	}
+
+	return nil
+}
+
+// Saves the rating embedded in an image as its file rating, so it counts as the rating of users
+// who haven't rated it. Rejected and unrated images have no file rating.
+func setImageFileRating(transaction *gorm.DB, item *ItemMetadata) error {
+	var extraInfo ImageExtraInfo
+	if err := json.Unmarshal(item.ExtraInfo, &extraInfo); err != nil {
+		log.Err(err).Msgf("Failed to read image information for item %d", item.ID)
+
+		return nil
+	}
+
+	result := transaction.Where("user_id = ? AND item_metadata_id = ?", FileRatingUserID, item.ID).Delete(&UserRating{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to remove file rating: %w", result.Error)
+	}
+
+	if extraInfo.Rating < MinRating || extraInfo.Rating > MaxRating {
+		return nil
+	}
+
+	result = transaction.Create(&UserRating{UserID: FileRatingUserID, ItemMetadataID: item.ID, Rating: extraInfo.Rating})
+	if result.Error != nil {
+		return fmt.Errorf("failed to save file rating: %w", result.Error)
+	}
+
+	return nil
+}
+
+// Filters a query on item metadata with the given rating filter.
+func filterOnUserRating(query *gorm.DB, filter RatingFilter) *gorm.DB {
+	query = joinUserRatings(query, filter.UserID)
+
+	if filter.MinRating > 0 {
+		query = query.Where(userRatingColumn+" >= ?", filter.MinRating)
+	}
+
+	return query
+}
+
+// Joins the ratings of the user and of the files to a query on item metadata, so it can use userRatingColumn.
+func joinUserRatings(query *gorm.DB, userID uint64) *gorm.DB {
+	return query.
+		Joins("LEFT JOIN user_ratings AS own_ratings ON own_ratings.item_metadata_id = item_metadata.id "+
+			"AND own_ratings.user_id = ?", userID).
+		Joins("LEFT JOIN user_ratings AS file_ratings ON file_ratings.item_metadata_id = item_metadata.id "+
+			"AND file_ratings.user_id = ?", FileRatingUserID)
+}
@@ -0,0 +1,279 @@
+package database_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/internal/databasetest"
+)
+
+const (
+	rater      = 1
+	otherRater = 2
+)
+
+// Sets the rating embedded in a photo, as the image provider reads it from XMP.
+func setFileRating(t *testing.T, photo *database.ItemMetadata, rating int) {
+	t.Helper()
+
+	photo.ExtraInfo = []byte(fmt.Sprintf(`{"rating":%d}`, rating))
+
+	if err := database.UpdateItem(photo); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func getUserRating(t *testing.T, userID uint64, item *database.ItemMetadata) *int {
+	t.Helper()
+
+	rating, err := database.GetUserRating(userID, fmtID(item.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rating
+}
+
+func assertUserRating(t *testing.T, userID uint64, item *database.ItemMetadata, want int) {
+	t.Helper()
+
+	rating := getUserRating(t, userID, item)
+
+	switch {
+	case want == 0 && rating != nil:
+		t.Errorf("user %d rating = %d, want none", userID, *rating)
+	case want != 0 && (rating == nil || *rating != want):
+		t.Errorf("user %d rating = %v, want %d", userID, rating, want)
+	}
+}
+
+// Returns the titles of the items, comma-separated, in order.
+func getOrderedTitles(items []*database.ItemMetadata) string {
+	titles := make([]string, 0, len(items))
+
+	for _, item := range items {
+		titles = append(titles, item.Title)
+	}
+
+	return strings.Join(titles, ",")
+}
+
+func TestSetUserRating(t *testing.T) {
+	databasetest.Setup(t)
+
+	photo := createPhoto(t, createPhotoLibrary(t), "Beach")
+
+	if err := database.SetUserRating(rater, photo.ID, 3); err != nil {
+		t.Fatalf("SetUserRating() error = %v", err)
+	}
+
+	if err := database.SetUserRating(rater, photo.ID, 5); err != nil {
+		t.Fatalf("SetUserRating() error = %v", err)
+	}
+
+	assertUserRating(t, rater, photo, 5)
+	assertUserRating(t, otherRater, photo, 0)
+
+	if err := database.SetUserRating(rater, photo.ID, database.MaxRating+1); err == nil {
+		t.Error("SetUserRating() should fail for ratings above the maximum")
+	}
+
+	if err := database.SetUserRating(rater, photo.ID, 0); err != nil {
+		t.Fatalf("SetUserRating() error = %v", err)
+	}
+
+	assertUserRating(t, rater, photo, 0)
+}
+
+func TestUserRatingOverridesFileRating(t *testing.T) {
+	databasetest.Setup(t)
+
+	photo := createPhoto(t, createPhotoLibrary(t), "Beach")
+	setFileRating(t, photo, 2)
+
+	if err := database.SetUserRating(rater, photo.ID, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	assertUserRating(t, rater, photo, 4)
+	assertUserRating(t, otherRater, photo, 2)
+
+	// Rescans sync the file rating, without touching the ratings of users
+	setFileRating(t, photo, 5)
+
+	assertUserRating(t, rater, photo, 4)
+	assertUserRating(t, otherRater, photo, 5)
+
+	// Rejected photos have no file rating
+	setFileRating(t, photo, -1)
+
+	assertUserRating(t, rater, photo, 4)
+	assertUserRating(t, otherRater, photo, 0)
+}
+
+func TestRatingFilterAndSort(t *testing.T) {
+	databasetest.Setup(t)
+
+	libraryID := createPhotoLibrary(t)
+	favorite := createPhoto(t, libraryID, "Favorite")
+	good := createPhoto(t, libraryID, "Good")
+	unrated := createPhoto(t, libraryID, "Unrated")
+	disliked := createPhoto(t, libraryID, "Disliked")
+
+	setFileRating(t, good, 3)
+	setFileRating(t, disliked, 5)
+
+	for itemID, rating := range map[uint64]int{favorite.ID: 5, disliked.ID: 1} {
+		if err := database.SetUserRating(rater, itemID, rating); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	album := createUserCollection(t, "Album", unrated.ID, disliked.ID, good.ID, favorite.ID)
+
+	limit, offset := int64(10), int64(0)
+	library := fmtID(libraryID)
+
+	for name, list := range map[string]func(database.ChildSortOrder, database.RatingFilter) ([]*database.ItemMetadata, error){
+		"library": func(sortOrder database.ChildSortOrder, filter database.RatingFilter) ([]*database.ItemMetadata, error) {
+			return database.GetItemsFromLibrary(library, sortOrder, filter, &limit, &offset)
+		},
+		"collection": func(sortOrder database.ChildSortOrder, filter database.RatingFilter) ([]*database.ItemMetadata, error) {
+			return database.GetCollectionItems(fmtID(album.ID), database.ManualSortOrder, sortOrder, filter, &limit, &offset)
+		},
+	} {
+		// Unrated items come last
+		sorted, err := list(database.UserRatingChildSortOrder, database.RatingFilter{UserID: rater})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if titles := getOrderedTitles(sorted); titles != "Favorite,Good,Disliked,Unrated" {
+			t.Errorf("%s sorted by rating = %s, want Favorite,Good,Disliked,Unrated", name, titles)
+		}
+
+		// The ratings of users override the file ratings
+		filtered, err := list(database.TitleChildSortOrder, database.RatingFilter{UserID: rater, MinRating: 3})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if titles := getOrderedTitles(filtered); titles != "Favorite,Good" {
+			t.Errorf("%s rated at least 3 = %s, want Favorite,Good", name, titles)
+		}
+
+		// Other users only see the file ratings
+		filtered, err = list(database.TitleChildSortOrder, database.RatingFilter{UserID: otherRater, MinRating: 3})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if titles := getOrderedTitles(filtered); titles != "Disliked,Good" {
+			t.Errorf("%s rated at least 3 by another user = %s, want Disliked,Good", name, titles)
+		}
+	}
+
+	for name, count := range map[string]func(database.RatingFilter) (*int64, error){
+		"library": func(filter database.RatingFilter) (*int64, error) {
+			return database.GetItemsCountFromLibrary(library, filter)
+		},
+		"collection": func(filter database.RatingFilter) (*int64, error) {
+			return database.GetCollectionItemsCount(fmtID(album.ID), filter)
+		},
+	} {
+		total, err := count(database.RatingFilter{UserID: rater, MinRating: 3})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if *total != 2 {
+			t.Errorf("%s counts %d items rated at least 3, want 2", name, *total)
+		}
+	}
+
+	// Collections keep their own order unless another one is given
+	manual, err := database.GetCollectionItems(fmtID(album.ID), database.ManualSortOrder, "", database.RatingFilter{}, &limit, &offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if titles := getOrderedTitles(manual); titles != "Unrated,Disliked,Good,Favorite" {
+		t.Errorf("collection in manual order = %s, want Unrated,Disliked,Good,Favorite", titles)
+	}
+}
+
+func TestRatingFilterOnChildren(t *testing.T) {
+	databasetest.Setup(t)
+
+	libraryID := createPhotoLibrary(t)
+
+	folder := database.ItemMetadata{Title: "Holidays", Type: database.ImageAlbumItem, LibraryID: libraryID}
+	if err := database.CreateImageAlbum(&folder); err != nil {
+		t.Fatal(err)
+	}
+
+	for title, rating := range map[string]int{"Beach": 2, "Sunset": 5, "Harbor": 0} {
+		photo := createPhoto(t, libraryID, title)
+		photo.ParentID = folder.ID
+
+		setFileRating(t, photo, rating)
+	}
+
+	limit, offset := int64(10), int64(0)
+	filter := database.RatingFilter{UserID: rater, MinRating: 2}
+
+	children, err := database.GetChildrenFromItem(fmtID(folder.ID), database.UserRatingChildSortOrder, filter, &limit, &offset)
+	if err != nil {
+		t.Fatalf("GetChildrenFromItem() error = %v", err)
+	}
+
+	if titles := getOrderedTitles(children); titles != "Sunset,Beach" {
+		t.Errorf("GetChildrenFromItem() rated at least 2 = %s, want Sunset,Beach", titles)
+	}
+
+	count, err := database.GetChildrenCountFromItem(fmtID(folder.ID), filter)
+	if err != nil {
+		t.Fatalf("GetChildrenCountFromItem() error = %v", err)
+	}
+
+	if *count != 2 {
+		t.Errorf("GetChildrenCountFromItem() = %d, want 2", *count)
+	}
+}
+
+func TestUpdateItemCommunityRatings(t *testing.T) {
+	databasetest.Setup(t)
+
+	movie := createMovie(t, "/movies/the-matrix.mkv")
+
+	movie.Ratings = []database.CommunityRating{
+		{Source: database.ImdbRatingSource, Value: 8.7, MaxValue: 10},
+		{Source: database.TmdbRatingSource, Value: 8.2, MaxValue: 10},
+	}
+	if err := database.UpdateItem(movie); err != nil {
+		t.Fatal(err)
+	}
+
+	// Refreshed ratings replace the saved ones
+	movie.Ratings = []database.CommunityRating{{Source: database.ImdbRatingSource, Value: 8.6, MaxValue: 10}}
+	if err := database.UpdateItem(movie); err != nil {
+		t.Fatal(err)
+	}
+
+	// Updates without ratings keep them
+	movie.Ratings = nil
+	if err := database.UpdateItem(movie); err != nil {
+		t.Fatal(err)
+	}
+
+	ratings, err := database.GetCommunityRatingsFromItem(fmtID(movie.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ratings) != 1 || ratings[0].Source != database.ImdbRatingSource || ratings[0].Value != 8.6 {
+		t.Errorf("GetCommunityRatingsFromItem() = %+v, want the refreshed IMDb rating", ratings)
+	}
+}
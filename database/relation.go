@@ -0,0 +1,224 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errInvalidRelationType = errors.New("invalid relation type")
+
+const (
+	// Walking the graph deeper quickly returns most of the database, through people credited on many items.
+	MaxRelationDepth = 5
+	// Caps the items returned when walking the graph, since some people are credited on thousands of items.
+	MaxRelatedItems = 500
+)
+
+// Describes how two items are related. Relations are directed, from the source to the target.
+type RelationType string
+
+const (
+	// The source person or group is credited on the target item.
+	CreditedOnRelation RelationType = "creditedOn"
+	// The source item is a sequel of the target item.
+	SequelOfRelation RelationType = "sequelOf"
+	// The source item is based on the target item, like a movie based on a true story or a book.
+	BasedOnRelation RelationType = "basedOn"
+	// The source album is the soundtrack of the target item.
+	SoundtrackOfRelation RelationType = "soundtrackOf"
+	// The source item is an adaptation of the target item, like an anime adapted from a manga.
+	AdaptationOfRelation RelationType = "adaptationOf"
+	// The source and target items belong to the same collection.
+	SameCollectionRelation RelationType = "sameCollection"
+)
+
+func (r RelationType) String() string {
+	return string(r)
+}
+
+func (r *RelationType) UnmarshalText(text []byte) error {
+	switch relationType := RelationType(text); relationType {
+	case CreditedOnRelation,
+		SequelOfRelation,
+		BasedOnRelation,
+		SoundtrackOfRelation,
+		AdaptationOfRelation,
+		SameCollectionRelation:
+		*r = relationType
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", errInvalidRelationType, text)
+}
+
+// A typed edge between two items, possibly from different libraries and media types.
+type ItemRelation struct {
+	ID       uint64       `gorm:"primary_key" json:"id"`
+	SourceID uint64       `gorm:"not null;uniqueIndex:idx_item_relation"`
+	TargetID uint64       `gorm:"not null;uniqueIndex:idx_item_relation;index"`
+	Type     RelationType `gorm:"not null;uniqueIndex:idx_item_relation" json:"type"`
+	// Relations added by users are kept when the metadata is refreshed.
+	UserDefined bool      `gorm:"not null;default:false" json:"userDefined"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Describes an item reached while walking the relation graph.
+type RelatedEdge struct {
+	ItemID uint64
+	// The item this one was reached from.
+	FromID uint64
+	Type   RelationType
+	// Whether the edge goes from FromID to ItemID, rather than the other way around.
+	Outgoing bool
+	// Number of edges between this item and the starting item.
+	Depth int
+}
+
+// Adds a relation between two items. Adding an existing relation is a no-op.
+func AddItemRelation(sourceID, targetID uint64, relationType RelationType, userDefined bool) error {
+	relation := ItemRelation{
+		SourceID:    sourceID,
+		TargetID:    targetID,
+		Type:        relationType,
+		UserDefined: userDefined,
+	}
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&relation)
+	if result.Error != nil {
+		return fmt.Errorf("failed to add relation: %w", result.Error)
+	}
+
+	return nil
+}
+
+// Removes a relation added by a user. Returns whether a relation was removed.
+func RemoveUserItemRelation(sourceID, targetID uint64, relationType RelationType) (bool, error) {
+	result := db.
+		Where("source_id = ? AND target_id = ? AND type = ? AND user_defined = ?", sourceID, targetID, relationType, true).
+		Delete(&ItemRelation{})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to remove relation: %w", result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// Replaces the people credited on an item in the relation graph. Relations added by users are kept.
+func setCreditedOnRelations(transaction *gorm.DB, itemID uint64, credits []Credit) error {
+	result := transaction.
+		Where("target_id = ? AND type = ? AND user_defined = ?", itemID, CreditedOnRelation, false).
+		Delete(&ItemRelation{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete credit relations: %w", result.Error)
+	}
+
+	relations := make([]ItemRelation, 0, len(credits))
+	seen := make(map[uint64]bool, len(credits))
+
+	for _, credit := range credits {
+		if seen[credit.PersonID] {
+			continue
+		}
+
+		seen[credit.PersonID] = true
+
+		relations = append(relations, ItemRelation{
+			SourceID: credit.PersonID,
+			TargetID: itemID,
+			Type:     CreditedOnRelation,
+		})
+	}
+
+	if len(relations) == 0 {
+		return nil
+	}
+
+	result = transaction.Clauses(clause.OnConflict{DoNothing: true}).Create(&relations)
+	if result.Error != nil {
+		return fmt.Errorf("failed to create credit relations: %w", result.Error)
+	}
+
+	return nil
+}
+
+// Walks the relation graph breadth-first from the given item, in both directions, up to the given depth.
+// Only relations of the given types are followed, or all of them if none are given.
+// Each item is returned once, with the shortest path leading to it.
+// The depth is capped to MaxRelationDepth, and at most MaxRelatedItems items are returned, closest first.
+func GetRelatedItems(itemID uint64, relationTypes []RelationType, depth int) ([]RelatedEdge, error) {
+	if depth > MaxRelationDepth {
+		depth = MaxRelationDepth
+	}
+
+	visited := map[uint64]bool{itemID: true}
+	frontier := []uint64{itemID}
+
+	var edges []RelatedEdge
+
+	for level := 1; level <= depth && len(frontier) > 0; level++ {
+		var relations []ItemRelation
+
+		query := db.Where("source_id IN ? OR target_id IN ?", frontier, frontier)
+		if len(relationTypes) > 0 {
+			query = query.Where("type IN ?", relationTypes)
+		}
+
+		if result := query.Order("id").Find(&relations); result.Error != nil {
+			return nil, fmt.Errorf("failed to get relations: %w", result.Error)
+		}
+
+		inFrontier := make(map[uint64]bool, len(frontier))
+		for _, id := range frontier {
+			inFrontier[id] = true
+		}
+
+		var next []uint64
+
+		for _, relation := range relations {
+			for _, edge := range []RelatedEdge{
+				{ItemID: relation.TargetID, FromID: relation.SourceID, Outgoing: true},
+				{ItemID: relation.SourceID, FromID: relation.TargetID, Outgoing: false},
+			} {
+				if !inFrontier[edge.FromID] || visited[edge.ItemID] {
+					continue
+				}
+
+				if len(edges) >= MaxRelatedItems {
+					return edges, nil
+				}
+
+				visited[edge.ItemID] = true
+
+				edge.Type = relation.Type
+				edge.Depth = level
+				edges = append(edges, edge)
+				next = append(next, edge.ItemID)
+			}
+		}
+
+		frontier = next
+	}
+
+	return edges, nil
+}
+
+// Returns the items with the given identifiers, in no particular order.
+func GetItemsByIDs(ids []uint64) ([]*ItemMetadata, error) {
+	var items []*ItemMetadata
+
+	if len(ids) == 0 {
+		return items, nil
+	}
+
+	if result := db.Preload("Library").Find(&items, ids); result.Error != nil {
+		return nil, fmt.Errorf("failed to get items: %w", result.Error)
+	}
+
+	return items, nil
+}
@@ -0,0 +1,161 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/internal/databasetest"
+)
+
+func addRelation(t *testing.T, sourceID, targetID uint64) {
+	t.Helper()
+
+	if err := database.AddItemRelation(sourceID, targetID, database.SequelOfRelation, false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetRelatedItemsCycle(t *testing.T) {
+	databasetest.Setup(t)
+
+	addRelation(t, 1, 2)
+	addRelation(t, 2, 3)
+	addRelation(t, 3, 1)
+
+	edges, err := database.GetRelatedItems(1, nil, database.MaxRelationDepth)
+	if err != nil {
+		t.Fatalf("GetRelatedItems() error = %v", err)
+	}
+
+	if len(edges) != 2 {
+		t.Fatalf("GetRelatedItems() = %+v, want items 2 and 3 once", edges)
+	}
+
+	for _, edge := range edges {
+		if edge.Depth != 1 {
+			t.Errorf("GetRelatedItems() reached item %d at depth %d, want 1", edge.ItemID, edge.Depth)
+		}
+	}
+}
+
+func TestGetRelatedItemsDepth(t *testing.T) {
+	databasetest.Setup(t)
+
+	// A chain longer than the maximum depth
+	for id := uint64(1); id <= database.MaxRelationDepth+3; id++ {
+		addRelation(t, id+1, id)
+	}
+
+	tests := []struct {
+		depth int
+		want  int
+	}{
+		{depth: 0, want: 0},
+		{depth: 1, want: 1},
+		{depth: 3, want: 3},
+		{depth: 100, want: database.MaxRelationDepth},
+	}
+
+	for _, tc := range tests {
+		edges, err := database.GetRelatedItems(1, nil, tc.depth)
+		if err != nil {
+			t.Fatalf("GetRelatedItems(%d) error = %v", tc.depth, err)
+		}
+
+		if len(edges) != tc.want {
+			t.Errorf("GetRelatedItems(%d) returned %d items, want %d", tc.depth, len(edges), tc.want)
+		}
+	}
+}
+
+func TestGetRelatedItemsLimit(t *testing.T) {
+	databasetest.Setup(t)
+
+	// A person credited on more items than returned
+	const personID = 1
+
+	for id := uint64(2); id <= database.MaxRelatedItems+10; id++ {
+		if err := database.AddItemRelation(personID, id, database.CreditedOnRelation, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	edges, err := database.GetRelatedItems(personID, nil, 2)
+	if err != nil {
+		t.Fatalf("GetRelatedItems() error = %v", err)
+	}
+
+	if len(edges) != database.MaxRelatedItems {
+		t.Errorf("GetRelatedItems() returned %d items, want %d", len(edges), database.MaxRelatedItems)
+	}
+}
+
+func TestUserRelations(t *testing.T) {
+	databasetest.Setup(t)
+
+	// Adding a relation twice is a no-op
+	for i := 0; i < 2; i++ {
+		if err := database.AddItemRelation(2, 1, database.SequelOfRelation, true); err != nil {
+			t.Fatalf("AddItemRelation() error = %v", err)
+		}
+	}
+
+	if edges, err := database.GetRelatedItems(1, nil, 1); err != nil || len(edges) != 1 {
+		t.Errorf("GetRelatedItems() = %+v, %v, want a single edge", edges, err)
+	}
+
+	addRelation(t, 3, 1)
+
+	// Only relations added by users can be removed
+	if removed, err := database.RemoveUserItemRelation(3, 1, database.SequelOfRelation); err != nil || removed {
+		t.Errorf("RemoveUserItemRelation() = %v, %v, want provider relations kept", removed, err)
+	}
+
+	if removed, err := database.RemoveUserItemRelation(2, 1, database.SequelOfRelation); err != nil || !removed {
+		t.Errorf("RemoveUserItemRelation() = %v, %v, want the user relation removed", removed, err)
+	}
+
+	if edges, err := database.GetRelatedItems(1, nil, 1); err != nil || len(edges) != 1 || edges[0].ItemID != 3 {
+		t.Errorf("GetRelatedItems() = %+v, %v, want only the provider relation", edges, err)
+	}
+}
+
+func TestUpdateItemReplacesCreditedOnRelations(t *testing.T) {
+	databasetest.Setup(t)
+
+	movie := database.ItemMetadata{Title: "The Matrix", Type: database.MovieItem}
+	if err := database.CreateMovie(&movie); err != nil {
+		t.Fatal(err)
+	}
+
+	person, err := database.GetOrCreatePerson(&database.ItemMetadata{Title: "Keanu Reeves"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A person credited twice is related to the item once
+	movie.Credits = []database.Credit{
+		{PersonID: person.ID, Role: database.CastRole, Character: "Neo"},
+		{PersonID: person.ID, Role: database.CrewRole, Job: "Stunts"},
+	}
+
+	if err := database.UpdateItem(&movie); err != nil {
+		t.Fatal(err)
+	}
+
+	creditedOn := []database.RelationType{database.CreditedOnRelation}
+
+	if edges, err := database.GetRelatedItems(movie.ID, creditedOn, 1); err != nil || len(edges) != 1 {
+		t.Errorf("GetRelatedItems() = %+v, %v, want the person once", edges, err)
+	}
+
+	movie.Credits = []database.Credit{}
+
+	if err := database.UpdateItem(&movie); err != nil {
+		t.Fatal(err)
+	}
+
+	if edges, err := database.GetRelatedItems(movie.ID, creditedOn, 1); err != nil || len(edges) != 0 {
+		t.Errorf("GetRelatedItems() = %+v, %v, want the relation removed with the credits", edges, err)
+	}
+}
@@ -0,0 +1,415 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// Name of the root tag holding the genres returned by metadata providers.
+	GenreTagNamespace = "Genres"
+	// Name of the root tag holding the keywords without hierarchy embedded in files.
+	KeywordTagNamespace = "Keywords"
+)
+
+var (
+	errTagCycle     = errors.New("a tag can't be moved under itself or its descendants")
+	errEmptyTagPath = errors.New("empty tag path")
+)
+
+// A tag in the tag tree, like Paris under Places / France. Root tags have no parent.
+type Tag struct {
+	ID        uint64    `gorm:"primary_key" json:"id"`
+	Name      string    `gorm:"not null;uniqueIndex:idx_tag" json:"name"`
+	ParentID  uint64    `gorm:"not null;default:0;uniqueIndex:idx_tag" json:"parentId"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Assigns a tag to an item.
+type ItemTag struct {
+	ID    uint64 `gorm:"primary_key" json:"id"`
+	TagID uint64 `gorm:"not null;uniqueIndex:idx_item_tag;index"`
+	// Only used to pass genres from providers, see Provider.GetMetadata.
+	Tag            Tag    `gorm:"foreignKey:TagID" json:"-"`
+	ItemMetadataID uint64 `gorm:"not null;uniqueIndex:idx_item_tag"`
+	// Tags assigned by users are kept when the metadata is refreshed.
+	UserDefined bool      `gorm:"not null;default:false" json:"userDefined"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Selects the given tag and all its descendants.
+const tagDescendantsQuery = /* sql */ `WITH RECURSIVE descendants(id) AS (
+		SELECT ?
+		UNION
+		SELECT tags.id FROM tags JOIN descendants ON tags.parent_id = descendants.id
+	) SELECT id FROM descendants`
+
+// Creates a tag under the given parent, or at the root when parentID is 0.
+func CreateTag(name string, parentID uint64) (*Tag, error) {
+	tag := Tag{Name: name, ParentID: parentID}
+
+	if result := db.Create(&tag); result.Error != nil {
+		return nil, fmt.Errorf("failed to create tag: %w", result.Error)
+	}
+
+	return &tag, nil
+}
+
+// Returns the tag with the given name under the given parent, creating it if it doesn't exist yet.
+func GetOrCreateTag(name string, parentID uint64) (*Tag, error) {
+	tag := Tag{Name: name, ParentID: parentID}
+
+	if result := db.Where(&tag, "Name", "ParentID").FirstOrCreate(&tag); result.Error != nil {
+		return nil, fmt.Errorf("failed to get tag: %w", result.Error)
+	}
+
+	return &tag, nil
+}
+
+// Returns the tag for the given genre, in the genres branch of the tag tree.
+func GetOrCreateGenreTag(name string) (*Tag, error) {
+	namespace, err := GetOrCreateTag(GenreTagNamespace, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetOrCreateTag(name, namespace.ID)
+}
+
+// Returns the tag at the given path, root first, creating the missing tags along the way.
+func GetOrCreateTagPath(path []string) (*Tag, error) {
+	var tag *Tag
+
+	parentID := uint64(0)
+
+	for _, name := range path {
+		var err error
+
+		tag, err = GetOrCreateTag(name, parentID)
+		if err != nil {
+			return nil, err
+		}
+
+		parentID = tag.ID
+	}
+
+	if tag == nil {
+		return nil, errEmptyTagPath
+	}
+
+	return tag, nil
+}
+
+func GetTagByID(id string) (*Tag, error) {
+	var tag Tag
+
+	if result := db.First(&tag, id); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &tag, nil
+}
+
+// Returns the children of the given tag, or the root tags when parentID is 0, sorted by name.
+func GetTagChildren(parentID uint64) ([]*Tag, error) {
+	var tags []*Tag
+
+	if result := db.Where("parent_id = ?", parentID).Order("name").Find(&tags); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return tags, nil
+}
+
+// Returns the ancestors of a tag, root first, followed by the tag itself.
+func GetTagPath(tag *Tag) ([]*Tag, error) {
+	path := []*Tag{tag}
+
+	for parentID := tag.ParentID; parentID != 0; {
+		var parent Tag
+
+		if result := db.First(&parent, parentID); result.Error != nil {
+			return nil, fmt.Errorf("failed to get parent tag: %w", result.Error)
+		}
+
+		path = append([]*Tag{&parent}, path...)
+		parentID = parent.ParentID
+	}
+
+	return path, nil
+}
+
+// Moves a tag under another one, or to the root when parentID is 0. The parent must exist.
+func MoveTag(tagID, parentID uint64) (*Tag, error) {
+	var tag Tag
+
+	err := db.Transaction(func(transaction *gorm.DB) error {
+		if result := transaction.First(&tag, tagID); result.Error != nil {
+			return result.Error
+		}
+
+		if parentID != 0 {
+			if result := transaction.First(&Tag{}, parentID); result.Error != nil {
+				return fmt.Errorf("failed to get parent tag: %w", result.Error)
+			}
+
+			descendants, err := getTagDescendantIDs(transaction, tagID)
+			if err != nil {
+				return err
+			}
+
+			for _, descendant := range descendants {
+				if descendant == parentID {
+					return errTagCycle
+				}
+			}
+		}
+
+		tag.ParentID = parentID
+
+		return transaction.Save(&tag).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to move tag: %w", err)
+	}
+
+	return &tag, nil
+}
+
+// Merges a tag into another one. The items and children of the source tag are moved to the target tag,
+// children with the same name being merged too, and the source tag is deleted.
+func MergeTags(sourceID, targetID uint64) error {
+	err := db.Transaction(func(transaction *gorm.DB) error {
+		if result := transaction.First(&Tag{}, sourceID); result.Error != nil {
+			return fmt.Errorf("failed to get source tag: %w", result.Error)
+		}
+
+		if result := transaction.First(&Tag{}, targetID); result.Error != nil {
+			return fmt.Errorf("failed to get target tag: %w", result.Error)
+		}
+
+		descendants, err := getTagDescendantIDs(transaction, sourceID)
+		if err != nil {
+			return err
+		}
+
+		for _, descendant := range descendants {
+			if descendant == targetID {
+				return errTagCycle
+			}
+		}
+
+		return mergeTags(transaction, sourceID, targetID)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to merge tags: %w", err)
+	}
+
+	return nil
+}
+
+func mergeTags(transaction *gorm.DB, sourceID, targetID uint64) error {
+	var children []Tag
+
+	if result := transaction.Where("parent_id = ?", sourceID).Find(&children); result.Error != nil {
+		return result.Error
+	}
+
+	for _, child := range children {
+		var existing Tag
+
+		result := transaction.Where("parent_id = ? AND name = ?", targetID, child.Name).First(&existing)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			if result := transaction.Model(&child).UpdateColumn("parent_id", targetID); result.Error != nil {
+				return result.Error
+			}
+
+			continue
+		}
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if err := mergeTags(transaction, child.ID, existing.ID); err != nil {
+			return err
+		}
+	}
+
+	// Items already tagged with the target keep their assignment, the others are moved over
+	result := transaction.Exec(
+		/* sql */ `UPDATE OR IGNORE item_tags SET tag_id = ? WHERE tag_id = ?`, targetID, sourceID)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result := transaction.Where("tag_id = ?", sourceID).Delete(&ItemTag{}); result.Error != nil {
+		return result.Error
+	}
+
+	return transaction.Delete(&Tag{}, sourceID).Error
+}
+
+// Deletes a tag and its descendants, removing them from all items.
+func DeleteTag(tagID uint64) error {
+	err := db.Transaction(func(transaction *gorm.DB) error {
+		descendants, err := getTagDescendantIDs(transaction, tagID)
+		if err != nil {
+			return err
+		}
+
+		if result := transaction.Where("tag_id IN ?", descendants).Delete(&ItemTag{}); result.Error != nil {
+			return result.Error
+		}
+
+		return transaction.Delete(&Tag{}, descendants).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+
+	return nil
+}
+
+// Assigns all the given tags to all the given items. Existing assignments are kept.
+func AssignTags(itemIDs, tagIDs []uint64) error {
+	if len(itemIDs) == 0 || len(tagIDs) == 0 {
+		return nil
+	}
+
+	itemTags := make([]ItemTag, 0, len(itemIDs)*len(tagIDs))
+
+	for _, itemID := range itemIDs {
+		for _, tagID := range tagIDs {
+			itemTags = append(itemTags, ItemTag{
+				TagID:          tagID,
+				ItemMetadataID: itemID,
+				UserDefined:    true,
+			})
+		}
+	}
+
+	result := db.
+		Omit(clause.Associations).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "tag_id"}, {Name: "item_metadata_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"user_defined": true}),
+		}).
+		Create(&itemTags)
+	if result.Error != nil {
+		return fmt.Errorf("failed to assign tags: %w", result.Error)
+	}
+
+	return nil
+}
+
+// Removes all the given tags from all the given items.
+func UnassignTags(itemIDs, tagIDs []uint64) error {
+	result := db.Where("item_metadata_id IN ? AND tag_id IN ?", itemIDs, tagIDs).Delete(&ItemTag{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to unassign tags: %w", result.Error)
+	}
+
+	return nil
+}
+
+// Replaces the tags assigned to an item by providers with the given ones. Tags must already exist.
+// Tags assigned by users are kept.
+func setProviderTags(transaction *gorm.DB, itemID uint64, itemTags []ItemTag) error {
+	result := transaction.Where("item_metadata_id = ? AND user_defined = ?", itemID, false).Delete(&ItemTag{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete tags: %w", result.Error)
+	}
+
+	if len(itemTags) == 0 {
+		return nil
+	}
+
+	for index := range itemTags {
+		itemTags[index].ID = 0
+		itemTags[index].ItemMetadataID = itemID
+		itemTags[index].UserDefined = false
+	}
+
+	result = transaction.
+		Omit(clause.Associations).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&itemTags)
+	if result.Error != nil {
+		return fmt.Errorf("failed to create tags: %w", result.Error)
+	}
+
+	return nil
+}
+
+// Returns the tags of the given item, sorted by name.
+func GetTagsFromItem(itemID string) ([]*Tag, error) {
+	var tags []*Tag
+
+	result := db.
+		Joins("JOIN item_tags ON item_tags.tag_id = tags.id").
+		Where("item_tags.item_metadata_id = ?", itemID).
+		Order("tags.name").
+		Find(&tags)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return tags, nil
+}
+
+// Returns the items with the given tag, and optionally with any of its descendants, sorted by title.
+func GetItemsFromTag(tagID uint64, includeDescendants bool, limit, offset *int64) ([]*ItemMetadata, error) {
+	var items []*ItemMetadata
+
+	result := filterItemsByTag(tagID, includeDescendants).
+		Preload("Library").
+		Order("sort_title").
+		Limit(int(*limit)).
+		Offset(int(*offset)).
+		Find(&items)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return items, nil
+}
+
+func GetItemsCountFromTag(tagID uint64, includeDescendants bool) (*int64, error) {
+	var count int64
+
+	if result := filterItemsByTag(tagID, includeDescendants).Count(&count); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &count, nil
+}
+
+func filterItemsByTag(tagID uint64, includeDescendants bool) *gorm.DB {
+	tagItems := db.Model(&ItemTag{}).Select("item_metadata_id")
+
+	if includeDescendants {
+		tagItems = tagItems.Where("tag_id IN (?)", db.Raw(tagDescendantsQuery, tagID))
+	} else {
+		tagItems = tagItems.Where("tag_id = ?", tagID)
+	}
+
+	return db.Model(&ItemMetadata{}).Where("id IN (?) AND hidden = ?", tagItems, false)
+}
+
+// Returns the identifiers of a tag and all its descendants.
+func getTagDescendantIDs(transaction *gorm.DB, tagID uint64) ([]uint64, error) {
+	var ids []uint64
+
+	if result := transaction.Raw(tagDescendantsQuery, tagID).Scan(&ids); result.Error != nil {
+		return nil, fmt.Errorf("failed to get tag descendants: %w", result.Error)
+	}
+
+	return ids, nil
+}
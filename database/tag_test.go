@@ -0,0 +1,256 @@
+package database_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/internal/databasetest"
+	"gorm.io/gorm"
+)
+
+func createTagPath(t *testing.T, path ...string) *database.Tag {
+	t.Helper()
+
+	tag, err := database.GetOrCreateTagPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tag
+}
+
+func createImage(t *testing.T) *database.ItemMetadata {
+	t.Helper()
+
+	image := database.ItemMetadata{Title: "Beach", Type: database.ImageItem}
+	if err := database.CreateImage(&image); err != nil {
+		t.Fatal(err)
+	}
+
+	return &image
+}
+
+func TestMoveTag(t *testing.T) {
+	databasetest.Setup(t)
+
+	places := createTagPath(t, "Places")
+	france := createTagPath(t, "France")
+	paris := createTagPath(t, "France", "Paris")
+
+	moved, err := database.MoveTag(france.ID, places.ID)
+	if err != nil {
+		t.Fatalf("MoveTag() error = %v", err)
+	}
+
+	if moved.ParentID != places.ID {
+		t.Errorf("MoveTag() parent = %d, want %d", moved.ParentID, places.ID)
+	}
+
+	path, err := database.GetTagPath(paris)
+	if err != nil || len(path) != 3 || path[0].ID != places.ID {
+		t.Errorf("GetTagPath() = %+v, %v, want Paris under Places / France", path, err)
+	}
+
+	if _, err := database.MoveTag(france.ID, 0); err != nil {
+		t.Errorf("MoveTag() to the root error = %v", err)
+	}
+
+	const missingID = 1000
+
+	if _, err := database.MoveTag(france.ID, missingID); err == nil {
+		t.Errorf("MoveTag() under a missing parent should fail")
+	}
+
+	if tag, _ := database.GetTagByID(fmtID(france.ID)); tag.ParentID != 0 {
+		t.Errorf("MoveTag() under a missing parent moved the tag under %d", tag.ParentID)
+	}
+}
+
+func TestMoveTagRejectsCycles(t *testing.T) {
+	databasetest.Setup(t)
+
+	france := createTagPath(t, "France")
+	paris := createTagPath(t, "France", "Paris")
+	montmartre := createTagPath(t, "France", "Paris", "Montmartre")
+
+	for _, parent := range []*database.Tag{france, paris, montmartre} {
+		if _, err := database.MoveTag(france.ID, parent.ID); err == nil {
+			t.Errorf("MoveTag() under %s should fail", parent.Name)
+		}
+	}
+
+	if err := database.MergeTags(france.ID, montmartre.ID); err == nil {
+		t.Errorf("MergeTags() into a descendant should fail")
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	databasetest.Setup(t)
+
+	source := createTagPath(t, "Travel")
+	sourceChild := createTagPath(t, "Travel", "Paris")
+	sourceOnly := createTagPath(t, "Travel", "Lyon")
+	target := createTagPath(t, "Places")
+	targetChild := createTagPath(t, "Places", "Paris")
+
+	first := database.ItemMetadata{Title: "First", Type: database.ImageItem}
+	second := database.ItemMetadata{Title: "Second", Type: database.ImageItem}
+
+	for _, item := range []*database.ItemMetadata{&first, &second} {
+		if err := database.CreateImage(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := database.AssignTags([]uint64{first.ID, second.ID}, []uint64{source.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.AssignTags([]uint64{first.ID}, []uint64{target.ID, sourceChild.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.MergeTags(source.ID, target.ID); err != nil {
+		t.Fatalf("MergeTags() error = %v", err)
+	}
+
+	if _, err := database.GetTagByID(fmtID(source.ID)); err == nil {
+		t.Errorf("MergeTags() should delete the source tag")
+	}
+
+	// Children with the same name are merged, the others are moved
+	if _, err := database.GetTagByID(fmtID(sourceChild.ID)); err == nil {
+		t.Errorf("MergeTags() should merge Paris into the target's Paris")
+	}
+
+	if tag, err := database.GetTagByID(fmtID(sourceOnly.ID)); err != nil || tag.ParentID != target.ID {
+		t.Errorf("GetTagByID() = %+v, %v, want Lyon under the target", tag, err)
+	}
+
+	for _, tc := range []struct {
+		tag  *database.Tag
+		want int64
+	}{
+		{tag: target, want: 2},
+		{tag: targetChild, want: 1},
+	} {
+		count, err := database.GetItemsCountFromTag(tc.tag.ID, false)
+		if err != nil || *count != tc.want {
+			t.Errorf("GetItemsCountFromTag(%s) = %v, %v, want %d", tc.tag.Name, count, err, tc.want)
+		}
+	}
+
+	const missingID = 1000
+
+	if err := database.MergeTags(target.ID, missingID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("MergeTags() into a missing tag error = %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+
+	if err := database.MergeTags(missingID, target.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("MergeTags() from a missing tag error = %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+}
+
+func TestUpdateItemRemovesImportedKeywords(t *testing.T) {
+	databasetest.Setup(t)
+
+	image := createImage(t)
+	beach := createTagPath(t, "Places", "Beach")
+	sunset := createTagPath(t, "Sunset")
+	favorite := createTagPath(t, "Favorite")
+
+	if err := database.AssignTags([]uint64{image.ID}, []uint64{favorite.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name string
+		tags []database.ItemTag
+		want []string
+	}{
+		{"synced", []database.ItemTag{{TagID: beach.ID}, {TagID: sunset.ID}}, []string{"Beach", "Favorite", "Sunset"}},
+		{"keyword removed", []database.ItemTag{{TagID: beach.ID}}, []string{"Beach", "Favorite"}},
+		{"unknown keywords", nil, []string{"Beach", "Favorite"}},
+		{"every keyword removed", []database.ItemTag{}, []string{"Favorite"}},
+	} {
+		image.Tags = test.tags
+
+		if err := database.UpdateItem(image); err != nil {
+			t.Fatalf("%s: UpdateItem() error = %v", test.name, err)
+		}
+
+		tags, err := database.GetTagsFromItem(fmtID(image.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names := make([]string, 0, len(tags))
+		for _, tag := range tags {
+			names = append(names, tag.Name)
+		}
+
+		if !reflect.DeepEqual(names, test.want) {
+			t.Errorf("%s: UpdateItem() tags = %v, want %v", test.name, names, test.want)
+		}
+	}
+}
+
+func TestGetItemsFromTag(t *testing.T) {
+	databasetest.Setup(t)
+
+	image := createImage(t)
+	paris := createTagPath(t, "Places", "France", "Paris")
+	places := createTagPath(t, "Places")
+
+	if err := database.AssignTags([]uint64{image.ID}, []uint64{paris.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	limit, offset := int64(10), int64(0)
+
+	for _, test := range []struct {
+		withDescendants bool
+		want            int
+	}{
+		{withDescendants: false, want: 0},
+		{withDescendants: true, want: 1},
+	} {
+		items, err := database.GetItemsFromTag(places.ID, test.withDescendants, &limit, &offset)
+		if err != nil || len(items) != test.want {
+			t.Errorf("GetItemsFromTag(%t) = %v, %v, want %d items", test.withDescendants, getTitles(items), err, test.want)
+		}
+	}
+}
+
+func TestDeleteTag(t *testing.T) {
+	databasetest.Setup(t)
+
+	image := createImage(t)
+	paris := createTagPath(t, "Places", "France", "Paris")
+	places := createTagPath(t, "Places")
+	favorite := createTagPath(t, "Favorite")
+
+	if err := database.AssignTags([]uint64{image.ID}, []uint64{paris.ID, favorite.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.DeleteTag(places.ID); err != nil {
+		t.Fatalf("DeleteTag() error = %v", err)
+	}
+
+	// The descendants go too, along with their assignments
+	if _, err := database.GetTagByID(fmtID(paris.ID)); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("GetTagByID() error = %v, want the descendants deleted", err)
+	}
+
+	if names := getTagNames(t, image.ID); !reflect.DeepEqual(names, []string{"Favorite"}) {
+		t.Errorf("item tags = %v, want only Favorite", names)
+	}
+
+	roots, err := database.GetTagChildren(0)
+	if err != nil || len(roots) != 1 || roots[0].ID != favorite.ID {
+		t.Errorf("GetTagChildren(0) = %+v, %v, want only Favorite", roots, err)
+	}
+}
@@ -0,0 +1,85 @@
+package duplicates
+
+// A BK-tree of hashes, to find the ones within a distance of a hash without comparing it to all of them.
+// Children are keyed by their distance to their parent, and the triangle inequality rules out
+// the subtrees too far from the searched hash.
+type BKTree struct {
+	root *bkNode
+}
+
+type bkNode struct {
+	hash     uint64
+	ids      []uint64
+	children map[int]*bkNode
+}
+
+// An item found in a BKTree.
+type Match struct {
+	ID       uint64
+	Hash     uint64
+	Distance int
+}
+
+// Adds an item to the tree. Items can share the same hash.
+func (t *BKTree) Add(hash, id uint64) {
+	if t.root == nil {
+		t.root = &bkNode{hash: hash, ids: []uint64{id}}
+
+		return
+	}
+
+	node := t.root
+
+	for {
+		distance := Distance(node.hash, hash)
+		if distance == 0 {
+			node.ids = append(node.ids, id)
+
+			return
+		}
+
+		child, ok := node.children[distance]
+		if !ok {
+			if node.children == nil {
+				node.children = make(map[int]*bkNode)
+			}
+
+			node.children[distance] = &bkNode{hash: hash, ids: []uint64{id}}
+
+			return
+		}
+
+		node = child
+	}
+}
+
+// Returns the items whose hash is within maxDistance of the given hash.
+func (t *BKTree) Search(hash uint64, maxDistance int) []Match {
+	var matches []Match
+
+	if t.root == nil {
+		return matches
+	}
+
+	nodes := []*bkNode{t.root}
+
+	for len(nodes) > 0 {
+		node := nodes[len(nodes)-1]
+		nodes = nodes[:len(nodes)-1]
+
+		distance := Distance(node.hash, hash)
+		if distance <= maxDistance {
+			for _, id := range node.ids {
+				matches = append(matches, Match{ID: id, Hash: node.hash, Distance: distance})
+			}
+		}
+
+		for childDistance, child := range node.children {
+			if childDistance >= distance-maxDistance && childDistance <= distance+maxDistance {
+				nodes = append(nodes, child)
+			}
+		}
+	}
+
+	return matches
+}
@@ -0,0 +1,207 @@
+package duplicates_test
+
+import (
+	"image"
+	"image/color"
+	"sort"
+	"testing"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/duplicates"
+)
+
+// Returns a horizontal gradient, optionally with a brighter pixel.
+func newGradient(width, height int, brightPixel *image.Point) image.Image {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetGray(x, y, color.Gray{Y: uint8(255 - x*255/width)})
+		}
+	}
+
+	if brightPixel != nil {
+		img.SetGray(brightPixel.X, brightPixel.Y, color.Gray{Y: 255})
+	}
+
+	return img
+}
+
+func TestDifferenceHash(t *testing.T) {
+	t.Parallel()
+
+	// Pixels get darker towards the right, so every bit is set
+	if hash := duplicates.DifferenceHash(newGradient(duplicates.HashWidth, duplicates.HashHeight, nil)); hash != ^uint64(0) {
+		t.Errorf("unexpected gradient hash %016x", hash)
+	}
+
+	small := duplicates.DifferenceHash(newGradient(duplicates.HashWidth, duplicates.HashHeight, nil))
+	large := duplicates.DifferenceHash(newGradient(duplicates.HashWidth*10, duplicates.HashHeight*10, nil))
+
+	if distance := duplicates.Distance(small, large); distance != 0 {
+		t.Errorf("expected resized images to have the same hash, got a distance of %d", distance)
+	}
+
+	edited := duplicates.DifferenceHash(newGradient(duplicates.HashWidth, duplicates.HashHeight, &image.Point{X: 4, Y: 2}))
+	if distance := duplicates.Distance(small, edited); distance != 1 {
+		t.Errorf("expected a distance of 1 for a brighter pixel, got %d", distance)
+	}
+}
+
+func TestHashFormatRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, hash := range []uint64{0, 1, 0x8000000000000000, ^uint64(0)} {
+		formatted := duplicates.FormatHash(hash)
+
+		parsed, err := duplicates.ParseHash(formatted)
+		if err != nil || parsed != hash || len(formatted) != 16 {
+			t.Errorf("expected %016x, got %s parsed as %016x: %v", hash, formatted, parsed, err)
+		}
+	}
+
+	if _, err := duplicates.ParseHash("not a hash"); err == nil {
+		t.Error("expected an invalid hash error")
+	}
+}
+
+func TestBKTreeSearch(t *testing.T) {
+	t.Parallel()
+
+	hashes := []uint64{0b0000, 0b0001, 0b0011, 0b0111, 0b1111, 0b1111_0000_0000, 0b0001}
+
+	var tree duplicates.BKTree
+
+	for id, hash := range hashes {
+		tree.Add(hash, uint64(id))
+	}
+
+	for _, query := range []uint64{0b0000, 0b0110, 0b1111_0000_0001} {
+		for maxDistance := 0; maxDistance <= 4; maxDistance++ {
+			var expected, found []uint64
+
+			for id, hash := range hashes {
+				if duplicates.Distance(hash, query) <= maxDistance {
+					expected = append(expected, uint64(id))
+				}
+			}
+
+			for _, match := range tree.Search(query, maxDistance) {
+				if match.Distance != duplicates.Distance(match.Hash, query) {
+					t.Errorf("unexpected distance for %+v", match)
+				}
+
+				found = append(found, match.ID)
+			}
+
+			sort.Slice(found, func(i, j int) bool { return found[i] < found[j] })
+
+			if len(found) != len(expected) {
+				t.Errorf("searching %b within %d: expected %v, got %v", query, maxDistance, expected, found)
+
+				continue
+			}
+
+			for index := range found {
+				if found[index] != expected[index] {
+					t.Errorf("searching %b within %d: expected %v, got %v", query, maxDistance, expected, found)
+
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestCompareVideos(t *testing.T) {
+	t.Parallel()
+
+	original := duplicates.VideoFingerprint{
+		Duration:    6_000_000,
+		FrameHashes: []uint64{0x1111, 0x2222, 0x3333, 0x4444, 0x5555, 0x6666, 0x7777, 0x8888, 0x9999},
+	}
+
+	// A re-encoded copy with slightly different frames, trimmed so that its last frames shifted by one position,
+	// and a black frame
+	copied := duplicates.VideoFingerprint{
+		Duration:    5_900_000,
+		FrameHashes: []uint64{0x1111, 0x2223, 0x3333, 0x4444, 0, 0x5555, 0x6666, 0x7777, 0x8888},
+	}
+
+	if isDuplicate, distance := duplicates.CompareVideos(original, copied, 10); !isDuplicate || distance != 0 {
+		t.Errorf("expected copies to match, got %t with a distance of %d", isDuplicate, distance)
+	}
+
+	longer := copied
+	longer.Duration = 7_000_000
+
+	if isDuplicate, _ := duplicates.CompareVideos(original, longer, 10); isDuplicate {
+		t.Error("expected videos of different durations not to match")
+	}
+
+	different := duplicates.VideoFingerprint{
+		Duration:    6_000_000,
+		FrameHashes: []uint64{0x1111, 0x2222, ^uint64(0), ^uint64(0), ^uint64(0), ^uint64(0), ^uint64(0), 0, 0},
+	}
+
+	if isDuplicate, _ := duplicates.CompareVideos(original, different, 10); isDuplicate {
+		t.Error("expected videos sharing a few frames not to match")
+	}
+
+	black := duplicates.VideoFingerprint{Duration: 6_000_000, FrameHashes: make([]uint64, 9)}
+
+	if isDuplicate, _ := duplicates.CompareVideos(black, black, 10); isDuplicate {
+		t.Error("expected videos without usable frames not to match")
+	}
+}
+
+func TestVideoFingerprintRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	fingerprint := duplicates.VideoFingerprint{Duration: 1000, FrameHashes: []uint64{0, 0xabc, ^uint64(0)}}
+
+	parsed, err := duplicates.ParseVideoFingerprint(fingerprint.Duration, fingerprint.FormatFrameHashes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed.Duration != fingerprint.Duration || len(parsed.FrameHashes) != len(fingerprint.FrameHashes) {
+		t.Fatalf("expected %+v, got %+v", fingerprint, parsed)
+	}
+
+	for index, hash := range fingerprint.FrameHashes {
+		if parsed.FrameHashes[index] != hash {
+			t.Errorf("expected %+v, got %+v", fingerprint, parsed)
+		}
+	}
+}
+
+func TestGroupCandidates(t *testing.T) {
+	t.Parallel()
+
+	candidates := []*database.DuplicateCandidate{
+		{ID: 1, ItemMetadataID: 1, DuplicateID: 2},
+		{ID: 2, ItemMetadataID: 3, DuplicateID: 4},
+		{ID: 3, ItemMetadataID: 5, DuplicateID: 2},
+		{ID: 4, ItemMetadataID: 4, DuplicateID: 6},
+	}
+
+	groups := duplicates.GroupCandidates(candidates)
+
+	expected := [][]uint64{{1, 3}, {2, 4}}
+	if len(groups) != len(expected) {
+		t.Fatalf("expected %d groups, got %d", len(expected), len(groups))
+	}
+
+	for index, group := range groups {
+		if len(group) != len(expected[index]) {
+			t.Fatalf("expected group %d to hold candidates %v, got %d", index, expected[index], len(group))
+		}
+
+		for candidateIndex, candidate := range group {
+			if candidate.ID != expected[index][candidateIndex] {
+				t.Errorf("expected group %d to hold candidates %v, got %d", index, expected[index], candidate.ID)
+			}
+		}
+	}
+}
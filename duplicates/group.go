@@ -0,0 +1,48 @@
+package duplicates
+
+import "github.com/meteorae/meteorae-server/database"
+
+// Groups candidates sharing items, so that all the copies of an item are reviewed together.
+// Groups keep the order of their first candidate, and candidates keep their order within groups.
+func GroupCandidates(candidates []*database.DuplicateCandidate) [][]*database.DuplicateCandidate {
+	parents := make(map[uint64]uint64)
+
+	var find func(id uint64) uint64
+
+	find = func(id uint64) uint64 {
+		parent, ok := parents[id]
+		if !ok || parent == id {
+			parents[id] = id
+
+			return id
+		}
+
+		root := find(parent)
+		parents[id] = root
+
+		return root
+	}
+
+	for _, candidate := range candidates {
+		parents[find(candidate.DuplicateID)] = find(candidate.ItemMetadataID)
+	}
+
+	var groups [][]*database.DuplicateCandidate
+
+	groupIndexes := make(map[uint64]int)
+
+	for _, candidate := range candidates {
+		root := find(candidate.ItemMetadataID)
+
+		index, ok := groupIndexes[root]
+		if !ok {
+			index = len(groups)
+			groupIndexes[root] = index
+			groups = append(groups, nil)
+		}
+
+		groups[index] = append(groups[index], candidate)
+	}
+
+	return groups
+}
@@ -0,0 +1,71 @@
+// Package duplicates finds items which look alike, like resized or re-encoded copies of a photo,
+// by comparing perceptual hashes, and queues them for review.
+package duplicates
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math/bits"
+	"strconv"
+)
+
+// Size of the images hashed by DifferenceHash. Rows have one more pixel than bits,
+// since each bit compares two neighboring pixels.
+const (
+	HashWidth  = 9
+	HashHeight = 8
+)
+
+// Computes the difference hash of an image, whose bits tell whether each pixel is brighter than the next one
+// on its row. Unlike cryptographic hashes, similar images get similar hashes, which survive resizing and
+// compression. The image is expected to be scaled down to HashWidth by HashHeight pixels, larger images are sampled.
+func DifferenceHash(img image.Image) uint64 {
+	bounds := img.Bounds()
+
+	var hash uint64
+
+	for y := 0; y < HashHeight; y++ {
+		previous := getLuminance(img, bounds, 0, y)
+
+		for x := 1; x < HashWidth; x++ {
+			current := getLuminance(img, bounds, x, y)
+
+			hash <<= 1
+			if previous > current {
+				hash |= 1
+			}
+
+			previous = current
+		}
+	}
+
+	return hash
+}
+
+func getLuminance(img image.Image, bounds image.Rectangle, x, y int) uint8 {
+	pixel := img.At(bounds.Min.X+x*bounds.Dx()/HashWidth, bounds.Min.Y+y*bounds.Dy()/HashHeight)
+
+	gray, _ := color.GrayModel.Convert(pixel).(color.Gray)
+
+	return gray.Y
+}
+
+// Returns the number of differing bits between two hashes, from 0 for identical hashes to 64.
+func Distance(first, second uint64) int {
+	return bits.OnesCount64(first ^ second)
+}
+
+// Formats a hash as 16 hexadecimal digits, for storage.
+func FormatHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+func ParseHash(hash string) (uint64, error) {
+	value, err := strconv.ParseUint(hash, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid perceptual hash %s: %w", hash, err)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,86 @@
+package duplicates
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/spf13/viper"
+)
+
+var imageIndex = hashIndex{itemType: database.ImageItem}
+
+// The perceptual hashes of the items of a type, loaded from the database on first use.
+type hashIndex struct {
+	itemType database.ItemType
+	mutex    sync.Mutex
+	tree     *BKTree
+	// The current hash of each item. The tree can't remove items, so it keeps the previous hashes
+	// of items whose hash changed, which are ignored.
+	hashes map[uint64]uint64
+}
+
+// Adds an image to the index, and queues the images looking like it for review.
+// Images are similar when their hashes differ by at most duplicates.max_distance bits.
+func FindImageDuplicates(item *database.ItemMetadata) error {
+	return imageIndex.findDuplicates(item)
+}
+
+func (i *hashIndex) findDuplicates(item *database.ItemMetadata) error {
+	if item.PerceptualHash == "" || item.Hidden {
+		return nil
+	}
+
+	hash, err := ParseHash(item.PerceptualHash)
+	if err != nil {
+		return err
+	}
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if i.tree == nil {
+		if err := i.load(); err != nil {
+			return err
+		}
+	}
+
+	for _, match := range i.tree.Search(hash, viper.GetInt("duplicates.max_distance")) {
+		if match.ID == item.ID || i.hashes[match.ID] != match.Hash {
+			continue
+		}
+
+		if err := database.AddDuplicateCandidate(match.ID, item.ID, match.Distance); err != nil {
+			return fmt.Errorf("failed to queue duplicate: %w", err)
+		}
+	}
+
+	if current, ok := i.hashes[item.ID]; !ok || current != hash {
+		i.tree.Add(hash, item.ID)
+		i.hashes[item.ID] = hash
+	}
+
+	return nil
+}
+
+func (i *hashIndex) load() error {
+	hashes, err := database.GetPerceptualHashes(i.itemType)
+	if err != nil {
+		return fmt.Errorf("failed to load perceptual hashes: %w", err)
+	}
+
+	i.tree = &BKTree{}
+	i.hashes = make(map[uint64]uint64, len(hashes))
+
+	for _, itemHash := range hashes {
+		hash, err := ParseHash(itemHash.PerceptualHash)
+		if err != nil {
+			continue
+		}
+
+		i.tree.Add(hash, itemHash.ID)
+		i.hashes[itemHash.ID] = hash
+	}
+
+	return nil
+}
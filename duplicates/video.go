@@ -0,0 +1,194 @@
+package duplicates
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/spf13/viper"
+)
+
+const (
+	// Copies of a video can be trimmed or have different intros, so their durations only need to be close.
+	maxDurationDifference = 0.05
+	// Part of the frames of a video which need a match in the other one.
+	minMatchingFrames = 0.7
+	// Videos with fewer usable frames, like mostly black ones, aren't compared.
+	minUsableFrames = 3
+)
+
+// The duration and frame hashes of a video, sampled at the same relative positions for all videos.
+// Frames without any detail, like black frames, hash to zero and are ignored, as all of them look alike.
+type VideoFingerprint struct {
+	// Duration of the video, in milliseconds.
+	Duration    int64
+	FrameHashes []uint64
+}
+
+// Parses a fingerprint stored in the database.
+func ParseVideoFingerprint(duration int64, frameHashes string) (VideoFingerprint, error) {
+	fingerprint := VideoFingerprint{Duration: duration}
+
+	for _, frameHash := range strings.Split(frameHashes, ",") {
+		if frameHash == "" {
+			fingerprint.FrameHashes = append(fingerprint.FrameHashes, 0)
+
+			continue
+		}
+
+		hash, err := ParseHash(frameHash)
+		if err != nil {
+			return fingerprint, err
+		}
+
+		fingerprint.FrameHashes = append(fingerprint.FrameHashes, hash)
+	}
+
+	return fingerprint, nil
+}
+
+// Formats the frame hashes of a fingerprint, for storage.
+func (f VideoFingerprint) FormatFrameHashes() string {
+	frameHashes := make([]string, 0, len(f.FrameHashes))
+
+	for _, hash := range f.FrameHashes {
+		frameHashes = append(frameHashes, FormatHash(hash))
+	}
+
+	return strings.Join(frameHashes, ",")
+}
+
+// Compares two fingerprints, and returns whether they belong to the same video along with the average distance
+// of their matching frames. A frame matches when it is within maxDistance bits of the frame sampled at the same
+// position in the other video, or at a neighboring position, since trimming a video shifts its frames.
+func CompareVideos(first, second VideoFingerprint, maxDistance int) (bool, int) {
+	longest := math.Max(float64(first.Duration), float64(second.Duration))
+	if longest == 0 || math.Abs(float64(first.Duration-second.Duration)) > longest*maxDurationDifference {
+		return false, 0
+	}
+
+	usable, matching, totalDistance := 0, 0, 0
+
+	for index, hash := range first.FrameHashes {
+		if hash == 0 {
+			continue
+		}
+
+		usable++
+
+		bestDistance := -1
+
+		for otherIndex := index - 1; otherIndex <= index+1; otherIndex++ {
+			if otherIndex < 0 || otherIndex >= len(second.FrameHashes) || second.FrameHashes[otherIndex] == 0 {
+				continue
+			}
+
+			distance := Distance(hash, second.FrameHashes[otherIndex])
+			if distance <= maxDistance && (bestDistance < 0 || distance < bestDistance) {
+				bestDistance = distance
+			}
+		}
+
+		if bestDistance >= 0 {
+			matching++
+			totalDistance += bestDistance
+		}
+	}
+
+	if usable < minUsableFrames || float64(matching) < float64(usable)*minMatchingFrames {
+		return false, 0
+	}
+
+	return true, totalDistance / matching
+}
+
+var videoIndex = videoHashIndex{}
+
+// The frame hashes of the videos of all libraries, loaded from the database on first use.
+type videoHashIndex struct {
+	mutex sync.Mutex
+	// Every frame hash, to find the videos sharing a frame with another one without comparing all of them.
+	tree         *BKTree
+	fingerprints map[uint64]VideoFingerprint
+}
+
+// Adds a video to the index, and queues the videos of any library which are copies of it for review.
+func FindVideoDuplicates(itemID uint64, fingerprint VideoFingerprint) error {
+	return videoIndex.findDuplicates(itemID, fingerprint)
+}
+
+func (i *videoHashIndex) findDuplicates(itemID uint64, fingerprint VideoFingerprint) error {
+	maxDistance := viper.GetInt("duplicates.max_distance")
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if i.tree == nil {
+		if err := i.load(); err != nil {
+			return err
+		}
+	}
+
+	compared := make(map[uint64]bool)
+
+	for _, hash := range fingerprint.FrameHashes {
+		if hash == 0 {
+			continue
+		}
+
+		for _, match := range i.tree.Search(hash, maxDistance) {
+			if match.ID == itemID || compared[match.ID] {
+				continue
+			}
+
+			compared[match.ID] = true
+
+			isDuplicate, distance := CompareVideos(i.fingerprints[match.ID], fingerprint, maxDistance)
+			if !isDuplicate {
+				continue
+			}
+
+			if err := database.AddDuplicateCandidate(match.ID, itemID, distance); err != nil {
+				return fmt.Errorf("failed to queue duplicate: %w", err)
+			}
+		}
+	}
+
+	// Previous hashes of the item stay in the tree, but are compared against its current fingerprint
+	i.add(itemID, fingerprint)
+
+	return nil
+}
+
+func (i *videoHashIndex) add(itemID uint64, fingerprint VideoFingerprint) {
+	for _, hash := range fingerprint.FrameHashes {
+		if hash != 0 {
+			i.tree.Add(hash, itemID)
+		}
+	}
+
+	i.fingerprints[itemID] = fingerprint
+}
+
+func (i *videoHashIndex) load() error {
+	fingerprints, err := database.GetVideoFingerprints()
+	if err != nil {
+		return fmt.Errorf("failed to load video fingerprints: %w", err)
+	}
+
+	i.tree = &BKTree{}
+	i.fingerprints = make(map[uint64]VideoFingerprint, len(fingerprints))
+
+	for _, itemFingerprint := range fingerprints {
+		fingerprint, err := ParseVideoFingerprint(itemFingerprint.Duration, itemFingerprint.FrameHashes)
+		if err != nil {
+			continue
+		}
+
+		i.add(itemFingerprint.ItemMetadataID, fingerprint)
+	}
+
+	return nil
+}
@@ -16,33 +16,50 @@ import (
 
 var ffprobeProcessTimeout = 5 * time.Second
 
-func AnalyzeAudio(mediaPart database.MediaPart) error {
+// Stores the streams of an audio file, and its Chromaprint fingerprint, used to identify it with AcoustID.
+// The fingerprint is read from the file tags when a tagger already computed it, and computed otherwise.
+// It is also set on the given part.
+func AnalyzeAudio(mediaPart *database.MediaPart) error {
 	log.Debug().Msgf("Analyzing %s", mediaPart.FilePath)
 
-	err := getFfprobeData(mediaPart)
+	err := getFfprobeData(*mediaPart)
 	if err != nil {
 		return fmt.Errorf("could not get ffprobe data: %w", err)
 	}
 
-	mediaFile, err := os.Open(mediaPart.FilePath)
+	fingerprint := readFingerprintTag(mediaPart.FilePath)
+	if fingerprint == "" {
+		fingerprint, err = ComputeChromaprint(mediaPart.FilePath)
+		if err != nil {
+			return fmt.Errorf("could not compute fingerprint: %w", err)
+		}
+	}
+
+	if err := database.SetAcoustID(mediaPart, fingerprint); err != nil {
+		return fmt.Errorf("could not save fingerprint: %w", err)
+	}
+
+	return nil
+}
+
+// Returns the fingerprint written in the tags of a file by taggers like MusicBrainz Picard, if any.
+func readFingerprintTag(filePath string) string {
+	mediaFile, err := os.Open(filePath)
 	if err != nil {
-		return fmt.Errorf("could not open file: %w", err)
+		log.Debug().Err(err).Msgf("Could not open %s to read tags", filePath)
+
+		return ""
 	}
 	defer mediaFile.Close()
 
 	mediaTags, err := tag.ReadFrom(mediaFile)
 	if err != nil {
-		return fmt.Errorf("could not read tags: %w", err)
-	}
-
-	musicBrainzTags := mbz.Extract(mediaTags)
+		log.Debug().Err(err).Msgf("No tags found in %s", filePath)
 
-	fingerprint := musicBrainzTags.Get(mbz.AcoustFingerprint)
-	if fingerprint != "" {
-		database.SetAcoustID(&mediaPart, fingerprint)
+		return ""
 	}
 
-	return nil
+	return mbz.Extract(mediaTags).Get(mbz.AcoustFingerprint)
 }
 
 func AnalyzeVideo(mediaPart database.MediaPart) error {
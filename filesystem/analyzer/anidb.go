@@ -0,0 +1,53 @@
+package analyzer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// Computes the ED2K hash and CRC32 of an anime file, used to identify it on AniDB, and checks the CRC32
+// against the one fansub groups write in file names. Files with a different CRC32 are flagged, as they
+// are likely corrupted. The hashes are also set on the given part.
+func AnalyzeAnime(mediaPart *database.MediaPart) error {
+	log.Debug().Msgf("Hashing %s", mediaPart.FilePath)
+
+	file, err := os.Open(mediaPart.FilePath)
+	if err != nil {
+		return fmt.Errorf("could not open file: %w", err)
+	}
+	defer file.Close()
+
+	ed2kHash := utils.NewED2K()
+	crcHash := crc32.NewIEEE()
+
+	// Both hashes are computed in a single read, since anime files can be large
+	if _, err := io.Copy(io.MultiWriter(ed2kHash, crcHash), file); err != nil {
+		return fmt.Errorf("could not hash file: %w", err)
+	}
+
+	crc := fmt.Sprintf("%08X", crcHash.Sum32())
+
+	fileName := filepath.Base(mediaPart.FilePath)
+	expectedCRC := utils.ParseAnimeFileName(fileName[:len(fileName)-len(filepath.Ext(fileName))]).CRC
+
+	crcMismatch := expectedCRC != "" && expectedCRC != crc
+	if crcMismatch {
+		log.Warn().Msgf("The CRC32 of %s is %s instead of %s, the file may be corrupted",
+			mediaPart.FilePath, crc, expectedCRC)
+	}
+
+	err = database.SetAniDBHashes(mediaPart, hex.EncodeToString(ed2kHash.Sum(nil)), crc, crcMismatch)
+	if err != nil {
+		return fmt.Errorf("could not save hashes: %w", err)
+	}
+
+	return nil
+}
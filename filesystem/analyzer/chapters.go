@@ -0,0 +1,158 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/meteorae/meteorae-server/database"
+)
+
+// Describes the duration, tags and chapters of a media file, as reported by ffprobe.
+type MediaInfo struct {
+	// Duration of the file, in milliseconds.
+	Duration int64
+	// Format-level tags, with lowercased keys.
+	Tags     map[string]string
+	Chapters []ChapterInfo
+}
+
+// Describes a chapter embedded in a media file. Timestamps are in milliseconds.
+type ChapterInfo struct {
+	Title     string
+	StartTime int64
+	EndTime   int64
+}
+
+// Returns the tag matching the first of the given keys present in the file, if any.
+func (m MediaInfo) Tag(keys ...string) string {
+	for _, key := range keys {
+		if value := strings.TrimSpace(m.Tags[key]); value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// Parses the release date tag, which is usually either a full date or a year.
+func (m MediaInfo) GetReleaseDate() time.Time {
+	date := m.Tag("date", "year")
+
+	for _, layout := range []string{"2006-01-02", "2006"} {
+		if len(date) < len(layout) {
+			continue
+		}
+
+		releaseDate, err := time.Parse(layout, date[:len(layout)])
+		if err == nil {
+			return releaseDate
+		}
+	}
+
+	return time.Time{}
+}
+
+// Converts the chapters embedded in the file, shifting them by the given offset in milliseconds.
+// Files without chapters are returned as a single chapter, named after their title or file name.
+func (m MediaInfo) GetChapters(filePath string, offset int64) []database.Chapter {
+	if len(m.Chapters) == 0 {
+		title := m.Tag("title")
+		if title == "" {
+			title = filepath.Base(filePath)
+			title = title[:len(title)-len(filepath.Ext(title))]
+		}
+
+		return []database.Chapter{{
+			Title:     title,
+			StartTime: offset,
+			EndTime:   offset + m.Duration,
+		}}
+	}
+
+	chapters := make([]database.Chapter, 0, len(m.Chapters))
+
+	for _, chapter := range m.Chapters {
+		chapters = append(chapters, database.Chapter{
+			Title:     chapter.Title,
+			StartTime: offset + chapter.StartTime,
+			EndTime:   offset + chapter.EndTime,
+		})
+	}
+
+	return chapters
+}
+
+type ffprobeChaptersOutput struct {
+	Chapters []struct {
+		StartTime string            `json:"start_time"`
+		EndTime   string            `json:"end_time"`
+		Tags      map[string]string `json:"tags"`
+	} `json:"chapters"`
+	Format struct {
+		Duration string            `json:"duration"`
+		Tags     map[string]string `json:"tags"`
+	} `json:"format"`
+}
+
+// Probes the given file for its duration, tags and chapter list.
+// go-ffprobe doesn't expose chapters, so we call ffprobe directly.
+func ProbeMediaInfo(filePath string) (*MediaInfo, error) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), ffprobeProcessTimeout)
+	defer cancelFn()
+
+	var stdout bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "ffprobe", //#nosec
+		"-loglevel", "fatal",
+		"-print_format", "json",
+		"-show_format",
+		"-show_chapters",
+		filePath,
+	)
+	cmd.Stdout = &stdout
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("could not probe file: %w", err)
+	}
+
+	var output ffprobeChaptersOutput
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return nil, fmt.Errorf("could not parse ffprobe output: %w", err)
+	}
+
+	mediaInfo := MediaInfo{
+		Duration: parseSeconds(output.Format.Duration),
+		Tags:     make(map[string]string, len(output.Format.Tags)),
+	}
+
+	for key, value := range output.Format.Tags {
+		mediaInfo.Tags[strings.ToLower(key)] = value
+	}
+
+	for _, chapter := range output.Chapters {
+		mediaInfo.Chapters = append(mediaInfo.Chapters, ChapterInfo{
+			Title:     chapter.Tags["title"],
+			StartTime: parseSeconds(chapter.StartTime),
+			EndTime:   parseSeconds(chapter.EndTime),
+		})
+	}
+
+	return &mediaInfo, nil
+}
+
+// Converts a duration in seconds, as formatted by ffprobe, to milliseconds.
+func parseSeconds(seconds string) int64 {
+	value, err := strconv.ParseFloat(seconds, 64)
+	if err != nil {
+		return 0
+	}
+
+	return int64(value * 1000) //nolint:gomnd
+}
@@ -0,0 +1,73 @@
+package analyzer_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/filesystem/analyzer"
+)
+
+func TestMediaInfoTag(t *testing.T) {
+	t.Parallel()
+
+	mediaInfo := analyzer.MediaInfo{Tags: map[string]string{"album": "  ", "title": "Dune"}}
+
+	if tag := mediaInfo.Tag("album", "title"); tag != "Dune" {
+		t.Errorf("Tag() = %q, want the first non-blank tag", tag)
+	}
+
+	if tag := mediaInfo.Tag("narrator"); tag != "" {
+		t.Errorf("Tag() = %q, want an empty string for missing tags", tag)
+	}
+}
+
+func TestMediaInfoGetReleaseDate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		tags map[string]string
+		want time.Time
+	}{
+		{map[string]string{"date": "2019-10-01T00:00:00Z"}, time.Date(2019, time.October, 1, 0, 0, 0, 0, time.UTC)},
+		{map[string]string{"year": "1965"}, time.Date(1965, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{map[string]string{"date": "soon"}, time.Time{}},
+		{nil, time.Time{}},
+	}
+
+	for _, tc := range tests {
+		if got := (analyzer.MediaInfo{Tags: tc.tags}).GetReleaseDate(); !got.Equal(tc.want) {
+			t.Errorf("GetReleaseDate() with %v = %v, want %v", tc.tags, got, tc.want)
+		}
+	}
+}
+
+func TestMediaInfoGetChapters(t *testing.T) {
+	t.Parallel()
+
+	mediaInfo := analyzer.MediaInfo{
+		Duration: 3000,
+		Chapters: []analyzer.ChapterInfo{
+			{Title: "Opening", StartTime: 0, EndTime: 1000},
+			{Title: "Ending", StartTime: 1000, EndTime: 3000},
+		},
+	}
+
+	want := []database.Chapter{
+		{Title: "Opening", StartTime: 500, EndTime: 1500},
+		{Title: "Ending", StartTime: 1500, EndTime: 3500},
+	}
+
+	if got := mediaInfo.GetChapters("/books/Dune/01.mp3", 500); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetChapters() = %+v, want chapters shifted by the offset %+v", got, want)
+	}
+
+	// Files without chapters are a single chapter named after the file
+	withoutChapters := analyzer.MediaInfo{Duration: 2000}
+	want = []database.Chapter{{Title: "01 - Prologue", StartTime: 3500, EndTime: 5500}}
+
+	if got := withoutChapters.GetChapters("/books/Dune/01 - Prologue.mp3", 3500); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetChapters() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,95 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errEmptyFingerprint = errors.New("empty fingerprint")
+
+// Length of audio used to compute fingerprints, as the AcoustID service expects.
+const chromaprintLength = 120 * time.Second
+
+type fpcalcOutput struct {
+	Duration    float64 `json:"duration"`
+	Fingerprint string  `json:"fingerprint"`
+}
+
+// Computes the Chromaprint fingerprint of an audio file, compressed and encoded as URL-safe base64,
+// like the AcoustID service expects. Uses fpcalc when it is installed, and the chromaprint muxer
+// of ffmpeg otherwise, which is only available in ffmpeg builds with chromaprint enabled.
+func ComputeChromaprint(filePath string) (string, error) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), ffmpegProcessTimeout)
+	defer cancelFn()
+
+	var (
+		fingerprint string
+		err         error
+	)
+
+	if _, lookErr := exec.LookPath("fpcalc"); lookErr == nil {
+		fingerprint, err = runFpcalc(ctx, filePath)
+	} else {
+		fingerprint, err = runChromaprintMuxer(ctx, filePath)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if fingerprint == "" {
+		return "", errEmptyFingerprint
+	}
+
+	return fingerprint, nil
+}
+
+func runFpcalc(ctx context.Context, filePath string) (string, error) {
+	var stdout bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "fpcalc", //#nosec
+		"-json",
+		"-length", strconv.Itoa(int(chromaprintLength.Seconds())),
+		filePath,
+	)
+	cmd.Stdout = &stdout
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("could not run fpcalc: %w", err)
+	}
+
+	var output fpcalcOutput
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return "", fmt.Errorf("could not parse fpcalc output: %w", err)
+	}
+
+	return output.Fingerprint, nil
+}
+
+func runChromaprintMuxer(ctx context.Context, filePath string) (string, error) {
+	var stdout bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", //#nosec
+		"-loglevel", "fatal",
+		"-i", filePath,
+		"-t", strconv.Itoa(int(chromaprintLength.Seconds())),
+		"-vn",
+		"-f", "chromaprint",
+		"-fp_format", "base64",
+		"-",
+	)
+	cmd.Stdout = &stdout
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("could not run the ffmpeg chromaprint muxer: %w", err)
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,216 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/duplicates"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	errUnknownDuration = errors.New("unknown video duration")
+	errInvalidFrame    = errors.New("unexpected frame size")
+)
+
+// Seeking can be slow on some containers, so extracting a frame gets more time than probing a file.
+var ffmpegProcessTimeout = 30 * time.Second
+
+// Relative positions of the frames sampled from videos. The start and end are skipped, since they often hold
+// black frames, logos or credits which many videos share.
+var fingerprintPositions = []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9}
+
+// Item types whose videos are fingerprinted to find their copies.
+var fingerprintedItemTypes = []database.ItemType{
+	database.MovieItem,
+	database.AnimeMovieItem,
+	database.AnimeEpisodeItem,
+	database.MusicVideoItem,
+}
+
+// Holds the media parts waiting for a fingerprint, without duplicates.
+type fingerprintQueue struct {
+	mutex   sync.Mutex
+	pending []database.MediaPart
+	queued  map[uint64]bool
+	wake    chan struct{}
+}
+
+var queue = fingerprintQueue{
+	queued: map[uint64]bool{},
+	wake:   make(chan struct{}, 1),
+}
+
+func (q *fingerprintQueue) push(mediaParts ...database.MediaPart) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for _, mediaPart := range mediaParts {
+		if q.queued[mediaPart.ID] {
+			continue
+		}
+
+		q.queued[mediaPart.ID] = true
+		q.pending = append(q.pending, mediaPart)
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *fingerprintQueue) pop() (database.MediaPart, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(q.pending) == 0 {
+		return database.MediaPart{}, false
+	}
+
+	mediaPart := q.pending[0]
+	q.pending = q.pending[1:]
+
+	delete(q.queued, mediaPart.ID)
+
+	return mediaPart, true
+}
+
+// Starts fingerprinting the queued videos one at a time, since sampling frames is slow, after queuing
+// the videos which weren't fingerprinted yet. Stops when the context is canceled.
+func StartVideoFingerprinting(ctx context.Context) {
+	go func() {
+		mediaParts, err := database.GetMediaPartsWithoutFingerprint(fingerprintedItemTypes)
+		if err != nil {
+			log.Err(err).Msg("Failed to get the videos to fingerprint")
+		} else if len(mediaParts) > 0 {
+			log.Info().Msgf("Queuing %d videos for fingerprinting", len(mediaParts))
+
+			queue.push(mediaParts...)
+		}
+
+		for {
+			mediaPart, ok := queue.pop()
+			if !ok {
+				select {
+				case <-ctx.Done():
+					return
+				case <-queue.wake:
+					continue
+				}
+			}
+
+			if err := FindVideoDuplicates(mediaPart); err != nil {
+				log.Warn().Err(err).Msgf("Failed to find duplicates of %s", mediaPart.FilePath)
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+}
+
+// Queues a video for fingerprinting, to find its copies once its metadata is saved.
+func QueueVideoFingerprint(mediaPart database.MediaPart) {
+	queue.push(mediaPart)
+}
+
+// Fingerprints a video, and queues its copies from any library for review.
+func FindVideoDuplicates(mediaPart database.MediaPart) error {
+	fingerprint, err := FingerprintVideo(mediaPart)
+	if err != nil {
+		return fmt.Errorf("could not fingerprint video: %w", err)
+	}
+
+	err = duplicates.FindVideoDuplicates(mediaPart.ItemMetadataID, *fingerprint)
+	if err != nil {
+		return fmt.Errorf("could not find duplicates: %w", err)
+	}
+
+	return nil
+}
+
+// Samples frames of a video at fixed relative positions and hashes them, along with its duration,
+// to find copies of the video in other formats or resolutions.
+func FingerprintVideo(mediaPart database.MediaPart) (*duplicates.VideoFingerprint, error) {
+	mediaInfo, err := ProbeMediaInfo(mediaPart.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if mediaInfo.Duration <= 0 {
+		return nil, errUnknownDuration
+	}
+
+	fingerprint := duplicates.VideoFingerprint{Duration: mediaInfo.Duration}
+
+	for _, position := range fingerprintPositions {
+		offset := time.Duration(float64(mediaInfo.Duration)*position) * time.Millisecond
+
+		frame, err := extractFrame(mediaPart.FilePath, offset)
+		if err != nil {
+			// Keep the position, so that the other frames still line up with other videos
+			log.Debug().Err(err).Msgf("Could not extract frame at %s from %s", offset, mediaPart.FilePath)
+
+			fingerprint.FrameHashes = append(fingerprint.FrameHashes, 0)
+
+			continue
+		}
+
+		fingerprint.FrameHashes = append(fingerprint.FrameHashes, duplicates.DifferenceHash(frame))
+	}
+
+	err = database.SetVideoFingerprint(&database.VideoFingerprint{
+		MediaPartID: mediaPart.ID,
+		Duration:    fingerprint.Duration,
+		FrameHashes: fingerprint.FormatFrameHashes(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &fingerprint, nil
+}
+
+// Extracts the frame at the given offset, scaled down to the size hashed by duplicates.DifferenceHash.
+func extractFrame(filePath string, offset time.Duration) (image.Image, error) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), ffmpegProcessTimeout)
+	defer cancelFn()
+
+	var stdout bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", //#nosec
+		"-loglevel", "fatal",
+		"-ss", strconv.FormatFloat(offset.Seconds(), 'f', 3, 64), //nolint:gomnd
+		"-i", filePath,
+		"-frames:v", "1",
+		"-vf", fmt.Sprintf("scale=%d:%d", duplicates.HashWidth, duplicates.HashHeight),
+		"-pix_fmt", "gray",
+		"-f", "rawvideo",
+		"-",
+	)
+	cmd.Stdout = &stdout
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("could not extract frame: %w", err)
+	}
+
+	if stdout.Len() != duplicates.HashWidth*duplicates.HashHeight {
+		return nil, fmt.Errorf("%w: got %d bytes", errInvalidFrame, stdout.Len())
+	}
+
+	return &image.Gray{
+		Pix:    stdout.Bytes(),
+		Stride: duplicates.HashWidth,
+		Rect:   image.Rect(0, 0, duplicates.HashWidth, duplicates.HashHeight),
+	}, nil
+}
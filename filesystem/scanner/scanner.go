@@ -10,9 +10,25 @@ import (
 	"github.com/meteorae/meteorae-server/helpers"
 	"github.com/meteorae/meteorae-server/resolvers/registry"
 	"github.com/meteorae/meteorae-server/utils"
+	"github.com/panjf2000/ants/v2"
 	"github.com/rs/zerolog/log"
 )
 
+// Schedules a scan of all the locations of a library, which must have its locations loaded.
+// Files already in the library are rescanned rather than resolved again.
+func ScanLibrary(library *database.Library) {
+	for _, location := range library.LibraryLocations {
+		rootPath := location.RootPath
+
+		err := ants.Submit(func() {
+			ScanDirectory(rootPath, *library)
+		})
+		if err != nil {
+			log.Err(err).Msgf("Failed to schedule directory scan for %s", rootPath)
+		}
+	}
+}
+
 func ScanDirectory(directory string, library database.Library) {
 	err := filepath.WalkDir(directory, func(path string, dirEntry fs.DirEntry, walkErr error) error {
 		// TODO: We should probably handle different types differently
@@ -26,9 +42,19 @@ func ScanDirectory(directory string, library database.Library) {
 			return nil
 		}
 
+		if item, err := database.GetItemByMediaPartPath(path); err == nil {
+			log.Debug().Msgf("Scheduling rescan job for %s", path)
+
+			if err := registry.RescanFile(item, library, dirEntry.IsDir()); err != nil {
+				log.Err(err).Msgf("Failed to schedule rescan job for %s", path)
+			}
+
+			return nil
+		}
+
 		mediaPart := database.MediaPart{}
 		if !dirEntry.IsDir() {
-			// Hash the file using SHA-1
+			// Hash the file path
 			hash, err := utils.HashFilePath(path)
 			if err != nil {
 				return fmt.Errorf("failed to hash file: %w", err)
@@ -44,6 +70,16 @@ func ScanDirectory(directory string, library database.Library) {
 				Hash:     hex.EncodeToString(hash),
 				Size:     fileInfo.Size(),
 			}
+
+			// Videos are also hashed the way subtitle providers identify them
+			if utils.IsStringInSlice(filepath.Ext(path), helpers.VideoFileExtensions) {
+				openSubtitlesHash, err := utils.OpenSubtitlesHash(path)
+				if err != nil {
+					log.Debug().Err(err).Msgf("Could not compute the OpenSubtitles hash of %s", path)
+				}
+
+				mediaPart.OpenSubtitleHash = openSubtitlesHash
+			}
 		} else {
 			mediaPart = database.MediaPart{
 				FilePath: path,
@@ -0,0 +1,69 @@
+#!/bin/sh
+# Regenerates cities.tsv.gz, the bundled geocoding dataset, from github.com/tidwall/cities.
+# See NOTICE for the license of the data. Run from any directory, with Go installed.
+set -eu
+
+version="${CITIES_VERSION:-v0.1.0}"
+output="$(cd "$(dirname "$0")" && pwd)/cities.tsv.gz"
+workdir="$(mktemp -d)"
+trap 'rm -rf "$workdir"' EXIT
+
+cat > "$workdir/go.mod" <<MOD
+module generate
+
+go 1.18
+
+require github.com/tidwall/cities $version
+MOD
+
+# One city per line, as name, country, latitude and longitude, without duplicate names in a country
+cat > "$workdir/main.go" <<'MAIN'
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/tidwall/cities"
+)
+
+func main() {
+	file, err := os.Create(os.Args[1])
+	if err != nil {
+		panic(err)
+	}
+
+	writer, err := gzip.NewWriterLevel(file, gzip.BestCompression)
+	if err != nil {
+		panic(err)
+	}
+
+	seen := make(map[string]bool)
+
+	for _, city := range cities.Cities {
+		key := city.Country + "\t" + city.City
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", city.City, city.Country,
+			strconv.FormatFloat(city.Latitude, 'f', 4, 64), strconv.FormatFloat(city.Longitude, 'f', 4, 64))
+	}
+
+	if err := writer.Close(); err != nil {
+		panic(err)
+	}
+
+	if err := file.Close(); err != nil {
+		panic(err)
+	}
+}
+MAIN
+
+cd "$workdir"
+go mod tidy
+go run . "$output"
@@ -0,0 +1,193 @@
+// Package geocoding finds the places photos were taken at from their coordinates, using an offline
+// dataset of cities, so no location ever leaves the server.
+//
+// The bundled dataset holds about 10,000 cities from github.com/tidwall/cities, with their country but no
+// region. See data/NOTICE for its source and license, and data/generate.sh to regenerate it.
+// A GeoNames dump, like cities1000.txt from https://download.geonames.org/export/dump/, can be used
+// instead by setting geocoding.geonames_path, for regions and smaller cities. Region and country names
+// are then read from admin1CodesASCII.txt and countryInfo.txt, next to it, when present.
+package geocoding
+
+import (
+	"bytes"
+	"compress/gzip"
+	_ "embed" // Needed for the bundled dataset
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// Cities further away than this from a photo aren't considered to be where it was taken.
+const maxDistance = 150
+
+const earthRadius = 6371
+
+//go:generate sh data/generate.sh
+
+//go:embed data/cities.tsv.gz
+var bundledCities []byte
+
+var (
+	index     *cityIndex
+	indexOnce sync.Once
+)
+
+// A populated place, reverse geocoded from coordinates.
+type Place struct {
+	Country string
+	// First-level administrative division, like a state or a region. Empty with the bundled dataset.
+	Region    string
+	City      string
+	Latitude  float64
+	Longitude float64
+}
+
+// Returns the place closest to the given coordinates, or nil when there is none near enough.
+// The dataset is loaded on first use.
+func ReverseGeocode(latitude, longitude float64) *Place {
+	indexOnce.Do(func() {
+		cities, err := loadCities()
+		if err != nil {
+			log.Err(err).Msg("Failed to load the geocoding dataset, places won't be available")
+		}
+
+		index = newCityIndex(cities)
+	})
+
+	return index.nearest(latitude, longitude)
+}
+
+func loadCities() ([]Place, error) {
+	if path := viper.GetString("geocoding.geonames_path"); path != "" {
+		return readGeoNames(path)
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(bundledCities))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundled cities: %w", err)
+	}
+
+	return readBundledCities(reader)
+}
+
+// Reads the bundled dataset, with one city per line, as name, country, latitude and longitude.
+func readBundledCities(reader io.Reader) ([]Place, error) {
+	var cities []Place
+
+	err := readTSV(reader, func(fields []string) {
+		if len(fields) != 4 { //nolint:gomnd
+			return
+		}
+
+		latitude, latitudeErr := strconv.ParseFloat(fields[2], 64)
+		longitude, longitudeErr := strconv.ParseFloat(fields[3], 64)
+
+		if latitudeErr != nil || longitudeErr != nil {
+			return
+		}
+
+		cities = append(cities, Place{
+			City:      fields[0],
+			Country:   fields[1],
+			Latitude:  latitude,
+			Longitude: longitude,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundled cities: %w", err)
+	}
+
+	return cities, nil
+}
+
+// Spatial index of cities, in cells of one degree, so lookups only compare nearby cities.
+type cityIndex struct {
+	cells map[int][]Place
+}
+
+func newCityIndex(cities []Place) *cityIndex {
+	index := cityIndex{cells: make(map[int][]Place)}
+
+	for _, city := range cities {
+		key := getCellKey(getCell(city.Latitude, city.Longitude))
+		index.cells[key] = append(index.cells[key], city)
+	}
+
+	return &index
+}
+
+func getCell(latitude, longitude float64) (int, int) {
+	return int(math.Floor(latitude)), int(math.Floor(longitude))
+}
+
+func getCellKey(latitudeCell, longitudeCell int) int {
+	// Wrap longitudes around the antimeridian
+	longitudeCell = ((longitudeCell+180)%360+360)%360 - 180
+
+	return latitudeCell*360 + longitudeCell //nolint:gomnd
+}
+
+func (i *cityIndex) nearest(latitude, longitude float64) *Place {
+	if math.IsNaN(latitude) || math.IsNaN(longitude) || math.Abs(latitude) > 90 || math.Abs(longitude) > 180 {
+		return nil
+	}
+
+	latitudeCell, longitudeCell := getCell(latitude, longitude)
+
+	// A degree of latitude is about 111 km, while degrees of longitude shrink towards the poles
+	const kilometersPerDegree = 111
+
+	latitudeRange := int(math.Ceil(maxDistance / kilometersPerDegree))
+	longitudeRange := 180
+
+	if cosine := math.Cos(math.Abs(latitude) * math.Pi / 180); cosine > 0 {
+		longitudeRange = int(math.Min(180, math.Ceil(maxDistance/(kilometersPerDegree*cosine))))
+	}
+
+	var (
+		nearest         *Place
+		nearestDistance = math.Inf(1)
+	)
+
+	for latitudeOffset := -latitudeRange; latitudeOffset <= latitudeRange; latitudeOffset++ {
+		for longitudeOffset := -longitudeRange; longitudeOffset <= longitudeRange; longitudeOffset++ {
+			cities := i.cells[getCellKey(latitudeCell+latitudeOffset, longitudeCell+longitudeOffset)]
+
+			for index := range cities {
+				distance := getDistance(latitude, longitude, cities[index].Latitude, cities[index].Longitude)
+				if distance < nearestDistance {
+					nearest = &cities[index]
+					nearestDistance = distance
+				}
+			}
+		}
+	}
+
+	if nearest == nil || nearestDistance > maxDistance {
+		return nil
+	}
+
+	place := *nearest
+
+	return &place
+}
+
+// Returns the great-circle distance between two points, in kilometers.
+func getDistance(latitude1, longitude1, latitude2, longitude2 float64) float64 {
+	toRadians := func(degrees float64) float64 {
+		return degrees * math.Pi / 180 //nolint:gomnd
+	}
+
+	latitudeDelta := toRadians(latitude2 - latitude1)
+	longitudeDelta := toRadians(longitude2 - longitude1)
+
+	a := math.Pow(math.Sin(latitudeDelta/2), 2) +
+		math.Cos(toRadians(latitude1))*math.Cos(toRadians(latitude2))*math.Pow(math.Sin(longitudeDelta/2), 2)
+
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
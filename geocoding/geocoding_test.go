@@ -0,0 +1,48 @@
+package geocoding_test
+
+import (
+	"testing"
+
+	"github.com/meteorae/meteorae-server/geocoding"
+)
+
+func TestReverseGeocode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		latitude, longitude float64
+		country, city       string
+	}{
+		{48.8530, 2.3499, "France", "Paris"},
+		{64.1466, -21.9426, "Iceland", "Reykjavik"},
+		{-18.1416, 178.4419, "Fiji", "Suva"},
+	}
+
+	for _, test := range tests {
+		place := geocoding.ReverseGeocode(test.latitude, test.longitude)
+		if place == nil || place.Country != test.country || place.City != test.city {
+			t.Errorf("expected %s, %s for %f, %f, got %+v", test.city, test.country, test.latitude, test.longitude, place)
+		}
+	}
+}
+
+func TestReverseGeocodeHasNoRegionByDefault(t *testing.T) {
+	t.Parallel()
+
+	// The bundled dataset has no regions, which are only read from GeoNames dumps
+	place := geocoding.ReverseGeocode(48.8530, 2.3499)
+	if place == nil || place.Region != "" {
+		t.Errorf("expected a place without region, got %+v", place)
+	}
+}
+
+func TestReverseGeocodeFarFromCities(t *testing.T) {
+	t.Parallel()
+
+	// The middle of the Pacific Ocean, and invalid coordinates
+	for _, coordinates := range [][2]float64{{-30, -140}, {91, 0}} {
+		if place := geocoding.ReverseGeocode(coordinates[0], coordinates[1]); place != nil {
+			t.Errorf("expected no place for %v, got %+v", coordinates, place)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package geocoding
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Columns of the GeoNames cities dump.
+const (
+	geoNamesName        = 1
+	geoNamesLatitude    = 4
+	geoNamesLongitude   = 5
+	geoNamesCountryCode = 8
+	geoNamesAdmin1Code  = 10
+	geoNamesColumns     = 19
+)
+
+// Reads a GeoNames cities dump. Region and country names are looked up in the GeoNames files next to it,
+// falling back to the region and country codes when they are missing.
+func readGeoNames(path string) ([]Place, error) {
+	directory := filepath.Dir(path)
+
+	regions, err := readGeoNamesNames(filepath.Join(directory, "admin1CodesASCII.txt"), 0, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	countries, err := readGeoNamesNames(filepath.Join(directory, "countryInfo.txt"), 0, 4) //nolint:gomnd
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoNames cities: %w", err)
+	}
+	defer file.Close()
+
+	var cities []Place
+
+	err = readTSV(file, func(fields []string) {
+		if len(fields) < geoNamesColumns {
+			return
+		}
+
+		latitude, latitudeErr := strconv.ParseFloat(fields[geoNamesLatitude], 64)
+		longitude, longitudeErr := strconv.ParseFloat(fields[geoNamesLongitude], 64)
+
+		if latitudeErr != nil || longitudeErr != nil {
+			return
+		}
+
+		countryCode := fields[geoNamesCountryCode]
+		regionCode := countryCode + "." + fields[geoNamesAdmin1Code]
+
+		cities = append(cities, Place{
+			City:      fields[geoNamesName],
+			Region:    getName(regions, regionCode, fields[geoNamesAdmin1Code]),
+			Country:   getName(countries, countryCode, countryCode),
+			Latitude:  latitude,
+			Longitude: longitude,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GeoNames cities: %w", err)
+	}
+
+	return cities, nil
+}
+
+// Reads the names of a GeoNames code file, by code. A missing file isn't an error.
+func readGeoNamesNames(path string, codeColumn, nameColumn int) (map[string]string, error) {
+	names := make(map[string]string)
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return names, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", filepath.Base(path), err)
+	}
+	defer file.Close()
+
+	err = readTSV(file, func(fields []string) {
+		if len(fields) > nameColumn && !strings.HasPrefix(fields[codeColumn], "#") {
+			names[fields[codeColumn]] = fields[nameColumn]
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+
+	return names, nil
+}
+
+func readTSV(reader io.Reader, handleLine func(fields []string)) error {
+	scanner := bufio.NewScanner(reader)
+	// Alternate names make some lines of the cities dump very long
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), 1<<20) //nolint:gomnd
+
+	for scanner.Scan() {
+		handleLine(strings.Split(scanner.Text(), "\t"))
+	}
+
+	return scanner.Err() //nolint:wrapcheck
+}
+
+func getName(names map[string]string, code, fallback string) string {
+	if name, ok := names[code]; ok {
+		return name
+	}
+
+	return fallback
+}
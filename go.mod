@@ -7,7 +7,7 @@ require (
 	github.com/gorilla/mux v1.8.0
 	github.com/middelink/go-parse-torrent-name v0.0.0-20190301154245-3ff4efacd4c4
 	github.com/panjf2000/ants/v2 v2.5.0
-	github.com/ryanbradynd05/go-tmdb v0.0.0-20201006144520-c0566c3d1506
+	github.com/rwcarlsen/goexif v0.0.0-20190401172101-9e8deecbddbd
 	github.com/spf13/viper v1.12.0
 	github.com/vektah/gqlparser/v2 v2.4.4
 	gopkg.in/vansante/go-ffprobe.v2 v2.0.3
@@ -51,7 +51,6 @@ require (
 	github.com/golang/protobuf v1.5.2 // indirect
 	github.com/gorilla/websocket v1.4.2 // indirect
 	github.com/hashicorp/golang-lru v0.5.4 // indirect
-	github.com/kylelemons/go-gypsy v1.0.0 // indirect
 	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
 	github.com/mitchellh/mapstructure v1.5.0 // indirect
 	github.com/prometheus/client_golang v1.12.2
@@ -88,6 +87,6 @@ require (
 	github.com/mattn/go-sqlite3 v1.14.13 // indirect
 	github.com/mholt/archiver/v3 v3.5.1
 	github.com/rs/zerolog v1.27.0
-	golang.org/x/crypto v0.0.0-20220411220226-7b82a4e95df4 // indirect
+	golang.org/x/crypto v0.0.0-20220411220226-7b82a4e95df4
 	golang.org/x/text v0.3.7
 )
@@ -0,0 +1,44 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/graph/model"
+	"github.com/meteorae/meteorae-server/helpers"
+	"github.com/rs/zerolog/log"
+)
+
+// Returns the artists linked to the given item.
+func getItemArtists(itemID string) ([]model.Item, error) {
+	artists, err := database.GetArtistsFromItem(itemID)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get artists for item %s", itemID)
+
+		return nil, fmt.Errorf("failed to get artists: %w", err)
+	}
+
+	return helpers.GetItemsFromItemMetadata(artists), nil
+}
+
+// Returns the items of the given type linked to an artist.
+func getArtistItems(artistID string, itemType database.ItemType, limit, offset *int64) (*model.ItemsResult, error) {
+	items, err := database.GetItemsFromArtist(artistID, itemType, limit, offset)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get items for artist %s", artistID)
+
+		return nil, fmt.Errorf("failed to get items: %w", err)
+	}
+
+	count, err := database.GetItemsCountFromArtist(artistID, itemType)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get items count for artist %s", artistID)
+
+		return nil, fmt.Errorf("failed to get items count: %w", err)
+	}
+
+	return &model.ItemsResult{
+		Items: helpers.GetItemsFromItemMetadata(items),
+		Total: count,
+	}, nil
+}
@@ -0,0 +1,20 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/rs/zerolog/log"
+)
+
+// Returns the chapters of the given item.
+func getItemChapters(itemID string) ([]*database.Chapter, error) {
+	chapters, err := database.GetChaptersFromItem(itemID)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get chapters for item %s", itemID)
+
+		return nil, fmt.Errorf("failed to get chapters: %w", err)
+	}
+
+	return chapters, nil
+}
@@ -0,0 +1,275 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/graph/model"
+	"github.com/meteorae/meteorae-server/helpers"
+	"github.com/rs/zerolog/log"
+)
+
+// Returns the items of a collection matching the rating filter, in the given order, or in the sort order
+// of the collection when none is given.
+func getCollectionItems(
+	ctx context.Context,
+	collectionID string,
+	sortOrder *string,
+	minUserRating *int64,
+	limit, offset *int64,
+) (*model.ItemsResult, error) {
+	collection, err := getCollection(collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	childSortOrder, err := parseChildSortOrder(sortOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	ratingFilter := getRatingFilter(ctx, minUserRating)
+
+	items, err := database.GetCollectionItems(
+		collectionID, database.GetCollectionSortOrder(collection), childSortOrder, ratingFilter, limit, offset)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get items for collection %s", collectionID)
+
+		return nil, fmt.Errorf("failed to get items: %w", err)
+	}
+
+	count, err := database.GetCollectionItemsCount(collectionID, ratingFilter)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get items count for collection %s", collectionID)
+
+		return nil, fmt.Errorf("failed to get items count: %w", err)
+	}
+
+	return &model.ItemsResult{
+		Items: helpers.GetItemsFromItemMetadata(items),
+		Total: count,
+	}, nil
+}
+
+// Returns all the collections, optionally only the user or provider ones.
+func getCollections(limit, offset *int64, userDefined *bool) (*model.ItemsResult, error) {
+	collections, err := database.GetCollections(userDefined, limit, offset)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get collections")
+
+		return nil, fmt.Errorf("failed to get collections: %w", err)
+	}
+
+	count, err := database.GetCollectionsCount(userDefined)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get collections count")
+
+		return nil, fmt.Errorf("failed to get collections count: %w", err)
+	}
+
+	return &model.ItemsResult{
+		Items: helpers.GetItemsFromItemMetadata(collections),
+		Total: count,
+	}, nil
+}
+
+func createCollection(title string, summary, sortOrder *string) (model.Item, error) {
+	collection := database.ItemMetadata{
+		Title:     title,
+		SortTitle: title,
+	}
+
+	if summary != nil {
+		collection.Summary = *summary
+	}
+
+	if err := setSortOrder(&collection, sortOrder); err != nil {
+		return nil, err
+	}
+
+	if err := database.CreateCollection(&collection); err != nil {
+		log.Error().Err(err).Msgf("Failed to create collection \"%s\"", title)
+
+		return nil, fmt.Errorf("failed to create collection: %w", err)
+	}
+
+	return *helpers.GetItemFromItemMetadata(&collection), nil
+}
+
+// Updates the given fields of a user collection, leaving the others untouched.
+func updateCollection(collectionID string, title, summary, sortOrder, thumb, art *string) (model.Item, error) {
+	collection, err := getUserCollection(collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if title != nil {
+		collection.Title = *title
+		collection.SortTitle = *title
+	}
+
+	if summary != nil {
+		collection.Summary = *summary
+	}
+
+	if err := setSortOrder(collection, sortOrder); err != nil {
+		return nil, err
+	}
+
+	if thumb != nil {
+		if collection.Thumb, err = saveArtwork(*thumb); err != nil {
+			return nil, err
+		}
+	}
+
+	if art != nil {
+		if collection.Art, err = saveArtwork(*art); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := database.UpdateItem(collection); err != nil {
+		log.Error().Err(err).Msgf("Failed to update collection %s", collectionID)
+
+		return nil, fmt.Errorf("failed to update collection: %w", err)
+	}
+
+	return *helpers.GetItemFromItemMetadata(collection), nil
+}
+
+func deleteCollection(collectionID string) (bool, error) {
+	collection, err := getUserCollection(collectionID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := database.DeleteCollection(collection.ID); err != nil {
+		log.Error().Err(err).Msgf("Failed to delete collection %s", collectionID)
+
+		return false, fmt.Errorf("failed to delete collection: %w", err)
+	}
+
+	return true, nil
+}
+
+func addToCollection(collectionID string, itemIDs []string) (model.Item, error) {
+	collection, ids, err := getUserCollectionAndItems(collectionID, itemIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, itemID := range itemIDs {
+		if _, err := database.GetItemByID(itemID); err != nil {
+			log.Error().Err(err).Msgf("Failed to get item %s", itemID)
+
+			return nil, fmt.Errorf("failed to get item: %w", err)
+		}
+	}
+
+	if err := database.AddItemsToCollection(collection.ID, ids); err != nil {
+		log.Error().Err(err).Msgf("Failed to add items to collection %s", collectionID)
+
+		return nil, fmt.Errorf("failed to add items to collection: %w", err)
+	}
+
+	return *helpers.GetItemFromItemMetadata(collection), nil
+}
+
+func removeFromCollection(collectionID string, itemIDs []string) (model.Item, error) {
+	collection, ids, err := getUserCollectionAndItems(collectionID, itemIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := database.RemoveItemsFromCollection(collection.ID, ids); err != nil {
+		log.Error().Err(err).Msgf("Failed to remove items from collection %s", collectionID)
+
+		return nil, fmt.Errorf("failed to remove items from collection: %w", err)
+	}
+
+	return *helpers.GetItemFromItemMetadata(collection), nil
+}
+
+func moveCollectionItem(collectionID, itemID string, index int64) (model.Item, error) {
+	collection, ids, err := getUserCollectionAndItems(collectionID, []string{itemID})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := database.MoveCollectionItem(collection.ID, ids[0], int(index)); err != nil {
+		log.Error().Err(err).Msgf("Failed to move item %s in collection %s", itemID, collectionID)
+
+		return nil, fmt.Errorf("failed to move item: %w", err)
+	}
+
+	return *helpers.GetItemFromItemMetadata(collection), nil
+}
+
+func getCollection(collectionID string) (*database.ItemMetadata, error) {
+	collection, err := database.GetItemByID(collectionID)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get collection %s", collectionID)
+
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+
+	if collection.Type != database.CollectionItem {
+		return nil, fmt.Errorf("%w: %s", errNotACollection, collectionID)
+	}
+
+	return collection, nil
+}
+
+// Returns the given collection, if it was created by a user.
+func getUserCollection(collectionID string) (*database.ItemMetadata, error) {
+	collection, err := getCollection(collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !database.IsUserCollection(collection) {
+		return nil, fmt.Errorf("%w: %s", errProviderCollection, collectionID)
+	}
+
+	return collection, nil
+}
+
+func getUserCollectionAndItems(collectionID string, itemIDs []string) (*database.ItemMetadata, []uint64, error) {
+	collection, err := getUserCollection(collectionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ids := make([]uint64, 0, len(itemIDs))
+
+	for _, itemID := range itemIDs {
+		id, err := strconv.ParseUint(itemID, 10, 64) //nolint:gomnd
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid item identifier %s: %w", itemID, err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return collection, ids, nil
+}
+
+func setSortOrder(collection *database.ItemMetadata, sortOrder *string) error {
+	if sortOrder == nil {
+		return nil
+	}
+
+	var parsedSortOrder database.CollectionSortOrder
+
+	if err := parsedSortOrder.UnmarshalText([]byte(*sortOrder)); err != nil {
+		return fmt.Errorf("failed to parse sort order: %w", err)
+	}
+
+	if err := database.SetCollectionSortOrder(collection, parsedSortOrder); err != nil {
+		return fmt.Errorf("failed to set sort order: %w", err)
+	}
+
+	return nil
+}
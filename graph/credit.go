@@ -0,0 +1,83 @@
+package graph
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/graph/model"
+	"github.com/meteorae/meteorae-server/helpers"
+	"github.com/rs/zerolog/log"
+)
+
+// Maps the GraphQL item types to the item types they represent, to filter credits.
+var mediaTypes = map[string][]database.ItemType{
+	"Movie":          {database.MovieItem, database.AnimeMovieItem},
+	"ImageAlbum":     {database.ImageAlbumItem},
+	"Image":          {database.ImageItem},
+	"MusicVideo":     {database.MusicVideoItem},
+	"MusicAlbum":     {database.MusicAlbumItem},
+	"Book":           {database.AudiobookItem},
+	"BookPart":       {database.AudiobookPartItem},
+	"Podcast":        {database.PodcastItem},
+	"PodcastEpisode": {database.PodcastEpisodeItem},
+}
+
+// Returns the cast and crew of the given item.
+func getItemCredits(itemID string) ([]*database.Credit, error) {
+	credits, err := database.GetCreditsFromItem(itemID)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get credits for item %s", itemID)
+
+		return nil, fmt.Errorf("failed to get credits: %w", err)
+	}
+
+	return credits, nil
+}
+
+// Returns the credits of the given person, optionally filtered by role and item type.
+func getPersonCredits(personID string, role, mediaType *string) ([]*database.Credit, error) {
+	var (
+		roleFilter string
+		itemTypes  []database.ItemType
+	)
+
+	if role != nil {
+		roleFilter = *role
+	}
+
+	if mediaType != nil {
+		var ok bool
+
+		itemTypes, ok = mediaTypes[*mediaType]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", errInvalidMediaType, *mediaType)
+		}
+	}
+
+	credits, err := database.GetCreditsFromPerson(personID, roleFilter, itemTypes)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get credits for person %s", personID)
+
+		return nil, fmt.Errorf("failed to get credits: %w", err)
+	}
+
+	return credits, nil
+}
+
+// Returns the given item, like the person or the item of a credit, as its GraphQL type.
+func getItemByID(itemID uint64) (model.Item, error) {
+	item, err := database.GetItemByID(strconv.FormatUint(itemID, 10)) //nolint:gomnd
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get item %d", itemID)
+
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+
+	result := helpers.GetItemFromItemMetadata(item)
+	if result == nil {
+		return nil, fmt.Errorf("%w: %d", errUnsupportedItemType, item.Type)
+	}
+
+	return *result, nil
+}
@@ -0,0 +1,176 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/duplicates"
+	"github.com/meteorae/meteorae-server/graph/model"
+	"github.com/rs/zerolog/log"
+)
+
+func getDuplicateCandidates(libraryID *string, limit, offset *int64) (*model.DuplicateCandidatesResult, error) {
+	candidates, err := database.GetPendingDuplicateCandidates(libraryID, limit, offset)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get duplicate candidates")
+
+		return nil, fmt.Errorf("failed to get duplicate candidates: %w", err)
+	}
+
+	count, err := database.GetPendingDuplicateCandidatesCount(libraryID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get duplicate candidates count")
+
+		return nil, fmt.Errorf("failed to get duplicate candidates count: %w", err)
+	}
+
+	return &model.DuplicateCandidatesResult{
+		Candidates: candidates,
+		Total:      count,
+	}, nil
+}
+
+func getDuplicateGroups(libraryID *string, limit, offset *int64) (*model.DuplicateGroupsResult, error) {
+	candidates, err := database.GetAllPendingDuplicateCandidates(libraryID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get duplicate candidates")
+
+		return nil, fmt.Errorf("failed to get duplicate candidates: %w", err)
+	}
+
+	groups := duplicates.GroupCandidates(candidates)
+	total := int64(len(groups))
+
+	start, end := *offset, *offset+*limit
+	if start > total {
+		start = total
+	}
+
+	if end > total {
+		end = total
+	}
+
+	result := make([]*model.DuplicateGroup, 0, end-start)
+
+	for _, candidates := range groups[start:end] {
+		copies, err := getDuplicateCopies(candidates)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &model.DuplicateGroup{
+			Copies:     copies,
+			Candidates: candidates,
+		})
+	}
+
+	return &model.DuplicateGroupsResult{
+		Groups: result,
+		Total:  &total,
+	}, nil
+}
+
+// Returns the items of a group, best first, flagging the one with the best resolution, then bitrate.
+func getDuplicateCopies(candidates []*database.DuplicateCandidate) ([]*model.DuplicateCopy, error) {
+	var itemIDs []uint64
+
+	seen := make(map[uint64]bool)
+
+	for _, candidate := range candidates {
+		for _, itemID := range []uint64{candidate.ItemMetadataID, candidate.DuplicateID} {
+			if !seen[itemID] {
+				seen[itemID] = true
+				itemIDs = append(itemIDs, itemID)
+			}
+		}
+	}
+
+	qualities, err := database.GetVideoQualities(itemIDs)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get video qualities")
+
+		return nil, fmt.Errorf("failed to get video qualities: %w", err)
+	}
+
+	// Items without a video stream keep their order, after the videos
+	sort.SliceStable(itemIDs, func(i, j int) bool {
+		first, firstOk := qualities[itemIDs[i]]
+		second, secondOk := qualities[itemIDs[j]]
+
+		if firstOk != secondOk {
+			return firstOk
+		}
+
+		if firstPixels, secondPixels := first.Width*first.Height, second.Width*second.Height; firstPixels != secondPixels {
+			return firstPixels > secondPixels
+		}
+
+		return first.Bitrate > second.Bitrate
+	})
+
+	copies := make([]*model.DuplicateCopy, 0, len(itemIDs))
+
+	for index, itemID := range itemIDs {
+		item, err := getItemByID(itemID)
+		if err != nil {
+			return nil, err
+		}
+
+		duplicateCopy := &model.DuplicateCopy{Item: item}
+
+		if quality, ok := qualities[itemID]; ok {
+			duplicateCopy.Width = &quality.Width
+			duplicateCopy.Height = &quality.Height
+			duplicateCopy.Best = index == 0
+
+			if quality.Bitrate > 0 {
+				duplicateCopy.Bitrate = &quality.Bitrate
+			}
+		}
+
+		copies = append(copies, duplicateCopy)
+	}
+
+	return copies, nil
+}
+
+// Reviews a duplicate candidate with the given database function.
+func reviewDuplicate(
+	id string,
+	review func(candidate *database.DuplicateCandidate) error,
+) (*database.DuplicateCandidate, error) {
+	candidate, err := database.GetDuplicateCandidate(id)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get duplicate candidate %s", id)
+
+		return nil, fmt.Errorf("failed to get duplicate candidate: %w", err)
+	}
+
+	if err := review(candidate); err != nil {
+		log.Error().Err(err).Msgf("Failed to review duplicate candidate %s", id)
+
+		return nil, fmt.Errorf("failed to review duplicate candidate: %w", err)
+	}
+
+	return candidate, nil
+}
+
+// Merges the items of a duplicate candidate, keeping the given item, or the item of the candidate when there is none.
+func mergeDuplicate(id string, keep *string) (*database.DuplicateCandidate, error) {
+	return reviewDuplicate(id, func(candidate *database.DuplicateCandidate) error {
+		keptID := candidate.ItemMetadataID
+
+		if keep != nil {
+			parsedID, err := strconv.ParseUint(*keep, 10, 64) //nolint:gomnd
+			if err != nil {
+				return fmt.Errorf("invalid item identifier %s: %w", *keep, err)
+			}
+
+			keptID = parsedID
+		}
+
+		return database.MergeDuplicateCandidate(candidate, keptID)
+	})
+}
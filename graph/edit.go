@@ -0,0 +1,135 @@
+package graph
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/graph/model"
+	"github.com/meteorae/meteorae-server/helpers"
+	"github.com/meteorae/meteorae-server/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// Applies manual edits to an item, and locks the edited fields.
+// Editing the title locks the sort title too, and resets it unless it's edited as well.
+func editItem(itemID string, input model.EditItemInput) (model.Item, error) {
+	// Validate the input first, so nothing is saved to the image cache for invalid edits
+	for _, field := range input.LockedFields {
+		if !database.IsLockableField(field) {
+			return nil, fmt.Errorf("%w: %s", errInvalidLockedField, field)
+		}
+	}
+
+	var releaseDate time.Time
+
+	if input.ReleaseDate != nil {
+		var err error
+		if releaseDate, err = parseEditedDate(*input.ReleaseDate); err != nil {
+			return nil, err
+		}
+	}
+
+	item, err := database.GetItemByID(itemID)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get item %s", itemID)
+
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+
+	lockedFields := item.GetLockedFields()
+
+	if input.Title != nil && input.SortTitle == nil {
+		sortTitle := utils.CleanSortTitle(*input.Title)
+		input.SortTitle = &sortTitle
+	}
+
+	for _, edit := range []struct {
+		field  string
+		value  *string
+		target *string
+	}{
+		{database.TitleField, input.Title, &item.Title},
+		{database.SortTitleField, input.SortTitle, &item.SortTitle},
+		{database.OriginalTitleField, input.OriginalTitle, &item.OriginalTitle},
+		{database.SummaryField, input.Summary, &item.Summary},
+		{database.TaglineField, input.Tagline, &item.Tagline},
+	} {
+		if edit.value == nil {
+			continue
+		}
+
+		*edit.target = *edit.value
+		lockedFields = append(lockedFields, edit.field)
+	}
+
+	if input.ReleaseDate != nil {
+		item.ReleaseDate = releaseDate
+		lockedFields = append(lockedFields, database.ReleaseDateField)
+	}
+
+	if input.Thumb != nil {
+		if item.Thumb, err = saveArtwork(*input.Thumb); err != nil {
+			return nil, err
+		}
+
+		lockedFields = append(lockedFields, database.ThumbField)
+	}
+
+	if input.Art != nil {
+		if item.Art, err = saveArtwork(*input.Art); err != nil {
+			return nil, err
+		}
+
+		lockedFields = append(lockedFields, database.ArtField)
+	}
+
+	if input.LockedFields != nil {
+		lockedFields = input.LockedFields
+	}
+
+	item.SetLockedFields(lockedFields)
+
+	if err := database.EditItem(item); err != nil {
+		log.Error().Err(err).Msgf("Failed to edit item %s", itemID)
+
+		return nil, fmt.Errorf("failed to edit item: %w", err)
+	}
+
+	result := helpers.GetItemFromItemMetadata(item)
+	if result == nil {
+		return nil, fmt.Errorf("%w: %d", errUnsupportedItemType, item.Type)
+	}
+
+	return *result, nil
+}
+
+func parseEditedDate(date string) (time.Time, error) {
+	if date == "" {
+		return time.Time{}, nil
+	}
+
+	parsedDate, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse release date: %w", err)
+	}
+
+	return parsedDate, nil
+}
+
+// Copies the given image to the image cache, and returns its hash. An empty location clears the artwork.
+// Artwork is either a public URL or the hash of a cached image, see helpers.SaveUserImageToCache.
+func saveArtwork(location string) (string, error) {
+	if location == "" {
+		return "", nil
+	}
+
+	hash, err := helpers.SaveUserImageToCache(location)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to save artwork %s", location)
+
+		return "", fmt.Errorf("failed to save artwork: %w", err)
+	}
+
+	return hash, nil
+}
@@ -0,0 +1,50 @@
+package graph_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/graph"
+	"github.com/meteorae/meteorae-server/graph/model"
+	"github.com/meteorae/meteorae-server/utils"
+)
+
+func stringPointer(value string) *string {
+	return &value
+}
+
+func TestEditItemValidation(t *testing.T) {
+	resolver := (&graph.Resolver{}).Mutation()
+	userContext := utils.GetContextWithUser(context.Background(), &database.User{ID: 1, Username: "admin"})
+
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		input model.EditItemInput
+	}{
+		{
+			name:  "Anonymous user",
+			ctx:   context.Background(),
+			input: model.EditItemInput{Title: stringPointer("The Matrix")},
+		},
+		{
+			name:  "Unknown locked field",
+			ctx:   userContext,
+			input: model.EditItemInput{LockedFields: []string{"title", "duration"}},
+		},
+		{
+			name:  "Invalid release date",
+			ctx:   userContext,
+			input: model.EditItemInput{ReleaseDate: stringPointer("31/03/1999")},
+		},
+	}
+
+	// Inputs are rejected before the item is loaded, so no database is needed
+	for _, tc := range tests {
+		item, err := resolver.EditItem(tc.ctx, "1", tc.input)
+		if err == nil {
+			t.Errorf("%s: EditItem() = %+v, want an error", tc.name, item)
+		}
+	}
+}
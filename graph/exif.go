@@ -0,0 +1,34 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/rs/zerolog/log"
+)
+
+func getImageExif(id string) (*database.ImageExif, error) {
+	imageExif, err := database.GetImageExif(id)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get EXIF data for item %s", id)
+
+		return nil, fmt.Errorf("failed to get EXIF data: %w", err)
+	}
+
+	return imageExif, nil
+}
+
+// Parses the sort order of the children query. Children are left in storage order when it isn't set.
+func parseChildSortOrder(sortOrder *string) (database.ChildSortOrder, error) {
+	var parsedSortOrder database.ChildSortOrder
+
+	if sortOrder == nil {
+		return parsedSortOrder, nil
+	}
+
+	if err := parsedSortOrder.UnmarshalText([]byte(*sortOrder)); err != nil {
+		return parsedSortOrder, fmt.Errorf("failed to parse sort order: %w", err)
+	}
+
+	return parsedSortOrder, nil
+}
@@ -0,0 +1,154 @@
+package graph
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/graph/model"
+	"github.com/meteorae/meteorae-server/helpers"
+	"github.com/meteorae/meteorae-server/utils"
+	"github.com/rs/zerolog/log"
+)
+
+func getImageFaces(itemID string) ([]*database.FaceRegion, error) {
+	regions, err := database.GetFaceRegionsFromItem(itemID)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get face regions for item %s", itemID)
+
+		return nil, fmt.Errorf("failed to get face regions: %w", err)
+	}
+
+	return regions, nil
+}
+
+func getFaceRegionPerson(region *database.FaceRegion) (model.Item, error) {
+	if region.PersonID == nil {
+		return nil, nil
+	}
+
+	return getItemByID(*region.PersonID)
+}
+
+func addFaceRegion(itemID string, input model.FaceRegionInput) (*database.FaceRegion, error) {
+	item, err := database.GetItemByID(itemID)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get item %s", itemID)
+
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+
+	if item.Type != database.ImageItem {
+		return nil, errNotAnImage
+	}
+
+	region := database.FaceRegion{ItemMetadataID: item.ID}
+
+	if err := applyFaceRegionInput(&region, input); err != nil {
+		return nil, err
+	}
+
+	if err := database.CreateFaceRegion(&region); err != nil {
+		log.Error().Err(err).Msgf("Failed to add face region to item %s", itemID)
+
+		return nil, fmt.Errorf("failed to add face region: %w", err)
+	}
+
+	return &region, nil
+}
+
+func editFaceRegion(id string, input model.FaceRegionInput) (*database.FaceRegion, error) {
+	region, err := database.GetFaceRegion(id)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get face region %s", id)
+
+		return nil, fmt.Errorf("failed to get face region: %w", err)
+	}
+
+	if err := applyFaceRegionInput(region, input); err != nil {
+		return nil, err
+	}
+
+	if err := database.UpdateFaceRegion(region); err != nil {
+		log.Error().Err(err).Msgf("Failed to edit face region %s", id)
+
+		return nil, fmt.Errorf("failed to edit face region: %w", err)
+	}
+
+	return region, nil
+}
+
+// Sets the box and person of a region, creating the person when only a name is given.
+func applyFaceRegionInput(region *database.FaceRegion, input model.FaceRegionInput) error {
+	region.X = input.X
+	region.Y = input.Y
+	region.Width = input.Width
+	region.Height = input.Height
+	region.PersonID = nil
+
+	switch {
+	case input.PersonID != nil:
+		person, err := database.GetItemByID(*input.PersonID)
+		if err != nil {
+			log.Error().Err(err).Msgf("Failed to get person %s", *input.PersonID)
+
+			return fmt.Errorf("failed to get person: %w", err)
+		}
+
+		if person.Type != database.PersonItem {
+			return errNotAPerson
+		}
+
+		region.PersonID = &person.ID
+	case input.PersonName != nil && *input.PersonName != "":
+		person, err := database.GetOrCreatePerson(&database.ItemMetadata{
+			Title:     *input.PersonName,
+			SortTitle: utils.CleanSortTitle(*input.PersonName),
+		})
+		if err != nil {
+			log.Error().Err(err).Msgf("Failed to get person \"%s\"", *input.PersonName)
+
+			return fmt.Errorf("failed to get person: %w", err)
+		}
+
+		region.PersonID = &person.ID
+	}
+
+	return nil
+}
+
+func removeFaceRegion(id string) (bool, error) {
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil { //nolint:gomnd
+		return false, fmt.Errorf("invalid face region identifier %s: %w", id, err)
+	}
+
+	if err := database.DeleteFaceRegion(id); err != nil {
+		log.Error().Err(err).Msgf("Failed to remove face region %s", id)
+
+		return false, fmt.Errorf("failed to remove face region: %w", err)
+	}
+
+	return true, nil
+}
+
+// Returns the photos showing the given person.
+func getPersonPhotos(personID string, limit, offset *int64) (*model.ItemsResult, error) {
+	items, err := database.GetPhotosFromPerson(personID, limit, offset)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get photos of person %s", personID)
+
+		return nil, fmt.Errorf("failed to get photos: %w", err)
+	}
+
+	count, err := database.GetPhotosCountFromPerson(personID)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get photos count of person %s", personID)
+
+		return nil, fmt.Errorf("failed to get photos count: %w", err)
+	}
+
+	return &model.ItemsResult{
+		Items: helpers.GetItemsFromItemMetadata(items),
+		Total: count,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/graph/model"
+	"github.com/meteorae/meteorae-server/helpers"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Returns the external identifiers of the given item.
+func getItemGuids(itemID string) ([]*model.GUID, error) {
+	identifiers, err := database.GetExternalIdentifiersFromItem(itemID)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get external identifiers for item %s", itemID)
+
+		return nil, fmt.Errorf("failed to get external identifiers: %w", err)
+	}
+
+	guids := make([]*model.GUID, 0, len(identifiers))
+
+	for _, identifier := range identifiers {
+		identifierType, err := identifier.IdentifierType.MarshalText()
+		if err != nil {
+			log.Err(err).Msgf("Skipping external identifier %d of item %s", identifier.ID, itemID)
+
+			continue
+		}
+
+		guids = append(guids, &model.GUID{
+			Type: string(identifierType),
+			ID:   identifier.Identifier,
+		})
+	}
+
+	return guids, nil
+}
+
+// Returns the item with the given external identifier, or nil if there is none.
+func getItemByExternalID(identifierType, identifier string) (model.Item, error) {
+	var parsedType database.IdentifierType
+
+	err := parsedType.UnmarshalText([]byte(identifierType))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse identifier type: %w", err)
+	}
+
+	item, err := database.GetItemByExternalIdentifier(parsedType, identifier)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil //nolint:nilnil
+	}
+
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get item with %s identifier %s", identifierType, identifier)
+
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+
+	result := helpers.GetItemFromItemMetadata(item)
+	if result == nil {
+		return nil, fmt.Errorf("%w: %d", errUnsupportedItemType, item.Type)
+	}
+
+	return *result, nil
+}
@@ -0,0 +1,33 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Returns the library of a shared item, like an artist or a collection, which is the library of the first item
+// it was linked to.
+func getSharedItemLibrary(itemID string) (*database.Library, error) {
+	id, err := strconv.ParseUint(itemID, 10, 64) //nolint:gomnd
+	if err != nil {
+		return nil, fmt.Errorf("invalid item identifier %s: %w", itemID, err)
+	}
+
+	library, err := database.GetSharedItemLibrary(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", errNoLibrary, itemID)
+	}
+
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get library of item %s", itemID)
+
+		return nil, fmt.Errorf("failed to get library: %w", err)
+	}
+
+	return library, nil
+}
@@ -0,0 +1,141 @@
+package graph
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/graph/model"
+	"github.com/meteorae/meteorae-server/helpers"
+	providers "github.com/meteorae/meteorae-server/providers/registry"
+	PTN "github.com/middelink/go-parse-torrent-name"
+	"github.com/rs/zerolog/log"
+)
+
+// Returns the possible matches for an item, defaulting to its current title and year.
+func searchItemMatches(itemID string, title *string, year *int64) ([]*model.MatchCandidate, error) {
+	item, err := database.GetItemByID(itemID)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get item %s", itemID)
+
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+
+	query := item.Title
+	if title != nil && *title != "" {
+		query = *title
+	}
+
+	var queryYear int
+	if year != nil {
+		queryYear = int(*year)
+	} else if !item.ReleaseDate.IsZero() {
+		queryYear = item.ReleaseDate.Year()
+	}
+
+	candidates, err := providers.SearchMatches(query, queryYear, item.MediaPart.FilePath, item.Library)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to search matches for item %s", itemID)
+
+		return nil, fmt.Errorf("failed to search matches: %w", err)
+	}
+
+	results := make([]*model.MatchCandidate, 0, len(candidates))
+
+	for _, candidate := range candidates {
+		result := model.MatchCandidate{
+			ProviderID: fmt.Sprintf("%s:%s", candidate.Provider, candidate.ID),
+			Provider:   candidate.Provider,
+			Title:      candidate.Title,
+			Score:      candidate.Score,
+		}
+
+		if candidate.Year > 0 {
+			year := int64(candidate.Year)
+			result.Year = &year
+		}
+
+		if candidate.Thumb != "" {
+			thumb := candidate.Thumb
+			result.Thumb = &thumb
+		}
+
+		results = append(results, &result)
+	}
+
+	return results, nil
+}
+
+// Matches an item to the given provider candidate, and saves it.
+func fixItemMatch(itemID, providerID string) (model.Item, error) {
+	providerName, id, found := strings.Cut(providerID, ":")
+	if !found {
+		return nil, fmt.Errorf("%w: %s", errInvalidProviderID, providerID)
+	}
+
+	item, err := database.GetItemByID(itemID)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get item %s", itemID)
+
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+
+	err = providers.FixMatch(item, item.Library, providerName, id)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to match item %s to %s", itemID, providerID)
+
+		return nil, fmt.Errorf("failed to fix match: %w", err)
+	}
+
+	return saveItem(item)
+}
+
+// Removes the metadata of an item, resetting its title to the one derived from its file name.
+func unmatchItem(itemID string) (model.Item, error) {
+	item, err := database.GetItemByID(itemID)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get item %s", itemID)
+
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+
+	providers.Unmatch(item, getFileTitle(item))
+
+	return saveItem(item)
+}
+
+func saveItem(item *database.ItemMetadata) (model.Item, error) {
+	err := database.UpdateItem(item)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to update item %d", item.ID)
+
+		return nil, fmt.Errorf("failed to update item: %w", err)
+	}
+
+	result := helpers.GetItemFromItemMetadata(item)
+	if result == nil {
+		return nil, fmt.Errorf("%w: %d", errUnsupportedItemType, item.Type)
+	}
+
+	return *result, nil
+}
+
+// Returns the title of an item as derived from its file name, the same way resolvers do.
+// Items without files, like artists, keep their current title.
+func getFileTitle(item *database.ItemMetadata) string {
+	if item.MediaPart.FilePath == "" {
+		return item.Title
+	}
+
+	fileName := filepath.Base(item.MediaPart.FilePath)
+
+	if item.Type == database.MovieItem {
+		info, err := PTN.Parse(fileName)
+		if err == nil && info.Title != "" {
+			return info.Title
+		}
+	}
+
+	return strings.TrimSuffix(fileName, filepath.Ext(fileName))
+}
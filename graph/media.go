@@ -0,0 +1,19 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/rs/zerolog/log"
+)
+
+func getItemMediaPart(itemID string) (*database.MediaPart, error) {
+	mediaPart, err := database.GetMediaPartFromItem(itemID)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get media part for item %s", itemID)
+
+		return nil, fmt.Errorf("failed to get media part: %w", err)
+	}
+
+	return mediaPart, nil
+}
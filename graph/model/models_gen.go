@@ -19,30 +19,223 @@ type AuthPayload struct {
 	User  *database.User `json:"user"`
 }
 
+// Item information about an audiobook.
+type Book struct {
+	ID           string                      `json:"id"`
+	Title        string                      `json:"title"`
+	ReleaseDate  *string                     `json:"releaseDate"`
+	Summary      *string                     `json:"summary"`
+	Thumb        *string                     `json:"thumb"`
+	Art          *string                     `json:"art"`
+	CreatedAt    time.Time                   `json:"createdAt"`
+	UpdatedAt    time.Time                   `json:"updatedAt"`
+	Guids        []*GUID                     `json:"guids"`
+	Credits      []*database.Credit          `json:"credits"`
+	Tags         []*database.Tag             `json:"tags"`
+	LockedFields []string                    `json:"lockedFields"`
+	Library      *database.Library           `json:"library"`
+	UserRating   *int64                      `json:"userRating"`
+	Ratings      []*database.CommunityRating `json:"ratings"`
+	Author       *string                     `json:"author"`
+	Narrator     *string                     `json:"narrator"`
+	Series       *string                     `json:"series"`
+	// Position of the book in its series, as written in the tags.
+	SeriesIndex *string `json:"seriesIndex"`
+	// Total duration of the book, in milliseconds.
+	Duration *int64 `json:"duration"`
+	// Chapters of the book. Timestamps are relative to the start of the book, even when it spans multiple files.
+	Chapters []*database.Chapter `json:"chapters"`
+}
+
+func (Book) IsItem() {}
+
+// Item information about one of the files of an audiobook.
+type BookPart struct {
+	ID           string                      `json:"id"`
+	Title        string                      `json:"title"`
+	Summary      *string                     `json:"summary"`
+	Thumb        *string                     `json:"thumb"`
+	Art          *string                     `json:"art"`
+	CreatedAt    time.Time                   `json:"createdAt"`
+	UpdatedAt    time.Time                   `json:"updatedAt"`
+	Guids        []*GUID                     `json:"guids"`
+	Credits      []*database.Credit          `json:"credits"`
+	Tags         []*database.Tag             `json:"tags"`
+	LockedFields []string                    `json:"lockedFields"`
+	Library      *database.Library           `json:"library"`
+	UserRating   *int64                      `json:"userRating"`
+	Ratings      []*database.CommunityRating `json:"ratings"`
+	Index        *int64                      `json:"index"`
+}
+
+func (BookPart) IsItem() {}
+
+// A geographic bounding box, in degrees. West is greater than east for boxes crossing the antimeridian.
+type BoundsInput struct {
+	North float64 `json:"north"`
+	South float64 `json:"south"`
+	East  float64 `json:"east"`
+	West  float64 `json:"west"`
+}
+
+// A collection of items from any library. Collections are either imported from metadata providers,
+// like a movie series, or created by users. Provider collections are updated with the items they hold.
+type Collection struct {
+	ID           string                      `json:"id"`
+	Title        string                      `json:"title"`
+	Summary      *string                     `json:"summary"`
+	Thumb        *string                     `json:"thumb"`
+	Art          *string                     `json:"art"`
+	CreatedAt    time.Time                   `json:"createdAt"`
+	UpdatedAt    time.Time                   `json:"updatedAt"`
+	Guids        []*GUID                     `json:"guids"`
+	Credits      []*database.Credit          `json:"credits"`
+	Tags         []*database.Tag             `json:"tags"`
+	LockedFields []string                    `json:"lockedFields"`
+	Library      *database.Library           `json:"library"`
+	UserRating   *int64                      `json:"userRating"`
+	Ratings      []*database.CommunityRating `json:"ratings"`
+	// Whether the collection was created by a user, rather than imported from a metadata provider.
+	UserDefined bool `json:"userDefined"`
+	// How the items are sorted, either manual, releaseDate or title.
+	SortOrder string `json:"sortOrder"`
+	// The items in the collection, in its sort order.
+	Items *ItemsResult `json:"items"`
+}
+
+func (Collection) IsItem() {}
+
+type DuplicateCandidatesResult struct {
+	Candidates []*database.DuplicateCandidate `json:"candidates"`
+	Total      *int64                         `json:"total"`
+}
+
+// A copy in a group of duplicates. Resolution and bitrate come from the first video stream, and are only set for videos.
+type DuplicateCopy struct {
+	Item   Item   `json:"item"`
+	Width  *int64 `json:"width"`
+	Height *int64 `json:"height"`
+	// Bitrate of the video stream, in bits per second.
+	Bitrate *int64 `json:"bitrate"`
+	// Whether this copy has the best resolution of its group, then the best bitrate.
+	Best bool `json:"best"`
+}
+
+// Items which are all copies of each other, along with the candidates linking them.
+type DuplicateGroup struct {
+	// The copies, best first.
+	Copies     []*DuplicateCopy               `json:"copies"`
+	Candidates []*database.DuplicateCandidate `json:"candidates"`
+}
+
+type DuplicateGroupsResult struct {
+	Groups []*DuplicateGroup `json:"groups"`
+	Total  *int64            `json:"total"`
+}
+
+// Fields to edit on an item. Fields left out are unchanged.
+type EditItemInput struct {
+	Title         *string `json:"title"`
+	SortTitle     *string `json:"sortTitle"`
+	OriginalTitle *string `json:"originalTitle"`
+	Summary       *string `json:"summary"`
+	Tagline       *string `json:"tagline"`
+	// Release date, in the YYYY-MM-DD format. An empty string clears it.
+	ReleaseDate *string `json:"releaseDate"`
+	// Poster, either a public http or https URL, copied to the image cache, or the hash of a cached image. An empty string clears it.
+	Thumb *string `json:"thumb"`
+	// Background art, either a public http or https URL, copied to the image cache, or the hash of a cached image. An empty string clears it.
+	Art *string `json:"art"`
+	// Fields to lock, out of title, sortTitle, originalTitle, summary, tagline, releaseDate, thumb and art.
+	LockedFields []string `json:"lockedFields"`
+}
+
+// A face region, with its box normalized to the image size, from 0 to 1, from the top left corner of the image as stored.
+// The person is either an existing person, or a person with the specified name, created if there is none yet.
+// Regions without a person show an unknown face.
+type FaceRegionInput struct {
+	X          float64 `json:"x"`
+	Y          float64 `json:"y"`
+	Width      float64 `json:"width"`
+	Height     float64 `json:"height"`
+	PersonID   *string `json:"personId"`
+	PersonName *string `json:"personName"`
+}
+
+// Item information about a group of people, such as a band.
+type Group struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Summary   *string   `json:"summary"`
+	Thumb     *string   `json:"thumb"`
+	Art       *string   `json:"art"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Guids     []*GUID   `json:"guids"`
+	// The work of the group, newest first. Filters on the cast or crew role, and on the item type, like Movie.
+	Credits      []*database.Credit          `json:"credits"`
+	Tags         []*database.Tag             `json:"tags"`
+	LockedFields []string                    `json:"lockedFields"`
+	Library      *database.Library           `json:"library"`
+	UserRating   *int64                      `json:"userRating"`
+	Ratings      []*database.CommunityRating `json:"ratings"`
+	// Music videos featuring the group, across all libraries.
+	MusicVideos *ItemsResult `json:"musicVideos"`
+	// Albums by the group, across all libraries.
+	Albums *ItemsResult `json:"albums"`
+}
+
+func (Group) IsItem() {}
+
+// An identifier of an item in an external database.
+type GUID struct {
+	// Type of the identifier, like imdb, tmdb, anidb, tvdb or musicbrainz.
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
 // Item information about an image.
 type Image struct {
-	ID        string            `json:"id"`
-	Title     string            `json:"title"`
-	Summary   *string           `json:"summary"`
-	Thumb     *string           `json:"thumb"`
-	Art       *string           `json:"art"`
-	CreatedAt time.Time         `json:"createdAt"`
-	UpdatedAt time.Time         `json:"updatedAt"`
-	Library   *database.Library `json:"library"`
+	ID           string                      `json:"id"`
+	Title        string                      `json:"title"`
+	Summary      *string                     `json:"summary"`
+	Thumb        *string                     `json:"thumb"`
+	Art          *string                     `json:"art"`
+	CreatedAt    time.Time                   `json:"createdAt"`
+	UpdatedAt    time.Time                   `json:"updatedAt"`
+	Guids        []*GUID                     `json:"guids"`
+	Credits      []*database.Credit          `json:"credits"`
+	Tags         []*database.Tag             `json:"tags"`
+	LockedFields []string                    `json:"lockedFields"`
+	Library      *database.Library           `json:"library"`
+	UserRating   *int64                      `json:"userRating"`
+	Ratings      []*database.CommunityRating `json:"ratings"`
+	// The EXIF data of the image, if it has any.
+	Exif *database.ImageExif `json:"exif"`
+	// Star rating embedded in the image or its sidecar, from 1 to 5, or -1 for rejected photos. 0 means unrated.
+	Rating *int64 `json:"rating"`
+	// The faces in the image, from left to right.
+	Faces []*database.FaceRegion `json:"faces"`
 }
 
 func (Image) IsItem() {}
 
 // Item information about an image album.
 type ImageAlbum struct {
-	ID        string            `json:"id"`
-	Title     string            `json:"title"`
-	Summary   *string           `json:"summary"`
-	Thumb     *string           `json:"thumb"`
-	Art       *string           `json:"art"`
-	CreatedAt time.Time         `json:"createdAt"`
-	UpdatedAt time.Time         `json:"updatedAt"`
-	Library   *database.Library `json:"library"`
+	ID           string                      `json:"id"`
+	Title        string                      `json:"title"`
+	Summary      *string                     `json:"summary"`
+	Thumb        *string                     `json:"thumb"`
+	Art          *string                     `json:"art"`
+	CreatedAt    time.Time                   `json:"createdAt"`
+	UpdatedAt    time.Time                   `json:"updatedAt"`
+	Guids        []*GUID                     `json:"guids"`
+	Credits      []*database.Credit          `json:"credits"`
+	Tags         []*database.Tag             `json:"tags"`
+	LockedFields []string                    `json:"lockedFields"`
+	Library      *database.Library           `json:"library"`
+	UserRating   *int64                      `json:"userRating"`
+	Ratings      []*database.CommunityRating `json:"ratings"`
 }
 
 func (ImageAlbum) IsItem() {}
@@ -64,21 +257,173 @@ type LibrariesResult struct {
 	Total     *int64              `json:"total"`
 }
 
-// Item information about a movie.
+// A possible match for an item, from one of the metadata providers.
+type MatchCandidate struct {
+	// Identifier to pass to fixMatch, in the form provider:id.
+	ProviderID string `json:"providerId"`
+	// Name of the provider that returned the candidate.
+	Provider string `json:"provider"`
+	Title    string `json:"title"`
+	Year     *int64 `json:"year"`
+	// URL of the candidate's poster.
+	Thumb *string `json:"thumb"`
+	// How well the candidate matches the search, from 0 to 1.
+	Score float64 `json:"score"`
+}
+
+// Item information about a movie, including anime movies.
 type Movie struct {
-	ID          string            `json:"id"`
-	Title       string            `json:"title"`
-	ReleaseDate *string           `json:"releaseDate"`
-	Summary     *string           `json:"summary"`
-	Thumb       *string           `json:"thumb"`
-	Art         *string           `json:"art"`
-	CreatedAt   time.Time         `json:"createdAt"`
-	UpdatedAt   time.Time         `json:"updatedAt"`
-	Library     *database.Library `json:"library"`
+	ID           string                      `json:"id"`
+	Title        string                      `json:"title"`
+	ReleaseDate  *string                     `json:"releaseDate"`
+	Summary      *string                     `json:"summary"`
+	Thumb        *string                     `json:"thumb"`
+	Art          *string                     `json:"art"`
+	CreatedAt    time.Time                   `json:"createdAt"`
+	UpdatedAt    time.Time                   `json:"updatedAt"`
+	Guids        []*GUID                     `json:"guids"`
+	Credits      []*database.Credit          `json:"credits"`
+	Tags         []*database.Tag             `json:"tags"`
+	LockedFields []string                    `json:"lockedFields"`
+	Library      *database.Library           `json:"library"`
+	UserRating   *int64                      `json:"userRating"`
+	Ratings      []*database.CommunityRating `json:"ratings"`
+	// The file of the movie.
+	MediaPart *database.MediaPart `json:"mediaPart"`
 }
 
 func (Movie) IsItem() {}
 
+// Item information about a music album.
+type MusicAlbum struct {
+	ID           string                      `json:"id"`
+	Title        string                      `json:"title"`
+	ReleaseDate  *string                     `json:"releaseDate"`
+	Summary      *string                     `json:"summary"`
+	Thumb        *string                     `json:"thumb"`
+	Art          *string                     `json:"art"`
+	CreatedAt    time.Time                   `json:"createdAt"`
+	UpdatedAt    time.Time                   `json:"updatedAt"`
+	Guids        []*GUID                     `json:"guids"`
+	Credits      []*database.Credit          `json:"credits"`
+	Tags         []*database.Tag             `json:"tags"`
+	LockedFields []string                    `json:"lockedFields"`
+	Library      *database.Library           `json:"library"`
+	UserRating   *int64                      `json:"userRating"`
+	Ratings      []*database.CommunityRating `json:"ratings"`
+	// Artists credited on the album, main artist first.
+	Artists []Item `json:"artists"`
+}
+
+func (MusicAlbum) IsItem() {}
+
+// Item information about a music video.
+type MusicVideo struct {
+	ID           string                      `json:"id"`
+	Title        string                      `json:"title"`
+	ReleaseDate  *string                     `json:"releaseDate"`
+	Summary      *string                     `json:"summary"`
+	Thumb        *string                     `json:"thumb"`
+	Art          *string                     `json:"art"`
+	CreatedAt    time.Time                   `json:"createdAt"`
+	UpdatedAt    time.Time                   `json:"updatedAt"`
+	Guids        []*GUID                     `json:"guids"`
+	Credits      []*database.Credit          `json:"credits"`
+	Tags         []*database.Tag             `json:"tags"`
+	LockedFields []string                    `json:"lockedFields"`
+	Library      *database.Library           `json:"library"`
+	UserRating   *int64                      `json:"userRating"`
+	Ratings      []*database.CommunityRating `json:"ratings"`
+	// Artists performing in the music video, main artist first.
+	Artists []Item `json:"artists"`
+}
+
+func (MusicVideo) IsItem() {}
+
+// Item information about a person, such as a solo artist.
+type Person struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Summary   *string   `json:"summary"`
+	Thumb     *string   `json:"thumb"`
+	Art       *string   `json:"art"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Guids     []*GUID   `json:"guids"`
+	// The work of the person, newest first. Filters on the cast or crew role, and on the item type, like Movie.
+	Credits      []*database.Credit          `json:"credits"`
+	Tags         []*database.Tag             `json:"tags"`
+	LockedFields []string                    `json:"lockedFields"`
+	Library      *database.Library           `json:"library"`
+	UserRating   *int64                      `json:"userRating"`
+	Ratings      []*database.CommunityRating `json:"ratings"`
+	// Music videos featuring the person, across all libraries.
+	MusicVideos *ItemsResult `json:"musicVideos"`
+	// Albums by the person, across all libraries.
+	Albums *ItemsResult `json:"albums"`
+	// Photos showing the person, across all libraries, by capture date.
+	Photos *ItemsResult `json:"photos"`
+}
+
+func (Person) IsItem() {}
+
+// Item information about a podcast.
+type Podcast struct {
+	ID           string                      `json:"id"`
+	Title        string                      `json:"title"`
+	Summary      *string                     `json:"summary"`
+	Thumb        *string                     `json:"thumb"`
+	Art          *string                     `json:"art"`
+	CreatedAt    time.Time                   `json:"createdAt"`
+	UpdatedAt    time.Time                   `json:"updatedAt"`
+	Guids        []*GUID                     `json:"guids"`
+	Credits      []*database.Credit          `json:"credits"`
+	Tags         []*database.Tag             `json:"tags"`
+	LockedFields []string                    `json:"lockedFields"`
+	Library      *database.Library           `json:"library"`
+	UserRating   *int64                      `json:"userRating"`
+	Ratings      []*database.CommunityRating `json:"ratings"`
+}
+
+func (Podcast) IsItem() {}
+
+// Item information about a podcast episode.
+type PodcastEpisode struct {
+	ID           string                      `json:"id"`
+	Title        string                      `json:"title"`
+	ReleaseDate  *string                     `json:"releaseDate"`
+	Summary      *string                     `json:"summary"`
+	Thumb        *string                     `json:"thumb"`
+	Art          *string                     `json:"art"`
+	CreatedAt    time.Time                   `json:"createdAt"`
+	UpdatedAt    time.Time                   `json:"updatedAt"`
+	Guids        []*GUID                     `json:"guids"`
+	Credits      []*database.Credit          `json:"credits"`
+	Tags         []*database.Tag             `json:"tags"`
+	LockedFields []string                    `json:"lockedFields"`
+	Library      *database.Library           `json:"library"`
+	UserRating   *int64                      `json:"userRating"`
+	Ratings      []*database.CommunityRating `json:"ratings"`
+	// Duration of the episode, in milliseconds.
+	Duration *int64              `json:"duration"`
+	Chapters []*database.Chapter `json:"chapters"`
+}
+
+func (PodcastEpisode) IsItem() {}
+
+// An item reached while walking the relationship graph.
+type RelatedItem struct {
+	Item Item `json:"item"`
+	// Type of the edge leading to the item, like sequelOf.
+	EdgeType string `json:"edgeType"`
+	// Identifier of the item the edge was followed from.
+	FromID string `json:"fromId"`
+	// Whether the edge points from the fromId item to this one, rather than the other way around.
+	Outgoing bool `json:"outgoing"`
+	// Number of edges between this item and the starting item.
+	Depth int64 `json:"depth"`
+}
+
 // Result of a query containing multiple users.
 type UsersResult struct {
 	Users []*database.User `json:"users"`
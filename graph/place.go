@@ -0,0 +1,115 @@
+package graph
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/graph/model"
+	"github.com/meteorae/meteorae-server/helpers"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	maxZoom = 22
+	// Number of map cells per tile side. Tiles are 256 pixels wide, so markers are about 64 pixels apart.
+	cellsPerTile = 4
+)
+
+func getPlaces(libraryID string, path []string) ([]*database.Place, error) {
+	places, err := database.GetPlaces(libraryID, path)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get places for library %s", libraryID)
+
+		return nil, fmt.Errorf("failed to get places: %w", err)
+	}
+
+	return places, nil
+}
+
+func getPlaceItems(libraryID string, path []string, limit, offset *int64) (*model.ItemsResult, error) {
+	items, err := database.GetItemsFromPlace(libraryID, path, limit, offset)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get items for place %v", path)
+
+		return nil, fmt.Errorf("failed to get items: %w", err)
+	}
+
+	count, err := database.GetItemsCountFromPlace(libraryID, path)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get items count for place %v", path)
+
+		return nil, fmt.Errorf("failed to get items count: %w", err)
+	}
+
+	return &model.ItemsResult{
+		Items: helpers.GetItemsFromItemMetadata(items),
+		Total: count,
+	}, nil
+}
+
+func getItemsInBounds(libraryID string, input model.BoundsInput, limit, offset *int64) (*model.ItemsResult, error) {
+	bounds, err := parseBounds(input)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := database.GetItemsInBounds(libraryID, bounds, limit, offset)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get items in bounds for library %s", libraryID)
+
+		return nil, fmt.Errorf("failed to get items: %w", err)
+	}
+
+	count, err := database.GetItemsCountInBounds(libraryID, bounds)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get items count in bounds for library %s", libraryID)
+
+		return nil, fmt.Errorf("failed to get items count: %w", err)
+	}
+
+	return &model.ItemsResult{
+		Items: helpers.GetItemsFromItemMetadata(items),
+		Total: count,
+	}, nil
+}
+
+// Clusters the photos inside the bounds on a grid matching the map tiles of the zoom level,
+// so markers stay about the same distance apart on screen.
+func getMapClusters(libraryID string, input model.BoundsInput, zoom int64) ([]*database.MapCluster, error) {
+	bounds, err := parseBounds(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if zoom < 0 || zoom > maxZoom {
+		return nil, fmt.Errorf("%w: %d", errInvalidZoom, zoom)
+	}
+
+	cellSize := 360 / (math.Pow(2, float64(zoom)) * cellsPerTile) //nolint:gomnd
+
+	clusters, err := database.GetMapClusters(libraryID, bounds, cellSize)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get map clusters for library %s", libraryID)
+
+		return nil, fmt.Errorf("failed to get map clusters: %w", err)
+	}
+
+	return clusters, nil
+}
+
+func parseBounds(input model.BoundsInput) (database.Bounds, error) {
+	bounds := database.Bounds{
+		North: input.North,
+		South: input.South,
+		East:  input.East,
+		West:  input.West,
+	}
+
+	if bounds.South > bounds.North || math.Abs(bounds.North) > 90 || math.Abs(bounds.South) > 90 ||
+		math.Abs(bounds.East) > 180 || math.Abs(bounds.West) > 180 {
+		return bounds, fmt.Errorf("%w: %+v", errInvalidBounds, input)
+	}
+
+	return bounds, nil
+}
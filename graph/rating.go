@@ -0,0 +1,91 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/graph/model"
+	"github.com/meteorae/meteorae-server/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// Returns the rating of the current user on an item, or the rating embedded in its file.
+// Anonymous requests only get the rating embedded in the file.
+func getItemUserRating(ctx context.Context, itemID string) (*int64, error) {
+	var userID uint64 = database.FileRatingUserID
+
+	if user := utils.GetUserFromContext(ctx); user != nil {
+		userID = user.ID
+	}
+
+	rating, err := database.GetUserRating(userID, itemID)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get user rating for item %s", itemID)
+
+		return nil, fmt.Errorf("failed to get user rating: %w", err)
+	}
+
+	if rating == nil {
+		return nil, nil //nolint:nilnil
+	}
+
+	userRating := int64(*rating)
+
+	return &userRating, nil
+}
+
+func getItemRatings(itemID string) ([]*database.CommunityRating, error) {
+	ratings, err := database.GetCommunityRatingsFromItem(itemID)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get community ratings for item %s", itemID)
+
+		return nil, fmt.Errorf("failed to get community ratings: %w", err)
+	}
+
+	return ratings, nil
+}
+
+// Sets the rating of the current user on an item, or removes it when the rating is nil or 0.
+func rateItem(ctx context.Context, itemID string, rating *int64) (model.Item, error) {
+	user := utils.GetUserFromContext(ctx)
+	if user == nil {
+		return nil, errNotAuthenticated
+	}
+
+	item, err := database.GetItemByID(itemID)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get item %s", itemID)
+
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+
+	var stars int
+
+	if rating != nil {
+		stars = int(*rating)
+	}
+
+	if err := database.SetUserRating(user.ID, item.ID, stars); err != nil {
+		log.Error().Err(err).Msgf("Failed to rate item %s", itemID)
+
+		return nil, fmt.Errorf("failed to rate item: %w", err)
+	}
+
+	return getItemByID(item.ID)
+}
+
+// Returns the rating filter of the current user. Anonymous requests filter on the ratings embedded in files.
+func getRatingFilter(ctx context.Context, minUserRating *int64) database.RatingFilter {
+	filter := database.RatingFilter{UserID: database.FileRatingUserID}
+
+	if user := utils.GetUserFromContext(ctx); user != nil {
+		filter.UserID = user.ID
+	}
+
+	if minUserRating != nil {
+		filter.MinRating = int(*minUserRating)
+	}
+
+	return filter
+}
@@ -0,0 +1,50 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/filesystem/scanner"
+	"github.com/meteorae/meteorae-server/providers/refresher"
+	"github.com/rs/zerolog/log"
+)
+
+// Queues a metadata refresh for an item, or for all the items of a library.
+func refreshMetadata(itemID, libraryID *string, force *bool) (bool, error) {
+	forceRefresh := force != nil && *force
+
+	switch {
+	case itemID != nil:
+		err := refresher.RefreshItem(*itemID, forceRefresh)
+		if err != nil {
+			log.Error().Err(err).Msgf("Failed to queue refresh for item %s", *itemID)
+
+			return false, fmt.Errorf("failed to queue refresh: %w", err)
+		}
+	case libraryID != nil:
+		err := refresher.RefreshLibrary(*libraryID, forceRefresh)
+		if err != nil {
+			log.Error().Err(err).Msgf("Failed to queue refresh for library %s", *libraryID)
+
+			return false, fmt.Errorf("failed to queue refresh: %w", err)
+		}
+	default:
+		return false, errMissingRefreshTarget
+	}
+
+	return true, nil
+}
+
+// Queues a scan of all the locations of a library.
+func scanLibrary(libraryID string) (bool, error) {
+	library, err := database.GetLibraryWithLocations(libraryID)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get library %s", libraryID)
+
+		return false, fmt.Errorf("failed to get library: %w", err)
+	}
+
+	scanner.ScanLibrary(library)
+
+	return true, nil
+}
@@ -0,0 +1,161 @@
+package graph
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/graph/model"
+	"github.com/meteorae/meteorae-server/helpers"
+	"github.com/rs/zerolog/log"
+)
+
+// Returns the items related to the given item, closest first.
+func getRelatedItems(itemID string, edgeTypes []string, depth *int64) ([]*model.RelatedItem, error) {
+	id, err := strconv.ParseUint(itemID, 10, 64) //nolint:gomnd
+	if err != nil {
+		return nil, fmt.Errorf("invalid item identifier %s: %w", itemID, err)
+	}
+
+	relationTypes, err := parseRelationTypes(edgeTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	maxDepth := 1
+	if depth != nil {
+		maxDepth = int(*depth)
+	}
+
+	edges, err := database.GetRelatedItems(id, relationTypes, maxDepth)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get items related to item %s", itemID)
+
+		return nil, fmt.Errorf("failed to get related items: %w", err)
+	}
+
+	ids := make([]uint64, 0, len(edges))
+	for _, edge := range edges {
+		ids = append(ids, edge.ItemID)
+	}
+
+	items, err := database.GetItemsByIDs(ids)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get items related to item %s", itemID)
+
+		return nil, fmt.Errorf("failed to get related items: %w", err)
+	}
+
+	itemsByID := make(map[uint64]*database.ItemMetadata, len(items))
+	for _, item := range items {
+		itemsByID[item.ID] = item
+	}
+
+	related := make([]*model.RelatedItem, 0, len(edges))
+
+	for _, edge := range edges {
+		item, ok := itemsByID[edge.ItemID]
+		if !ok {
+			continue
+		}
+
+		result := helpers.GetItemFromItemMetadata(item)
+		if result == nil {
+			log.Debug().Msgf("Skipping related item %d with unsupported type %d", item.ID, item.Type)
+
+			continue
+		}
+
+		related = append(related, &model.RelatedItem{
+			Item:     *result,
+			EdgeType: edge.Type.String(),
+			FromID:   strconv.FormatUint(edge.FromID, 10), //nolint:gomnd
+			Outgoing: edge.Outgoing,
+			Depth:    int64(edge.Depth),
+		})
+	}
+
+	return related, nil
+}
+
+// Adds a user-defined edge between two items.
+func addRelation(sourceID, targetID, edgeType string) (bool, error) {
+	source, target, relationType, err := parseRelation(sourceID, targetID, edgeType)
+	if err != nil {
+		return false, err
+	}
+
+	for _, id := range []string{sourceID, targetID} {
+		if _, err := database.GetItemByID(id); err != nil {
+			log.Error().Err(err).Msgf("Failed to get item %s", id)
+
+			return false, fmt.Errorf("failed to get item: %w", err)
+		}
+	}
+
+	err = database.AddItemRelation(source, target, relationType, true)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to add %s relation from item %s to item %s", edgeType, sourceID, targetID)
+
+		return false, fmt.Errorf("failed to add relation: %w", err)
+	}
+
+	return true, nil
+}
+
+// Removes a user-defined edge between two items.
+func removeRelation(sourceID, targetID, edgeType string) (bool, error) {
+	source, target, relationType, err := parseRelation(sourceID, targetID, edgeType)
+	if err != nil {
+		return false, err
+	}
+
+	removed, err := database.RemoveUserItemRelation(source, target, relationType)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to remove %s relation from item %s to item %s", edgeType, sourceID, targetID)
+
+		return false, fmt.Errorf("failed to remove relation: %w", err)
+	}
+
+	return removed, nil
+}
+
+func parseRelation(sourceID, targetID, edgeType string) (uint64, uint64, database.RelationType, error) {
+	var relationType database.RelationType
+
+	if err := relationType.UnmarshalText([]byte(edgeType)); err != nil {
+		return 0, 0, "", fmt.Errorf("failed to parse edge type: %w", err)
+	}
+
+	source, err := strconv.ParseUint(sourceID, 10, 64) //nolint:gomnd
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("invalid item identifier %s: %w", sourceID, err)
+	}
+
+	target, err := strconv.ParseUint(targetID, 10, 64) //nolint:gomnd
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("invalid item identifier %s: %w", targetID, err)
+	}
+
+	if source == target {
+		return 0, 0, "", errSelfRelation
+	}
+
+	return source, target, relationType, nil
+}
+
+func parseRelationTypes(edgeTypes []string) ([]database.RelationType, error) {
+	relationTypes := make([]database.RelationType, 0, len(edgeTypes))
+
+	for _, edgeType := range edgeTypes {
+		var relationType database.RelationType
+
+		if err := relationType.UnmarshalText([]byte(edgeType)); err != nil {
+			return nil, fmt.Errorf("failed to parse edge type: %w", err)
+		}
+
+		relationTypes = append(relationTypes, relationType)
+	}
+
+	return relationTypes, nil
+}
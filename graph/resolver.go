@@ -1,7 +1,38 @@
 package graph
 
-import "errors"
+import (
+	"context"
+	"errors"
 
-var errInvalidCredentials = errors.New("invalid credentials")
+	"github.com/meteorae/meteorae-server/utils"
+)
+
+var (
+	errInvalidCredentials   = errors.New("invalid credentials")
+	errInvalidProviderID    = errors.New("invalid provider identifier")
+	errUnsupportedItemType  = errors.New("unsupported item type")
+	errMissingRefreshTarget = errors.New("either an item or a library is required")
+	errInvalidMediaType     = errors.New("invalid media type")
+	errSelfRelation         = errors.New("an item can't be related to itself")
+	errNotACollection       = errors.New("item is not a collection")
+	errProviderCollection   = errors.New("collections imported from metadata providers can't be edited")
+	errSelfMerge            = errors.New("a tag can't be merged into itself")
+	errInvalidLockedField   = errors.New("invalid locked field")
+	errInvalidBounds        = errors.New("invalid bounds")
+	errInvalidZoom          = errors.New("zoom levels go from 0 to 22")
+	errNotAnImage           = errors.New("item is not an image")
+	errNotAPerson           = errors.New("item is not a person")
+	errNotAuthenticated     = errors.New("not authenticated")
+	errNoLibrary            = errors.New("item isn't linked to any library yet")
+)
 
 type Resolver struct{}
+
+// Returns an error unless a user is logged in, for mutations changing what every user sees.
+func requireUser(ctx context.Context) error {
+	if utils.GetUserFromContext(ctx) == nil {
+		return errNotAuthenticated
+	}
+
+	return nil
+}
@@ -14,10 +14,290 @@ import (
 	"github.com/meteorae/meteorae-server/graph/generated"
 	"github.com/meteorae/meteorae-server/graph/model"
 	"github.com/meteorae/meteorae-server/helpers"
-	ants "github.com/panjf2000/ants/v2"
 	"github.com/rs/zerolog/log"
 )
 
+func (r *bookResolver) Guids(ctx context.Context, obj *model.Book) ([]*model.GUID, error) {
+	return getItemGuids(obj.ID)
+}
+
+func (r *bookResolver) Credits(ctx context.Context, obj *model.Book) ([]*database.Credit, error) {
+	return getItemCredits(obj.ID)
+}
+
+func (r *bookResolver) Tags(ctx context.Context, obj *model.Book) ([]*database.Tag, error) {
+	return getItemTags(obj.ID)
+}
+
+func (r *bookResolver) UserRating(ctx context.Context, obj *model.Book) (*int64, error) {
+	return getItemUserRating(ctx, obj.ID)
+}
+
+func (r *bookResolver) Ratings(
+	ctx context.Context,
+	obj *model.Book,
+) ([]*database.CommunityRating, error) {
+	return getItemRatings(obj.ID)
+}
+
+func (r *bookResolver) Chapters(ctx context.Context, obj *model.Book) ([]*database.Chapter, error) {
+	return getItemChapters(obj.ID)
+}
+
+func (r *bookPartResolver) Guids(ctx context.Context, obj *model.BookPart) ([]*model.GUID, error) {
+	return getItemGuids(obj.ID)
+}
+
+func (r *bookPartResolver) Credits(
+	ctx context.Context,
+	obj *model.BookPart,
+) ([]*database.Credit, error) {
+	return getItemCredits(obj.ID)
+}
+
+func (r *bookPartResolver) Tags(ctx context.Context, obj *model.BookPart) ([]*database.Tag, error) {
+	return getItemTags(obj.ID)
+}
+
+func (r *bookPartResolver) UserRating(ctx context.Context, obj *model.BookPart) (*int64, error) {
+	return getItemUserRating(ctx, obj.ID)
+}
+
+func (r *bookPartResolver) Ratings(
+	ctx context.Context,
+	obj *model.BookPart,
+) ([]*database.CommunityRating, error) {
+	return getItemRatings(obj.ID)
+}
+
+func (r *collectionResolver) Guids(
+	ctx context.Context,
+	obj *model.Collection,
+) ([]*model.GUID, error) {
+	return getItemGuids(obj.ID)
+}
+
+func (r *collectionResolver) Credits(
+	ctx context.Context,
+	obj *model.Collection,
+) ([]*database.Credit, error) {
+	return getItemCredits(obj.ID)
+}
+
+func (r *collectionResolver) Tags(
+	ctx context.Context,
+	obj *model.Collection,
+) ([]*database.Tag, error) {
+	return getItemTags(obj.ID)
+}
+
+func (r *collectionResolver) Library(
+	ctx context.Context,
+	obj *model.Collection,
+) (*database.Library, error) {
+	return getSharedItemLibrary(obj.ID)
+}
+
+func (r *collectionResolver) UserRating(
+	ctx context.Context,
+	obj *model.Collection,
+) (*int64, error) {
+	return getItemUserRating(ctx, obj.ID)
+}
+
+func (r *collectionResolver) Ratings(
+	ctx context.Context,
+	obj *model.Collection,
+) ([]*database.CommunityRating, error) {
+	return getItemRatings(obj.ID)
+}
+
+func (r *collectionResolver) Items(
+	ctx context.Context,
+	obj *model.Collection,
+	limit *int64,
+	offset *int64,
+) (*model.ItemsResult, error) {
+	return getCollectionItems(ctx, obj.ID, nil, nil, limit, offset)
+}
+
+func (r *creditResolver) Person(ctx context.Context, obj *database.Credit) (model.Item, error) {
+	return getItemByID(obj.PersonID)
+}
+
+func (r *creditResolver) Item(ctx context.Context, obj *database.Credit) (model.Item, error) {
+	return getItemByID(obj.ItemMetadataID)
+}
+
+func (r *duplicateCandidateResolver) ID(
+	ctx context.Context,
+	obj *database.DuplicateCandidate,
+) (string, error) {
+	return strconv.FormatUint(obj.ID, 10), nil //nolint:gomnd
+}
+
+func (r *duplicateCandidateResolver) Item(
+	ctx context.Context,
+	obj *database.DuplicateCandidate,
+) (model.Item, error) {
+	return getItemByID(obj.ItemMetadataID)
+}
+
+func (r *duplicateCandidateResolver) Duplicate(
+	ctx context.Context,
+	obj *database.DuplicateCandidate,
+) (model.Item, error) {
+	return getItemByID(obj.DuplicateID)
+}
+
+func (r *duplicateCandidateResolver) Status(
+	ctx context.Context,
+	obj *database.DuplicateCandidate,
+) (string, error) {
+	return string(obj.Status), nil
+}
+
+func (r *faceRegionResolver) ID(ctx context.Context, obj *database.FaceRegion) (string, error) {
+	return strconv.FormatUint(obj.ID, 10), nil //nolint:gomnd
+}
+
+func (r *faceRegionResolver) Item(
+	ctx context.Context,
+	obj *database.FaceRegion,
+) (model.Item, error) {
+	return getItemByID(obj.ItemMetadataID)
+}
+
+func (r *faceRegionResolver) Person(
+	ctx context.Context,
+	obj *database.FaceRegion,
+) (model.Item, error) {
+	return getFaceRegionPerson(obj)
+}
+
+func (r *faceRegionResolver) Source(ctx context.Context, obj *database.FaceRegion) (string, error) {
+	return string(obj.Source), nil
+}
+
+func (r *groupResolver) Guids(ctx context.Context, obj *model.Group) ([]*model.GUID, error) {
+	return getItemGuids(obj.ID)
+}
+
+func (r *groupResolver) Credits(
+	ctx context.Context,
+	obj *model.Group,
+	role *string,
+	mediaType *string,
+) ([]*database.Credit, error) {
+	return getPersonCredits(obj.ID, role, mediaType)
+}
+
+func (r *groupResolver) Tags(ctx context.Context, obj *model.Group) ([]*database.Tag, error) {
+	return getItemTags(obj.ID)
+}
+
+func (r *groupResolver) Library(ctx context.Context, obj *model.Group) (*database.Library, error) {
+	return getSharedItemLibrary(obj.ID)
+}
+
+func (r *groupResolver) UserRating(ctx context.Context, obj *model.Group) (*int64, error) {
+	return getItemUserRating(ctx, obj.ID)
+}
+
+func (r *groupResolver) Ratings(
+	ctx context.Context,
+	obj *model.Group,
+) ([]*database.CommunityRating, error) {
+	return getItemRatings(obj.ID)
+}
+
+func (r *groupResolver) MusicVideos(
+	ctx context.Context,
+	obj *model.Group,
+	limit *int64,
+	offset *int64,
+) (*model.ItemsResult, error) {
+	return getArtistItems(obj.ID, database.MusicVideoItem, limit, offset)
+}
+
+func (r *groupResolver) Albums(
+	ctx context.Context,
+	obj *model.Group,
+	limit *int64,
+	offset *int64,
+) (*model.ItemsResult, error) {
+	return getArtistItems(obj.ID, database.MusicAlbumItem, limit, offset)
+}
+
+func (r *imageResolver) Guids(ctx context.Context, obj *model.Image) ([]*model.GUID, error) {
+	return getItemGuids(obj.ID)
+}
+
+func (r *imageResolver) Credits(ctx context.Context, obj *model.Image) ([]*database.Credit, error) {
+	return getItemCredits(obj.ID)
+}
+
+func (r *imageResolver) Tags(ctx context.Context, obj *model.Image) ([]*database.Tag, error) {
+	return getItemTags(obj.ID)
+}
+
+func (r *imageResolver) UserRating(ctx context.Context, obj *model.Image) (*int64, error) {
+	return getItemUserRating(ctx, obj.ID)
+}
+
+func (r *imageResolver) Ratings(
+	ctx context.Context,
+	obj *model.Image,
+) ([]*database.CommunityRating, error) {
+	return getItemRatings(obj.ID)
+}
+
+func (r *imageResolver) Exif(ctx context.Context, obj *model.Image) (*database.ImageExif, error) {
+	return getImageExif(obj.ID)
+}
+
+func (r *imageResolver) Faces(
+	ctx context.Context,
+	obj *model.Image,
+) ([]*database.FaceRegion, error) {
+	return getImageFaces(obj.ID)
+}
+
+func (r *imageAlbumResolver) Guids(
+	ctx context.Context,
+	obj *model.ImageAlbum,
+) ([]*model.GUID, error) {
+	return getItemGuids(obj.ID)
+}
+
+func (r *imageAlbumResolver) Credits(
+	ctx context.Context,
+	obj *model.ImageAlbum,
+) ([]*database.Credit, error) {
+	return getItemCredits(obj.ID)
+}
+
+func (r *imageAlbumResolver) Tags(
+	ctx context.Context,
+	obj *model.ImageAlbum,
+) ([]*database.Tag, error) {
+	return getItemTags(obj.ID)
+}
+
+func (r *imageAlbumResolver) UserRating(
+	ctx context.Context,
+	obj *model.ImageAlbum,
+) (*int64, error) {
+	return getItemUserRating(ctx, obj.ID)
+}
+
+func (r *imageAlbumResolver) Ratings(
+	ctx context.Context,
+	obj *model.ImageAlbum,
+) ([]*database.CommunityRating, error) {
+	return getItemRatings(obj.ID)
+}
+
 func (r *libraryResolver) ID(ctx context.Context, obj *database.Library) (string, error) {
 	return strconv.FormatUint(obj.ID, 10), nil //nolint:gomnd
 }
@@ -26,6 +306,13 @@ func (r *libraryResolver) Type(ctx context.Context, obj *database.Library) (stri
 	return obj.Type.String(), nil
 }
 
+func (r *libraryResolver) SubtitleLanguages(
+	ctx context.Context,
+	obj *database.Library,
+) ([]string, error) {
+	return obj.GetSubtitleLanguages(), nil
+}
+
 func (r *libraryResolver) Locations(ctx context.Context, obj *database.Library) ([]string, error) {
 	locations := make([]string, 0, len(obj.LibraryLocations))
 	for _, location := range obj.LibraryLocations {
@@ -35,6 +322,131 @@ func (r *libraryResolver) Locations(ctx context.Context, obj *database.Library)
 	return locations, nil
 }
 
+func (r *mapClusterResolver) Item(
+	ctx context.Context,
+	obj *database.MapCluster,
+) (model.Item, error) {
+	return getItemByID(obj.ItemID)
+}
+
+func (r *mediaPartResolver) ID(ctx context.Context, obj *database.MediaPart) (string, error) {
+	return strconv.FormatUint(obj.ID, 10), nil //nolint:gomnd
+}
+
+func (r *movieResolver) Guids(ctx context.Context, obj *model.Movie) ([]*model.GUID, error) {
+	return getItemGuids(obj.ID)
+}
+
+func (r *movieResolver) Credits(ctx context.Context, obj *model.Movie) ([]*database.Credit, error) {
+	return getItemCredits(obj.ID)
+}
+
+func (r *movieResolver) Tags(ctx context.Context, obj *model.Movie) ([]*database.Tag, error) {
+	return getItemTags(obj.ID)
+}
+
+func (r *movieResolver) UserRating(ctx context.Context, obj *model.Movie) (*int64, error) {
+	return getItemUserRating(ctx, obj.ID)
+}
+
+func (r *movieResolver) Ratings(
+	ctx context.Context,
+	obj *model.Movie,
+) ([]*database.CommunityRating, error) {
+	return getItemRatings(obj.ID)
+}
+
+func (r *movieResolver) MediaPart(
+	ctx context.Context,
+	obj *model.Movie,
+) (*database.MediaPart, error) {
+	return getItemMediaPart(obj.ID)
+}
+
+func (r *musicAlbumResolver) Guids(
+	ctx context.Context,
+	obj *model.MusicAlbum,
+) ([]*model.GUID, error) {
+	return getItemGuids(obj.ID)
+}
+
+func (r *musicAlbumResolver) Credits(
+	ctx context.Context,
+	obj *model.MusicAlbum,
+) ([]*database.Credit, error) {
+	return getItemCredits(obj.ID)
+}
+
+func (r *musicAlbumResolver) Tags(
+	ctx context.Context,
+	obj *model.MusicAlbum,
+) ([]*database.Tag, error) {
+	return getItemTags(obj.ID)
+}
+
+func (r *musicAlbumResolver) UserRating(
+	ctx context.Context,
+	obj *model.MusicAlbum,
+) (*int64, error) {
+	return getItemUserRating(ctx, obj.ID)
+}
+
+func (r *musicAlbumResolver) Ratings(
+	ctx context.Context,
+	obj *model.MusicAlbum,
+) ([]*database.CommunityRating, error) {
+	return getItemRatings(obj.ID)
+}
+
+func (r *musicAlbumResolver) Artists(
+	ctx context.Context,
+	obj *model.MusicAlbum,
+) ([]model.Item, error) {
+	return getItemArtists(obj.ID)
+}
+
+func (r *musicVideoResolver) Guids(
+	ctx context.Context,
+	obj *model.MusicVideo,
+) ([]*model.GUID, error) {
+	return getItemGuids(obj.ID)
+}
+
+func (r *musicVideoResolver) Credits(
+	ctx context.Context,
+	obj *model.MusicVideo,
+) ([]*database.Credit, error) {
+	return getItemCredits(obj.ID)
+}
+
+func (r *musicVideoResolver) Tags(
+	ctx context.Context,
+	obj *model.MusicVideo,
+) ([]*database.Tag, error) {
+	return getItemTags(obj.ID)
+}
+
+func (r *musicVideoResolver) UserRating(
+	ctx context.Context,
+	obj *model.MusicVideo,
+) (*int64, error) {
+	return getItemUserRating(ctx, obj.ID)
+}
+
+func (r *musicVideoResolver) Ratings(
+	ctx context.Context,
+	obj *model.MusicVideo,
+) ([]*database.CommunityRating, error) {
+	return getItemRatings(obj.ID)
+}
+
+func (r *musicVideoResolver) Artists(
+	ctx context.Context,
+	obj *model.MusicVideo,
+) ([]model.Item, error) {
+	return getItemArtists(obj.ID)
+}
+
 func (r *mutationResolver) Login(
 	ctx context.Context,
 	username string,
@@ -94,8 +506,11 @@ func (r *mutationResolver) AddLibrary(
 	name string,
 	language string,
 	locations []string,
+	includeAdult *bool,
+	subtitleLanguages []string,
 ) (*database.Library, error) {
-	library, libraryLocations, err := database.CreateLibrary(name, language, typeArg, locations)
+	library, _, err := database.CreateLibrary(
+		name, language, typeArg, locations, includeAdult != nil && *includeAdult, subtitleLanguages)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create library")
 
@@ -103,18 +518,456 @@ func (r *mutationResolver) AddLibrary(
 	}
 
 	// TODO: Move this to a library manager
-	for _, location := range libraryLocations {
-		err := ants.Submit(func() {
-			scanner.ScanDirectory(location.RootPath, *library)
-		})
-		if err != nil {
-			log.Err(err).Msgf("Failed to schedule directory scan for %s", location.RootPath)
-		}
+	scanner.ScanLibrary(library)
+
+	return library, nil
+}
+
+func (r *mutationResolver) SetLibrarySubtitleLanguages(
+	ctx context.Context,
+	id string,
+	languages []string,
+) (*database.Library, error) {
+	library, err := database.SetLibrarySubtitleLanguages(id, languages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set subtitle languages: %w", err)
 	}
 
 	return library, nil
 }
 
+func (r *mutationResolver) ScanLibrary(ctx context.Context, id string) (bool, error) {
+	return scanLibrary(id)
+}
+
+func (r *mutationResolver) FixMatch(
+	ctx context.Context,
+	itemID string,
+	providerID string,
+) (model.Item, error) {
+	if err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	return fixItemMatch(itemID, providerID)
+}
+
+func (r *mutationResolver) Unmatch(ctx context.Context, itemID string) (model.Item, error) {
+	if err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	return unmatchItem(itemID)
+}
+
+func (r *mutationResolver) RefreshMetadata(
+	ctx context.Context,
+	itemID *string,
+	libraryID *string,
+	force *bool,
+) (bool, error) {
+	if err := requireUser(ctx); err != nil {
+		return false, err
+	}
+
+	return refreshMetadata(itemID, libraryID, force)
+}
+
+func (r *mutationResolver) AddRelation(
+	ctx context.Context,
+	sourceID string,
+	targetID string,
+	edgeType string,
+) (bool, error) {
+	if err := requireUser(ctx); err != nil {
+		return false, err
+	}
+
+	return addRelation(sourceID, targetID, edgeType)
+}
+
+func (r *mutationResolver) RemoveRelation(
+	ctx context.Context,
+	sourceID string,
+	targetID string,
+	edgeType string,
+) (bool, error) {
+	if err := requireUser(ctx); err != nil {
+		return false, err
+	}
+
+	return removeRelation(sourceID, targetID, edgeType)
+}
+
+func (r *mutationResolver) CreateCollection(
+	ctx context.Context,
+	title string,
+	summary *string,
+	sortOrder *string,
+) (model.Item, error) {
+	if err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	return createCollection(title, summary, sortOrder)
+}
+
+func (r *mutationResolver) UpdateCollection(
+	ctx context.Context,
+	id string,
+	title *string,
+	summary *string,
+	sortOrder *string,
+	thumb *string,
+	art *string,
+) (model.Item, error) {
+	if err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	return updateCollection(id, title, summary, sortOrder, thumb, art)
+}
+
+func (r *mutationResolver) DeleteCollection(ctx context.Context, id string) (bool, error) {
+	if err := requireUser(ctx); err != nil {
+		return false, err
+	}
+
+	return deleteCollection(id)
+}
+
+func (r *mutationResolver) AddToCollection(
+	ctx context.Context,
+	collectionID string,
+	itemIds []string,
+) (model.Item, error) {
+	if err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	return addToCollection(collectionID, itemIds)
+}
+
+func (r *mutationResolver) RemoveFromCollection(
+	ctx context.Context,
+	collectionID string,
+	itemIds []string,
+) (model.Item, error) {
+	if err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	return removeFromCollection(collectionID, itemIds)
+}
+
+func (r *mutationResolver) MoveCollectionItem(
+	ctx context.Context,
+	collectionID string,
+	itemID string,
+	index int64,
+) (model.Item, error) {
+	if err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	return moveCollectionItem(collectionID, itemID, index)
+}
+
+func (r *mutationResolver) CreateTag(
+	ctx context.Context,
+	name string,
+	parentID *string,
+) (*database.Tag, error) {
+	if err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	return createTag(name, parentID)
+}
+
+func (r *mutationResolver) MoveTag(
+	ctx context.Context,
+	id string,
+	parentID *string,
+) (*database.Tag, error) {
+	if err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	return moveTag(id, parentID)
+}
+
+func (r *mutationResolver) MergeTags(
+	ctx context.Context,
+	sourceID string,
+	targetID string,
+) (*database.Tag, error) {
+	if err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	return mergeTags(sourceID, targetID)
+}
+
+func (r *mutationResolver) DeleteTag(ctx context.Context, id string) (bool, error) {
+	if err := requireUser(ctx); err != nil {
+		return false, err
+	}
+
+	return deleteTag(id)
+}
+
+func (r *mutationResolver) AssignTags(
+	ctx context.Context,
+	itemIds []string,
+	tagIds []string,
+) (bool, error) {
+	if err := requireUser(ctx); err != nil {
+		return false, err
+	}
+
+	return assignTags(itemIds, tagIds)
+}
+
+func (r *mutationResolver) UnassignTags(
+	ctx context.Context,
+	itemIds []string,
+	tagIds []string,
+) (bool, error) {
+	if err := requireUser(ctx); err != nil {
+		return false, err
+	}
+
+	return unassignTags(itemIds, tagIds)
+}
+
+func (r *mutationResolver) EditItem(
+	ctx context.Context,
+	id string,
+	input model.EditItemInput,
+) (model.Item, error) {
+	if err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	return editItem(id, input)
+}
+
+func (r *mutationResolver) KeepDuplicate(
+	ctx context.Context,
+	id string,
+) (*database.DuplicateCandidate, error) {
+	if err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	return reviewDuplicate(id, database.KeepDuplicateCandidate)
+}
+
+func (r *mutationResolver) MergeDuplicate(
+	ctx context.Context,
+	id string,
+	keep *string,
+) (*database.DuplicateCandidate, error) {
+	if err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	return mergeDuplicate(id, keep)
+}
+
+func (r *mutationResolver) HideDuplicate(
+	ctx context.Context,
+	id string,
+) (*database.DuplicateCandidate, error) {
+	if err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	return reviewDuplicate(id, database.HideDuplicateCandidate)
+}
+
+func (r *mutationResolver) UnhideDuplicate(
+	ctx context.Context,
+	id string,
+) (*database.DuplicateCandidate, error) {
+	if err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	return reviewDuplicate(id, database.UnhideDuplicateCandidate)
+}
+
+func (r *mutationResolver) AddFaceRegion(
+	ctx context.Context,
+	itemID string,
+	input model.FaceRegionInput,
+) (*database.FaceRegion, error) {
+	if err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	return addFaceRegion(itemID, input)
+}
+
+func (r *mutationResolver) EditFaceRegion(
+	ctx context.Context,
+	id string,
+	input model.FaceRegionInput,
+) (*database.FaceRegion, error) {
+	if err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	return editFaceRegion(id, input)
+}
+
+func (r *mutationResolver) RemoveFaceRegion(ctx context.Context, id string) (bool, error) {
+	if err := requireUser(ctx); err != nil {
+		return false, err
+	}
+
+	return removeFaceRegion(id)
+}
+
+func (r *mutationResolver) RateItem(
+	ctx context.Context,
+	itemID string,
+	rating *int64,
+) (model.Item, error) {
+	return rateItem(ctx, itemID, rating)
+}
+
+func (r *personResolver) Guids(ctx context.Context, obj *model.Person) ([]*model.GUID, error) {
+	return getItemGuids(obj.ID)
+}
+
+func (r *personResolver) Credits(
+	ctx context.Context,
+	obj *model.Person,
+	role *string,
+	mediaType *string,
+) ([]*database.Credit, error) {
+	return getPersonCredits(obj.ID, role, mediaType)
+}
+
+func (r *personResolver) Tags(ctx context.Context, obj *model.Person) ([]*database.Tag, error) {
+	return getItemTags(obj.ID)
+}
+
+func (r *personResolver) Library(
+	ctx context.Context,
+	obj *model.Person,
+) (*database.Library, error) {
+	return getSharedItemLibrary(obj.ID)
+}
+
+func (r *personResolver) UserRating(ctx context.Context, obj *model.Person) (*int64, error) {
+	return getItemUserRating(ctx, obj.ID)
+}
+
+func (r *personResolver) Ratings(
+	ctx context.Context,
+	obj *model.Person,
+) ([]*database.CommunityRating, error) {
+	return getItemRatings(obj.ID)
+}
+
+func (r *personResolver) MusicVideos(
+	ctx context.Context,
+	obj *model.Person,
+	limit *int64,
+	offset *int64,
+) (*model.ItemsResult, error) {
+	return getArtistItems(obj.ID, database.MusicVideoItem, limit, offset)
+}
+
+func (r *personResolver) Albums(
+	ctx context.Context,
+	obj *model.Person,
+	limit *int64,
+	offset *int64,
+) (*model.ItemsResult, error) {
+	return getArtistItems(obj.ID, database.MusicAlbumItem, limit, offset)
+}
+
+func (r *personResolver) Photos(
+	ctx context.Context,
+	obj *model.Person,
+	limit *int64,
+	offset *int64,
+) (*model.ItemsResult, error) {
+	return getPersonPhotos(obj.ID, limit, offset)
+}
+
+func (r *podcastResolver) Guids(ctx context.Context, obj *model.Podcast) ([]*model.GUID, error) {
+	return getItemGuids(obj.ID)
+}
+
+func (r *podcastResolver) Credits(
+	ctx context.Context,
+	obj *model.Podcast,
+) ([]*database.Credit, error) {
+	return getItemCredits(obj.ID)
+}
+
+func (r *podcastResolver) Tags(ctx context.Context, obj *model.Podcast) ([]*database.Tag, error) {
+	return getItemTags(obj.ID)
+}
+
+func (r *podcastResolver) UserRating(ctx context.Context, obj *model.Podcast) (*int64, error) {
+	return getItemUserRating(ctx, obj.ID)
+}
+
+func (r *podcastResolver) Ratings(
+	ctx context.Context,
+	obj *model.Podcast,
+) ([]*database.CommunityRating, error) {
+	return getItemRatings(obj.ID)
+}
+
+func (r *podcastEpisodeResolver) Guids(
+	ctx context.Context,
+	obj *model.PodcastEpisode,
+) ([]*model.GUID, error) {
+	return getItemGuids(obj.ID)
+}
+
+func (r *podcastEpisodeResolver) Credits(
+	ctx context.Context,
+	obj *model.PodcastEpisode,
+) ([]*database.Credit, error) {
+	return getItemCredits(obj.ID)
+}
+
+func (r *podcastEpisodeResolver) Tags(
+	ctx context.Context,
+	obj *model.PodcastEpisode,
+) ([]*database.Tag, error) {
+	return getItemTags(obj.ID)
+}
+
+func (r *podcastEpisodeResolver) UserRating(
+	ctx context.Context,
+	obj *model.PodcastEpisode,
+) (*int64, error) {
+	return getItemUserRating(ctx, obj.ID)
+}
+
+func (r *podcastEpisodeResolver) Ratings(
+	ctx context.Context,
+	obj *model.PodcastEpisode,
+) ([]*database.CommunityRating, error) {
+	return getItemRatings(obj.ID)
+}
+
+func (r *podcastEpisodeResolver) Chapters(
+	ctx context.Context,
+	obj *model.PodcastEpisode,
+) ([]*database.Chapter, error) {
+	return getItemChapters(obj.ID)
+}
+
 func (r *queryResolver) User(ctx context.Context, id string) (*database.User, error) {
 	user, err := database.GetUserByID(id)
 	if err != nil {
@@ -158,15 +1011,24 @@ func (r *queryResolver) Items(
 	limit *int64,
 	offset *int64,
 	libraryID string,
+	sortOrder *string,
+	minUserRating *int64,
 ) (*model.ItemsResult, error) {
-	items, err := database.GetItemsFromLibrary(libraryID, limit, offset)
+	itemSortOrder, err := parseChildSortOrder(sortOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	ratingFilter := getRatingFilter(ctx, minUserRating)
+
+	items, err := database.GetItemsFromLibrary(libraryID, itemSortOrder, ratingFilter, limit, offset)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get items")
 
 		return nil, fmt.Errorf("failed to get items: %w", err)
 	}
 
-	count, err := database.GetItemsCountFromLibrary(libraryID)
+	count, err := database.GetItemsCountFromLibrary(libraryID, ratingFilter)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get items count")
 
@@ -189,15 +1051,36 @@ func (r *queryResolver) Children(
 	limit *int64,
 	offset *int64,
 	item string,
+	sortOrder *string,
+	minUserRating *int64,
 ) (*model.ItemsResult, error) {
-	items, err := database.GetChildrenFromItem(item, limit, offset)
+	parent, err := database.GetItemByID(item)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get item")
+
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+
+	// Collections hold items from anywhere, rather than being their parent
+	if parent.Type == database.CollectionItem {
+		return getCollectionItems(ctx, item, sortOrder, minUserRating, limit, offset)
+	}
+
+	childSortOrder, err := parseChildSortOrder(sortOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	ratingFilter := getRatingFilter(ctx, minUserRating)
+
+	items, err := database.GetChildrenFromItem(item, childSortOrder, ratingFilter, limit, offset)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get items")
 
 		return nil, fmt.Errorf("failed to get items: %w", err)
 	}
 
-	count, err := database.GetChildrenCountFromItem(item)
+	count, err := database.GetChildrenCountFromItem(item, ratingFilter)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get items count")
 
@@ -262,25 +1145,244 @@ func (r *queryResolver) Latest(ctx context.Context, limit *int64) ([]*model.Late
 	return latest, nil
 }
 
+func (r *queryResolver) SearchMatches(
+	ctx context.Context,
+	itemID string,
+	title *string,
+	year *int64,
+) ([]*model.MatchCandidate, error) {
+	return searchItemMatches(itemID, title, year)
+}
+
+func (r *queryResolver) ItemByExternalID(
+	ctx context.Context,
+	typeArg string,
+	id string,
+) (model.Item, error) {
+	return getItemByExternalID(typeArg, id)
+}
+
+func (r *queryResolver) Related(
+	ctx context.Context,
+	itemID string,
+	edgeTypes []string,
+	depth *int64,
+) ([]*model.RelatedItem, error) {
+	return getRelatedItems(itemID, edgeTypes, depth)
+}
+
+func (r *queryResolver) Collections(
+	ctx context.Context,
+	limit *int64,
+	offset *int64,
+	userDefined *bool,
+) (*model.ItemsResult, error) {
+	return getCollections(limit, offset, userDefined)
+}
+
+func (r *queryResolver) Tag(ctx context.Context, id string) (*database.Tag, error) {
+	return getTag(id)
+}
+
+func (r *queryResolver) Tags(ctx context.Context, parentID *string) ([]*database.Tag, error) {
+	return getTags(parentID)
+}
+
+func (r *queryResolver) ItemsByTag(
+	ctx context.Context,
+	tagID string,
+	includeDescendants *bool,
+	limit *int64,
+	offset *int64,
+) (*model.ItemsResult, error) {
+	return getTagItems(tagID, includeDescendants, limit, offset)
+}
+
+func (r *queryResolver) Places(
+	ctx context.Context,
+	libraryID string,
+	path []string,
+) ([]*database.Place, error) {
+	return getPlaces(libraryID, path)
+}
+
+func (r *queryResolver) ItemsInPlace(
+	ctx context.Context,
+	libraryID string,
+	path []string,
+	limit *int64,
+	offset *int64,
+) (*model.ItemsResult, error) {
+	return getPlaceItems(libraryID, path, limit, offset)
+}
+
+func (r *queryResolver) ItemsInBounds(
+	ctx context.Context,
+	libraryID string,
+	bounds model.BoundsInput,
+	limit *int64,
+	offset *int64,
+) (*model.ItemsResult, error) {
+	return getItemsInBounds(libraryID, bounds, limit, offset)
+}
+
+func (r *queryResolver) MapClusters(
+	ctx context.Context,
+	libraryID string,
+	bounds model.BoundsInput,
+	zoom int64,
+) ([]*database.MapCluster, error) {
+	return getMapClusters(libraryID, bounds, zoom)
+}
+
+func (r *queryResolver) DuplicateCandidates(
+	ctx context.Context,
+	libraryID *string,
+	limit *int64,
+	offset *int64,
+) (*model.DuplicateCandidatesResult, error) {
+	return getDuplicateCandidates(libraryID, limit, offset)
+}
+
+func (r *queryResolver) DuplicateGroups(
+	ctx context.Context,
+	libraryID *string,
+	limit *int64,
+	offset *int64,
+) (*model.DuplicateGroupsResult, error) {
+	return getDuplicateGroups(libraryID, limit, offset)
+}
+
+func (r *queryResolver) PhotosOfPerson(
+	ctx context.Context,
+	personID string,
+	limit *int64,
+	offset *int64,
+) (*model.ItemsResult, error) {
+	return getPersonPhotos(personID, limit, offset)
+}
+
+func (r *tagResolver) ID(ctx context.Context, obj *database.Tag) (string, error) {
+	return strconv.FormatUint(obj.ID, 10), nil //nolint:gomnd
+}
+
+func (r *tagResolver) Path(ctx context.Context, obj *database.Tag) ([]string, error) {
+	return getTagPath(obj)
+}
+
+func (r *tagResolver) Parent(ctx context.Context, obj *database.Tag) (*database.Tag, error) {
+	return getTagParent(obj)
+}
+
+func (r *tagResolver) Children(ctx context.Context, obj *database.Tag) ([]*database.Tag, error) {
+	return getTagChildren(obj.ID)
+}
+
+func (r *tagResolver) Items(
+	ctx context.Context,
+	obj *database.Tag,
+	includeDescendants *bool,
+	limit *int64,
+	offset *int64,
+) (*model.ItemsResult, error) {
+	return getTagItems(strconv.FormatUint(obj.ID, 10), includeDescendants, limit, offset) //nolint:gomnd
+}
+
 func (r *userResolver) ID(ctx context.Context, obj *database.User) (string, error) {
 	return strconv.FormatUint(obj.ID, 10), nil //nolint:gomnd
 }
 
+// Book returns generated.BookResolver implementation.
+func (r *Resolver) Book() generated.BookResolver { return &bookResolver{r} }
+
+// BookPart returns generated.BookPartResolver implementation.
+func (r *Resolver) BookPart() generated.BookPartResolver { return &bookPartResolver{r} }
+
+// Collection returns generated.CollectionResolver implementation.
+func (r *Resolver) Collection() generated.CollectionResolver { return &collectionResolver{r} }
+
+// Credit returns generated.CreditResolver implementation.
+func (r *Resolver) Credit() generated.CreditResolver { return &creditResolver{r} }
+
+// DuplicateCandidate returns generated.DuplicateCandidateResolver implementation.
+func (r *Resolver) DuplicateCandidate() generated.DuplicateCandidateResolver {
+	return &duplicateCandidateResolver{r}
+}
+
+// FaceRegion returns generated.FaceRegionResolver implementation.
+func (r *Resolver) FaceRegion() generated.FaceRegionResolver { return &faceRegionResolver{r} }
+
+// Group returns generated.GroupResolver implementation.
+func (r *Resolver) Group() generated.GroupResolver { return &groupResolver{r} }
+
+// Image returns generated.ImageResolver implementation.
+func (r *Resolver) Image() generated.ImageResolver { return &imageResolver{r} }
+
+// ImageAlbum returns generated.ImageAlbumResolver implementation.
+func (r *Resolver) ImageAlbum() generated.ImageAlbumResolver { return &imageAlbumResolver{r} }
+
 // Library returns generated.LibraryResolver implementation.
 func (r *Resolver) Library() generated.LibraryResolver { return &libraryResolver{r} }
 
+// MapCluster returns generated.MapClusterResolver implementation.
+func (r *Resolver) MapCluster() generated.MapClusterResolver { return &mapClusterResolver{r} }
+
+// MediaPart returns generated.MediaPartResolver implementation.
+func (r *Resolver) MediaPart() generated.MediaPartResolver { return &mediaPartResolver{r} }
+
+// Movie returns generated.MovieResolver implementation.
+func (r *Resolver) Movie() generated.MovieResolver { return &movieResolver{r} }
+
+// MusicAlbum returns generated.MusicAlbumResolver implementation.
+func (r *Resolver) MusicAlbum() generated.MusicAlbumResolver { return &musicAlbumResolver{r} }
+
+// MusicVideo returns generated.MusicVideoResolver implementation.
+func (r *Resolver) MusicVideo() generated.MusicVideoResolver { return &musicVideoResolver{r} }
+
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
+// Person returns generated.PersonResolver implementation.
+func (r *Resolver) Person() generated.PersonResolver { return &personResolver{r} }
+
+// Podcast returns generated.PodcastResolver implementation.
+func (r *Resolver) Podcast() generated.PodcastResolver { return &podcastResolver{r} }
+
+// PodcastEpisode returns generated.PodcastEpisodeResolver implementation.
+func (r *Resolver) PodcastEpisode() generated.PodcastEpisodeResolver {
+	return &podcastEpisodeResolver{r}
+}
+
 // Query returns generated.QueryResolver implementation.
 func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
 
+// Tag returns generated.TagResolver implementation.
+func (r *Resolver) Tag() generated.TagResolver { return &tagResolver{r} }
+
 // User returns generated.UserResolver implementation.
 func (r *Resolver) User() generated.UserResolver { return &userResolver{r} }
 
 type (
-	libraryResolver  struct{ *Resolver }
-	mutationResolver struct{ *Resolver }
-	queryResolver    struct{ *Resolver }
-	userResolver     struct{ *Resolver }
+	bookResolver               struct{ *Resolver }
+	bookPartResolver           struct{ *Resolver }
+	collectionResolver         struct{ *Resolver }
+	creditResolver             struct{ *Resolver }
+	duplicateCandidateResolver struct{ *Resolver }
+	faceRegionResolver         struct{ *Resolver }
+	groupResolver              struct{ *Resolver }
+	imageResolver              struct{ *Resolver }
+	imageAlbumResolver         struct{ *Resolver }
+	libraryResolver            struct{ *Resolver }
+	mapClusterResolver         struct{ *Resolver }
+	mediaPartResolver          struct{ *Resolver }
+	movieResolver              struct{ *Resolver }
+	musicAlbumResolver         struct{ *Resolver }
+	musicVideoResolver         struct{ *Resolver }
+	mutationResolver           struct{ *Resolver }
+	personResolver             struct{ *Resolver }
+	podcastResolver            struct{ *Resolver }
+	podcastEpisodeResolver     struct{ *Resolver }
+	queryResolver              struct{ *Resolver }
+	tagResolver                struct{ *Resolver }
+	userResolver               struct{ *Resolver }
 )
@@ -0,0 +1,276 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/graph/model"
+	"github.com/meteorae/meteorae-server/helpers"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Returns the tags of the given item.
+func getItemTags(itemID string) ([]*database.Tag, error) {
+	tags, err := database.GetTagsFromItem(itemID)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get tags for item %s", itemID)
+
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+// Returns the given tag, or nil if there is none.
+func getTag(tagID string) (*database.Tag, error) {
+	tag, err := database.GetTagByID(tagID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil //nolint:nilnil
+	}
+
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get tag %s", tagID)
+
+		return nil, fmt.Errorf("failed to get tag: %w", err)
+	}
+
+	return tag, nil
+}
+
+// Returns the children of the given tag, or the root tags when there is no parent.
+func getTags(parentID *string) ([]*database.Tag, error) {
+	id, err := parseOptionalTagID(parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return getTagChildren(id)
+}
+
+func getTagChildren(parentID uint64) ([]*database.Tag, error) {
+	tags, err := database.GetTagChildren(parentID)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get children of tag %d", parentID)
+
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+func getTagParent(tag *database.Tag) (*database.Tag, error) {
+	if tag.ParentID == 0 {
+		return nil, nil //nolint:nilnil
+	}
+
+	return getTag(strconv.FormatUint(tag.ParentID, 10)) //nolint:gomnd
+}
+
+// Returns the names of the tag and its ancestors, root first.
+func getTagPath(tag *database.Tag) ([]string, error) {
+	path, err := database.GetTagPath(tag)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get path of tag %d", tag.ID)
+
+		return nil, fmt.Errorf("failed to get tag path: %w", err)
+	}
+
+	names := make([]string, 0, len(path))
+	for _, ancestor := range path {
+		names = append(names, ancestor.Name)
+	}
+
+	return names, nil
+}
+
+// Returns the items with the given tag, and optionally with any of its descendants.
+func getTagItems(tagID string, includeDescendants *bool, limit, offset *int64) (*model.ItemsResult, error) {
+	id, err := strconv.ParseUint(tagID, 10, 64) //nolint:gomnd
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag identifier %s: %w", tagID, err)
+	}
+
+	withDescendants := includeDescendants != nil && *includeDescendants
+
+	items, err := database.GetItemsFromTag(id, withDescendants, limit, offset)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get items for tag %s", tagID)
+
+		return nil, fmt.Errorf("failed to get items: %w", err)
+	}
+
+	count, err := database.GetItemsCountFromTag(id, withDescendants)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get items count for tag %s", tagID)
+
+		return nil, fmt.Errorf("failed to get items count: %w", err)
+	}
+
+	return &model.ItemsResult{
+		Items: helpers.GetItemsFromItemMetadata(items),
+		Total: count,
+	}, nil
+}
+
+func createTag(name string, parentID *string) (*database.Tag, error) {
+	id, err := parseOptionalTagID(parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if id != 0 {
+		if _, err := database.GetTagByID(*parentID); err != nil {
+			log.Error().Err(err).Msgf("Failed to get tag %s", *parentID)
+
+			return nil, fmt.Errorf("failed to get parent tag: %w", err)
+		}
+	}
+
+	tag, err := database.CreateTag(name, id)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to create tag \"%s\"", name)
+
+		return nil, fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	return tag, nil
+}
+
+func moveTag(tagID string, parentID *string) (*database.Tag, error) {
+	ids, err := parseTagIDs([]string{tagID})
+	if err != nil {
+		return nil, err
+	}
+
+	parent, err := parseOptionalTagID(parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := database.MoveTag(ids[0], parent)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to move tag %s", tagID)
+
+		return nil, fmt.Errorf("failed to move tag: %w", err)
+	}
+
+	return tag, nil
+}
+
+// Merges the source tag into the target tag, and returns the target tag.
+func mergeTags(sourceID, targetID string) (*database.Tag, error) {
+	ids, err := parseTagIDs([]string{sourceID, targetID})
+	if err != nil {
+		return nil, err
+	}
+
+	if ids[0] == ids[1] {
+		return nil, fmt.Errorf("%w: %s", errSelfMerge, sourceID)
+	}
+
+	target, err := database.GetTagByID(targetID)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get tag %s", targetID)
+
+		return nil, fmt.Errorf("failed to get tag: %w", err)
+	}
+
+	if err := database.MergeTags(ids[0], ids[1]); err != nil {
+		log.Error().Err(err).Msgf("Failed to merge tag %s into tag %s", sourceID, targetID)
+
+		return nil, fmt.Errorf("failed to merge tags: %w", err)
+	}
+
+	return target, nil
+}
+
+func deleteTag(tagID string) (bool, error) {
+	ids, err := parseTagIDs([]string{tagID})
+	if err != nil {
+		return false, err
+	}
+
+	if err := database.DeleteTag(ids[0]); err != nil {
+		log.Error().Err(err).Msgf("Failed to delete tag %s", tagID)
+
+		return false, fmt.Errorf("failed to delete tag: %w", err)
+	}
+
+	return true, nil
+}
+
+func assignTags(itemIDs, tagIDs []string) (bool, error) {
+	items, tags, err := parseTagAssignment(itemIDs, tagIDs)
+	if err != nil {
+		return false, err
+	}
+
+	if err := database.AssignTags(items, tags); err != nil {
+		log.Error().Err(err).Msg("Failed to assign tags")
+
+		return false, fmt.Errorf("failed to assign tags: %w", err)
+	}
+
+	return true, nil
+}
+
+func unassignTags(itemIDs, tagIDs []string) (bool, error) {
+	items, tags, err := parseTagAssignment(itemIDs, tagIDs)
+	if err != nil {
+		return false, err
+	}
+
+	if err := database.UnassignTags(items, tags); err != nil {
+		log.Error().Err(err).Msg("Failed to unassign tags")
+
+		return false, fmt.Errorf("failed to unassign tags: %w", err)
+	}
+
+	return true, nil
+}
+
+func parseTagAssignment(itemIDs, tagIDs []string) ([]uint64, []uint64, error) {
+	items, err := parseTagIDs(itemIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tags, err := parseTagIDs(tagIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return items, tags, nil
+}
+
+func parseTagIDs(ids []string) ([]uint64, error) {
+	parsed := make([]uint64, 0, len(ids))
+
+	for _, id := range ids {
+		parsedID, err := strconv.ParseUint(id, 10, 64) //nolint:gomnd
+		if err != nil {
+			return nil, fmt.Errorf("invalid identifier %s: %w", id, err)
+		}
+
+		parsed = append(parsed, parsedID)
+	}
+
+	return parsed, nil
+}
+
+// Parses an optional tag identifier, where 0 stands for the root of the tag tree.
+func parseOptionalTagID(id *string) (uint64, error) {
+	if id == nil {
+		return 0, nil
+	}
+
+	ids, err := parseTagIDs([]string{*id})
+	if err != nil {
+		return 0, err
+	}
+
+	return ids[0], nil
+}
@@ -3,17 +3,20 @@ package helpers
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"io"
 	"io/fs"
 	"io/ioutil"
-	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/adrg/xdg"
 	"github.com/bmatcuk/doublestar/v4"
 	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/meteorae/meteorae-server/providers/httpclient"
 	"github.com/meteorae/meteorae-server/utils"
 )
 
@@ -27,6 +30,15 @@ var (
 	BaseFilePermissions      = os.FileMode(BaseFileMode)
 )
 
+var (
+	// Returned for images given by users which are neither public URLs nor images already in the cache.
+	ErrInvalidImageLocation = errors.New("images must be http or https URLs, or the hash of a cached image")
+	errInvalidImageHash     = errors.New("invalid image hash")
+)
+
+// Matches the hashes of cached images, see SaveImageToCache.
+var imageHashRegexp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
 var VideoFileExtensions = []string{
 	".m4v",
 	".3gp",
@@ -234,10 +246,71 @@ func ShouldIgnore(path string, d fs.DirEntry) bool {
 	return isMatched
 }
 
+// Returns the paths of the existing XMP sidecars of a file. Sidecars are named either like
+// photo.jpg.xmp, as written by digiKam and darktable, or like photo.xmp, as written by Lightroom.
+func GetSidecarPaths(filePath string) []string {
+	basePath := strings.TrimSuffix(filePath, filepath.Ext(filePath))
+
+	var sidecarPaths []string
+
+	for _, sidecarPath := range []string{
+		filePath + ".xmp",
+		filePath + ".XMP",
+		basePath + ".xmp",
+		basePath + ".XMP",
+	} {
+		if fileInfo, err := os.Stat(sidecarPath); err == nil && !fileInfo.IsDir() {
+			sidecarPaths = append(sidecarPaths, sidecarPath)
+		}
+	}
+
+	return sidecarPaths
+}
+
 func EnsurePathExists(path string) error {
 	return fmt.Errorf("failed to ensure path exists: %w", os.MkdirAll(path, BaseDirectoryPermissions))
 }
 
+// Saves an image to the image cache, either from a remote URL or from a local file path.
+// Returns the hash of the image file.
+func SaveImageToCache(location string) (string, error) {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return SaveExternalImageToCache(location)
+	}
+
+	return SaveLocalImageToCache(location)
+}
+
+// Saves an image given by a user, like custom artwork, to the image cache, and returns its hash.
+// Only the hashes of images already in the cache and http or https URLs to public addresses are accepted,
+// so users can't read files from the server or reach services on its network.
+func SaveUserImageToCache(location string) (string, error) {
+	if imageHashRegexp.MatchString(location) {
+		cachedFilePath, err := GetCachedImagePath(location)
+		if err != nil {
+			return "", err
+		}
+
+		if _, err := os.Stat(cachedFilePath); err != nil {
+			return "", fmt.Errorf("%w: %s isn't cached", ErrInvalidImageLocation, location)
+		}
+
+		return location, nil
+	}
+
+	parsedURL, err := url.Parse(location)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return "", ErrInvalidImageLocation
+	}
+
+	file, err := httpclient.DownloadPublic(location)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch image: %w", err)
+	}
+
+	return SaveImageDataToCache(file)
+}
+
 // Saves a local image file to the image cache.
 // Returns the hash of the image file.
 func SaveLocalImageToCache(filePath string) (string, error) {
@@ -246,43 +319,29 @@ func SaveLocalImageToCache(filePath string) (string, error) {
 		return "", fmt.Errorf("failed to open local image file: %w", err)
 	}
 
-	return saveImageToCache(file)
+	return SaveImageDataToCache(file)
 }
 
 // Saves a remote image file to the image cache.
 // Returns the hash of the image file.
 func SaveExternalImageToCache(filePath string) (string, error) {
-	var fileBuffer bytes.Buffer
-
-	response, err := http.Get(filePath) //#nosec
+	file, err := httpclient.Download(filePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch image \"%s\": %w", filePath, err)
 	}
-	defer response.Body.Close()
 
-	_, err = io.Copy(&fileBuffer, response.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to copy remote image into memory: %w", err)
-	}
-
-	return saveImageToCache(fileBuffer.Bytes())
+	return SaveImageDataToCache(file)
 }
 
-// Internal method to generate the hash of the image file and save it to the cache.
+// Generates the hash of an image file and saves it to the cache, converted to WebP.
 // Returns the hash of the image file.
-func saveImageToCache(file []byte) (string, error) {
+func SaveImageDataToCache(file []byte) (string, error) {
 	hash, err := utils.HashFileBytes(file)
 	if err != nil {
 		return "", fmt.Errorf("failed to hash remote image file: %w", err)
 	}
 
 	fileHash := hex.EncodeToString(hash)
-	prefix := fileHash[0:2]
-
-	imageCachePath, err := xdg.CacheFile("meteorae/images")
-	if err != nil {
-		return "", fmt.Errorf("failed to get image cache path: %w", err)
-	}
 
 	fileBuffer := bytes.NewBuffer(file)
 
@@ -296,19 +355,45 @@ func saveImageToCache(file []byte) (string, error) {
 		return "", fmt.Errorf("failed to set image format: %w", err)
 	}
 
-	cachedFilePath := filepath.Join(imageCachePath, prefix, fileHash)
+	err = WriteCachedImage(fileHash, export)
+	if err != nil {
+		return "", err
+	}
 
-	err = os.MkdirAll(cachedFilePath, BaseDirectoryPermissions)
+	return fileHash, nil
+}
+
+// Returns the path of the full size version of a cached image, as saved by SaveImageToCache.
+func GetCachedImagePath(hash string) (string, error) {
+	// Anything else could escape the image cache
+	if !imageHashRegexp.MatchString(hash) {
+		return "", fmt.Errorf("%w: %q", errInvalidImageHash, hash)
+	}
+
+	imageCachePath, err := xdg.CacheFile("meteorae/images")
 	if err != nil {
-		return "", fmt.Errorf("failed to create image cache directory: %w", err)
+		return "", fmt.Errorf("failed to get image cache path: %w", err)
 	}
 
-	cachedFilePath = filepath.Join(cachedFilePath, "0x0.webp")
+	return filepath.Join(imageCachePath, hash[0:2], hash, "0x0.webp"), nil
+}
 
-	err = ioutil.WriteFile(cachedFilePath, export, BaseFilePermissions)
+// Writes the full size version of an image to the cache, for images already converted by SaveImageToCache.
+func WriteCachedImage(hash string, image []byte) error {
+	cachedFilePath, err := GetCachedImagePath(hash)
 	if err != nil {
-		return "", fmt.Errorf("failed to write image to disk: %w", err)
+		return err
 	}
 
-	return fileHash, nil
+	err = os.MkdirAll(filepath.Dir(cachedFilePath), BaseDirectoryPermissions)
+	if err != nil {
+		return fmt.Errorf("failed to create image cache directory: %w", err)
+	}
+
+	err = ioutil.WriteFile(cachedFilePath, image, BaseFilePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to write image to disk: %w", err)
+	}
+
+	return nil
 }
@@ -0,0 +1,25 @@
+package helpers_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/meteorae/meteorae-server/helpers"
+)
+
+func TestSaveUserImageToCacheRejectsLocalFiles(t *testing.T) {
+	for _, location := range []string{
+		"/etc/passwd",
+		"../config.yaml",
+		"file:///etc/passwd",
+		"ftp://example.com/poster.jpg",
+		"http:///poster.jpg",
+		// A hash which isn't in the cache
+		strings.Repeat("0", 64),
+	} {
+		if _, err := helpers.SaveUserImageToCache(location); !errors.Is(err, helpers.ErrInvalidImageLocation) {
+			t.Errorf("SaveUserImageToCache(%q) error = %v, want ErrInvalidImageLocation", location, err)
+		}
+	}
+}
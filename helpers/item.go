@@ -1,11 +1,13 @@
 package helpers
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 
 	"github.com/meteorae/meteorae-server/database"
 	"github.com/meteorae/meteorae-server/graph/model"
+	"github.com/rs/zerolog/log"
 )
 
 func GetItemFromItemMetadata(itemMetadata *database.ItemMetadata) *model.Item {
@@ -24,52 +26,193 @@ func GetItemFromItemMetadata(itemMetadata *database.ItemMetadata) *model.Item {
 	var item model.Item
 
 	switch itemMetadata.Type {
-	case database.MovieItem:
+	case database.MovieItem, database.AnimeMovieItem:
 		isoReleaseDate := itemMetadata.ReleaseDate.Format("2006-01-02")
 
 		item = model.Movie{
-			ID:          itemID,
-			Title:       itemMetadata.Title,
-			ReleaseDate: &isoReleaseDate,
-			Summary:     &itemMetadata.Summary,
-			Thumb:       &thumbURL,
-			Art:         &artURL,
-			Library:     &itemMetadata.Library,
-			CreatedAt:   itemMetadata.CreatedAt,
-			UpdatedAt:   itemMetadata.UpdatedAt,
+			ID:           itemID,
+			Title:        itemMetadata.Title,
+			ReleaseDate:  &isoReleaseDate,
+			Summary:      &itemMetadata.Summary,
+			Thumb:        &thumbURL,
+			Art:          &artURL,
+			Library:      &itemMetadata.Library,
+			CreatedAt:    itemMetadata.CreatedAt,
+			UpdatedAt:    itemMetadata.UpdatedAt,
+			LockedFields: itemMetadata.GetLockedFields(),
 		}
 	case database.ImageAlbumItem:
 		item = model.ImageAlbum{
-			ID:        itemID,
-			Title:     itemMetadata.Title,
-			Summary:   &itemMetadata.Summary,
-			Thumb:     &thumbURL,
-			Art:       &artURL,
-			Library:   &itemMetadata.Library,
-			CreatedAt: itemMetadata.CreatedAt,
-			UpdatedAt: itemMetadata.UpdatedAt,
+			ID:           itemID,
+			Title:        itemMetadata.Title,
+			Summary:      &itemMetadata.Summary,
+			Thumb:        &thumbURL,
+			Art:          &artURL,
+			Library:      &itemMetadata.Library,
+			CreatedAt:    itemMetadata.CreatedAt,
+			UpdatedAt:    itemMetadata.UpdatedAt,
+			LockedFields: itemMetadata.GetLockedFields(),
 		}
 	case database.ImageItem:
+		var extraInfo database.ImageExtraInfo
+		if len(itemMetadata.ExtraInfo) > 0 {
+			if err := json.Unmarshal(itemMetadata.ExtraInfo, &extraInfo); err != nil {
+				log.Err(err).Msgf("Failed to read image information for item %d", itemMetadata.ID)
+			}
+		}
+
+		rating := int64(extraInfo.Rating)
+
 		item = model.Image{
-			ID:        itemID,
-			Title:     itemMetadata.Title,
-			Summary:   &itemMetadata.Summary,
-			Thumb:     &thumbURL,
-			Art:       &artURL,
-			Library:   &itemMetadata.Library,
-			CreatedAt: itemMetadata.CreatedAt,
-			UpdatedAt: itemMetadata.UpdatedAt,
+			ID:           itemID,
+			Title:        itemMetadata.Title,
+			Summary:      &itemMetadata.Summary,
+			Thumb:        &thumbURL,
+			Art:          &artURL,
+			Library:      &itemMetadata.Library,
+			CreatedAt:    itemMetadata.CreatedAt,
+			UpdatedAt:    itemMetadata.UpdatedAt,
+			LockedFields: itemMetadata.GetLockedFields(),
+			Rating:       &rating,
+		}
+	case database.MusicVideoItem:
+		isoReleaseDate := itemMetadata.ReleaseDate.Format("2006-01-02")
+
+		item = model.MusicVideo{
+			ID:           itemID,
+			Title:        itemMetadata.Title,
+			ReleaseDate:  &isoReleaseDate,
+			Summary:      &itemMetadata.Summary,
+			Thumb:        &thumbURL,
+			Art:          &artURL,
+			Library:      &itemMetadata.Library,
+			CreatedAt:    itemMetadata.CreatedAt,
+			UpdatedAt:    itemMetadata.UpdatedAt,
+			LockedFields: itemMetadata.GetLockedFields(),
+		}
+	case database.MusicAlbumItem:
+		isoReleaseDate := itemMetadata.ReleaseDate.Format("2006-01-02")
+
+		item = model.MusicAlbum{
+			ID:           itemID,
+			Title:        itemMetadata.Title,
+			ReleaseDate:  &isoReleaseDate,
+			Summary:      &itemMetadata.Summary,
+			Thumb:        &thumbURL,
+			Art:          &artURL,
+			Library:      &itemMetadata.Library,
+			CreatedAt:    itemMetadata.CreatedAt,
+			UpdatedAt:    itemMetadata.UpdatedAt,
+			LockedFields: itemMetadata.GetLockedFields(),
+		}
+	case database.PersonItem:
+		item = model.Person{
+			ID:           itemID,
+			Title:        itemMetadata.Title,
+			Summary:      &itemMetadata.Summary,
+			Thumb:        &thumbURL,
+			Art:          &artURL,
+			CreatedAt:    itemMetadata.CreatedAt,
+			UpdatedAt:    itemMetadata.UpdatedAt,
+			LockedFields: itemMetadata.GetLockedFields(),
+		}
+	case database.GroupItem:
+		item = model.Group{
+			ID:           itemID,
+			Title:        itemMetadata.Title,
+			Summary:      &itemMetadata.Summary,
+			Thumb:        &thumbURL,
+			Art:          &artURL,
+			CreatedAt:    itemMetadata.CreatedAt,
+			UpdatedAt:    itemMetadata.UpdatedAt,
+			LockedFields: itemMetadata.GetLockedFields(),
+		}
+	case database.AudiobookItem:
+		isoReleaseDate := itemMetadata.ReleaseDate.Format("2006-01-02")
+
+		var extraInfo database.AudiobookExtraInfo
+		if len(itemMetadata.ExtraInfo) > 0 {
+			if err := json.Unmarshal(itemMetadata.ExtraInfo, &extraInfo); err != nil {
+				log.Err(err).Msgf("Failed to read audiobook information for item %d", itemMetadata.ID)
+			}
+		}
+
+		item = model.Book{
+			ID:           itemID,
+			Title:        itemMetadata.Title,
+			ReleaseDate:  &isoReleaseDate,
+			Summary:      &itemMetadata.Summary,
+			Thumb:        &thumbURL,
+			Art:          &artURL,
+			Library:      &itemMetadata.Library,
+			CreatedAt:    itemMetadata.CreatedAt,
+			UpdatedAt:    itemMetadata.UpdatedAt,
+			LockedFields: itemMetadata.GetLockedFields(),
+			Author:       &extraInfo.Author,
+			Narrator:     &extraInfo.Narrator,
+			Series:       &extraInfo.Series,
+			SeriesIndex:  &extraInfo.SeriesIndex,
+			Duration:     &itemMetadata.Duration,
+		}
+	case database.AudiobookPartItem:
+		item = model.BookPart{
+			ID:           itemID,
+			Title:        itemMetadata.Title,
+			Summary:      &itemMetadata.Summary,
+			Thumb:        &thumbURL,
+			Art:          &artURL,
+			Library:      &itemMetadata.Library,
+			CreatedAt:    itemMetadata.CreatedAt,
+			UpdatedAt:    itemMetadata.UpdatedAt,
+			LockedFields: itemMetadata.GetLockedFields(),
+			Index:        &itemMetadata.Index,
+		}
+	case database.PodcastItem:
+		item = model.Podcast{
+			ID:           itemID,
+			Title:        itemMetadata.Title,
+			Summary:      &itemMetadata.Summary,
+			Thumb:        &thumbURL,
+			Art:          &artURL,
+			Library:      &itemMetadata.Library,
+			CreatedAt:    itemMetadata.CreatedAt,
+			UpdatedAt:    itemMetadata.UpdatedAt,
+			LockedFields: itemMetadata.GetLockedFields(),
+		}
+	case database.PodcastEpisodeItem:
+		isoReleaseDate := itemMetadata.ReleaseDate.Format("2006-01-02")
+
+		item = model.PodcastEpisode{
+			ID:           itemID,
+			Title:        itemMetadata.Title,
+			ReleaseDate:  &isoReleaseDate,
+			Summary:      &itemMetadata.Summary,
+			Thumb:        &thumbURL,
+			Art:          &artURL,
+			Library:      &itemMetadata.Library,
+			CreatedAt:    itemMetadata.CreatedAt,
+			UpdatedAt:    itemMetadata.UpdatedAt,
+			LockedFields: itemMetadata.GetLockedFields(),
+			Duration:     &itemMetadata.Duration,
+		}
+	case database.CollectionItem:
+		item = model.Collection{
+			ID:           itemID,
+			Title:        itemMetadata.Title,
+			Summary:      &itemMetadata.Summary,
+			Thumb:        &thumbURL,
+			Art:          &artURL,
+			CreatedAt:    itemMetadata.CreatedAt,
+			UpdatedAt:    itemMetadata.UpdatedAt,
+			LockedFields: itemMetadata.GetLockedFields(),
+			UserDefined:  database.IsUserCollection(itemMetadata),
+			SortOrder:    database.GetCollectionSortOrder(itemMetadata).String(),
 		}
 	case database.AnimeEpisodeItem,
-		database.AnimeMovieItem,
 		database.AnimeSeasonItem,
 		database.AnimeShowItem,
-		database.CollectionItem,
-		database.GroupItem,
-		database.MusicAlbumItem,
 		database.MusicMediumItem,
 		database.MusicTrackItem,
-		database.PersonItem,
 		database.TVEpisodeItem,
 		database.TVSeasonItem,
 		database.TVShowItem:
@@ -78,3 +221,16 @@ func GetItemFromItemMetadata(itemMetadata *database.ItemMetadata) *model.Item {
 
 	return &item
 }
+
+// Converts a list of item metadata, skipping the items that can't be represented yet.
+func GetItemsFromItemMetadata(itemsMetadata []*database.ItemMetadata) []model.Item {
+	items := make([]model.Item, 0, len(itemsMetadata))
+
+	for _, itemMetadata := range itemsMetadata {
+		if item := GetItemFromItemMetadata(itemMetadata); item != nil {
+			items = append(items, *item)
+		}
+	}
+
+	return items
+}
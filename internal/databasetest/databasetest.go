@@ -0,0 +1,21 @@
+// Package databasetest sets up databases for the tests of the packages using the database.
+package databasetest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/meteorae/meteorae-server/database"
+)
+
+// Replaces the database with an empty one in a temporary directory, for the duration of a test.
+func Setup(t testing.TB) {
+	t.Helper()
+
+	restore, err := database.ReplaceWithEmptyDatabase(filepath.Join(t.TempDir(), "meteorae.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(restore)
+}
@@ -16,8 +16,14 @@ import (
 	"github.com/getsentry/sentry-go"
 	_ "github.com/meteorae/meteorae-server/config"
 	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/filesystem/analyzer"
 	"github.com/meteorae/meteorae-server/helpers"
 	_ "github.com/meteorae/meteorae-server/logging"
+	_ "github.com/meteorae/meteorae-server/providers/all"
+	"github.com/meteorae/meteorae-server/providers/anidb"
+	"github.com/meteorae/meteorae-server/providers/httpclient"
+	"github.com/meteorae/meteorae-server/providers/refresher"
+	"github.com/meteorae/meteorae-server/providers/subtitles"
 	_ "github.com/meteorae/meteorae-server/resolvers/all"
 	"github.com/meteorae/meteorae-server/server"
 	"github.com/panjf2000/ants/v2"
@@ -62,6 +68,15 @@ func main() {
 	vips.Startup(nil)
 	defer vips.Shutdown()
 
+	refresherCtx, stopRefresher := context.WithCancel(context.Background())
+	defer stopRefresher()
+
+	refresher.Start(refresherCtx)
+	anidb.StartTitleImports(refresherCtx)
+	httpclient.StartCachePruning(refresherCtx)
+	analyzer.StartVideoFingerprinting(refresherCtx)
+	subtitles.StartDataPruning(refresherCtx)
+
 	srv, err := server.GetWebServer()
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to initialize web server")
@@ -0,0 +1,121 @@
+// Package acoustid identifies audio files from their Chromaprint fingerprint, with the AcoustID web service
+// or a compatible server, which link fingerprints to MusicBrainz recordings.
+package acoustid
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/meteorae/meteorae-server/providers/httpclient"
+	"github.com/spf13/viper"
+)
+
+const serviceHost = "api.acoustid.org"
+
+var (
+	// Returned when looking fingerprints up with the AcoustID service without an application key.
+	ErrMissingAPIKey = errors.New("the AcoustID service needs an application key")
+	errLookupFailed  = errors.New("lookup failed")
+)
+
+func init() {
+	// The AcoustID service allows 3 requests per second
+	httpclient.SetRateLimit(serviceHost, 3) //nolint:gomnd
+}
+
+// A recording the fingerprint of a file matches.
+type Match struct {
+	// The MusicBrainz identifier of the recording.
+	RecordingID string
+	// How well the fingerprint matches, from 0 to 1.
+	Score float64
+}
+
+type lookupResponse struct {
+	Status string `json:"status"`
+	Error  struct {
+		Message string `json:"message"`
+	} `json:"error"`
+	Results []struct {
+		ID         string  `json:"id"`
+		Score      float64 `json:"score"`
+		Recordings []struct {
+			ID string `json:"id"`
+		} `json:"recordings"`
+	} `json:"results"`
+}
+
+// Returns the recordings matching a fingerprint, best match first. Duration is the length of the whole file.
+func Lookup(fingerprint string, duration time.Duration) ([]Match, error) {
+	parameters := url.Values{
+		"format":      {"json"},
+		"meta":        {"recordingids"},
+		"duration":    {strconv.Itoa(int(duration.Round(time.Second).Seconds()))},
+		"fingerprint": {fingerprint},
+	}
+
+	baseURL := strings.TrimSuffix(viper.GetString("providers.acoustid.url"), "/")
+
+	// Compatible servers may not need an application key
+	if apiKey := viper.GetString("providers.acoustid.api_key"); apiKey != "" {
+		parameters.Set("client", apiKey)
+	} else if parsedURL, err := url.Parse(baseURL); err == nil && parsedURL.Host == serviceHost {
+		return nil, ErrMissingAPIKey
+	}
+
+	body, err := httpclient.Get(fmt.Sprintf("%s/lookup?%s", baseURL, parameters.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to call AcoustID: %w", err)
+	}
+
+	var response lookupResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode AcoustID response: %w", err)
+	}
+
+	if response.Status != "ok" {
+		return nil, fmt.Errorf("%w: %s", errLookupFailed, response.Error.Message)
+	}
+
+	var matches []Match
+
+	seen := make(map[string]bool)
+
+	for _, result := range response.Results {
+		for _, recording := range result.Recordings {
+			if seen[recording.ID] {
+				continue
+			}
+
+			seen[recording.ID] = true
+			matches = append(matches, Match{RecordingID: recording.ID, Score: result.Score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+
+	return matches, nil
+}
+
+// Returns the recording best matching a fingerprint, or an empty string when no recording
+// matches with at least the "providers.acoustid.min_score" score.
+func GetRecordingID(fingerprint string, duration time.Duration) (string, error) {
+	matches, err := Lookup(fingerprint, duration)
+	if err != nil {
+		return "", err
+	}
+
+	if len(matches) == 0 || matches[0].Score < viper.GetFloat64("providers.acoustid.min_score") {
+		return "", nil
+	}
+
+	return matches[0].RecordingID, nil
+}
@@ -0,0 +1,67 @@
+package acoustid_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/meteorae/meteorae-server/providers/acoustid"
+	"github.com/spf13/viper"
+)
+
+// Serves a canned lookup response, so we don't depend on the real service.
+func newFakeAcoustID(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		query := request.URL.Query()
+
+		if request.URL.Path != "/lookup" || query.Get("fingerprint") != "AQADtEmkSEkSJQ" || query.Get("duration") != "215" {
+			fmt.Fprint(writer, `{"status": "error", "error": {"code": 3, "message": "invalid fingerprint"}}`)
+
+			return
+		}
+
+		fmt.Fprint(writer, `{"status": "ok", "results": [
+			{"id": "low", "score": 0.4, "recordings": [{"id": "b1a9c0e9-d987-4042-ae91-78d6a3267d69"}]},
+			{"id": "high", "score": 0.95, "recordings": [{"id": "cd2e7c47-16f5-46c6-a37c-a1eb7bf599ff"}]}
+		]}`)
+	}))
+}
+
+func TestLookup(t *testing.T) {
+	server := newFakeAcoustID(t)
+	defer server.Close()
+
+	viper.Set("providers.acoustid.url", server.URL)
+	viper.Set("providers.acoustid.api_key", "")
+	viper.Set("providers.acoustid.min_score", 0.8)
+
+	recordingID, err := acoustid.GetRecordingID("AQADtEmkSEkSJQ", 215400*time.Millisecond)
+	if err != nil {
+		t.Fatalf("GetRecordingID() error = %v", err)
+	}
+
+	if recordingID != "cd2e7c47-16f5-46c6-a37c-a1eb7bf599ff" {
+		t.Errorf("GetRecordingID() = %s, want the recording with the best score", recordingID)
+	}
+
+	viper.Set("providers.acoustid.min_score", 0.99)
+
+	if recordingID, err := acoustid.GetRecordingID("AQADtEmkSEkSJQ", 215*time.Second); err != nil || recordingID != "" {
+		t.Errorf("GetRecordingID() = %s, %v, want no recording under the minimum score", recordingID, err)
+	}
+
+	if _, err := acoustid.Lookup("invalid", 215*time.Second); err == nil {
+		t.Error("Lookup() succeeded, want the service error")
+	}
+
+	viper.Set("providers.acoustid.url", "https://api.acoustid.org/v2")
+
+	if _, err := acoustid.Lookup("AQADtEmkSEkSJQ", 215*time.Second); !errors.Is(err, acoustid.ErrMissingAPIKey) {
+		t.Errorf("Lookup() error = %v, want ErrMissingAPIKey", err)
+	}
+}
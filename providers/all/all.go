@@ -0,0 +1,11 @@
+package all
+
+import (
+	// Import all providers to trigger their init() functions and register them.
+	_ "github.com/meteorae/meteorae-server/providers/anidb"
+	_ "github.com/meteorae/meteorae-server/providers/image"
+	_ "github.com/meteorae/meteorae-server/providers/movie"
+	_ "github.com/meteorae/meteorae-server/providers/musicbrainz"
+	_ "github.com/meteorae/meteorae-server/providers/nfo"
+	_ "github.com/meteorae/meteorae-server/providers/opensubtitles"
+)
@@ -0,0 +1,165 @@
+package anidb
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/meteorae/meteorae-server/providers/httpclient"
+	"github.com/spf13/viper"
+)
+
+var (
+	// Returned when calling the AniDB HTTP API without a registered client.
+	ErrMissingClient = errors.New("the AniDB HTTP API needs a registered client")
+	errRequestFailed = errors.New("request failed")
+)
+
+// Descriptions link to AniDB pages, like "http://anidb.net/ch123 [Name]", which we replace with the name.
+var descriptionLinkRegexp = regexp.MustCompile(`https?://anidb\.net/\S+ \[([^\]]+)\]`)
+
+type anidbTitle struct {
+	Language string `xml:"lang,attr"`
+	Type     string `xml:"type,attr"`
+	Title    string `xml:",chardata"`
+}
+
+type anidbAnime struct {
+	ID           int64        `xml:"id,attr"`
+	Type         string       `xml:"type"`
+	EpisodeCount int          `xml:"episodecount"`
+	StartDate    string       `xml:"startdate"`
+	Titles       []anidbTitle `xml:"titles>title"`
+	Description  string       `xml:"description"`
+	Picture      string       `xml:"picture"`
+	Creators     []struct {
+		ID   int64  `xml:"id,attr"`
+		Type string `xml:"type,attr"`
+		Name string `xml:",chardata"`
+	} `xml:"creators>name"`
+	Characters []struct {
+		Type   string `xml:"type,attr"`
+		Name   string `xml:"name"`
+		Seiyuu *struct {
+			ID      int64  `xml:"id,attr"`
+			Picture string `xml:"picture,attr"`
+			Name    string `xml:",chardata"`
+		} `xml:"seiyuu"`
+	} `xml:"characters>character"`
+	Episodes []anidbEpisode `xml:"episodes>episode"`
+}
+
+type anidbEpisode struct {
+	ID      int64        `xml:"id,attr"`
+	Number  string       `xml:"epno"`
+	Length  int64        `xml:"length"`
+	AirDate string       `xml:"airdate"`
+	Titles  []anidbTitle `xml:"title"`
+	Summary string       `xml:"summary"`
+}
+
+// Fetches an anime from the AniDB HTTP API, with its titles, credits and episodes.
+func getAnime(animeID string) (*anidbAnime, error) {
+	client := viper.GetString("providers.anidb.client")
+	if client == "" {
+		return nil, ErrMissingClient
+	}
+
+	parameters := url.Values{
+		"request":   {"anime"},
+		"client":    {client},
+		"clientver": {viper.GetString("providers.anidb.client_version")},
+		"protover":  {"1"},
+		"aid":       {animeID},
+	}
+
+	baseURL := strings.TrimSuffix(viper.GetString("providers.anidb.url"), "/")
+
+	body, err := httpclient.Get(fmt.Sprintf("%s?%s", baseURL, parameters.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to call AniDB: %w", err)
+	}
+
+	// Errors, like bans, are returned with a 200 OK status
+	if message, ok := getErrorMessage(body); ok {
+		return nil, fmt.Errorf("%w: %s", errRequestFailed, message)
+	}
+
+	var anime anidbAnime
+	if err := xml.Unmarshal(body, &anime); err != nil {
+		return nil, fmt.Errorf("failed to decode AniDB response: %w", err)
+	}
+
+	return &anime, nil
+}
+
+// Returns the error message of an AniDB response, if it holds an error rather than data.
+func getErrorMessage(body []byte) (string, bool) {
+	var response struct {
+		XMLName xml.Name
+		Message string `xml:",chardata"`
+	}
+
+	if err := xml.Unmarshal(body, &response); err != nil || response.XMLName.Local != "error" {
+		return "", false
+	}
+
+	return strings.TrimSpace(response.Message), true
+}
+
+// Returns the full URL of a picture, from the file name returned by the API.
+func getImageURL(picture string) string {
+	baseURL := strings.TrimSuffix(viper.GetString("providers.anidb.image_url"), "/")
+
+	return fmt.Sprintf("%s/%s", baseURL, picture)
+}
+
+// Returns the title in the given language, like "en-US", or the main title, usually romanized Japanese.
+func pickTitle(titles []anidbTitle, libraryLanguage string) string {
+	language, _, _ := strings.Cut(strings.ToLower(libraryLanguage), "-")
+
+	var mainTitle, fallbackTitle string
+
+	for _, title := range titles {
+		switch {
+		case title.Language == language && (title.Type == "official" || title.Type == ""):
+			return title.Title
+		case title.Type == "main":
+			mainTitle = title.Title
+		case title.Language == "x-jat" && fallbackTitle == "":
+			fallbackTitle = title.Title
+		}
+	}
+
+	if mainTitle != "" {
+		return mainTitle
+	}
+
+	if fallbackTitle != "" {
+		return fallbackTitle
+	}
+
+	if len(titles) > 0 {
+		return titles[0].Title
+	}
+
+	return ""
+}
+
+// Returns the main title of an anime, which is the title it's known by on AniDB.
+func getMainTitle(titles []anidbTitle) string {
+	for _, title := range titles {
+		if title.Type == "main" {
+			return title.Title
+		}
+	}
+
+	return ""
+}
+
+func cleanDescription(description string) string {
+	return strings.TrimSpace(descriptionLinkRegexp.ReplaceAllString(description, "$1"))
+}
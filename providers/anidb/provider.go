@@ -0,0 +1,285 @@
+// Package anidb fetches anime metadata from AniDB. Files are identified from their ED2K hash and size
+// with the UDP API when an account is set, and anime are matched by name against a local copy of the
+// AniDB title dump otherwise, so most lookups never reach AniDB.
+package anidb
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/providers/httpclient"
+	"github.com/meteorae/meteorae-server/providers/registry"
+	"github.com/meteorae/meteorae-server/utils"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// How many of the best matching stored titles are ranked by anime.
+	maxTitleCandidates = 500
+	// How similar a stored title needs to be to the searched one to match, from 0 to 1.
+	minTitleSimilarity = 0.7
+	maxSearchResults   = 10
+)
+
+func init() {
+	// The HTTP API allows a request every 2 seconds
+	httpclient.SetRateLimit("api.anidb.net:9001", 0.5) //nolint:gomnd
+
+	registry.Register(anidbProvider)
+}
+
+var anidbProvider registry.Provider = Provider{}
+
+// Fetches anime information from AniDB.
+// Results are identified as "{anime}" for anime, and "{anime}/{episode number}" for episodes.
+type Provider struct{}
+
+func (p Provider) GetName() string {
+	return "AniDB"
+}
+
+func (p Provider) SupportsLibraryType(library database.Library) bool {
+	return library.Type == database.AnimeMovieLibrary || library.Type == database.AnimeTVLibrary
+}
+
+// Identifies the file from its ED2K hash when possible, and searches the stored titles otherwise.
+func (p Provider) Search(query registry.SearchQuery, library database.Library) ([]registry.SearchResult, error) {
+	if query.MediaPart.ED2KHash != "" && query.MediaPart.Size > 0 {
+		file, err := lookupFile(query.MediaPart.Size, query.MediaPart.ED2KHash)
+
+		switch {
+		case errors.Is(err, ErrMissingCredentials):
+			log.Debug().Msgf("Skipping the identification of %s by hash, as no AniDB account is set", query.FilePath)
+		case err != nil:
+			log.Warn().Err(err).Msgf("Failed to identify %s by hash", query.FilePath)
+		case file != nil:
+			animeID := strconv.FormatInt(file.AnimeID, 10) //nolint:gomnd
+
+			result := registry.SearchResult{ID: animeID, Title: query.Title}
+			if library.Type == database.AnimeTVLibrary {
+				result.ID = formatID(animeID, file.EpisodeNumber)
+			}
+
+			return []registry.SearchResult{result}, nil
+		}
+	}
+
+	return searchTitles(query, library)
+}
+
+// Searches the stored titles for anime named like the query, best matches first.
+func searchTitles(query registry.SearchQuery, library database.Library) ([]registry.SearchResult, error) {
+	candidates, err := database.SearchAnimeTitles(query.Title, func(title string) float64 {
+		return utils.TitleSimilarity(query.Title, title)
+	}, maxTitleCandidates)
+	if err != nil {
+		return nil, fmt.Errorf("could not search anime titles: %w", err)
+	}
+
+	// Anime have many titles, so keep the score of the best matching one
+	scores := make(map[int64]float64)
+
+	for _, candidate := range candidates {
+		score := utils.TitleSimilarity(query.Title, candidate.Title)
+		if score >= minTitleSimilarity && score > scores[candidate.AnimeID] {
+			scores[candidate.AnimeID] = score
+		}
+	}
+
+	animeIDs := make([]int64, 0, len(scores))
+	for animeID := range scores {
+		animeIDs = append(animeIDs, animeID)
+	}
+
+	sort.Slice(animeIDs, func(i, j int) bool {
+		if scores[animeIDs[i]] != scores[animeIDs[j]] {
+			return scores[animeIDs[i]] > scores[animeIDs[j]]
+		}
+
+		return animeIDs[i] < animeIDs[j]
+	})
+
+	if len(animeIDs) > maxSearchResults {
+		animeIDs = animeIDs[:maxSearchResults]
+	}
+
+	// Episode numbers are only known from file names when searching by title
+	var episode int
+
+	if library.Type == database.AnimeTVLibrary && query.FilePath != "" {
+		fileName := filepath.Base(query.FilePath)
+		episode = utils.ParseAnimeFileName(fileName[:len(fileName)-len(filepath.Ext(fileName))]).Episode
+	}
+
+	results := make([]registry.SearchResult, 0, len(animeIDs))
+
+	for _, animeID := range animeIDs {
+		id := strconv.FormatInt(animeID, 10) //nolint:gomnd
+		if episode > 0 {
+			id = formatID(id, strconv.Itoa(episode))
+		}
+
+		results = append(results, registry.SearchResult{ID: id, Title: getStoredMainTitle(animeID)})
+	}
+
+	return results, nil
+}
+
+func getStoredMainTitle(animeID int64) string {
+	titles, err := database.GetAnimeTitles(animeID)
+	if err != nil || len(titles) == 0 {
+		log.Debug().Err(err).Msgf("No stored title for anime %d", animeID)
+
+		return ""
+	}
+
+	return titles[0].Title
+}
+
+func (p Provider) GetMetadata(id string, library database.Library) (*database.ItemMetadata, error) {
+	animeID, episodeNumber := parseID(id)
+
+	anime, err := getAnime(animeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch information for anime %s: %w", animeID, err)
+	}
+
+	if episodeNumber != "" {
+		return getEpisodeMetadata(anime, episodeNumber, library)
+	}
+
+	metadata := &database.ItemMetadata{
+		Title:         pickTitle(anime.Titles, library.Language),
+		OriginalTitle: getMainTitle(anime.Titles),
+		Summary:       cleanDescription(anime.Description),
+		ReleaseDate:   parseDate(anime.StartDate),
+		ExternalIdentifiers: []database.ExternalIdentifier{{
+			IdentifierType: database.AnidbIdentifier,
+			Identifier:     strconv.FormatInt(anime.ID, 10), //nolint:gomnd
+		}},
+		Credits: getCredits(anime),
+	}
+
+	metadata.SortTitle = utils.CleanSortTitle(metadata.Title)
+
+	// Movies are a single episode
+	for _, episode := range anime.Episodes {
+		if episode.Number == "1" {
+			metadata.Duration = int64(time.Duration(episode.Length) * time.Minute / time.Millisecond)
+		}
+	}
+
+	return metadata, nil
+}
+
+func getEpisodeMetadata(anime *anidbAnime, episodeNumber string, library database.Library) (*database.ItemMetadata, error) {
+	for _, episode := range anime.Episodes {
+		if !strings.EqualFold(episode.Number, episodeNumber) {
+			continue
+		}
+
+		title := pickTitle(episode.Titles, library.Language)
+
+		return &database.ItemMetadata{
+			Title:       title,
+			SortTitle:   utils.CleanSortTitle(title),
+			Summary:     cleanDescription(episode.Summary),
+			ReleaseDate: parseDate(episode.AirDate),
+			Duration:    int64(time.Duration(episode.Length) * time.Minute / time.Millisecond),
+			ExternalIdentifiers: []database.ExternalIdentifier{{
+				IdentifierType: database.AnidbIdentifier,
+				Identifier:     strconv.FormatInt(episode.ID, 10), //nolint:gomnd
+			}},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: anime %d has no episode %s", errRequestFailed, anime.ID, episodeNumber)
+}
+
+// Converts the voice actors of an anime to cast credits, and its staff to crew credits.
+// Credits are ordered the same way as on AniDB.
+func getCredits(anime *anidbAnime) []database.Credit {
+	credits := make([]database.Credit, 0, len(anime.Characters)+len(anime.Creators))
+
+	for _, character := range anime.Characters {
+		if character.Seiyuu == nil {
+			continue
+		}
+
+		person := getPerson(character.Seiyuu.ID, character.Seiyuu.Name)
+		if character.Seiyuu.Picture != "" {
+			person.Thumb = getImageURL(character.Seiyuu.Picture)
+		}
+
+		credits = append(credits, database.Credit{
+			Person:    person,
+			Role:      database.CastRole,
+			Character: character.Name,
+			Index:     len(credits),
+		})
+	}
+
+	for index, creator := range anime.Creators {
+		credits = append(credits, database.Credit{
+			Person: getPerson(creator.ID, creator.Name),
+			Role:   database.CrewRole,
+			Job:    creator.Type,
+			Index:  index,
+		})
+	}
+
+	return credits
+}
+
+func getPerson(id int64, name string) database.ItemMetadata {
+	return database.ItemMetadata{
+		Title:     name,
+		SortTitle: name,
+		Type:      database.PersonItem,
+		ExternalIdentifiers: []database.ExternalIdentifier{{
+			IdentifierType: database.AnidbIdentifier,
+			Identifier:     strconv.FormatInt(id, 10), //nolint:gomnd
+		}},
+	}
+}
+
+func (p Provider) GetImages(id string, library database.Library) ([]registry.Image, error) {
+	animeID, _ := parseID(id)
+
+	anime, err := getAnime(animeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch images for anime %s: %w", animeID, err)
+	}
+
+	if anime.Picture == "" {
+		return []registry.Image{}, nil
+	}
+
+	return []registry.Image{{Type: registry.PosterImage, URL: getImageURL(anime.Picture)}}, nil
+}
+
+func formatID(animeID, episodeNumber string) string {
+	return fmt.Sprintf("%s/%s", animeID, episodeNumber)
+}
+
+// Splits a result identifier into its anime and episode number, which is empty for anime.
+func parseID(id string) (string, string) {
+	animeID, episodeNumber, _ := strings.Cut(id, "/")
+
+	return animeID, episodeNumber
+}
+
+func parseDate(date string) time.Time {
+	parsedDate, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return parsedDate
+}
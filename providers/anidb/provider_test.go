@@ -0,0 +1,182 @@
+package anidb_test
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/providers/anidb"
+	"github.com/meteorae/meteorae-server/providers/registry"
+	"github.com/spf13/viper"
+)
+
+const fakeAnime = `<?xml version="1.0" encoding="UTF-8"?>
+<anime id="22" restricted="false">
+	<type>TV Series</type>
+	<episodecount>26</episodecount>
+	<startdate>1995-10-04</startdate>
+	<titles>
+		<title xml:lang="x-jat" type="main">Shinseiki Evangelion</title>
+		<title xml:lang="en" type="official">Neon Genesis Evangelion</title>
+	</titles>
+	<creators>
+		<name id="5111" type="Direction">Anno Hideaki</name>
+	</creators>
+	<description>* Based on a manga by http://anidb.net/cr5111 [Anno Hideaki].</description>
+	<picture>22.jpg</picture>
+	<characters>
+		<character id="90" type="main character in">
+			<name>Ikari Shinji</name>
+			<seiyuu id="12" picture="12.jpg">Ogata Megumi</seiyuu>
+		</character>
+	</characters>
+	<episodes>
+		<episode id="305">
+			<epno type="1">5</epno>
+			<length>25</length>
+			<airdate>1995-11-01</airdate>
+			<title xml:lang="ja">レイ、心のむこうに</title>
+			<title xml:lang="en">Rei I</title>
+		</episode>
+	</episodes>
+</anime>`
+
+// Serves canned AniDB HTTP API responses, so we don't depend on the real API.
+func newFakeHTTPAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		query := request.URL.Query()
+
+		switch {
+		case query.Get("client") != "testclient":
+			fmt.Fprint(writer, `<error code="302">client version missing or invalid</error>`)
+		case query.Get("request") == "anime" && query.Get("aid") == "22":
+			fmt.Fprint(writer, fakeAnime)
+		default:
+			fmt.Fprint(writer, `<error>Anime not found</error>`)
+		}
+	}))
+}
+
+// Answers AniDB UDP API commands, so we don't depend on the real API.
+func newFakeUDPAPI(t *testing.T) net.PacketConn {
+	t.Helper()
+
+	connection, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		buffer := make([]byte, 1400)
+
+		for {
+			length, address, err := connection.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+
+			command := string(buffer[:length])
+
+			var answer string
+
+			switch {
+			case strings.HasPrefix(command, "AUTH ") && strings.Contains(command, "pass=p&amp;ss"):
+				answer = "200 sess1 LOGIN ACCEPTED"
+			case strings.HasPrefix(command, "AUTH "):
+				answer = "500 LOGIN FAILED"
+			case !strings.Contains(command, "s=sess1"):
+				answer = "501 LOGIN FIRST"
+			case strings.Contains(command, "ed2k=0123456789abcdef0123456789abcdef&") &&
+				strings.HasSuffix(command, "&size=1234"):
+				answer = "220 FILE\n1001|22|305|5\n"
+			default:
+				answer = "320 NO SUCH FILE"
+			}
+
+			if _, err := connection.WriteTo([]byte(answer), address); err != nil {
+				return
+			}
+		}
+	}()
+
+	return connection
+}
+
+func TestProvider(t *testing.T) {
+	httpServer := newFakeHTTPAPI(t)
+	defer httpServer.Close()
+
+	udpServer := newFakeUDPAPI(t)
+	defer udpServer.Close()
+
+	viper.Set("providers.anidb.url", httpServer.URL+"/httpapi")
+	viper.Set("providers.anidb.image_url", "https://images.example.com/main")
+	viper.Set("providers.anidb.client", "testclient")
+	viper.Set("providers.anidb.udp_address", udpServer.LocalAddr().String())
+	viper.Set("providers.anidb.username", "user")
+	viper.Set("providers.anidb.password", "p&ss")
+	viper.Set("providers.anidb.udp_packet_interval", 0)
+
+	provider := anidb.Provider{}
+	library := database.Library{Type: database.AnimeTVLibrary, Language: "en-US"}
+
+	results, err := provider.Search(registry.SearchQuery{
+		Title:     "Evangelion",
+		MediaPart: database.MediaPart{Size: 1234, ED2KHash: "0123456789abcdef0123456789abcdef"},
+	}, library)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	if len(results) != 1 || results[0].ID != "22/5" {
+		t.Fatalf("Search() = %+v, want episode 5 of anime 22, identified by hash", results)
+	}
+
+	metadata, err := provider.GetMetadata(results[0].ID, library)
+	if err != nil {
+		t.Fatalf("GetMetadata() error = %v", err)
+	}
+
+	if metadata.Title != "Rei I" || metadata.ReleaseDate.Format("2006-01-02") != "1995-11-01" ||
+		len(metadata.ExternalIdentifiers) != 1 || metadata.ExternalIdentifiers[0].Identifier != "305" {
+		t.Errorf("GetMetadata() for an episode = %+v, want episode 305, \"Rei I\"", metadata)
+	}
+
+	metadata, err = provider.GetMetadata("22", library)
+	if err != nil {
+		t.Fatalf("GetMetadata() error = %v", err)
+	}
+
+	if metadata.Title != "Neon Genesis Evangelion" || metadata.OriginalTitle != "Shinseiki Evangelion" ||
+		metadata.Summary != "* Based on a manga by Anno Hideaki." {
+		t.Errorf("GetMetadata() for an anime = %+v, want Neon Genesis Evangelion without description links", metadata)
+	}
+
+	if len(metadata.Credits) != 2 ||
+		metadata.Credits[0].Character != "Ikari Shinji" ||
+		metadata.Credits[0].Person.Thumb != "https://images.example.com/main/12.jpg" ||
+		metadata.Credits[1].Role != database.CrewRole || metadata.Credits[1].Job != "Direction" {
+		t.Errorf("GetMetadata() credits = %+v, want a voice actor and a director", metadata.Credits)
+	}
+
+	images, err := provider.GetImages("22", library)
+	if err != nil {
+		t.Fatalf("GetImages() error = %v", err)
+	}
+
+	wantImage := registry.Image{Type: registry.PosterImage, URL: "https://images.example.com/main/22.jpg"}
+
+	if len(images) != 1 || images[0] != wantImage {
+		t.Errorf("GetImages() = %+v, want %+v", images, wantImage)
+	}
+
+	if _, err := provider.GetMetadata("404", library); err == nil {
+		t.Errorf("GetMetadata() for a missing anime should fail")
+	}
+}
@@ -0,0 +1,150 @@
+package anidb
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/providers/httpclient"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// How long to wait before retrying a failed import of the title dump.
+const titlesRetryDelay = 24 * time.Hour
+
+// Columns of the AniDB title dump, like "1|1|x-jat|Seikai no Monshou".
+const (
+	titleAnimeID = iota
+	titleType
+	titleLanguage
+	titleText
+	titleColumns
+)
+
+// Starts importing the AniDB title dump whenever the imported titles are older than
+// "providers.anidb.titles_import_interval". Failed imports are retried a day later, since AniDB
+// bans clients downloading the dump too often. Stops when the context is canceled.
+func StartTitleImports(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+
+		var lastAttempt time.Time
+
+		for {
+			if time.Since(lastAttempt) > titlesRetryDelay && areTitlesOutdated() {
+				lastAttempt = time.Now()
+
+				if err := ImportTitles(); err != nil {
+					log.Err(err).Msg("Failed to import anime titles")
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func areTitlesOutdated() bool {
+	importedAt, err := database.GetAnimeTitlesImportedAt()
+	if err != nil {
+		log.Err(err).Msg("Failed to check when anime titles were imported")
+
+		return false
+	}
+
+	return time.Since(importedAt) > viper.GetDuration("providers.anidb.titles_import_interval")
+}
+
+// Downloads the AniDB title dump from "providers.anidb.titles_url", and replaces the stored titles with it.
+func ImportTitles() error {
+	titlesURL := viper.GetString("providers.anidb.titles_url")
+
+	log.Info().Msgf("Importing anime titles from %s", titlesURL)
+
+	body, err := httpclient.Download(titlesURL)
+	if err != nil {
+		return fmt.Errorf("failed to download anime titles: %w", err)
+	}
+
+	var reader io.Reader = bytes.NewReader(body)
+
+	// The dump is usually compressed, unless the HTTP client already decompressed it
+	if bytes.HasPrefix(body, []byte{0x1f, 0x8b}) {
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return fmt.Errorf("failed to decompress anime titles: %w", err)
+		}
+		defer gzipReader.Close()
+
+		reader = gzipReader
+	}
+
+	titles, err := readTitles(reader)
+	if err != nil {
+		return err
+	}
+
+	if err := database.ReplaceAnimeTitles(titles); err != nil {
+		return fmt.Errorf("failed to save anime titles: %w", err)
+	}
+
+	log.Info().Msgf("Imported %d anime titles", len(titles))
+
+	return nil
+}
+
+// Reads an AniDB title dump, made of "anime|type|language|title" lines and comments.
+func readTitles(reader io.Reader) ([]database.AnimeTitle, error) {
+	var titles []database.AnimeTitle
+
+	scanner := bufio.NewScanner(reader)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Titles can contain the separator, so only split the first columns
+		fields := strings.SplitN(line, "|", titleColumns)
+		if len(fields) < titleColumns {
+			continue
+		}
+
+		animeID, err := strconv.ParseInt(fields[titleAnimeID], 10, 64) //nolint:gomnd
+		if err != nil {
+			continue
+		}
+
+		titleTypeValue, err := strconv.Atoi(fields[titleType])
+		if err != nil {
+			continue
+		}
+
+		titles = append(titles, database.AnimeTitle{
+			AnimeID:  animeID,
+			Type:     database.AnimeTitleType(titleTypeValue),
+			Language: fields[titleLanguage],
+			Title:    fields[titleText],
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read anime titles: %w", err)
+	}
+
+	return titles, nil
+}
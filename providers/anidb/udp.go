@@ -0,0 +1,233 @@
+package anidb
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// How long to wait for an answer from the UDP API.
+	udpTimeout = 10 * time.Second
+	// Sessions expire after 35 minutes without packets, so we log in again a bit before.
+	udpSessionLifetime = 30 * time.Minute
+	udpBufferSize      = 1400
+)
+
+// Codes of the UDP API answers we handle.
+const (
+	loginAcceptedCode           = 200
+	loginAcceptedNewVersionCode = 201
+	fileCode                    = 220
+	noSuchFileCode              = 320
+	loginFirstCode              = 501
+	invalidSessionCode          = 506
+)
+
+var (
+	// Returned when identifying files without an AniDB account.
+	ErrMissingCredentials = errors.New("identifying files on AniDB needs an account")
+	errLoginFailed        = errors.New("login failed")
+	errUnexpectedAnswer   = errors.New("unexpected answer")
+)
+
+// The file matching an ED2K hash, as identified by AniDB.
+type fileMatch struct {
+	AnimeID   int64
+	EpisodeID int64
+	// The episode number, like "1", or "S1" for specials.
+	EpisodeNumber string
+}
+
+// Sends commands to the AniDB UDP API, keeping a session open between commands.
+// Packets are spaced by "providers.anidb.udp_packet_interval", to avoid being banned.
+type udpClient struct {
+	mutex      sync.Mutex
+	connection net.Conn
+	session    string
+	lastPacket time.Time
+}
+
+var sharedUDPClient udpClient
+
+// Returns the file with the given size and ED2K hash, or nil if AniDB doesn't know it.
+func lookupFile(size int64, ed2kHash string) (*fileMatch, error) {
+	if viper.GetString("providers.anidb.username") == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	// The file mask selects the anime and episode, and the anime mask the episode number
+	answer, err := sharedUDPClient.send("FILE", map[string]string{
+		"size":  strconv.FormatInt(size, 10), //nolint:gomnd
+		"ed2k":  ed2kHash,
+		"fmask": "60000000",
+		"amask": "00008000",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch answer.code {
+	case noSuchFileCode:
+		return nil, nil
+	case fileCode:
+	default:
+		return nil, fmt.Errorf("%w to FILE: %d %s", errUnexpectedAnswer, answer.code, answer.message)
+	}
+
+	// The file identifier comes first, followed by the selected fields
+	fields := strings.Split(answer.data, "|")
+	if len(fields) < 4 { //nolint:gomnd
+		return nil, fmt.Errorf("%w to FILE: %s", errUnexpectedAnswer, answer.data)
+	}
+
+	animeID, err := strconv.ParseInt(fields[1], 10, 64) //nolint:gomnd
+	if err != nil {
+		return nil, fmt.Errorf("%w to FILE: %s", errUnexpectedAnswer, answer.data)
+	}
+
+	episodeID, err := strconv.ParseInt(fields[2], 10, 64) //nolint:gomnd
+	if err != nil {
+		return nil, fmt.Errorf("%w to FILE: %s", errUnexpectedAnswer, answer.data)
+	}
+
+	return &fileMatch{AnimeID: animeID, EpisodeID: episodeID, EpisodeNumber: fields[3]}, nil
+}
+
+type udpAnswer struct {
+	code    int
+	message string
+	// The lines following the status line, if any.
+	data string
+}
+
+// Sends a command with the current session, logging in first when needed.
+func (c *udpClient) send(command string, parameters map[string]string) (*udpAnswer, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Sessions can expire early, for instance when the server restarts, so log in again once if needed
+	for attempt := 0; ; attempt++ {
+		if c.session == "" || time.Since(c.lastPacket) > udpSessionLifetime {
+			if err := c.login(); err != nil {
+				return nil, err
+			}
+		}
+
+		parameters["s"] = c.session
+
+		answer, err := c.exchange(command, parameters)
+		if err != nil {
+			return nil, err
+		}
+
+		if (answer.code == loginFirstCode || answer.code == invalidSessionCode) && attempt == 0 {
+			c.session = ""
+
+			continue
+		}
+
+		return answer, nil
+	}
+}
+
+func (c *udpClient) login() error {
+	if c.connection != nil {
+		c.connection.Close()
+	}
+
+	connection, err := net.Dial("udp", viper.GetString("providers.anidb.udp_address"))
+	if err != nil {
+		return fmt.Errorf("failed to connect to AniDB: %w", err)
+	}
+
+	c.connection = connection
+	c.session = ""
+
+	answer, err := c.exchange("AUTH", map[string]string{
+		"user":      viper.GetString("providers.anidb.username"),
+		"pass":      viper.GetString("providers.anidb.password"),
+		"protover":  "3",
+		"client":    viper.GetString("providers.anidb.client"),
+		"clientver": viper.GetString("providers.anidb.client_version"),
+	})
+	if err != nil {
+		return err
+	}
+
+	if answer.code != loginAcceptedCode && answer.code != loginAcceptedNewVersionCode {
+		return fmt.Errorf("%w: %d %s", errLoginFailed, answer.code, answer.message)
+	}
+
+	// The session key comes first in the message, like "200 abc12 LOGIN ACCEPTED"
+	c.session, _, _ = strings.Cut(answer.message, " ")
+
+	return nil
+}
+
+// Sends a single command and reads its answer, waiting first so packets stay under the rate limit.
+func (c *udpClient) exchange(command string, parameters map[string]string) (*udpAnswer, error) {
+	if wait := time.Until(c.lastPacket.Add(viper.GetDuration("providers.anidb.udp_packet_interval"))); wait > 0 {
+		time.Sleep(wait)
+	}
+
+	c.lastPacket = time.Now()
+
+	if err := c.connection.SetDeadline(time.Now().Add(udpTimeout)); err != nil {
+		return nil, fmt.Errorf("failed to set AniDB timeout: %w", err)
+	}
+
+	if _, err := c.connection.Write([]byte(formatCommand(command, parameters))); err != nil {
+		return nil, fmt.Errorf("failed to send %s to AniDB: %w", command, err)
+	}
+
+	buffer := make([]byte, udpBufferSize)
+
+	length, err := c.connection.Read(buffer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read AniDB answer to %s: %w", command, err)
+	}
+
+	return parseAnswer(string(buffer[:length]))
+}
+
+// Formats a command, like "FILE size=1&ed2k=abc". Values are escaped the way the API expects.
+func formatCommand(command string, parameters map[string]string) string {
+	escaper := strings.NewReplacer("&", "&amp;", "\n", "<br />")
+
+	// Sort the parameters, so commands are the same from one call to the next
+	names := make([]string, 0, len(parameters))
+	for name := range parameters {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, name+"="+escaper.Replace(parameters[name]))
+	}
+
+	return command + " " + strings.Join(pairs, "&")
+}
+
+// Parses an answer like "220 FILE\n1|2|3", made of a code, a message, and optional data lines.
+func parseAnswer(answer string) (*udpAnswer, error) {
+	statusLine, data, _ := strings.Cut(strings.TrimRight(answer, "\n"), "\n")
+
+	codeText, message, _ := strings.Cut(statusLine, " ")
+
+	code, err := strconv.Atoi(codeText)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errUnexpectedAnswer, statusLine)
+	}
+
+	return &udpAnswer{code: code, message: message, data: data}, nil
+}
@@ -0,0 +1,162 @@
+package audiobook
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/filesystem/analyzer"
+	"github.com/meteorae/meteorae-server/helpers"
+	"github.com/meteorae/meteorae-server/utils"
+	"github.com/rs/zerolog/log"
+)
+
+var partNumberRegexp = regexp.MustCompile(`\d+`)
+
+// M4B files are self-contained audiobooks, rather than parts of a larger book.
+const bookFileExtension = ".m4b"
+
+// Returns whether the given file is a standalone audiobook.
+func IsBookFile(filePath string) bool {
+	return strings.EqualFold(filepath.Ext(filePath), bookFileExtension)
+}
+
+// Returns whether the given file is one of the parts of a directory audiobook.
+func IsPartFile(filePath string) bool {
+	return !IsBookFile(filePath) && utils.IsStringInSlice(filepath.Ext(filePath), helpers.AudioFileExtensions)
+}
+
+// Returns the audiobook parts directly inside the given directory, ordered by part number.
+func GetPartFiles(directory string) ([]string, error) {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, fmt.Errorf("could not read directory %s: %w", directory, err)
+	}
+
+	var files []string
+
+	for _, entry := range entries {
+		if entry.IsDir() || !IsPartFile(entry.Name()) {
+			continue
+		}
+
+		files = append(files, filepath.Join(directory, entry.Name()))
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		firstIndex, secondIndex := GetPartIndex(files[i]), GetPartIndex(files[j])
+		if firstIndex != secondIndex {
+			return firstIndex < secondIndex
+		}
+
+		return files[i] < files[j]
+	})
+
+	return files, nil
+}
+
+// Returns the part number from a file name, like 3 for "Chapter 03.mp3", or 0 if there is none.
+func GetPartIndex(filePath string) int64 {
+	// The extension is left out, since some have digits, like "mp3"
+	fileName := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+
+	match := partNumberRegexp.FindString(fileName)
+	if match == "" {
+		return 0
+	}
+
+	index, err := strconv.ParseInt(match, 10, 64) //nolint:gomnd
+	if err != nil {
+		return 0
+	}
+
+	return index
+}
+
+// Gathers the tags, duration and chapters of an audiobook.
+// Books are either a single file, usually M4B, or a directory of numbered files,
+// in which case each file without embedded chapters becomes a chapter of the book.
+func GetInformation(item *database.ItemMetadata, library database.Library) error {
+	files := []string{item.MediaPart.FilePath}
+
+	fileInfo, err := os.Stat(item.MediaPart.FilePath)
+	if err != nil {
+		return fmt.Errorf("could not stat %s: %w", item.MediaPart.FilePath, err)
+	}
+
+	if fileInfo.IsDir() {
+		files, err = GetPartFiles(item.MediaPart.FilePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	var (
+		chapters []database.Chapter
+		offset   int64
+		tags     *analyzer.MediaInfo
+	)
+
+	for _, file := range files {
+		mediaInfo, err := analyzer.ProbeMediaInfo(file)
+		if err != nil {
+			log.Err(err).Msgf("Failed to probe audiobook part %s", file)
+
+			continue
+		}
+
+		if tags == nil {
+			tags = mediaInfo
+		}
+
+		chapters = append(chapters, mediaInfo.GetChapters(file, offset)...)
+		offset += mediaInfo.Duration
+	}
+
+	if tags == nil {
+		return fmt.Errorf("could not probe any file for audiobook %s", item.MediaPart.FilePath)
+	}
+
+	for index := range chapters {
+		chapters[index].Index = int64(index)
+	}
+
+	if title := tags.Tag("album", "title"); title != "" {
+		item.Title = title
+	}
+
+	item.SortTitle = utils.CleanSortTitle(item.Title)
+	item.Summary = tags.Tag("description", "comment", "synopsis")
+	item.Duration = offset
+	item.ReleaseDate = tags.GetReleaseDate()
+
+	extraInfo, err := json.Marshal(database.AudiobookExtraInfo{
+		Author:      tags.Tag("album_artist", "artist"),
+		Narrator:    tags.Tag("narrator", "composer"),
+		Series:      tags.Tag("series", "mvnm", "grouping"),
+		SeriesIndex: tags.Tag("series-part", "series_index", "mvin"),
+	})
+	if err != nil {
+		return fmt.Errorf("could not marshal audiobook information: %w", err)
+	}
+
+	item.ExtraInfo = extraInfo
+
+	err = database.SetChapters(item.ID, chapters)
+	if err != nil {
+		return fmt.Errorf("could not save chapters for audiobook %s: %w", item.MediaPart.FilePath, err)
+	}
+
+	err = database.UpdateAudiobook(item)
+	if err != nil {
+		return fmt.Errorf("could not get information for audiobook %s: %w", item.MediaPart.FilePath, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package audiobook_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/meteorae/meteorae-server/providers/audiobook"
+)
+
+func TestIsBookFile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		filePath string
+		isBook   bool
+		isPart   bool
+	}{
+		{"/books/Dune.m4b", true, false},
+		{"/books/Dune.M4B", true, false},
+		{"/books/Dune/01.mp3", false, true},
+		{"/books/Dune/cover.jpg", false, false},
+	}
+
+	for _, tc := range tests {
+		if got := audiobook.IsBookFile(tc.filePath); got != tc.isBook {
+			t.Errorf("IsBookFile(%s) = %v, want %v", tc.filePath, got, tc.isBook)
+		}
+
+		if got := audiobook.IsPartFile(tc.filePath); got != tc.isPart {
+			t.Errorf("IsPartFile(%s) = %v, want %v", tc.filePath, got, tc.isPart)
+		}
+	}
+}
+
+func TestGetPartIndex(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]int64{
+		"/books/Dune/Chapter 03.mp3": 3,
+		"/books/Dune/12 - End.mp3":   12,
+		"/books/Dune/Prologue.mp3":   0,
+	}
+
+	for filePath, want := range tests {
+		if got := audiobook.GetPartIndex(filePath); got != want {
+			t.Errorf("GetPartIndex(%s) = %d, want %d", filePath, got, want)
+		}
+	}
+}
+
+func TestGetPartFiles(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir()
+
+	for _, name := range []string{"Chapter 10.mp3", "Chapter 2.mp3", "Chapter 1.mp3", "cover.jpg", "Dune.m4b"} {
+		if err := os.WriteFile(filepath.Join(directory, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Mkdir(filepath.Join(directory, "Extras 0.mp3"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := audiobook.GetPartFiles(directory)
+	if err != nil {
+		t.Fatalf("GetPartFiles() error = %v", err)
+	}
+
+	want := []string{
+		filepath.Join(directory, "Chapter 1.mp3"),
+		filepath.Join(directory, "Chapter 2.mp3"),
+		filepath.Join(directory, "Chapter 10.mp3"),
+	}
+
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("GetPartFiles() = %v, want the parts by number %v", files, want)
+	}
+}
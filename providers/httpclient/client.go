@@ -0,0 +1,530 @@
+// Package httpclient is the shared outbound HTTP layer used to reach metadata providers.
+// Requests are rate limited per host, retried on rate limiting and server errors, and
+// successful responses can be cached on disk. Expired responses are pruned periodically.
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+const (
+	cacheDirectoryMode = 0o755
+	// How often expired responses are removed from the cache.
+	cachePruneInterval = time.Hour
+	// Prefix of the files responses are written to, before being moved in place.
+	cacheTempPrefix = ".tmp-"
+)
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "meteorae_outgoing_requests_total",
+		Help: "The total number of outgoing requests, including retries",
+	}, []string{"host"})
+	failuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "meteorae_outgoing_request_failures_total",
+		Help: "The total number of failed outgoing requests, by status code, or error for network failures",
+	}, []string{"host", "status"})
+	cacheHitsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "meteorae_outgoing_cache_hits_total",
+		Help: "The total number of outgoing requests served from the response cache",
+	}, []string{"host"})
+)
+
+// Returned for responses with a status other than 200 OK, once retries are exhausted.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Sends requests with rate limiting, retries and an optional response cache for GET requests.
+type Client struct {
+	HTTPClient *http.Client
+	// Directory of the response cache. The cache is disabled when empty.
+	CacheDir string
+	// How long cached responses are used for.
+	CacheTTL time.Duration
+	// How many times failed requests are retried.
+	MaxRetries int
+	// Delay before the first retry, doubled for each following retry, with jitter.
+	RetryDelay time.Duration
+	// Caps the delays requested by servers before retrying, so a provider can't stall requests for hours.
+	MaxRetryAfter time.Duration
+	// Larger responses are rejected. Responses aren't limited when 0.
+	MaxResponseSize int64
+	// Sent with every request, since some providers like MusicBrainz block anonymous clients.
+	UserAgent string
+}
+
+var (
+	defaultClient     *Client
+	defaultClientOnce sync.Once
+	publicClient      *Client
+	publicClientOnce  sync.Once
+)
+
+var (
+	// Returned when connecting to loopback, private or other non-public addresses with a public client.
+	ErrNonPublicAddress = errors.New("non-public address")
+	// Returned for responses larger than the client's MaxResponseSize.
+	ErrResponseTooLarge = errors.New("response too large")
+)
+
+// Tracks the refreshes requested by users, during which cached responses from before they started are ignored.
+var bypass struct {
+	sync.Mutex
+	active int
+	since  time.Time
+}
+
+// Returns a client configured from the "providers.http" settings.
+func New() *Client {
+	return &Client{
+		HTTPClient: &http.Client{Timeout: viper.GetDuration("providers.http.timeout")},
+		CacheDir:   viper.GetString("providers.http.cache_dir"),
+		CacheTTL:   viper.GetDuration("providers.http.cache_ttl"),
+		MaxRetries: viper.GetInt("providers.http.max_retries"),
+		RetryDelay: viper.GetDuration("providers.http.retry_delay"),
+		UserAgent:  viper.GetString("providers.http.user_agent"),
+
+		MaxRetryAfter:   viper.GetDuration("providers.http.max_retry_after"),
+		MaxResponseSize: viper.GetInt64("providers.http.max_response_size"),
+	}
+}
+
+// Returns a client configured like New, which only connects to public addresses, without caching.
+// Meant for URLs given by users, so they can't reach services on the server or its network.
+// Addresses are checked when connecting, so redirects and DNS changes can't get around it.
+func NewPublic() *Client {
+	client := New()
+	client.CacheDir = ""
+	client.HTTPClient.Transport = &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: client.HTTPClient.Timeout,
+			Control: checkPublicAddress,
+		}).DialContext,
+		TLSHandshakeTimeout: client.HTTPClient.Timeout,
+	}
+
+	return client
+}
+
+func getDefaultClient() *Client {
+	defaultClientOnce.Do(func() {
+		defaultClient = New()
+	})
+
+	return defaultClient
+}
+
+// Fetches the given URL with the default client, using the response cache.
+func Get(requestURL string) ([]byte, error) {
+	return getDefaultClient().Get(requestURL)
+}
+
+// Fetches the given URL with the default client, bypassing the response cache.
+// Meant for large files like images, which are cached elsewhere.
+func Download(requestURL string) ([]byte, error) {
+	return getDefaultClient().Download(requestURL)
+}
+
+// Fetches the given URL given by a user, only connecting to public addresses, see NewPublic.
+func DownloadPublic(requestURL string) ([]byte, error) {
+	publicClientOnce.Do(func() {
+		publicClient = NewPublic()
+	})
+
+	return publicClient.Download(requestURL)
+}
+
+// Runs fn ignoring the responses cached before it started, for refreshes requested by users, who expect
+// up-to-date metadata. Fresh responses are still cached. Requests running concurrently ignore the same
+// responses until fn returns, which only costs them a request.
+func BypassCache(fn func() error) error {
+	bypass.Lock()
+	if bypass.active == 0 {
+		bypass.since = time.Now()
+	}
+	bypass.active++
+	bypass.Unlock()
+
+	defer func() {
+		bypass.Lock()
+		bypass.active--
+		bypass.Unlock()
+	}()
+
+	return fn()
+}
+
+// Removes the expired responses from the cache of the default client every hour, and the files left
+// over by interrupted writes. Stops when the context is canceled.
+func StartCachePruning(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(cachePruneInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := getDefaultClient().PruneCache(); err != nil {
+				log.Err(err).Msg("Failed to prune the response cache")
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Sends a request with the default client, bypassing the response cache.
+// Meant for APIs needing other methods or headers, like API keys sent as headers.
+func Send(method, requestURL string, header http.Header, body []byte) ([]byte, error) {
+	return getDefaultClient().Send(method, requestURL, header, body)
+}
+
+// Fetches the given URL, from the response cache when it holds a fresh enough copy.
+func (c *Client) Get(requestURL string) ([]byte, error) {
+	cachePath := c.getCachePath(requestURL)
+
+	if body, ok := c.readCache(cachePath); ok {
+		cacheHitsTotal.WithLabelValues(getHost(requestURL)).Inc()
+
+		return body, nil
+	}
+
+	body, err := c.Download(requestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	c.writeCache(cachePath, body)
+
+	return body, nil
+}
+
+// Fetches the given URL, bypassing the response cache.
+func (c *Client) Download(requestURL string) ([]byte, error) {
+	return c.Send(http.MethodGet, requestURL, nil, nil)
+}
+
+// Sends a request with the given headers and body, which can be nil, bypassing the response cache.
+func (c *Client) Send(method, requestURL string, header http.Header, body []byte) ([]byte, error) {
+	host := getHost(requestURL)
+
+	for attempt := 0; ; attempt++ {
+		waitForHost(host)
+		requestsTotal.WithLabelValues(host).Inc()
+
+		responseBody, retryAfter, err := c.fetch(method, requestURL, header, body)
+		if err == nil {
+			return responseBody, nil
+		}
+
+		var statusError *StatusError
+		if errors.As(err, &statusError) {
+			failuresTotal.WithLabelValues(host, strconv.Itoa(statusError.StatusCode)).Inc()
+		} else {
+			failuresTotal.WithLabelValues(host, "error").Inc()
+		}
+
+		if attempt >= c.MaxRetries || !isRetryable(err) {
+			return nil, err
+		}
+
+		delay := c.getRetryDelay(attempt, retryAfter)
+
+		log.Debug().Err(err).Msgf("Retrying request to %s in %s", host, delay)
+
+		time.Sleep(delay)
+	}
+}
+
+// Sends a single request. Returns the delay requested by the server before retrying, if any.
+func (c *Client) fetch(method, requestURL string, header http.Header, body []byte) ([]byte, time.Duration, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	request, err := http.NewRequest(method, requestURL, bodyReader) //nolint:noctx
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request to %s: %w", getHost(requestURL), err)
+	}
+
+	for name, values := range header {
+		request.Header[name] = values
+	}
+
+	if c.UserAgent != "" {
+		request.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	response, err := c.HTTPClient.Do(request)
+	if err != nil {
+		var urlError *url.Error
+		if errors.As(err, &urlError) {
+			urlError.URL = redactURL(urlError.URL)
+		}
+
+		return nil, 0, fmt.Errorf("failed to fetch %s: %w", getHost(requestURL), err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		retryAfter := parseRetryAfter(response.Header.Get("Retry-After"), time.Now())
+
+		return nil, retryAfter, &StatusError{URL: redactURL(requestURL), StatusCode: response.StatusCode}
+	}
+
+	var responseReader io.Reader = response.Body
+	if c.MaxResponseSize > 0 {
+		// Read one more byte, to tell responses of exactly the maximum size from larger ones
+		responseReader = io.LimitReader(response.Body, c.MaxResponseSize+1)
+	}
+
+	responseBody, err := io.ReadAll(responseReader)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read response from %s: %w", getHost(requestURL), err)
+	}
+
+	if c.MaxResponseSize > 0 && int64(len(responseBody)) > c.MaxResponseSize {
+		return nil, 0, fmt.Errorf("%w: %s returned more than %d bytes", ErrResponseTooLarge, getHost(requestURL),
+			c.MaxResponseSize)
+	}
+
+	return responseBody, 0, nil
+}
+
+// Returns the delay requested by a Retry-After header, given either in seconds or as an HTTP date.
+// Returns 0 when the header is missing or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return date.Sub(now)
+	}
+
+	return 0
+}
+
+// Returns whether a request failing with the given error is worth retrying.
+// Network errors, rate limiting and server errors are usually temporary.
+func isRetryable(err error) bool {
+	if errors.Is(err, ErrNonPublicAddress) || errors.Is(err, ErrResponseTooLarge) {
+		return false
+	}
+
+	var statusError *StatusError
+	if !errors.As(err, &statusError) {
+		return true
+	}
+
+	return statusError.StatusCode == http.StatusTooManyRequests || statusError.StatusCode >= http.StatusInternalServerError
+}
+
+// Returns how long to wait before the given retry, using exponential backoff with jitter.
+// A delay requested by the server takes precedence, up to MaxRetryAfter.
+func (c *Client) getRetryDelay(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		if c.MaxRetryAfter > 0 && retryAfter > c.MaxRetryAfter {
+			return c.MaxRetryAfter
+		}
+
+		return retryAfter
+	}
+
+	delay := c.RetryDelay << attempt
+	if delay <= 0 {
+		return 0
+	}
+
+	// Spread retries between half and all of the delay, so concurrent requests don't retry in lockstep
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1)) //nolint:gosec
+}
+
+func (c *Client) getCachePath(requestURL string) string {
+	if c.CacheDir == "" {
+		return ""
+	}
+
+	hash := sha256.Sum256([]byte(requestURL))
+
+	return filepath.Join(c.CacheDir, hex.EncodeToString(hash[:]))
+}
+
+func (c *Client) readCache(cachePath string) ([]byte, bool) {
+	if cachePath == "" {
+		return nil, false
+	}
+
+	fileInfo, err := os.Stat(cachePath)
+	if err != nil || time.Since(fileInfo.ModTime()) > c.CacheTTL || isBypassed(fileInfo.ModTime()) {
+		return nil, false
+	}
+
+	body, err := os.ReadFile(cachePath)
+	if err != nil {
+		log.Err(err).Msgf("Failed to read cached response %s", cachePath)
+
+		return nil, false
+	}
+
+	return body, true
+}
+
+// Returns whether responses cached at the given time are ignored, see BypassCache.
+func isBypassed(cachedAt time.Time) bool {
+	bypass.Lock()
+	defer bypass.Unlock()
+
+	return bypass.active > 0 && cachedAt.Before(bypass.since)
+}
+
+// Writes a response to the cache. Responses are written to a temporary file first, and then moved in place,
+// so concurrent reads never see partial responses.
+func (c *Client) writeCache(cachePath string, body []byte) {
+	if cachePath == "" {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cachePath), cacheDirectoryMode); err != nil {
+		log.Err(err).Msgf("Failed to create response cache directory %s", filepath.Dir(cachePath))
+
+		return
+	}
+
+	// Temporary files are only readable by their owner, like cached responses should be
+	file, err := os.CreateTemp(filepath.Dir(cachePath), cacheTempPrefix+"*")
+	if err != nil {
+		log.Err(err).Msgf("Failed to cache response %s", cachePath)
+
+		return
+	}
+
+	_, err = file.Write(body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(file.Name(), cachePath)
+	}
+
+	if err != nil {
+		log.Err(err).Msgf("Failed to cache response %s", cachePath)
+
+		if err := os.Remove(file.Name()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Err(err).Msgf("Failed to remove temporary file %s", file.Name())
+		}
+	}
+}
+
+// Removes the responses older than the cache TTL from the cache, along with the temporary files left over
+// by interrupted writes.
+func (c *Client) PruneCache() error {
+	if c.CacheDir == "" {
+		return nil
+	}
+
+	entries, err := os.ReadDir(c.CacheDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to list cached responses: %w", err)
+	}
+
+	for _, entry := range entries {
+		fileInfo, err := entry.Info()
+		if err != nil || fileInfo.IsDir() || time.Since(fileInfo.ModTime()) <= c.CacheTTL {
+			continue
+		}
+
+		cachePath := filepath.Join(c.CacheDir, entry.Name())
+		if err := os.Remove(cachePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Err(err).Msgf("Failed to remove expired response %s", cachePath)
+		}
+	}
+
+	return nil
+}
+
+// Refuses connections to addresses which aren't reachable from the internet, see NewPublic.
+func checkPublicAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid address %s: %w", address, err)
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, host)
+	}
+
+	return nil
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast()
+}
+
+func getHost(requestURL string) string {
+	parsedURL, err := url.Parse(requestURL)
+	if err != nil {
+		return ""
+	}
+
+	return parsedURL.Host
+}
+
+// Removes the query from a URL, since it often holds API keys.
+func redactURL(requestURL string) string {
+	parsedURL, err := url.Parse(requestURL)
+	if err != nil {
+		return ""
+	}
+
+	parsedURL.RawQuery = ""
+
+	return parsedURL.String()
+}
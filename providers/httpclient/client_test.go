@@ -0,0 +1,341 @@
+package httpclient_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/meteorae/meteorae-server/providers/httpclient"
+)
+
+func newTestClient(t *testing.T) *httpclient.Client {
+	t.Helper()
+
+	return &httpclient.Client{
+		HTTPClient: &http.Client{Timeout: time.Second},
+		CacheDir:   t.TempDir(),
+		CacheTTL:   time.Hour,
+		MaxRetries: 2,
+		RetryDelay: time.Millisecond,
+	}
+}
+
+func TestRetriesServerErrors(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		fmt.Fprint(writer, "ok")
+	}))
+	defer server.Close()
+
+	body, err := newTestClient(t).Download(server.URL)
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+
+	if string(body) != "ok" || requests != 3 {
+		t.Errorf("Download() = %q after %d requests, want \"ok\" after 3", body, requests)
+	}
+}
+
+func TestDoesNotRetryClientErrors(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		writer.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	_, err := newTestClient(t).Download(server.URL + "/movie/1?api_key=secret")
+
+	var statusError *httpclient.StatusError
+	if !errors.As(err, &statusError) || statusError.StatusCode != http.StatusNotFound {
+		t.Fatalf("Download() error = %v, want a 404 status error", err)
+	}
+
+	if requests != 1 {
+		t.Errorf("Download() sent %d requests, want 1", requests)
+	}
+
+	if statusError.URL != server.URL+"/movie/1" {
+		t.Errorf("Download() error URL = %s, want it without the query", statusError.URL)
+	}
+}
+
+func TestGivesUpAfterMaxRetries(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		writer.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	if _, err := newTestClient(t).Download(server.URL); err == nil {
+		t.Fatal("Download() error = nil, want an error")
+	}
+
+	if requests != 3 {
+		t.Errorf("Download() sent %d requests, want 3", requests)
+	}
+}
+
+func TestCachesResponses(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		fmt.Fprintf(writer, "response %d", atomic.AddInt32(&requests, 1))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t)
+
+	for i := 0; i < 2; i++ {
+		body, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+
+		if string(body) != "response 1" {
+			t.Errorf("Get() = %q, want the cached \"response 1\"", body)
+		}
+	}
+
+	client.CacheTTL = 0
+
+	if body, _ := client.Get(server.URL); string(body) != "response 2" {
+		t.Errorf("Get() = %q, want a fresh \"response 2\" once the cache expired", body)
+	}
+}
+
+func TestRateLimitsPerHost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		fmt.Fprint(writer, "ok")
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	httpclient.SetRateLimit(serverURL.Host, 20) //nolint:gomnd
+
+	client := newTestClient(t)
+	start := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.Download(server.URL); err != nil {
+			t.Fatalf("Download() error = %v", err)
+		}
+	}
+
+	// Three requests at 20 per second are spaced by at least 2 intervals of 50ms
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("3 requests took %s, want at least 100ms", elapsed)
+	}
+}
+
+func TestSendsUserAgent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		fmt.Fprint(writer, request.UserAgent())
+	}))
+	defer server.Close()
+
+	client := newTestClient(t)
+	client.UserAgent = "Meteorae/test ( https://example.com )"
+
+	body, err := client.Download(server.URL)
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+
+	if string(body) != client.UserAgent {
+		t.Errorf("Download() sent User-Agent %q, want %q", body, client.UserAgent)
+	}
+}
+
+func TestSendsHeadersAndBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+
+		fmt.Fprintf(writer, "%s %s %s", request.Method, request.Header.Get("Api-Key"), body)
+	}))
+	defer server.Close()
+
+	body, err := newTestClient(t).Send(http.MethodPost, server.URL, http.Header{"Api-Key": {"secret"}}, []byte(`{"id":1}`))
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if want := `POST secret {"id":1}`; string(body) != want {
+		t.Errorf("Send() = %q, want %q", body, want)
+	}
+}
+
+func TestPublicClientRejectsPrivateAddresses(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	defer server.Close()
+
+	client := httpclient.NewPublic()
+	client.MaxRetries = 2
+
+	_, err := client.Download(server.URL)
+	if !errors.Is(err, httpclient.ErrNonPublicAddress) {
+		t.Errorf("Download() error = %v, want ErrNonPublicAddress", err)
+	}
+
+	if requests != 0 {
+		t.Errorf("server received %d requests, want none", requests)
+	}
+}
+
+func TestCapsRetryAfter(t *testing.T) {
+	for _, retryAfter := range []string{
+		"3600",
+		time.Now().Add(time.Hour).UTC().Format(http.TimeFormat),
+		time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat),
+	} {
+		var requests int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			if atomic.AddInt32(&requests, 1) == 1 {
+				writer.Header().Set("Retry-After", retryAfter)
+				writer.WriteHeader(http.StatusTooManyRequests)
+
+				return
+			}
+
+			fmt.Fprint(writer, "ok")
+		}))
+
+		client := newTestClient(t)
+		client.MaxRetryAfter = 10 * time.Millisecond
+
+		start := time.Now()
+
+		body, err := client.Download(server.URL)
+		if err != nil || string(body) != "ok" {
+			t.Errorf("Download() with Retry-After %q = %q, %v, want ok", retryAfter, body, err)
+		}
+
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Download() with Retry-After %q waited %s, want at most MaxRetryAfter", retryAfter, elapsed)
+		}
+
+		server.Close()
+	}
+}
+
+func TestRejectsLargeResponses(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(writer, request.URL.Query().Get("body"))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t)
+	client.MaxResponseSize = 4
+
+	if body, err := client.Download(server.URL + "?body=1234"); err != nil || string(body) != "1234" {
+		t.Errorf("Download() = %q, %v, want responses of the maximum size", body, err)
+	}
+
+	_, err := client.Download(server.URL + "?body=12345")
+	if !errors.Is(err, httpclient.ErrResponseTooLarge) {
+		t.Errorf("Download() error = %v, want ErrResponseTooLarge", err)
+	}
+
+	if requests != 2 {
+		t.Errorf("server received %d requests, want no retries of large responses", requests)
+	}
+}
+
+func TestBypassCache(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		fmt.Fprintf(writer, "response %d", atomic.AddInt32(&requests, 1))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t)
+
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	err := httpclient.BypassCache(func() error {
+		// Fresh responses are cached for the rest of the refresh
+		for i := 0; i < 2; i++ {
+			if body, _ := client.Get(server.URL); string(body) != "response 2" {
+				t.Errorf("Get() = %q, want a fresh \"response 2\"", body)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("BypassCache() error = %v", err)
+	}
+
+	if body, _ := client.Get(server.URL); string(body) != "response 2" {
+		t.Errorf("Get() = %q, want the cached \"response 2\"", body)
+	}
+}
+
+func TestPruneCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		fmt.Fprint(writer, "ok")
+	}))
+	defer server.Close()
+
+	client := newTestClient(t)
+
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	// Responses are moved in place, without leaving temporary files behind
+	if entries, _ := os.ReadDir(client.CacheDir); len(entries) != 1 {
+		t.Fatalf("cache holds %d files, want the response only", len(entries))
+	}
+
+	if err := client.PruneCache(); err != nil {
+		t.Fatalf("PruneCache() error = %v", err)
+	}
+
+	if entries, _ := os.ReadDir(client.CacheDir); len(entries) != 1 {
+		t.Errorf("PruneCache() removed a fresh response")
+	}
+
+	client.CacheTTL = 0
+
+	if err := client.PruneCache(); err != nil {
+		t.Fatalf("PruneCache() error = %v", err)
+	}
+
+	if entries, _ := os.ReadDir(client.CacheDir); len(entries) != 0 {
+		t.Errorf("PruneCache() kept %d expired responses", len(entries))
+	}
+}
@@ -0,0 +1,77 @@
+package httpclient
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Spaces out the requests to a host, so they stay under its rate limit.
+type hostLimiter struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+var (
+	limitersMutex sync.Mutex
+	limiters      = map[string]*hostLimiter{}
+)
+
+// Sets the maximum number of requests per second to the given host, like "musicbrainz.org".
+// Hosts without a specific limit use "providers.http.requests_per_second".
+func SetRateLimit(host string, requestsPerSecond float64) {
+	limiter := getLimiter(host)
+
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	limiter.interval = getInterval(requestsPerSecond)
+}
+
+// Blocks until a request can be sent to the given host.
+func waitForHost(host string) {
+	limiter := getLimiter(host)
+
+	limiter.mutex.Lock()
+
+	now := time.Now()
+	if limiter.next.Before(now) {
+		limiter.next = now
+	}
+
+	wait := limiter.next.Sub(now)
+	limiter.next = limiter.next.Add(limiter.interval)
+
+	limiter.mutex.Unlock()
+
+	time.Sleep(wait)
+}
+
+func getLimiter(host string) *hostLimiter {
+	host = strings.ToLower(host)
+
+	limitersMutex.Lock()
+	defer limitersMutex.Unlock()
+
+	limiter, ok := limiters[host]
+	if !ok {
+		limiter = &hostLimiter{
+			interval: getInterval(viper.GetFloat64("providers.http.requests_per_second")),
+		}
+		limiters[host] = limiter
+	}
+
+	return limiter
+}
+
+// Returns the delay between two requests for the given rate. Rates of 0 or less disable the limit.
+func getInterval(requestsPerSecond float64) time.Duration {
+	if requestsPerSecond <= 0 {
+		return 0
+	}
+
+	return time.Duration(float64(time.Second) / requestsPerSecond)
+}
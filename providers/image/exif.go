@@ -0,0 +1,238 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"  // Registers the GIF decoder, for dimensions
+	_ "image/jpeg" // Registers the JPEG decoder, for dimensions
+	_ "image/png"  // Registers the PNG decoder, for dimensions
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/rwcarlsen/goexif/exif"
+	"github.com/rwcarlsen/goexif/tiff"
+)
+
+// Layout of EXIF dates, which have no time zone.
+const exifTimeLayout = "2006:01:02 15:04:05"
+
+// Layout of the time zone offsets of EXIF dates, like "+02:00".
+const exifOffsetLayout = "-07:00"
+
+// Time zone offsets of the dates, added in EXIF 2.31, which goexif doesn't read.
+const (
+	offsetTime          exif.FieldName = "OffsetTime"
+	offsetTimeOriginal  exif.FieldName = "OffsetTimeOriginal"
+	offsetTimeDigitized exif.FieldName = "OffsetTimeDigitized"
+)
+
+var offsetFields = map[uint16]exif.FieldName{
+	0x9010: offsetTime,          //nolint:gomnd
+	0x9011: offsetTimeOriginal,  //nolint:gomnd
+	0x9012: offsetTimeDigitized, //nolint:gomnd
+}
+
+// Reads the time zone offsets from the EXIF sub-directory, after goexif parsed the rest.
+type offsetParser struct{}
+
+func (offsetParser) Parse(data *exif.Exif) error {
+	pointer := getInt(data, exif.ExifIFDPointer)
+	if pointer == nil {
+		return nil
+	}
+
+	reader := bytes.NewReader(data.Raw)
+
+	// The offsets are optional, so broken directories are left to goexif to report
+	if _, err := reader.Seek(*pointer, io.SeekStart); err != nil {
+		return nil //nolint:nilerr
+	}
+
+	directory, _, err := tiff.DecodeDir(reader, data.Tiff.Order)
+	if err != nil {
+		return nil //nolint:nilerr
+	}
+
+	data.LoadTags(directory, offsetFields, false)
+
+	return nil
+}
+
+// Reads the EXIF data of an image file. Images without EXIF data only get their dimensions,
+// when their format is supported by the standard library.
+func readExif(filePath string) (*database.ImageExif, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image: %w", err)
+	}
+	defer file.Close()
+
+	var imageExif database.ImageExif
+
+	// Invalid EXIF data isn't fatal, there is still useful information in most cases
+	if data, err := exif.Decode(file); data != nil && (err == nil || !exif.IsCriticalError(err)) {
+		imageExif = getExifFields(data)
+	}
+
+	if imageExif.Width == nil || imageExif.Height == nil {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to read image: %w", err)
+		}
+
+		if config, _, err := image.DecodeConfig(file); err == nil {
+			width, height := int64(config.Width), int64(config.Height)
+			imageExif.Width = &width
+			imageExif.Height = &height
+		}
+	}
+
+	return &imageExif, nil
+}
+
+func getExifFields(data *exif.Exif) database.ImageExif {
+	imageExif := database.ImageExif{
+		CameraMake:   getString(data, exif.Make),
+		CameraModel:  getString(data, exif.Model),
+		Lens:         getString(data, exif.LensModel),
+		ExposureTime: getFraction(data, exif.ExposureTime),
+		Aperture:     getFloat(data, exif.FNumber),
+		ISO:          getInt(data, exif.ISOSpeedRatings),
+		FocalLength:  getFloat(data, exif.FocalLength),
+		Orientation:  getInt(data, exif.Orientation),
+		Width:        getInt(data, exif.PixelXDimension),
+		Height:       getInt(data, exif.PixelYDimension),
+	}
+
+	if imageExif.Width == nil || imageExif.Height == nil {
+		imageExif.Width = getInt(data, exif.ImageWidth)
+		imageExif.Height = getInt(data, exif.ImageLength)
+	}
+
+	if dateTaken := getDate(data); !dateTaken.IsZero() {
+		imageExif.DateTaken = &dateTaken
+	}
+
+	if latitude, longitude, err := data.LatLong(); err == nil {
+		imageExif.Latitude = &latitude
+		imageExif.Longitude = &longitude
+
+		if altitude := getFloat(data, exif.GPSAltitude); altitude != nil {
+			if reference := getInt(data, exif.GPSAltitudeRef); reference != nil && *reference == 1 {
+				*altitude = -*altitude
+			}
+
+			imageExif.Altitude = altitude
+		}
+	}
+
+	return imageExif
+}
+
+// Returns when the photo was taken, falling back to when the file was last changed by the camera.
+// Most cameras record the local time without a time zone, so it is returned as is, in UTC,
+// unless the offset of the date was recorded too.
+func getDate(data *exif.Exif) time.Time {
+	for _, field := range []struct{ date, offset exif.FieldName }{
+		{exif.DateTimeOriginal, offsetTimeOriginal},
+		{exif.DateTimeDigitized, offsetTimeDigitized},
+		{exif.DateTime, offsetTime},
+	} {
+		date := getString(data, field.date)
+
+		dateTaken, err := time.Parse(exifTimeLayout, date)
+		if err != nil {
+			continue
+		}
+
+		// Unknown offsets are recorded as blanks
+		offset := getString(data, field.offset)
+		if dateTakenWithOffset, err := time.Parse(exifTimeLayout+exifOffsetLayout, date+offset); err == nil {
+			return dateTakenWithOffset
+		}
+
+		return dateTaken
+	}
+
+	return time.Time{}
+}
+
+func getTag(data *exif.Exif, field exif.FieldName) *tiff.Tag {
+	tag, err := data.Get(field)
+	if err != nil || tag.Count == 0 {
+		return nil
+	}
+
+	return tag
+}
+
+func getString(data *exif.Exif, field exif.FieldName) string {
+	tag := getTag(data, field)
+	if tag == nil {
+		return ""
+	}
+
+	value, err := tag.StringVal()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(strings.TrimRight(value, "\x00"))
+}
+
+func getInt(data *exif.Exif, field exif.FieldName) *int64 {
+	tag := getTag(data, field)
+	if tag == nil {
+		return nil
+	}
+
+	value, err := tag.Int64(0)
+	if err != nil {
+		return nil
+	}
+
+	return &value
+}
+
+func getFloat(data *exif.Exif, field exif.FieldName) *float64 {
+	tag := getTag(data, field)
+	if tag == nil {
+		return nil
+	}
+
+	numerator, denominator, err := tag.Rat2(0)
+	if err != nil || denominator == 0 {
+		return nil
+	}
+
+	value := float64(numerator) / float64(denominator)
+
+	return &value
+}
+
+// Returns a rational value as a fraction, like "1/250", or as a whole number of seconds.
+func getFraction(data *exif.Exif, field exif.FieldName) string {
+	tag := getTag(data, field)
+	if tag == nil {
+		return ""
+	}
+
+	numerator, denominator, err := tag.Rat2(0)
+	if err != nil || numerator == 0 || denominator == 0 {
+		return ""
+	}
+
+	if numerator%denominator == 0 {
+		return fmt.Sprint(numerator / denominator)
+	}
+
+	// Cameras often store exposures like 10/2500
+	if denominator%numerator == 0 {
+		return fmt.Sprintf("1/%d", denominator/numerator)
+	}
+
+	return fmt.Sprintf("%d/%d", numerator, denominator)
+}
@@ -0,0 +1,214 @@
+package image_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	imageProvider "github.com/meteorae/meteorae-server/providers/image"
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+const (
+	dateTimeTag            = 0x0132
+	exifPointerTag         = 0x8769
+	exposureTimeTag        = 0x829A
+	dateTimeOriginalTag    = 0x9003
+	dateTimeDigitizedTag   = 0x9004
+	offsetTimeTag          = 0x9010
+	offsetTimeOriginalTag  = 0x9011
+	offsetTimeDigitizedTag = 0x9012
+)
+
+type tiffTag struct {
+	id       uint16
+	dataType uint16
+	count    uint32
+	value    []byte
+}
+
+func asciiTag(id uint16, value string) tiffTag {
+	return tiffTag{id: id, dataType: 2, count: uint32(len(value) + 1), value: []byte(value + "\x00")}
+}
+
+func rationalTag(id uint16, numerator, denominator uint32) tiffTag {
+	value := make([]byte, 8)
+	binary.LittleEndian.PutUint32(value, numerator)
+	binary.LittleEndian.PutUint32(value[4:], denominator)
+
+	return tiffTag{id: id, dataType: 5, count: 1, value: value}
+}
+
+// Decodes a little-endian TIFF holding the given main and EXIF sub-directory tags.
+func newExif(t *testing.T, mainTags, exifTags []tiffTag) *exif.Exif {
+	t.Helper()
+
+	const headerSize, entrySize = 8, 12
+
+	mainSize := 2 + entrySize*(len(mainTags)+1) + 4
+	exifOffset := headerSize + mainSize
+	dataOffset := exifOffset + 2 + entrySize*len(exifTags) + 4
+
+	pointer := make([]byte, 4)
+	binary.LittleEndian.PutUint32(pointer, uint32(exifOffset))
+	mainTags = append(mainTags, tiffTag{id: exifPointerTag, dataType: 4, count: 1, value: pointer})
+
+	var buffer, data bytes.Buffer
+
+	buffer.WriteString("II*\x00")
+	_ = binary.Write(&buffer, binary.LittleEndian, uint32(headerSize))
+
+	for _, tags := range [][]tiffTag{mainTags, exifTags} {
+		_ = binary.Write(&buffer, binary.LittleEndian, uint16(len(tags)))
+
+		for _, tag := range tags {
+			_ = binary.Write(&buffer, binary.LittleEndian, tag.id)
+			_ = binary.Write(&buffer, binary.LittleEndian, tag.dataType)
+			_ = binary.Write(&buffer, binary.LittleEndian, tag.count)
+
+			if len(tag.value) <= 4 {
+				buffer.Write(append(tag.value, make([]byte, 4-len(tag.value))...))
+
+				continue
+			}
+
+			_ = binary.Write(&buffer, binary.LittleEndian, uint32(dataOffset+data.Len()))
+			data.Write(tag.value)
+
+			if data.Len()%2 == 1 {
+				data.WriteByte(0)
+			}
+		}
+
+		_ = binary.Write(&buffer, binary.LittleEndian, uint32(0))
+	}
+
+	buffer.Write(data.Bytes())
+
+	decoded, err := exif.Decode(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return decoded
+}
+
+func TestGetFraction(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		name                   string
+		numerator, denominator uint32
+		expected               string
+	}{
+		{"fraction", 1, 250, "1/250"},
+		{"reducible fraction", 10, 2500, "1/250"},
+		{"whole seconds", 30, 1, "30"},
+		{"reducible whole seconds", 20, 10, "2"},
+		{"irreducible fraction", 3, 10, "3/10"},
+		{"zero denominator", 1, 0, ""},
+		{"zero numerator", 0, 1, ""},
+	} {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := newExif(t, nil, []tiffTag{rationalTag(exposureTimeTag, test.numerator, test.denominator)})
+
+			if fraction := imageProvider.GetFraction(data, exif.ExposureTime); fraction != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, fraction)
+			}
+		})
+	}
+}
+
+func TestGetFractionWithoutTag(t *testing.T) {
+	t.Parallel()
+
+	if fraction := imageProvider.GetFraction(newExif(t, nil, nil), exif.ExposureTime); fraction != "" {
+		t.Errorf("expected no fraction, got %q", fraction)
+	}
+}
+
+func TestGetDate(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		name               string
+		mainTags, exifTags []tiffTag
+		expected           time.Time
+	}{
+		{
+			name:     "without offset",
+			exifTags: []tiffTag{asciiTag(dateTimeOriginalTag, "2021:07:14 18:30:05")},
+			expected: time.Date(2021, 7, 14, 18, 30, 5, 0, time.UTC),
+		},
+		{
+			name: "with offset",
+			exifTags: []tiffTag{
+				asciiTag(dateTimeOriginalTag, "2021:07:14 18:30:05"),
+				asciiTag(offsetTimeOriginalTag, "+02:00"),
+			},
+			expected: time.Date(2021, 7, 14, 16, 30, 5, 0, time.UTC),
+		},
+		{
+			name: "with negative offset",
+			exifTags: []tiffTag{
+				asciiTag(dateTimeOriginalTag, "2021:07:14 18:30:05"),
+				asciiTag(offsetTimeOriginalTag, "-05:30"),
+			},
+			expected: time.Date(2021, 7, 15, 0, 0, 5, 0, time.UTC),
+		},
+		{
+			name: "with unknown offset",
+			exifTags: []tiffTag{
+				asciiTag(dateTimeOriginalTag, "2021:07:14 18:30:05"),
+				asciiTag(offsetTimeOriginalTag, "   :  "),
+			},
+			expected: time.Date(2021, 7, 14, 18, 30, 5, 0, time.UTC),
+		},
+		{
+			name: "with the offset of another date",
+			exifTags: []tiffTag{
+				asciiTag(dateTimeOriginalTag, "2021:07:14 18:30:05"),
+				asciiTag(offsetTimeDigitizedTag, "+02:00"),
+			},
+			expected: time.Date(2021, 7, 14, 18, 30, 5, 0, time.UTC),
+		},
+		{
+			name: "digitized fallback",
+			exifTags: []tiffTag{
+				asciiTag(dateTimeOriginalTag, "0000:00:00 00:00:00"),
+				asciiTag(dateTimeDigitizedTag, "2021:07:14 18:30:05"),
+				asciiTag(offsetTimeDigitizedTag, "+02:00"),
+			},
+			expected: time.Date(2021, 7, 14, 16, 30, 5, 0, time.UTC),
+		},
+		{
+			name: "modification fallback",
+			mainTags: []tiffTag{
+				asciiTag(dateTimeTag, "2021:07:14 18:30:05"),
+			},
+			exifTags: []tiffTag{asciiTag(offsetTimeTag, "+00:00")},
+			expected: time.Date(2021, 7, 14, 18, 30, 5, 0, time.UTC),
+		},
+		{
+			name:     "invalid date",
+			exifTags: []tiffTag{asciiTag(dateTimeOriginalTag, "July 14, 2021")},
+		},
+		{name: "missing date"},
+	} {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			date := imageProvider.GetDate(newExif(t, test.mainTags, test.exifTags))
+			if !date.Equal(test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, date)
+			}
+		})
+	}
+}
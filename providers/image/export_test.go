@@ -0,0 +1,6 @@
+package image
+
+var (
+	GetDate     = getDate
+	GetFraction = getFraction
+)
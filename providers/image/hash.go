@@ -0,0 +1,31 @@
+package image
+
+import (
+	"fmt"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/meteorae/meteorae-server/duplicates"
+)
+
+// Computes the perceptual hash of an image. vips scales it down to the size of the hash,
+// after applying its EXIF orientation, so rotated copies get the same hash.
+func getPerceptualHash(filePath string) (string, error) {
+	thumbnail, err := vips.NewThumbnailWithSizeFromFile(
+		filePath, duplicates.HashWidth, duplicates.HashHeight, vips.InterestingNone, vips.SizeForce)
+	if err != nil {
+		return "", fmt.Errorf("failed to scale image down: %w", err)
+	}
+	defer thumbnail.Close()
+
+	if err := thumbnail.ToColorSpace(vips.InterpretationBW); err != nil {
+		return "", fmt.Errorf("failed to convert image to grayscale: %w", err)
+	}
+
+	// PNG is lossless, so the pixels are hashed as vips computed them
+	pixels, err := thumbnail.ToImage(vips.NewDefaultPNGExportParams())
+	if err != nil {
+		return "", fmt.Errorf("failed to export image: %w", err)
+	}
+
+	return duplicates.FormatHash(duplicates.DifferenceHash(pixels)), nil
+}
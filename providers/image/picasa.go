@@ -0,0 +1,146 @@
+package image
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/meteorae/meteorae-server/database"
+)
+
+// Names of the files Picasa writes in each folder, newest first.
+var picasaFileNames = []string{".picasa.ini", "Picasa.ini"}
+
+// Contact of the faces Picasa couldn't name.
+const picasaUnknownContact = "ffffffffffffffff"
+
+// Reads the faces of an image from the .picasa.ini file of its folder, if there is one.
+// Faces are listed in the section of the image, like faces=rect64(3f845bcb59418507),8e62398ebda8c1a5
+// where the rectangle holds the left, top, right and bottom edges as 16-bit fractions of the image size,
+// and the contact is named in the contacts sections.
+func readPicasaFaces(filePath string) ([]faceRegion, error) {
+	for _, fileName := range picasaFileNames {
+		iniPath := filepath.Join(filepath.Dir(filePath), fileName)
+
+		sections, err := readPicasaINI(iniPath)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return getPicasaFaces(sections, filepath.Base(filePath)), nil
+	}
+
+	return nil, nil
+}
+
+// Reads an INI file into its sections, keyed by their lowercased name.
+func readPicasaINI(iniPath string) (map[string]map[string]string, error) {
+	file, err := os.Open(iniPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", iniPath, err)
+	}
+	defer file.Close()
+
+	sections := make(map[string]map[string]string)
+
+	var section map[string]string
+
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.ToLower(line[1 : len(line)-1])
+
+			section = make(map[string]string)
+			sections[name] = section
+
+			continue
+		}
+
+		if key, value, ok := strings.Cut(line, "="); ok && section != nil {
+			section[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", iniPath, err)
+	}
+
+	return sections, nil
+}
+
+func getPicasaFaces(sections map[string]map[string]string, fileName string) []faceRegion {
+	section, ok := sections[strings.ToLower(fileName)]
+	if !ok || section["faces"] == "" {
+		return nil
+	}
+
+	var faces []faceRegion
+
+	for _, face := range strings.Split(section["faces"], ";") {
+		rectangle, contact, _ := strings.Cut(face, ",")
+
+		region, ok := parsePicasaRectangle(rectangle)
+		if !ok {
+			continue
+		}
+
+		if contact != picasaUnknownContact {
+			region.Name = getPicasaContactName(sections, contact)
+		}
+
+		faces = append(faces, region)
+	}
+
+	return faces
+}
+
+// Parses a rect64 value, whose leading zeros are left out.
+func parsePicasaRectangle(rectangle string) (faceRegion, bool) {
+	if !strings.HasPrefix(rectangle, "rect64(") || !strings.HasSuffix(rectangle, ")") {
+		return faceRegion{}, false
+	}
+
+	value, err := strconv.ParseUint(rectangle[len("rect64("):len(rectangle)-1], 16, 64)
+	if err != nil {
+		return faceRegion{}, false
+	}
+
+	edges := [4]float64{}
+
+	for index := range edges {
+		edges[index] = float64(value>>(48-16*index)&0xFFFF) / 0xFFFF //nolint:gomnd
+	}
+
+	return faceRegion{
+		Source: database.PicasaFaceRegionSource,
+		X:      edges[0],
+		Y:      edges[1],
+		Width:  edges[2] - edges[0],
+		Height: edges[3] - edges[1],
+	}, true
+}
+
+// Returns the name of a contact, from the contacts sections of recent and older versions of Picasa,
+// whose values look like Alice Smith;alice@example.com;
+func getPicasaContactName(sections map[string]map[string]string, contact string) string {
+	for _, sectionName := range []string{"contacts2", "contacts"} {
+		if value, ok := sections[sectionName][contact]; ok {
+			name, _, _ := strings.Cut(value, ";")
+
+			return strings.TrimSpace(name)
+		}
+	}
+
+	return ""
+}
@@ -1,40 +1,153 @@
 package image
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/meteorae/meteorae-server/database"
-	"github.com/meteorae/meteorae-server/helpers"
+	"github.com/meteorae/meteorae-server/geocoding"
+	"github.com/meteorae/meteorae-server/providers/registry"
+	"github.com/meteorae/meteorae-server/utils"
 	"github.com/rs/zerolog/log"
+	"github.com/rwcarlsen/goexif/exif"
 )
 
-func GetInformation(item *database.ItemMetadata, library database.Library) error {
-	thumb, err := helpers.SaveLocalImageToCache(item.MediaPart.FilePath)
+func init() {
+	registry.Register(imageProvider)
+	exif.RegisterParsers(offsetParser{})
+}
+
+var imageProvider registry.Provider = Provider{}
+
+// Uses the image files themselves as the thumbnail of their items, and reads their EXIF data,
+// along with the titles, captions, ratings, keywords and faces embedded in them, in XMP sidecars or in
+// Picasa files.
+type Provider struct{}
+
+func (p Provider) GetName() string {
+	return "Local Image"
+}
+
+func (p Provider) SupportsLibraryType(library database.Library) bool {
+	return library.Type == database.ImageLibrary
+}
+
+// Local images are identified by their path, so the search always matches the file itself.
+func (p Provider) Search(query registry.SearchQuery, library database.Library) ([]registry.SearchResult, error) {
+	return []registry.SearchResult{{
+		ID:    query.FilePath,
+		Title: query.Title,
+	}}, nil
+}
+
+// Returns the EXIF data of the image, with the date it was taken as its release date and the place
+// it was taken at, the metadata embedded in it or in its sidecars, and its perceptual hash.
+// Keywords are saved as tags, and the people named in face regions are saved as persons.
+func (p Provider) GetMetadata(id string, library database.Library) (*database.ItemMetadata, error) {
+	imageExif, err := readExif(id)
 	if err != nil {
-		return fmt.Errorf("could not save image to local cache %s: %w", item.MediaPart.FilePath, err)
+		return nil, err
 	}
 
-	item.Thumb = thumb
+	if imageExif.Latitude != nil && imageExif.Longitude != nil {
+		if place := geocoding.ReverseGeocode(*imageExif.Latitude, *imageExif.Longitude); place != nil {
+			imageExif.Country = place.Country
+			imageExif.Region = place.Region
+			imageExif.City = place.City
+		}
+	}
 
-	// Figure out if the parent already has a preview thumbnail. If not, we set it to the current item's thumbnail.
-	parent, err := database.GetImageAlbum(item.ParentID)
+	embedded, err := readEmbeddedMetadata(id)
 	if err != nil {
-		log.Err(err).Msgf("Failed to get image album for path %s: %s", item.MediaPart.FilePath, err)
+		return nil, err
 	}
 
-	if parent.Thumb == "" {
-		parent.Thumb = thumb
+	extraInfo, err := json.Marshal(database.ImageExtraInfo{Rating: embedded.Rating})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image information: %w", err)
+	}
 
-		err = database.UpdateImageAlbum(parent)
+	metadata := database.ItemMetadata{
+		Title:       embedded.Title,
+		Summary:     embedded.Description,
+		ExtraInfo:   extraInfo,
+		Exif:        imageExif,
+		Tags:        getKeywordTags(embedded),
+		FaceRegions: getFaceRegions(embedded),
+	}
+
+	if perceptualHash, err := getPerceptualHash(id); err == nil {
+		metadata.PerceptualHash = perceptualHash
+	} else {
+		log.Warn().Err(err).Msgf("Failed to compute the perceptual hash of %s", id)
+	}
+
+	if embedded.Title != "" {
+		metadata.SortTitle = utils.CleanSortTitle(embedded.Title)
+	}
+
+	if imageExif.DateTaken != nil {
+		metadata.ReleaseDate = *imageExif.DateTaken
+	}
+
+	return &metadata, nil
+}
+
+// Saves the keywords of an image to the tag tree. Keywords that can't be saved are skipped.
+func getKeywordTags(embedded *embeddedMetadata) []database.ItemTag {
+	keywordPaths := embedded.getKeywordPaths()
+	itemTags := make([]database.ItemTag, 0, len(keywordPaths))
+
+	for _, path := range keywordPaths {
+		tag, err := database.GetOrCreateTagPath(path)
 		if err != nil {
-			log.Err(err).Msgf("Failed to update image album for path %s: %s", item.MediaPart.FilePath, err)
+			log.Err(err).Msgf("Failed to save keyword \"%s\"", strings.Join(path, "|"))
+
+			continue
 		}
+
+		itemTags = append(itemTags, database.ItemTag{TagID: tag.ID})
 	}
 
-	err = database.UpdateImage(item)
-	if err != nil {
-		return fmt.Errorf("could not get information for image %s: %w", item.MediaPart.FilePath, err)
+	return itemTags
+}
+
+// Converts the faces of an image, linking them to the people with their name, who are created as needed.
+// Faces whose person can't be saved are kept without it.
+func getFaceRegions(embedded *embeddedMetadata) []database.FaceRegion {
+	regions := make([]database.FaceRegion, 0, len(embedded.FaceRegions))
+
+	for _, face := range embedded.FaceRegions {
+		region := database.FaceRegion{
+			X:      face.X,
+			Y:      face.Y,
+			Width:  face.Width,
+			Height: face.Height,
+			Source: face.Source,
+		}
+
+		if face.Name != "" {
+			person, err := database.GetOrCreatePerson(&database.ItemMetadata{
+				Title:     face.Name,
+				SortTitle: utils.CleanSortTitle(face.Name),
+			})
+			if err != nil {
+				log.Err(err).Msgf("Failed to save person \"%s\"", face.Name)
+			} else {
+				region.Person = person
+			}
+		}
+
+		regions = append(regions, region)
 	}
 
-	return nil
+	return regions
+}
+
+func (p Provider) GetImages(id string, library database.Library) ([]registry.Image, error) {
+	return []registry.Image{{
+		Type: registry.PosterImage,
+		URL:  id,
+	}}, nil
 }
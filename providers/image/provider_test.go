@@ -0,0 +1,330 @@
+package image_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/internal/databasetest"
+	imageProvider "github.com/meteorae/meteorae-server/providers/image"
+)
+
+const sidecar = `<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:dc="http://purl.org/dc/elements/1.1/"
+    xmlns:xmp="http://ns.adobe.com/xap/1.0/"
+    xmp:Rating="4">
+   <dc:title>
+    <rdf:Alt>
+     <rdf:li xml:lang="x-default">Sunset</rdf:li>
+    </rdf:Alt>
+   </dc:title>
+   <dc:description>
+    <rdf:Alt>
+     <rdf:li xml:lang="x-default">The beach at dusk</rdf:li>
+    </rdf:Alt>
+   </dc:description>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>`
+
+func writePNG(t *testing.T, path string) {
+	t.Helper()
+
+	var buffer bytes.Buffer
+
+	if err := png.Encode(&buffer, image.NewGray(image.Rect(0, 0, 3, 2))); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, buffer.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Returns a minimal JPEG holding IPTC-IIM data in its Photoshop segment.
+func newIPTCJPEG(datasets map[byte]string) []byte {
+	var iim bytes.Buffer
+
+	for dataset, value := range datasets {
+		iim.Write([]byte{0x1C, 2, dataset})
+		_ = binary.Write(&iim, binary.BigEndian, uint16(len(value)))
+		iim.WriteString(value)
+	}
+
+	var resources bytes.Buffer
+
+	resources.WriteString("Photoshop 3.0\x00")
+	resources.WriteString("8BIM")
+	resources.Write([]byte{0x04, 0x04, 0, 0})
+	_ = binary.Write(&resources, binary.BigEndian, uint32(iim.Len()))
+	resources.Write(iim.Bytes())
+
+	if iim.Len()%2 == 1 {
+		resources.WriteByte(0)
+	}
+
+	var jpeg bytes.Buffer
+
+	jpeg.Write([]byte{0xFF, 0xD8, 0xFF, 0xED})
+	_ = binary.Write(&jpeg, binary.BigEndian, uint16(resources.Len()+2))
+	jpeg.Write(resources.Bytes())
+	jpeg.Write([]byte{0xFF, 0xD9})
+
+	return jpeg.Bytes()
+}
+
+func getRating(t *testing.T, metadata *database.ItemMetadata) int {
+	t.Helper()
+
+	var extraInfo database.ImageExtraInfo
+	if err := json.Unmarshal(metadata.ExtraInfo, &extraInfo); err != nil {
+		t.Fatal(err)
+	}
+
+	return extraInfo.Rating
+}
+
+func TestGetMetadataReadsSidecar(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir()
+	imagePath := filepath.Join(directory, "sunset.png")
+
+	writePNG(t, imagePath)
+
+	if err := os.WriteFile(imagePath+".xmp", []byte(sidecar), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, err := imageProvider.Provider{}.GetMetadata(imagePath, database.Library{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if metadata.Title != "Sunset" || metadata.Summary != "The beach at dusk" {
+		t.Errorf("unexpected text: %q, %q", metadata.Title, metadata.Summary)
+	}
+
+	if rating := getRating(t, metadata); rating != 4 {
+		t.Errorf("expected a rating of 4, got %d", rating)
+	}
+
+	if metadata.Exif.Width == nil || *metadata.Exif.Width != 3 {
+		t.Errorf("unexpected width: %v", metadata.Exif.Width)
+	}
+}
+
+func TestGetMetadataReadsIPTC(t *testing.T) {
+	t.Parallel()
+
+	imagePath := filepath.Join(t.TempDir(), "photo.jpg")
+
+	// 0xE9 is é in Latin-1, used by older files
+	data := newIPTCJPEG(map[byte]string{5: "Caf\xe9", 120: "Morning coffee"})
+
+	if err := os.WriteFile(imagePath, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, err := imageProvider.Provider{}.GetMetadata(imagePath, database.Library{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if metadata.Title != "Café" || metadata.Summary != "Morning coffee" {
+		t.Errorf("unexpected text: %q, %q", metadata.Title, metadata.Summary)
+	}
+
+	if rating := getRating(t, metadata); rating != 0 {
+		t.Errorf("expected no rating, got %d", rating)
+	}
+}
+
+// Keywords as written by Lightroom and digiKam, along with their flat list in dc:subject.
+const keywordsSidecar = `<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:dc="http://purl.org/dc/elements/1.1/"
+    xmlns:lr="http://ns.adobe.com/lightroom/1.0/"
+    xmlns:digiKam="http://www.digikam.org/ns/1.0/">
+   <dc:subject>
+    <rdf:Bag>
+     <rdf:li>Paris</rdf:li>
+     <rdf:li>France</rdf:li>
+     <rdf:li>Alice</rdf:li>
+     <rdf:li>holiday</rdf:li>
+    </rdf:Bag>
+   </dc:subject>
+   <lr:hierarchicalSubject>
+    <rdf:Bag>
+     <rdf:li>Places|France|Paris</rdf:li>
+    </rdf:Bag>
+   </lr:hierarchicalSubject>
+   <digiKam:TagsList>
+    <rdf:Seq>
+     <rdf:li>People/Alice</rdf:li>
+    </rdf:Seq>
+   </digiKam:TagsList>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>`
+
+// Returns the paths of the tags of an item, with their names separated by |.
+func getTagPaths(t *testing.T, itemTags []database.ItemTag) []string {
+	t.Helper()
+
+	paths := make([]string, 0, len(itemTags))
+
+	for _, itemTag := range itemTags {
+		tag, err := database.GetTagByID(strconv.FormatUint(itemTag.TagID, 10))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		path, err := database.GetTagPath(tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names := make([]string, 0, len(path))
+		for _, parent := range path {
+			names = append(names, parent.Name)
+		}
+
+		paths = append(paths, strings.Join(names, "|"))
+	}
+
+	return paths
+}
+
+func TestGetMetadataReadsKeywords(t *testing.T) {
+	databasetest.Setup(t)
+
+	imagePath := filepath.Join(t.TempDir(), "paris.png")
+
+	writePNG(t, imagePath)
+
+	if err := os.WriteFile(imagePath+".xmp", []byte(keywordsSidecar), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, err := imageProvider.Provider{}.GetMetadata(imagePath, database.Library{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Flat keywords already in a hierarchy aren't saved twice
+	paths := strings.Join(getTagPaths(t, metadata.Tags), ", ")
+	if paths != "Places|France|Paris, People|Alice, Keywords|holiday" {
+		t.Errorf("unexpected keywords: %s", paths)
+	}
+}
+
+// MWG regions as written by Lightroom and digiKam, with the area centered on x and y.
+const regionsSidecar = `<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:mwg-rs="http://www.metadataworkinggroup.com/schemas/regions/"
+    xmlns:stArea="http://ns.adobe.com/xmp/sType/Area#">
+   <mwg-rs:Regions rdf:parseType="Resource">
+    <mwg-rs:RegionList>
+     <rdf:Bag>
+      <rdf:li>
+       <rdf:Description mwg-rs:Type="Face">
+        <mwg-rs:Area stArea:x="0.5" stArea:y="0.4" stArea:w="0.2" stArea:h="0.4" stArea:unit="normalized"/>
+       </rdf:Description>
+      </rdf:li>
+      <rdf:li>
+       <rdf:Description mwg-rs:Type="Pet">
+        <mwg-rs:Area stArea:x="0.1" stArea:y="0.1" stArea:w="0.1" stArea:h="0.1" stArea:unit="normalized"/>
+       </rdf:Description>
+      </rdf:li>
+     </rdf:Bag>
+    </mwg-rs:RegionList>
+   </mwg-rs:Regions>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>`
+
+func assertFaceRegion(t *testing.T, region database.FaceRegion, x, y, width, height float64) {
+	t.Helper()
+
+	const epsilon = 0.001
+
+	for _, pair := range [][2]float64{{region.X, x}, {region.Y, y}, {region.Width, width}, {region.Height, height}} {
+		if math.Abs(pair[0]-pair[1]) > epsilon {
+			t.Errorf("expected a region at %v, %v of %v by %v, got %+v", x, y, width, height, region)
+
+			return
+		}
+	}
+}
+
+func TestGetMetadataReadsFaceRegions(t *testing.T) {
+	t.Parallel()
+
+	imagePath := filepath.Join(t.TempDir(), "family.png")
+
+	writePNG(t, imagePath)
+
+	if err := os.WriteFile(imagePath+".xmp", []byte(regionsSidecar), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, err := imageProvider.Provider{}.GetMetadata(imagePath, database.Library{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(metadata.FaceRegions) != 1 {
+		t.Fatalf("expected only the face region, got %+v", metadata.FaceRegions)
+	}
+
+	assertFaceRegion(t, metadata.FaceRegions[0], 0.4, 0.2, 0.2, 0.4)
+
+	if metadata.FaceRegions[0].Source != database.XMPFaceRegionSource || metadata.FaceRegions[0].Person != nil {
+		t.Errorf("unexpected face region %+v", metadata.FaceRegions[0])
+	}
+}
+
+func TestGetMetadataReadsPicasaFaces(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir()
+	imagePath := filepath.Join(directory, "IMG_0001.png")
+
+	writePNG(t, imagePath)
+
+	// Edges are 16-bit fractions, and leading zeros are left out
+	picasaINI := "[img_0001.png]\r\nfaces=rect64(4000400080008000),ffffffffffffffff;rect64(invalid),ffffffffffffffff\r\n"
+
+	if err := os.WriteFile(filepath.Join(directory, ".picasa.ini"), []byte(picasaINI), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, err := imageProvider.Provider{}.GetMetadata(imagePath, database.Library{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(metadata.FaceRegions) != 1 {
+		t.Fatalf("expected a single face region, got %+v", metadata.FaceRegions)
+	}
+
+	assertFaceRegion(t, metadata.FaceRegions[0], 0.25, 0.25, 0.25, 0.25)
+
+	if metadata.FaceRegions[0].Source != database.PicasaFaceRegionSource {
+		t.Errorf("unexpected source %s", metadata.FaceRegions[0].Source)
+	}
+}
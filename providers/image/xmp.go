@@ -0,0 +1,535 @@
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/helpers"
+	"github.com/rs/zerolog/log"
+)
+
+// Namespaces of the XMP properties we read.
+const (
+	rdfNamespace       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	dcNamespace        = "http://purl.org/dc/elements/1.1/"
+	xmpNamespace       = "http://ns.adobe.com/xap/1.0/"
+	lightroomNamespace = "http://ns.adobe.com/lightroom/1.0/"
+	digiKamNamespace   = "http://www.digikam.org/ns/1.0/"
+	regionsNamespace   = "http://www.metadataworkinggroup.com/schemas/regions/"
+	areaNamespace      = "http://ns.adobe.com/xmp/sType/Area#"
+)
+
+// IPTC-IIM datasets we read, from the application record.
+const (
+	iptcApplicationRecord = 2
+	iptcObjectName        = 5
+	iptcKeywords          = 25
+	iptcCaption           = 120
+)
+
+var (
+	xmpPacketStart = []byte("<x:xmpmeta")
+	xmpPacketEnd   = []byte("</x:xmpmeta>")
+)
+
+// Metadata written into image files or their sidecars by photo managers like Lightroom and digiKam.
+type embeddedMetadata struct {
+	Title       string
+	Description string
+	// Star rating from 1 to 5, or -1 for rejected photos. 0 means unrated.
+	Rating int
+	// Keywords with their hierarchy, root first.
+	HierarchicalKeywords [][]string
+	Keywords             []string
+	FaceRegions          []faceRegion
+}
+
+// A face written into an image by another application, with its box normalized to the image size,
+// from its top left corner.
+type faceRegion struct {
+	// Name of the person, if known.
+	Name   string
+	Source database.FaceRegionSource
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+}
+
+// A region from an MWG region list, as written in XMP. The area is centered on x and y.
+type xmpRegion struct {
+	name, regionType    string
+	x, y, width, height string
+	unit                string
+}
+
+// Returns the region as a face region, if it is a face with a normalized area.
+func (r *xmpRegion) getFaceRegion() (faceRegion, bool) {
+	if (r.regionType != "" && r.regionType != "Face") || (r.unit != "" && r.unit != "normalized") {
+		return faceRegion{}, false
+	}
+
+	var values [4]float64
+
+	for index, value := range []string{r.x, r.y, r.width, r.height} {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return faceRegion{}, false
+		}
+
+		values[index] = parsed
+	}
+
+	return faceRegion{
+		Name:   r.name,
+		Source: database.XMPFaceRegionSource,
+		X:      values[0] - values[2]/2, //nolint:gomnd
+		Y:      values[1] - values[3]/2, //nolint:gomnd
+		Width:  values[2],
+		Height: values[3],
+	}, true
+}
+
+func (r *xmpRegion) setProperty(name xml.Name, value string) {
+	switch name {
+	case xml.Name{Space: regionsNamespace, Local: "Name"}:
+		r.name = value
+	case xml.Name{Space: regionsNamespace, Local: "Type"}:
+		r.regionType = value
+	case xml.Name{Space: areaNamespace, Local: "x"}:
+		r.x = value
+	case xml.Name{Space: areaNamespace, Local: "y"}:
+		r.y = value
+	case xml.Name{Space: areaNamespace, Local: "w"}:
+		r.width = value
+	case xml.Name{Space: areaNamespace, Local: "h"}:
+		r.height = value
+	case xml.Name{Space: areaNamespace, Local: "unit"}:
+		r.unit = value
+	}
+}
+
+// Reads the metadata embedded in an image and its XMP sidecars, and the faces from .picasa.ini files.
+// Sidecars take precedence over embedded XMP, which takes precedence over IPTC and Picasa.
+// Keywords from all sources are kept.
+func readEmbeddedMetadata(filePath string) (*embeddedMetadata, error) {
+	var metadata embeddedMetadata
+
+	for _, sidecarPath := range helpers.GetSidecarPaths(filePath) {
+		data, err := os.ReadFile(sidecarPath)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to read XMP sidecar %s", sidecarPath)
+
+			continue
+		}
+
+		sidecarMetadata, err := parseXMP(data)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to parse XMP sidecar %s", sidecarPath)
+
+			continue
+		}
+
+		metadata.merge(sidecarMetadata)
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	if packet := findXMPPacket(data); packet != nil {
+		xmpMetadata, err := parseXMP(packet)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to parse the XMP data of %s", filePath)
+		} else {
+			metadata.merge(xmpMetadata)
+		}
+	}
+
+	metadata.merge(parseIPTC(data))
+
+	picasaFaces, err := readPicasaFaces(filePath)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to read the Picasa faces of %s", filePath)
+	}
+
+	metadata.merge(&embeddedMetadata{FaceRegions: picasaFaces})
+
+	return &metadata, nil
+}
+
+// Fills the empty fields with the ones from other, and adds its keywords.
+func (m *embeddedMetadata) merge(other *embeddedMetadata) {
+	if other == nil {
+		return
+	}
+
+	if m.Title == "" {
+		m.Title = other.Title
+	}
+
+	if m.Description == "" {
+		m.Description = other.Description
+	}
+
+	if m.Rating == 0 {
+		m.Rating = other.Rating
+	}
+
+	// Each application writes all the faces it knows of, so they aren't merged
+	if len(m.FaceRegions) == 0 {
+		m.FaceRegions = other.FaceRegions
+	}
+
+	for _, path := range other.HierarchicalKeywords {
+		if !containsPath(m.HierarchicalKeywords, path) {
+			m.HierarchicalKeywords = append(m.HierarchicalKeywords, path)
+		}
+	}
+
+	for _, keyword := range other.Keywords {
+		if !containsKeyword(m.Keywords, keyword) {
+			m.Keywords = append(m.Keywords, keyword)
+		}
+	}
+}
+
+// Returns the tag paths of the keywords. Keywords without hierarchy are filed under the keywords
+// namespace, unless they are part of a hierarchy, since Lightroom also writes every level of
+// hierarchical keywords as flat keywords.
+func (m *embeddedMetadata) getKeywordPaths() [][]string {
+	paths := make([][]string, 0, len(m.HierarchicalKeywords)+len(m.Keywords))
+	paths = append(paths, m.HierarchicalKeywords...)
+
+	for _, keyword := range m.Keywords {
+		if !isInHierarchy(m.HierarchicalKeywords, keyword) {
+			paths = append(paths, []string{database.KeywordTagNamespace, keyword})
+		}
+	}
+
+	return paths
+}
+
+func containsPath(paths [][]string, path []string) bool {
+	for _, existing := range paths {
+		if strings.EqualFold(strings.Join(existing, "|"), strings.Join(path, "|")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsKeyword(keywords []string, keyword string) bool {
+	for _, existing := range keywords {
+		if strings.EqualFold(existing, keyword) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isInHierarchy(paths [][]string, keyword string) bool {
+	for _, path := range paths {
+		for _, name := range path {
+			if strings.EqualFold(name, keyword) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Returns the XMP packet embedded in a file, if any. This works for most image formats,
+// since XMP is designed to be found by scanning files.
+func findXMPPacket(data []byte) []byte {
+	start := bytes.Index(data, xmpPacketStart)
+	if start < 0 {
+		return nil
+	}
+
+	end := bytes.Index(data[start:], xmpPacketEnd)
+	if end < 0 {
+		return nil
+	}
+
+	return data[start : start+end+len(xmpPacketEnd)]
+}
+
+// Parses an XMP packet or sidecar. Properties are read both from elements and from the
+// attributes of rdf:Description, which are the two ways of writing simple properties.
+func parseXMP(data []byte) (*embeddedMetadata, error) {
+	properties := make(map[xml.Name][]string)
+
+	var (
+		stack       []xml.Name
+		faceRegions []faceRegion
+		// The region being read, and the depth of its list item
+		region      *xmpRegion
+		regionDepth int
+	)
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode XMP: %w", err)
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			if region == nil && isRegionListItem(stack, element.Name) {
+				region = &xmpRegion{}
+				regionDepth = len(stack)
+			}
+
+			if region != nil {
+				for _, attribute := range element.Attr {
+					region.setProperty(attribute.Name, attribute.Value)
+				}
+			} else if element.Name.Space == rdfNamespace && element.Name.Local == "Description" {
+				for _, attribute := range element.Attr {
+					properties[attribute.Name] = append(properties[attribute.Name], attribute.Value)
+				}
+			}
+
+			stack = append(stack, element.Name)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+
+			if region != nil && len(stack) == regionDepth {
+				if face, ok := region.getFaceRegion(); ok {
+					faceRegions = append(faceRegions, face)
+				}
+
+				region = nil
+			}
+		case xml.CharData:
+			text := strings.TrimSpace(string(element))
+			if text == "" {
+				continue
+			}
+
+			if region != nil {
+				region.setProperty(stack[len(stack)-1], text)
+
+				continue
+			}
+
+			if property, ok := getXMPProperty(stack); ok {
+				properties[property] = append(properties[property], text)
+			}
+		}
+	}
+
+	metadata := embeddedMetadata{
+		Title:       getFirst(properties[xml.Name{Space: dcNamespace, Local: "title"}]),
+		Description: getFirst(properties[xml.Name{Space: dcNamespace, Local: "description"}]),
+		Rating:      parseRating(getFirst(properties[xml.Name{Space: xmpNamespace, Local: "Rating"}])),
+		Keywords:    properties[xml.Name{Space: dcNamespace, Local: "subject"}],
+		FaceRegions: faceRegions,
+	}
+
+	for _, keyword := range properties[xml.Name{Space: lightroomNamespace, Local: "hierarchicalSubject"}] {
+		metadata.addHierarchicalKeyword(strings.Split(keyword, "|"))
+	}
+
+	for _, keyword := range properties[xml.Name{Space: digiKamNamespace, Local: "TagsList"}] {
+		metadata.addHierarchicalKeyword(strings.Split(keyword, "/"))
+	}
+
+	return &metadata, nil
+}
+
+// Returns the property holding the current element, which is the child of the closest rdf:Description.
+// Values are either directly in the property, or in the rdf:li elements of a list.
+func getXMPProperty(stack []xml.Name) (xml.Name, bool) {
+	for index := len(stack) - 1; index > 0; index-- {
+		parent := stack[index-1]
+		if parent.Space != rdfNamespace || parent.Local != "Description" {
+			continue
+		}
+
+		innermost := stack[len(stack)-1]
+		if index == len(stack)-1 || (innermost.Space == rdfNamespace && innermost.Local == "li") {
+			return stack[index], true
+		}
+
+		return xml.Name{}, false
+	}
+
+	return xml.Name{}, false
+}
+
+// Returns whether an element is an item of an MWG region list, which holds a single region.
+func isRegionListItem(stack []xml.Name, name xml.Name) bool {
+	if name.Space != rdfNamespace || name.Local != "li" || len(stack) < 2 { //nolint:gomnd
+		return false
+	}
+
+	list, parent := stack[len(stack)-1], stack[len(stack)-2]
+
+	return list.Space == rdfNamespace && (list.Local == "Bag" || list.Local == "Seq") &&
+		parent.Space == regionsNamespace && parent.Local == "RegionList"
+}
+
+func (m *embeddedMetadata) addHierarchicalKeyword(path []string) {
+	cleanPath := make([]string, 0, len(path))
+
+	for _, name := range path {
+		if name = strings.TrimSpace(name); name != "" {
+			cleanPath = append(cleanPath, name)
+		}
+	}
+
+	if len(cleanPath) > 0 && !containsPath(m.HierarchicalKeywords, cleanPath) {
+		m.HierarchicalKeywords = append(m.HierarchicalKeywords, cleanPath)
+	}
+}
+
+func getFirst(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+// Parses an XMP rating, which some applications write as a decimal number.
+func parseRating(value string) int {
+	rating, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+
+	return int(math.Round(math.Max(-1, math.Min(5, rating)))) //nolint:gomnd
+}
+
+// Parses the IPTC-IIM data of a JPEG file, stored in its Photoshop segment. Returns nil if there is none.
+func parseIPTC(data []byte) *embeddedMetadata {
+	if !bytes.HasPrefix(data, []byte{0xFF, 0xD8}) {
+		return nil
+	}
+
+	photoshopHeader := []byte("Photoshop 3.0\x00")
+
+	// Walk the JPEG segments until the image data
+	for position := 2; position+4 <= len(data) && data[position] == 0xFF; {
+		marker := data[position+1]
+		if marker == 0xDA || marker == 0xD9 {
+			break
+		}
+
+		end := position + 2 + int(binary.BigEndian.Uint16(data[position+2:]))
+		if end > len(data) {
+			break
+		}
+
+		segment := data[position+4 : end]
+		if marker == 0xED && bytes.HasPrefix(segment, photoshopHeader) {
+			return parsePhotoshopResources(segment[len(photoshopHeader):])
+		}
+
+		position = end
+	}
+
+	return nil
+}
+
+// Finds the IPTC-IIM resource among Photoshop image resources.
+func parsePhotoshopResources(data []byte) *embeddedMetadata {
+	const iptcResourceID = 0x0404
+
+	for len(data) >= 12 && bytes.HasPrefix(data, []byte("8BIM")) {
+		resourceID := binary.BigEndian.Uint16(data[4:])
+
+		// The resource name is a Pascal string, padded to an even size
+		nameSize := 1 + int(data[6])
+		nameSize += nameSize % 2
+
+		sizeOffset := 6 + nameSize
+		if sizeOffset+4 > len(data) {
+			return nil
+		}
+
+		size := int(binary.BigEndian.Uint32(data[sizeOffset:]))
+		start := sizeOffset + 4
+
+		if size < 0 || start+size > len(data) {
+			return nil
+		}
+
+		if resourceID == iptcResourceID {
+			return parseIIM(data[start : start+size])
+		}
+
+		data = data[start+size+size%2:]
+	}
+
+	return nil
+}
+
+func parseIIM(data []byte) *embeddedMetadata {
+	var metadata embeddedMetadata
+
+	for len(data) >= 5 && data[0] == 0x1C {
+		record, dataset := data[1], data[2]
+		size := int(binary.BigEndian.Uint16(data[3:]))
+
+		// Extended datasets are only used for large binary data, which we don't need
+		if size&0x8000 != 0 || 5+size > len(data) {
+			break
+		}
+
+		value := strings.TrimSpace(decodeIIMString(data[5 : 5+size]))
+		data = data[5+size:]
+
+		if record != iptcApplicationRecord || value == "" {
+			continue
+		}
+
+		switch dataset {
+		case iptcObjectName:
+			metadata.Title = value
+		case iptcCaption:
+			metadata.Description = value
+		case iptcKeywords:
+			metadata.Keywords = append(metadata.Keywords, value)
+		}
+	}
+
+	return &metadata
+}
+
+// Decodes an IPTC-IIM string, which is UTF-8 in recent files, and usually Latin-1 in older ones.
+func decodeIIMString(value []byte) string {
+	if utf8.Valid(value) {
+		return string(value)
+	}
+
+	runes := make([]rune, 0, len(value))
+
+	for _, character := range value {
+		runes = append(runes, rune(character))
+	}
+
+	return string(runes)
+}
@@ -2,110 +2,322 @@ package movie
 
 import (
 	"fmt"
+	"net/url"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/meteorae/meteorae-server/database"
-	"github.com/meteorae/meteorae-server/helpers"
+	"github.com/meteorae/meteorae-server/providers/registry"
 	"github.com/meteorae/meteorae-server/utils"
 	"github.com/rs/zerolog/log"
-	"github.com/ryanbradynd05/go-tmdb"
+	"github.com/spf13/viper"
 	"golang.org/x/text/language"
 )
 
-var errNoResultsFound = fmt.Errorf("no results found")
+// Some movies have multiple languages or versions in the name using "aka", we only keep the first one.
+var akaRegexp = regexp.MustCompile("(.*) aka .*")
 
-var apiKey = "c9ae218044f9b20a4fcbba36d543a730" //#nosec
+func init() {
+	registry.Register(movieProvider)
+}
+
+var movieProvider registry.Provider = Provider{}
+
+// Fetches movie information from The Movie Database.
+type Provider struct{}
 
-var config = tmdb.Config{
-	APIKey:   apiKey,
-	Proxies:  nil,
-	UseProxy: false,
+func (p Provider) GetName() string {
+	return "TMDb"
 }
 
-var tmdbAPI *tmdb.TMDb = tmdb.Init(config)
+func (p Provider) SupportsLibraryType(library database.Library) bool {
+	return library.Type == database.MovieLibrary
+}
 
-func GetInformation(item *database.ItemMetadata, library database.Library) error {
+func (p Provider) Search(query registry.SearchQuery, library database.Library) ([]registry.SearchResult, error) {
 	// Remove unwanted characters from the title
-	item.Title = utils.RemoveUnwantedCharacters(item.Title)
-
-	// Some movies have multiple languages or versions in the name using "aka", get only the first one
-	reg := regexp.MustCompile("(.*) aka .*")
-	cleanTitle := reg.FindStringSubmatch(item.Title)
+	title := utils.RemoveUnwantedCharacters(query.Title)
 
+	cleanTitle := akaRegexp.FindStringSubmatch(title)
 	if len(cleanTitle) > 0 {
-		log.Info().Msgf("Title cleaned up to %s", cleanTitle[1])
-		item.Title = cleanTitle[1]
+		log.Debug().Msgf("Title cleaned up to %s", cleanTitle[1])
+		title = cleanTitle[1]
 	}
 
-	searchResults, err := tmdbAPI.SearchMovie(item.Title, map[string]string{
-		"language":      "en-US", // Make this configurable
-		"include_adult": "false", // Make this configurable
-	})
+	parameters := url.Values{
+		"query":         {title},
+		"language":      {getLanguage(library.Language)},
+		"include_adult": {strconv.FormatBool(library.IncludeAdult)},
+	}
+
+	if query.Year > 0 {
+		parameters.Set("year", strconv.Itoa(query.Year))
+	}
+
+	var searchResults tmdbSearchResults
+
+	err := getTMDb("/search/movie", parameters, &searchResults)
 	if err != nil {
-		return fmt.Errorf("could not search for movie: %w", err)
+		return nil, fmt.Errorf("could not search for movie: %w", err)
 	}
 
-	if len(searchResults.Results) > 0 {
-		resultMovie := searchResults.Results[0]
+	results := make([]registry.SearchResult, 0, len(searchResults.Results))
 
-		movieData, err := tmdbAPI.GetMovieInfo(resultMovie.ID, map[string]string{})
-		if err != nil {
-			return fmt.Errorf("failed to fetch information for movie \"%s\": %w", item.Title, err)
+	for _, result := range searchResults.Results {
+		var thumb string
+		if result.PosterPath != "" {
+			thumb = getTMDbImageURL(result.PosterPath)
 		}
 
-		releaseDate, err := time.Parse("2006-01-02", movieData.ReleaseDate)
-		if err != nil {
-			log.Err(err).Msgf("Failed to parse release date for movie \"%s\"", item.Title)
+		results = append(results, registry.SearchResult{
+			ID:    strconv.FormatInt(result.ID, 10), //nolint:gomnd
+			Title: result.Title,
+			Year:  parseReleaseDate(result.ReleaseDate).Year(),
+			Thumb: thumb,
+		})
+	}
 
-			releaseDate = time.Time{}
-		}
+	return results, nil
+}
 
-		languageTag, err := language.Parse(movieData.OriginalLanguage)
-		if err != nil {
-			log.Err(err).Msgf("Failed to parse original language for movie \"%s\", using Undefined", item.Title)
+func (p Provider) GetMetadata(id string, library database.Library) (*database.ItemMetadata, error) {
+	var movieData tmdbMovie
 
-			languageTag = language.Und
-		}
+	err := getTMDb(fmt.Sprintf("/movie/%s", url.PathEscape(id)), url.Values{
+		"language":           {getLanguage(library.Language)},
+		"append_to_response": {"credits"},
+	}, &movieData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch information for movie %s: %w", id, err)
+	}
 
-		var artHash string
+	languageTag, err := language.Parse(movieData.OriginalLanguage)
+	if err != nil {
+		log.Err(err).Msgf("Failed to parse original language for movie \"%s\", using Undefined", movieData.Title)
 
-		if movieData.BackdropPath != "" {
-			artPath := fmt.Sprintf("https://image.tmdb.org/t/p/original/%s", movieData.BackdropPath)
+		languageTag = language.Und
+	}
 
-			artHash, err = helpers.SaveExternalImageToCache(artPath)
-			if err != nil {
-				return fmt.Errorf("failed to download backdrop art for movie \"%s\": %w", item.Title, err)
-			}
+	identifiers := []database.ExternalIdentifier{{
+		IdentifierType: database.TmdbIdentifier,
+		Identifier:     strconv.FormatInt(movieData.ID, 10), //nolint:gomnd
+	}}
+
+	if movieData.IMDbID != "" {
+		identifiers = append(identifiers, database.ExternalIdentifier{
+			IdentifierType: database.ImdbIdentifier,
+			Identifier:     movieData.IMDbID,
+		})
+	}
+
+	return &database.ItemMetadata{
+		Title:               movieData.Title,
+		SortTitle:           utils.CleanSortTitle(movieData.Title),
+		OriginalTitle:       movieData.OriginalTitle,
+		ReleaseDate:         parseReleaseDate(movieData.ReleaseDate),
+		Summary:             movieData.Overview,
+		Tagline:             movieData.Tagline,
+		Popularity:          movieData.Popularity,
+		OriginalLanguage:    languageTag.String(),
+		Duration:            int64(time.Duration(movieData.Runtime) * time.Minute / time.Millisecond),
+		ExternalIdentifiers: identifiers,
+		Credits:             getCredits(movieData),
+		Collections:         getCollections(movieData),
+		Tags:                getGenres(movieData),
+		Ratings:             getRatings(movieData),
+	}, nil
+}
+
+// Returns the average rating of TMDb users, unless nobody rated the movie yet.
+func getRatings(movieData tmdbMovie) []database.CommunityRating {
+	if movieData.VoteCount == 0 {
+		return []database.CommunityRating{}
+	}
+
+	return []database.CommunityRating{{
+		Source:   database.TmdbRatingSource,
+		Value:    movieData.VoteAverage,
+		MaxValue: 10, //nolint:gomnd
+		Votes:    movieData.VoteCount,
+	}}
+}
+
+func getGenres(movieData tmdbMovie) []database.ItemTag {
+	genres := make([]database.ItemTag, 0, len(movieData.Genres))
+
+	for _, genre := range movieData.Genres {
+		genres = append(genres, database.ItemTag{Tag: database.Tag{Name: genre.Name}})
+	}
+
+	return genres
+}
+
+// Returns the collection the movie belongs to, like a movie series, if any.
+func getCollections(movieData tmdbMovie) []database.CollectionMember {
+	if movieData.Collection == nil {
+		return []database.CollectionMember{}
+	}
+
+	collectionID := strconv.FormatInt(movieData.Collection.ID, 10) //nolint:gomnd
+
+	collection := database.ItemMetadata{
+		Title:         movieData.Collection.Name,
+		SortTitle:     utils.CleanSortTitle(movieData.Collection.Name),
+		Type:          database.CollectionItem,
+		MatchProvider: movieProvider.GetName(),
+		MatchID:       collectionID,
+		ExternalIdentifiers: []database.ExternalIdentifier{{
+			IdentifierType: database.TmdbIdentifier,
+			Identifier:     collectionID,
+		}},
+	}
+
+	if movieData.Collection.PosterPath != "" {
+		collection.Thumb = getTMDbImageURL(movieData.Collection.PosterPath)
+	}
+
+	if movieData.Collection.BackdropPath != "" {
+		collection.Art = getTMDbImageURL(movieData.Collection.BackdropPath)
+	}
+
+	return []database.CollectionMember{{Collection: collection}}
+}
+
+// Converts the cast and crew of a movie to credits. Credits are ordered the same way as on TMDb.
+func getCredits(movieData tmdbMovie) []database.Credit {
+	credits := make([]database.Credit, 0, len(movieData.Credits.Cast)+len(movieData.Credits.Crew))
+
+	for index, cast := range movieData.Credits.Cast {
+		credits = append(credits, database.Credit{
+			Person:    getPerson(cast.ID, cast.Name, cast.ProfilePath),
+			Role:      database.CastRole,
+			Character: cast.Character,
+			Index:     index,
+		})
+	}
+
+	for index, crew := range movieData.Credits.Crew {
+		credits = append(credits, database.Credit{
+			Person:     getPerson(crew.ID, crew.Name, crew.ProfilePath),
+			Role:       database.CrewRole,
+			Department: crew.Department,
+			Job:        crew.Job,
+			Index:      index,
+		})
+	}
+
+	return credits
+}
+
+func getPerson(id int64, name, profilePath string) database.ItemMetadata {
+	var thumb string
+	if profilePath != "" {
+		thumb = getTMDbImageURL(profilePath)
+	}
+
+	return database.ItemMetadata{
+		Title:     name,
+		SortTitle: name,
+		Type:      database.PersonItem,
+		Thumb:     thumb,
+		ExternalIdentifiers: []database.ExternalIdentifier{{
+			IdentifierType: database.TmdbIdentifier,
+			Identifier:     strconv.FormatInt(id, 10), //nolint:gomnd
+		}},
+	}
+}
+
+// Returns the posters in the library language first, then in the fallback language, then without text.
+// Backdrops without text come first instead, since text on them is rarely wanted.
+func (p Provider) GetImages(id string, library database.Library) ([]registry.Image, error) {
+	var images tmdbImages
+
+	preferredLanguages := getImageLanguages(library.Language)
+
+	err := getTMDb(fmt.Sprintf("/movie/%s/images", url.PathEscape(id)), url.Values{
+		"include_image_language": {strings.Join(preferredLanguages, ",") + ",null"},
+	}, &images)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch images for movie %s: %w", id, err)
+	}
+
+	// Images without text have no language
+	sortImages(images.Posters, append(preferredLanguages[:len(preferredLanguages):len(preferredLanguages)], ""))
+	sortImages(images.Backdrops, append([]string{""}, preferredLanguages...))
+
+	results := make([]registry.Image, 0, len(images.Posters)+len(images.Backdrops))
+
+	for _, poster := range images.Posters {
+		results = append(results, registry.Image{
+			Type: registry.PosterImage,
+			URL:  getTMDbImageURL(poster.FilePath),
+		})
+	}
+
+	for _, backdrop := range images.Backdrops {
+		results = append(results, registry.Image{
+			Type: registry.ArtImage,
+			URL:  getTMDbImageURL(backdrop.FilePath),
+		})
+	}
+
+	return results, nil
+}
+
+// Returns the ISO 639-1 codes of the library language and of the fallback language, which TMDb uses for images.
+func getImageLanguages(libraryLanguage string) []string {
+	var languages []string
+
+	for _, tag := range []string{getLanguage(libraryLanguage), viper.GetString("providers.fallback_language")} {
+		parsed, err := language.Parse(tag)
+		if err != nil {
+			continue
 		}
 
-		var posterHash string
+		base, _ := parsed.Base()
+		if !utils.IsStringInSlice(base.String(), languages) {
+			languages = append(languages, base.String())
+		}
+	}
 
-		if movieData.PosterPath != "" {
-			posterPath := fmt.Sprintf("https://image.tmdb.org/t/p/original/%s", movieData.PosterPath)
+	return languages
+}
 
-			posterHash, err = helpers.SaveExternalImageToCache(posterPath)
-			if err != nil {
-				return fmt.Errorf("failed to download poster art for movie \"%s\": %w", item.Title, err)
+// Sorts images by the position of their language in the given order, keeping the order of TMDb,
+// by votes, otherwise. Images in other languages come last.
+func sortImages(images []tmdbImage, languages []string) {
+	rank := func(image tmdbImage) int {
+		for index, imageLanguage := range languages {
+			if image.Language == imageLanguage {
+				return index
 			}
 		}
 
-		item.Title = movieData.Title
-		item.SortTitle = utils.CleanSortTitle(movieData.Title)
-		item.OriginalTitle = movieData.OriginalTitle
-		item.ReleaseDate = releaseDate
-		item.Summary = movieData.Overview
-		item.Tagline = movieData.Tagline
-		item.Popularity = movieData.Popularity
-		item.OriginalLanguage = languageTag.String()
-		item.Thumb = posterHash
-		item.Art = artHash
-
-		err = database.UpdateMovie(item)
-		if err != nil {
-			return fmt.Errorf("failed to update movie \"%s\": %w", item.Title, err)
-		}
+		return len(languages)
+	}
+
+	sort.SliceStable(images, func(i, j int) bool {
+		return rank(images[i]) < rank(images[j])
+	})
+}
+
+// Returns the language to request, falling back to the configured language for libraries without one.
+func getLanguage(libraryLanguage string) string {
+	if libraryLanguage == "" {
+		return viper.GetString("providers.fallback_language")
+	}
+
+	return libraryLanguage
+}
+
+func parseReleaseDate(date string) time.Time {
+	releaseDate, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return time.Time{}
 	}
 
-	return errNoResultsFound
+	return releaseDate
 }
@@ -0,0 +1,151 @@
+package movie_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/providers/movie"
+	"github.com/meteorae/meteorae-server/providers/registry"
+	"github.com/spf13/viper"
+)
+
+// Serves canned TMDb responses, so we don't depend on the real API.
+func newFakeTMDb(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	responses := map[string]string{
+		"/search/movie": `{"results": [{"id": 603, "title": "The Matrix", "release_date": "1999-03-30"}]}`,
+		"/movie/603": `{"id": 603, "imdb_id": "tt0133093", "title": "The Matrix", "original_title": "The Matrix", "original_language": "en",
+			"overview": "A hacker learns the truth.", "tagline": "Welcome to the Real World.",
+			"release_date": "1999-03-30", "popularity": 80.5, "runtime": 136,
+			"vote_average": 8.2, "vote_count": 24000,
+			"genres": [{"id": 28, "name": "Action"}, {"id": 878, "name": "Science Fiction"}],
+			"belongs_to_collection": {"id": 2344, "name": "The Matrix Collection", "poster_path": "/collection.jpg"},
+			"credits": {"cast": [{"id": 6384, "name": "Keanu Reeves", "character": "Neo"}],
+			"crew": [{"id": 1130, "name": "Kym Barrett", "department": "Costume & Make-Up", "job": "Costume Design"}]}}`,
+		"/movie/603/images": `{"posters": [{"file_path": "/poster-en.jpg", "iso_639_1": "en"},
+			{"file_path": "/poster.jpg", "iso_639_1": null}, {"file_path": "/poster-fr.jpg", "iso_639_1": "fr"}],
+			"backdrops": [{"file_path": "/art-fr.jpg", "iso_639_1": "fr"}, {"file_path": "/art.jpg"}]}`,
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Query().Get("api_key") != "test-key" {
+			writer.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		if strings.HasSuffix(request.URL.Path, "/images") &&
+			request.URL.Query().Get("include_image_language") != "fr,en,null" {
+			writer.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		response, ok := responses[request.URL.Path]
+		if !ok {
+			writer.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		fmt.Fprint(writer, response)
+	}))
+}
+
+func TestProvider(t *testing.T) {
+	server := newFakeTMDb(t)
+	defer server.Close()
+
+	viper.Set("providers.tmdb.url", server.URL)
+	viper.Set("providers.tmdb.image_url", "https://images.example.com/original")
+	viper.Set("providers.tmdb.api_key", "test-key")
+
+	provider := movie.Provider{}
+	library := database.Library{Type: database.MovieLibrary}
+
+	results, err := provider.Search(registry.SearchQuery{Title: "The Matrix aka Matrix"}, library)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	if len(results) != 1 || results[0].ID != "603" || results[0].Year != 1999 {
+		t.Fatalf("Search() = %+v, want a single result for 603 in 1999", results)
+	}
+
+	metadata, err := provider.GetMetadata(results[0].ID, library)
+	if err != nil {
+		t.Fatalf("GetMetadata() error = %v", err)
+	}
+
+	if metadata.Title != "The Matrix" || metadata.Tagline != "Welcome to the Real World." {
+		t.Errorf("GetMetadata() = %+v, want The Matrix with its tagline", metadata)
+	}
+
+	wantIdentifiers := []database.ExternalIdentifier{
+		{IdentifierType: database.TmdbIdentifier, Identifier: "603"},
+		{IdentifierType: database.ImdbIdentifier, Identifier: "tt0133093"},
+	}
+
+	if !reflect.DeepEqual(metadata.ExternalIdentifiers, wantIdentifiers) {
+		t.Errorf("GetMetadata() identifiers = %+v, want %+v", metadata.ExternalIdentifiers, wantIdentifiers)
+	}
+
+	if len(metadata.Credits) != 2 ||
+		metadata.Credits[0].Role != database.CastRole || metadata.Credits[0].Character != "Neo" ||
+		metadata.Credits[1].Role != database.CrewRole || metadata.Credits[1].Job != "Costume Design" {
+		t.Errorf("GetMetadata() credits = %+v, want Neo in the cast and a costume designer in the crew", metadata.Credits)
+	}
+
+	if len(metadata.Collections) != 1 ||
+		metadata.Collections[0].Collection.MatchID != "2344" ||
+		metadata.Collections[0].Collection.Thumb != "https://images.example.com/original/collection.jpg" {
+		t.Errorf("GetMetadata() collections = %+v, want The Matrix Collection", metadata.Collections)
+	}
+
+	if len(metadata.Tags) != 2 || metadata.Tags[1].Tag.Name != "Science Fiction" {
+		t.Errorf("GetMetadata() genres = %+v, want Action and Science Fiction", metadata.Tags)
+	}
+
+	wantRatings := []database.CommunityRating{
+		{Source: database.TmdbRatingSource, Value: 8.2, MaxValue: 10, Votes: 24000},
+	}
+
+	if !reflect.DeepEqual(metadata.Ratings, wantRatings) {
+		t.Errorf("GetMetadata() ratings = %+v, want %+v", metadata.Ratings, wantRatings)
+	}
+
+	if metadata.Duration != (136 * time.Minute).Milliseconds() {
+		t.Errorf("GetMetadata() duration = %d, want %d", metadata.Duration, (136 * time.Minute).Milliseconds())
+	}
+
+	viper.Set("providers.fallback_language", "en-US")
+
+	images, err := provider.GetImages(results[0].ID, database.Library{Type: database.MovieLibrary, Language: "fr-FR"})
+	if err != nil {
+		t.Fatalf("GetImages() error = %v", err)
+	}
+
+	// Posters in the library language come first, and backdrops without text
+	wantImages := []registry.Image{
+		{Type: registry.PosterImage, URL: "https://images.example.com/original/poster-fr.jpg"},
+		{Type: registry.PosterImage, URL: "https://images.example.com/original/poster-en.jpg"},
+		{Type: registry.PosterImage, URL: "https://images.example.com/original/poster.jpg"},
+		{Type: registry.ArtImage, URL: "https://images.example.com/original/art.jpg"},
+		{Type: registry.ArtImage, URL: "https://images.example.com/original/art-fr.jpg"},
+	}
+
+	if !reflect.DeepEqual(images, wantImages) {
+		t.Errorf("GetImages() = %+v, want %+v", images, wantImages)
+	}
+
+	if _, err := provider.GetMetadata("404", library); err == nil {
+		t.Errorf("GetMetadata() for a missing movie should fail")
+	}
+}
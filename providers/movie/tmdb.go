@@ -0,0 +1,103 @@
+package movie
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/meteorae/meteorae-server/providers/httpclient"
+	"github.com/spf13/viper"
+)
+
+type tmdbSearchResults struct {
+	Results []struct {
+		ID          int64  `json:"id"`
+		Title       string `json:"title"`
+		ReleaseDate string `json:"release_date"`
+		PosterPath  string `json:"poster_path"`
+	} `json:"results"`
+}
+
+type tmdbMovie struct {
+	ID               int64   `json:"id"`
+	IMDbID           string  `json:"imdb_id"`
+	Title            string  `json:"title"`
+	OriginalTitle    string  `json:"original_title"`
+	OriginalLanguage string  `json:"original_language"`
+	Overview         string  `json:"overview"`
+	Tagline          string  `json:"tagline"`
+	ReleaseDate      string  `json:"release_date"`
+	Popularity       float32 `json:"popularity"`
+	VoteAverage      float64 `json:"vote_average"`
+	VoteCount        int64   `json:"vote_count"`
+	Runtime          int64   `json:"runtime"`
+	PosterPath       string  `json:"poster_path"`
+	BackdropPath     string  `json:"backdrop_path"`
+	Collection       *struct {
+		ID           int64  `json:"id"`
+		Name         string `json:"name"`
+		PosterPath   string `json:"poster_path"`
+		BackdropPath string `json:"backdrop_path"`
+	} `json:"belongs_to_collection"`
+	Genres []struct {
+		Name string `json:"name"`
+	} `json:"genres"`
+	Credits struct {
+		Cast []struct {
+			ID          int64  `json:"id"`
+			Name        string `json:"name"`
+			Character   string `json:"character"`
+			ProfilePath string `json:"profile_path"`
+		} `json:"cast"`
+		Crew []struct {
+			ID          int64  `json:"id"`
+			Name        string `json:"name"`
+			Department  string `json:"department"`
+			Job         string `json:"job"`
+			ProfilePath string `json:"profile_path"`
+		} `json:"crew"`
+	} `json:"credits"`
+}
+
+type tmdbImage struct {
+	FilePath string `json:"file_path"`
+	// The language of the text on the image, or empty for images without text.
+	Language string `json:"iso_639_1"`
+}
+
+type tmdbImages struct {
+	Posters   []tmdbImage `json:"posters"`
+	Backdrops []tmdbImage `json:"backdrops"`
+}
+
+// Returns the full URL of an image, from the path returned by the API.
+func getTMDbImageURL(path string) string {
+	baseURL := strings.TrimSuffix(viper.GetString("providers.tmdb.image_url"), "/")
+
+	return fmt.Sprintf("%s/%s", baseURL, strings.TrimPrefix(path, "/"))
+}
+
+// Calls the given TMDb API endpoint, and decodes the response into target.
+func getTMDb(path string, parameters url.Values, target interface{}) error {
+	if parameters == nil {
+		parameters = url.Values{}
+	}
+
+	parameters.Set("api_key", viper.GetString("providers.tmdb.api_key"))
+
+	baseURL := strings.TrimSuffix(viper.GetString("providers.tmdb.url"), "/")
+	requestURL := fmt.Sprintf("%s%s?%s", baseURL, path, parameters.Encode())
+
+	body, err := httpclient.Get(requestURL)
+	if err != nil {
+		return fmt.Errorf("failed to call TMDb: %w", err)
+	}
+
+	err = json.Unmarshal(body, target)
+	if err != nil {
+		return fmt.Errorf("failed to decode TMDb response: %w", err)
+	}
+
+	return nil
+}
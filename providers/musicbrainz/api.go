@@ -0,0 +1,186 @@
+package musicbrainz
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/meteorae/meteorae-server/providers/httpclient"
+	"github.com/spf13/viper"
+)
+
+// Release dates are as precise as MusicBrainz knows them, down to the day.
+var dateLayouts = []string{"2006-01-02", "2006-01", "2006"}
+
+type mbArtist struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	SortName string `json:"sort-name"`
+	Type     string `json:"type"`
+}
+
+type mbArtistCredit []struct {
+	Name       string   `json:"name"`
+	JoinPhrase string   `json:"joinphrase"`
+	Artist     mbArtist `json:"artist"`
+}
+
+// Returns the credit as displayed on the release, like "Simon & Garfunkel".
+func (c mbArtistCredit) String() string {
+	var builder strings.Builder
+
+	for _, credit := range c {
+		builder.WriteString(credit.Name)
+		builder.WriteString(credit.JoinPhrase)
+	}
+
+	return builder.String()
+}
+
+type mbRelease struct {
+	ID           string         `json:"id"`
+	Title        string         `json:"title"`
+	Status       string         `json:"status"`
+	Date         string         `json:"date"`
+	Country      string         `json:"country"`
+	ArtistCredit mbArtistCredit `json:"artist-credit"`
+	LabelInfo    []struct {
+		CatalogNumber string `json:"catalog-number"`
+		Label         *struct {
+			Name string `json:"name"`
+		} `json:"label"`
+	} `json:"label-info"`
+	ReleaseGroup struct {
+		ID               string `json:"id"`
+		Title            string `json:"title"`
+		PrimaryType      string `json:"primary-type"`
+		FirstReleaseDate string `json:"first-release-date"`
+	} `json:"release-group"`
+	Media []struct {
+		Position int `json:"position"`
+		Tracks   []struct {
+			Position  int `json:"position"`
+			Recording struct {
+				ID string `json:"id"`
+			} `json:"recording"`
+		} `json:"tracks"`
+	} `json:"media"`
+}
+
+type mbRecording struct {
+	ID               string         `json:"id"`
+	Title            string         `json:"title"`
+	Score            int            `json:"score"`
+	Length           int64          `json:"length"`
+	FirstReleaseDate string         `json:"first-release-date"`
+	ArtistCredit     mbArtistCredit `json:"artist-credit"`
+	Releases         []mbRelease    `json:"releases"`
+	Relations        []struct {
+		Type       string    `json:"type"`
+		TargetType string    `json:"target-type"`
+		Attributes []string  `json:"attributes"`
+		Artist     *mbArtist `json:"artist"`
+	} `json:"relations"`
+}
+
+type mbRecordingSearchResults struct {
+	Recordings []mbRecording `json:"recordings"`
+}
+
+type coverArtImages struct {
+	Images []struct {
+		Image string `json:"image"`
+		Front bool   `json:"front"`
+	} `json:"images"`
+}
+
+// Calls the given MusicBrainz API endpoint, and decodes the response into target.
+func getMusicBrainz(path string, parameters url.Values, target interface{}) error {
+	if parameters == nil {
+		parameters = url.Values{}
+	}
+
+	parameters.Set("fmt", "json")
+
+	baseURL := strings.TrimSuffix(viper.GetString("providers.musicbrainz.url"), "/")
+	requestURL := fmt.Sprintf("%s%s?%s", baseURL, path, parameters.Encode())
+
+	body, err := httpclient.Get(requestURL)
+	if err != nil {
+		return fmt.Errorf("failed to call MusicBrainz: %w", err)
+	}
+
+	err = json.Unmarshal(body, target)
+	if err != nil {
+		return fmt.Errorf("failed to decode MusicBrainz response: %w", err)
+	}
+
+	return nil
+}
+
+// Returns the cover art of the given release or release group, like "/release/{id}".
+// Releases without any cover art have no images.
+func getCoverArt(path string) (*coverArtImages, error) {
+	baseURL := strings.TrimSuffix(viper.GetString("providers.coverartarchive.url"), "/")
+
+	var images coverArtImages
+
+	body, err := httpclient.Get(baseURL + path)
+	if err != nil {
+		var statusError *httpclient.StatusError
+		if errors.As(err, &statusError) && statusError.StatusCode == http.StatusNotFound {
+			return &images, nil
+		}
+
+		return nil, fmt.Errorf("failed to call the Cover Art Archive: %w", err)
+	}
+
+	err = json.Unmarshal(body, &images)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode Cover Art Archive response: %w", err)
+	}
+
+	return &images, nil
+}
+
+func getRecording(id string, includes ...string) (*mbRecording, error) {
+	var recording mbRecording
+
+	// Includes are separated by spaces, which are encoded as "+" like the API expects
+	err := getMusicBrainz(fmt.Sprintf("/recording/%s", url.PathEscape(id)), url.Values{
+		"inc": {strings.Join(includes, " ")},
+	}, &recording)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch recording %s: %w", id, err)
+	}
+
+	return &recording, nil
+}
+
+func getRelease(id string) (*mbRelease, error) {
+	var release mbRelease
+
+	err := getMusicBrainz(fmt.Sprintf("/release/%s", url.PathEscape(id)), url.Values{
+		"inc": {"artist-credits labels release-groups recordings"},
+	}, &release)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch release %s: %w", id, err)
+	}
+
+	return &release, nil
+}
+
+// Parses a MusicBrainz date, which may only have a year, or a year and a month.
+func parseDate(date string) time.Time {
+	for _, layout := range dateLayouts {
+		if parsed, err := time.Parse(layout, date); err == nil {
+			return parsed
+		}
+	}
+
+	return time.Time{}
+}
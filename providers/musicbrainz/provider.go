@@ -0,0 +1,429 @@
+// Package musicbrainz fetches music metadata from MusicBrainz, and album covers from the Cover Art Archive.
+package musicbrainz
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dhowden/tag"
+	"github.com/dhowden/tag/mbz"
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/providers/httpclient"
+	"github.com/meteorae/meteorae-server/providers/registry"
+	"github.com/meteorae/meteorae-server/utils"
+	"github.com/rs/zerolog/log"
+)
+
+const serviceHost = "musicbrainz.org"
+
+// Relations to people performing on a recording, as opposed to the ones producing it.
+var performanceRelations = map[string]bool{
+	"conductor":            true,
+	"instrument":           true,
+	"performer":            true,
+	"performing orchestra": true,
+	"vocal":                true,
+}
+
+func init() {
+	// MusicBrainz allows a single request per second
+	httpclient.SetRateLimit(serviceHost, 1)
+
+	registry.Register(musicBrainzProvider)
+}
+
+var musicBrainzProvider registry.Provider = Provider{}
+
+// Fetches music track information from MusicBrainz.
+// Results are identified as "{release}/{recording}", since the same recording can appear on many releases.
+type Provider struct{}
+
+func (p Provider) GetName() string {
+	return "MusicBrainz"
+}
+
+func (p Provider) SupportsLibraryType(library database.Library) bool {
+	return library.Type == database.MusicLibrary
+}
+
+// Returns the recording tagged in the file or identified from its fingerprint when known,
+// or searches for recordings matching the title, artist and album of the track otherwise.
+func (p Provider) Search(query registry.SearchQuery, library database.Library) ([]registry.SearchResult, error) {
+	var trackTags tag.Metadata
+	if query.FilePath != "" {
+		trackTags = readTags(query.FilePath)
+	}
+
+	recordingID, releaseID := getKnownIdentifiers(trackTags, query.Identifiers)
+	if recordingID != "" {
+		recording, err := getRecording(recordingID, "artist-credits", "releases")
+		if err != nil {
+			return nil, fmt.Errorf("could not get recording: %w", err)
+		}
+
+		if releaseID == "" {
+			releaseID = pickRelease(recording.Releases, getAlbum(trackTags))
+		}
+
+		return []registry.SearchResult{getSearchResult(recording, releaseID)}, nil
+	}
+
+	parameters := map[string]string{"recording": query.Title}
+
+	if trackTags != nil {
+		parameters["artist"] = trackTags.Artist()
+		parameters["release"] = trackTags.Album()
+	}
+
+	searchQuery := buildQuery(parameters)
+	if searchQuery == "" {
+		return []registry.SearchResult{}, nil
+	}
+
+	var searchResults mbRecordingSearchResults
+
+	err := getMusicBrainz("/recording", url.Values{"query": {searchQuery}}, &searchResults)
+	if err != nil {
+		return nil, fmt.Errorf("could not search for recording: %w", err)
+	}
+
+	results := make([]registry.SearchResult, 0, len(searchResults.Recordings))
+
+	for index := range searchResults.Recordings {
+		recording := &searchResults.Recordings[index]
+
+		results = append(results, getSearchResult(recording, pickRelease(recording.Releases, getAlbum(trackTags))))
+	}
+
+	return results, nil
+}
+
+func (p Provider) GetMetadata(id string, library database.Library) (*database.ItemMetadata, error) {
+	releaseID, recordingID := parseID(id)
+
+	recording, err := getRecording(recordingID, "artist-credits", "releases", "artist-rels")
+	if err != nil {
+		return nil, err
+	}
+
+	if releaseID == "" {
+		releaseID = pickRelease(recording.Releases, "")
+	}
+
+	extraInfo := database.MusicTrackExtraInfo{}
+	releaseDate := parseDate(recording.FirstReleaseDate)
+
+	if releaseID != "" {
+		release, err := getRelease(releaseID)
+		if err != nil {
+			return nil, err
+		}
+
+		extraInfo = getExtraInfo(release, recording.ID)
+
+		if releaseDate.IsZero() {
+			releaseDate = parseDate(release.Date)
+		}
+	}
+
+	encodedExtraInfo, err := json.Marshal(extraInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode information for recording %s: %w", recordingID, err)
+	}
+
+	return &database.ItemMetadata{
+		Title:       recording.Title,
+		SortTitle:   utils.CleanSortTitle(recording.Title),
+		ReleaseDate: releaseDate,
+		Duration:    recording.Length,
+		ExtraInfo:   encodedExtraInfo,
+		ExternalIdentifiers: []database.ExternalIdentifier{{
+			IdentifierType: database.MusicbrainzIdentifier,
+			Identifier:     recording.ID,
+		}},
+		Credits: getCredits(recording),
+	}, nil
+}
+
+// Returns the front covers of the release, or of its release group when the release has none.
+func (p Provider) GetImages(id string, library database.Library) ([]registry.Image, error) {
+	releaseID, recordingID := parseID(id)
+
+	if releaseID == "" {
+		recording, err := getRecording(recordingID, "releases")
+		if err != nil {
+			return nil, err
+		}
+
+		releaseID = pickRelease(recording.Releases, "")
+		if releaseID == "" {
+			return []registry.Image{}, nil
+		}
+	}
+
+	coverArt, err := getCoverArt(fmt.Sprintf("/release/%s", releaseID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch cover art for release %s: %w", releaseID, err)
+	}
+
+	images := getFrontCovers(coverArt)
+	if len(images) > 0 {
+		return images, nil
+	}
+
+	release, err := getRelease(releaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	if release.ReleaseGroup.ID == "" {
+		return images, nil
+	}
+
+	coverArt, err = getCoverArt(fmt.Sprintf("/release-group/%s", release.ReleaseGroup.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch cover art for release group %s: %w", release.ReleaseGroup.ID, err)
+	}
+
+	return getFrontCovers(coverArt), nil
+}
+
+func getFrontCovers(coverArt *coverArtImages) []registry.Image {
+	images := make([]registry.Image, 0, len(coverArt.Images))
+
+	for _, image := range coverArt.Images {
+		if image.Front && image.Image != "" {
+			images = append(images, registry.Image{Type: registry.PosterImage, URL: image.Image})
+		}
+	}
+
+	return images
+}
+
+// Returns the recording and release identifiers written in the file by taggers like MusicBrainz Picard,
+// or the recording identified from the audio fingerprint of the file.
+func getKnownIdentifiers(trackTags tag.Metadata, identifiers []database.ExternalIdentifier) (string, string) {
+	if trackTags != nil {
+		info := mbz.Extract(trackTags)
+
+		recordingID := info.Get(mbz.Recording)
+
+		// Vorbis comments store the recording as the track identifier
+		if recordingID == "" && trackTags.Format() == tag.VORBIS {
+			recordingID = info.Get(mbz.Track)
+		}
+
+		if recordingID != "" {
+			return recordingID, info.Get(mbz.Album)
+		}
+	}
+
+	for _, identifier := range identifiers {
+		if identifier.IdentifierType == database.MusicbrainzIdentifier {
+			return identifier.Identifier, ""
+		}
+	}
+
+	return "", ""
+}
+
+// Returns the release a track most likely comes from. Releases named like the album
+// the track is tagged with come first, then official releases, in the order MusicBrainz returns them.
+func pickRelease(releases []mbRelease, album string) string {
+	if len(releases) == 0 {
+		return ""
+	}
+
+	if album != "" {
+		for _, release := range releases {
+			if strings.EqualFold(release.Title, album) {
+				return release.ID
+			}
+		}
+	}
+
+	for _, release := range releases {
+		if release.Status == "Official" {
+			return release.ID
+		}
+	}
+
+	return releases[0].ID
+}
+
+func getSearchResult(recording *mbRecording, releaseID string) registry.SearchResult {
+	return registry.SearchResult{
+		ID:    formatID(releaseID, recording.ID),
+		Title: recording.Title,
+		Year:  parseDate(recording.FirstReleaseDate).Year(),
+	}
+}
+
+// Returns the album details of a track, from the release it appears on.
+func getExtraInfo(release *mbRelease, recordingID string) database.MusicTrackExtraInfo {
+	extraInfo := database.MusicTrackExtraInfo{
+		Album:            release.Title,
+		AlbumArtist:      release.ArtistCredit.String(),
+		ReleaseID:        release.ID,
+		ReleaseGroupID:   release.ReleaseGroup.ID,
+		ReleaseGroupType: release.ReleaseGroup.PrimaryType,
+		Country:          release.Country,
+	}
+
+	for _, labelInfo := range release.LabelInfo {
+		if labelInfo.Label != nil && extraInfo.Label == "" {
+			extraInfo.Label = labelInfo.Label.Name
+		}
+
+		if labelInfo.CatalogNumber != "" && extraInfo.CatalogNumber == "" {
+			extraInfo.CatalogNumber = labelInfo.CatalogNumber
+		}
+	}
+
+	for _, medium := range release.Media {
+		for _, track := range medium.Tracks {
+			if track.Recording.ID == recordingID {
+				extraInfo.DiscNumber = medium.Position
+				extraInfo.TrackNumber = track.Position
+
+				return extraInfo
+			}
+		}
+	}
+
+	return extraInfo
+}
+
+// Converts the artists of a recording to cast credits, and the people who worked on it to crew credits.
+// Credits are ordered the same way as on MusicBrainz.
+func getCredits(recording *mbRecording) []database.Credit {
+	credits := make([]database.Credit, 0, len(recording.ArtistCredit)+len(recording.Relations))
+
+	for index, artistCredit := range recording.ArtistCredit {
+		credits = append(credits, database.Credit{
+			Person: getPerson(artistCredit.Artist),
+			Role:   database.CastRole,
+			Index:  index,
+		})
+	}
+
+	for index, relation := range recording.Relations {
+		if relation.TargetType != "artist" || relation.Artist == nil {
+			continue
+		}
+
+		department := "Production"
+		if performanceRelations[relation.Type] {
+			department = "Performance"
+		}
+
+		job := capitalize(relation.Type)
+		if len(relation.Attributes) > 0 {
+			job = fmt.Sprintf("%s (%s)", job, strings.Join(relation.Attributes, ", "))
+		}
+
+		credits = append(credits, database.Credit{
+			Person:     getPerson(*relation.Artist),
+			Role:       database.CrewRole,
+			Department: department,
+			Job:        job,
+			Index:      index,
+		})
+	}
+
+	return credits
+}
+
+func getPerson(artist mbArtist) database.ItemMetadata {
+	person := database.ItemMetadata{
+		Title:     artist.Name,
+		SortTitle: artist.SortName,
+		Type:      database.PersonItem,
+		ExternalIdentifiers: []database.ExternalIdentifier{{
+			IdentifierType: database.MusicbrainzIdentifier,
+			Identifier:     artist.ID,
+		}},
+	}
+
+	// Orchestras and choirs are groups too
+	if artist.Type != "" && artist.Type != "Person" && artist.Type != "Character" {
+		person.Type = database.GroupItem
+	}
+
+	return person
+}
+
+// Builds a search query for the given fields, ignoring the empty ones.
+func buildQuery(fields map[string]string) string {
+	terms := make([]string, 0, len(fields))
+
+	// Keep the order stable, so that cached responses can be reused
+	for _, field := range []string{"recording", "artist", "release"} {
+		value := strings.TrimSpace(fields[field])
+		if value == "" {
+			continue
+		}
+
+		escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+		terms = append(terms, fmt.Sprintf(`%s:"%s"`, field, escaped))
+	}
+
+	return strings.Join(terms, " AND ")
+}
+
+func formatID(releaseID, recordingID string) string {
+	return fmt.Sprintf("%s/%s", releaseID, recordingID)
+}
+
+// Splits a result identifier into its release and recording. Bare recording identifiers are accepted too.
+func parseID(id string) (string, string) {
+	releaseID, recordingID, found := strings.Cut(id, "/")
+	if !found {
+		return "", id
+	}
+
+	return releaseID, recordingID
+}
+
+func getAlbum(trackTags tag.Metadata) string {
+	if trackTags == nil {
+		return ""
+	}
+
+	return trackTags.Album()
+}
+
+func capitalize(text string) string {
+	if text == "" {
+		return text
+	}
+
+	first, size := utf8.DecodeRuneInString(text)
+
+	return string(unicode.ToUpper(first)) + text[size:]
+}
+
+// Reads the tags embedded in an audio file, or returns nil if it has none.
+func readTags(filePath string) tag.Metadata {
+	file, err := os.Open(filePath)
+	if err != nil {
+		log.Debug().Err(err).Msgf("Could not open %s to read tags", filePath)
+
+		return nil
+	}
+	defer file.Close()
+
+	trackTags, err := tag.ReadFrom(file)
+	if err != nil {
+		log.Debug().Err(err).Msgf("No embedded tags found in %s", filePath)
+
+		return nil
+	}
+
+	return trackTags
+}
@@ -0,0 +1,155 @@
+package musicbrainz_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/providers/musicbrainz"
+	"github.com/meteorae/meteorae-server/providers/registry"
+	"github.com/spf13/viper"
+)
+
+// Serves canned MusicBrainz and Cover Art Archive responses, so we don't depend on the real APIs.
+func newFakeMusicBrainz(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	responses := map[string]string{
+		"/ws/2/recording": `{"recordings": [{"id": "rec-1", "title": "Bohemian Rhapsody", "score": 100,
+			"first-release-date": "1975-10-31",
+			"releases": [{"id": "rel-bootleg", "title": "Live", "status": "Bootleg"},
+			{"id": "rel-1", "title": "A Night at the Opera", "status": "Official"}]}]}`,
+		"/ws/2/recording/rec-1": `{"id": "rec-1", "title": "Bohemian Rhapsody", "length": 354320,
+			"first-release-date": "1975-10",
+			"artist-credit": [{"name": "Queen", "joinphrase": "",
+			"artist": {"id": "art-1", "name": "Queen", "sort-name": "Queen", "type": "Group"}}],
+			"releases": [{"id": "rel-1", "title": "A Night at the Opera", "status": "Official"}],
+			"relations": [{"type": "producer", "target-type": "artist", "attributes": [],
+			"artist": {"id": "art-2", "name": "Roy Thomas Baker", "sort-name": "Baker, Roy Thomas", "type": "Person"}},
+			{"type": "instrument", "target-type": "artist", "attributes": ["piano"],
+			"artist": {"id": "art-3", "name": "Freddie Mercury", "sort-name": "Mercury, Freddie", "type": "Person"}},
+			{"type": "recorded at", "target-type": "place", "attributes": []}]}`,
+		"/ws/2/release/rel-1": `{"id": "rel-1", "title": "A Night at the Opera", "date": "1975-11-21", "country": "GB",
+			"artist-credit": [{"name": "Queen", "joinphrase": "", "artist": {"id": "art-1", "name": "Queen"}}],
+			"label-info": [{"catalog-number": "EMTC 103", "label": {"name": "EMI"}}],
+			"release-group": {"id": "rg-1", "title": "A Night at the Opera", "primary-type": "Album"},
+			"media": [{"position": 1, "tracks": [{"position": 11, "recording": {"id": "rec-1"}}]}]}`,
+		"/caa/release-group/rg-1": `{"images": [{"front": false, "image": "https://covers.example.com/back.jpg"},
+			{"front": true, "image": "https://covers.example.com/front.jpg"}]}`,
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/ws/2/recording" &&
+			request.URL.Query().Get("query") != `recording:"Bohemian \"Rhapsody\""` {
+			writer.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		response, ok := responses[request.URL.Path]
+		if !ok {
+			writer.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		fmt.Fprint(writer, response)
+	}))
+}
+
+func TestProvider(t *testing.T) {
+	server := newFakeMusicBrainz(t)
+	defer server.Close()
+
+	viper.Set("providers.musicbrainz.url", server.URL+"/ws/2")
+	viper.Set("providers.coverartarchive.url", server.URL+"/caa")
+
+	provider := musicbrainz.Provider{}
+	library := database.Library{Type: database.MusicLibrary}
+
+	results, err := provider.Search(registry.SearchQuery{Title: `Bohemian "Rhapsody"`}, library)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	if len(results) != 1 || results[0].ID != "rel-1/rec-1" || results[0].Year != 1975 {
+		t.Fatalf("Search() = %+v, want rec-1 on its official release, from 1975", results)
+	}
+
+	results, err = provider.Search(registry.SearchQuery{
+		Title: "Unknown track",
+		Identifiers: []database.ExternalIdentifier{
+			{IdentifierType: database.MusicbrainzIdentifier, Identifier: "rec-1"},
+		},
+	}, library)
+	if err != nil {
+		t.Fatalf("Search() with a known recording error = %v", err)
+	}
+
+	if len(results) != 1 || results[0].ID != "rel-1/rec-1" {
+		t.Fatalf("Search() with a known recording = %+v, want rec-1 without searching", results)
+	}
+
+	metadata, err := provider.GetMetadata(results[0].ID, library)
+	if err != nil {
+		t.Fatalf("GetMetadata() error = %v", err)
+	}
+
+	if metadata.Title != "Bohemian Rhapsody" || metadata.Duration != 354320 ||
+		!metadata.ReleaseDate.Equal(time.Date(1975, time.October, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("GetMetadata() = %+v, want Bohemian Rhapsody from October 1975", metadata)
+	}
+
+	if len(metadata.ExternalIdentifiers) != 1 || metadata.ExternalIdentifiers[0].Identifier != "rec-1" {
+		t.Errorf("GetMetadata() identifiers = %+v, want the recording", metadata.ExternalIdentifiers)
+	}
+
+	if len(metadata.Credits) != 3 ||
+		metadata.Credits[0].Role != database.CastRole || metadata.Credits[0].Person.Type != database.GroupItem ||
+		metadata.Credits[1].Job != "Producer" || metadata.Credits[1].Department != "Production" ||
+		metadata.Credits[2].Job != "Instrument (piano)" || metadata.Credits[2].Department != "Performance" {
+		t.Errorf("GetMetadata() credits = %+v, want Queen, a producer and a pianist", metadata.Credits)
+	}
+
+	var extraInfo database.MusicTrackExtraInfo
+	if err := json.Unmarshal(metadata.ExtraInfo, &extraInfo); err != nil {
+		t.Fatalf("GetMetadata() extra info is invalid: %v", err)
+	}
+
+	wantExtraInfo := database.MusicTrackExtraInfo{
+		Album:            "A Night at the Opera",
+		AlbumArtist:      "Queen",
+		ReleaseID:        "rel-1",
+		ReleaseGroupID:   "rg-1",
+		ReleaseGroupType: "Album",
+		Label:            "EMI",
+		CatalogNumber:    "EMTC 103",
+		Country:          "GB",
+		DiscNumber:       1,
+		TrackNumber:      11,
+	}
+
+	if extraInfo != wantExtraInfo {
+		t.Errorf("GetMetadata() extra info = %+v, want %+v", extraInfo, wantExtraInfo)
+	}
+
+	// The release has no cover art, so the release group's front cover is used
+	images, err := provider.GetImages(results[0].ID, library)
+	if err != nil {
+		t.Fatalf("GetImages() error = %v", err)
+	}
+
+	wantImage := registry.Image{Type: registry.PosterImage, URL: "https://covers.example.com/front.jpg"}
+
+	if len(images) != 1 || images[0] != wantImage {
+		t.Errorf("GetImages() = %+v, want %+v", images, wantImage)
+	}
+
+	if _, err := provider.GetMetadata("rel-1/404", library); err == nil {
+		t.Errorf("GetMetadata() for a missing recording should fail")
+	}
+}
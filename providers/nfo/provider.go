@@ -0,0 +1,241 @@
+// Package nfo reads the metadata of movies from the NFO files written next to them by Kodi and other
+// media managers, including the IMDb and Rotten Tomatoes ratings they hold.
+package nfo
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/providers/registry"
+	"github.com/meteorae/meteorae-server/utils"
+)
+
+// The scale of ratings without a maximum, which Kodi uses by default.
+const defaultMaxRating = 10
+
+var errNotAMovie = errors.New("not a movie NFO file")
+
+func init() {
+	registry.Register(nfoProvider)
+}
+
+var nfoProvider registry.Provider = Provider{}
+
+// Reads the metadata of movies from their NFO file, named after the movie file, or "movie.nfo" in its
+// directory. Movies are identified by the path of their NFO file.
+type Provider struct{}
+
+type nfoMovie struct {
+	XMLName       xml.Name `xml:"movie"`
+	Title         string   `xml:"title"`
+	OriginalTitle string   `xml:"originaltitle"`
+	SortTitle     string   `xml:"sorttitle"`
+	Plot          string   `xml:"plot"`
+	Tagline       string   `xml:"tagline"`
+	Runtime       int64    `xml:"runtime"`
+	Premiered     string   `xml:"premiered"`
+	Year          int      `xml:"year"`
+	Genres        []string `xml:"genre"`
+	UniqueIDs     []struct {
+		Type  string `xml:"type,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"uniqueid"`
+	Ratings []struct {
+		Name  string  `xml:"name,attr"`
+		Max   float64 `xml:"max,attr"`
+		Value float64 `xml:"value"`
+		Votes string  `xml:"votes"`
+	} `xml:"ratings>rating"`
+}
+
+func (p Provider) GetName() string {
+	return "Local NFO"
+}
+
+func (p Provider) SupportsLibraryType(library database.Library) bool {
+	return library.Type == database.MovieLibrary
+}
+
+// Returns the NFO file of the movie, if it has one.
+func (p Provider) Search(query registry.SearchQuery, library database.Library) ([]registry.SearchResult, error) {
+	nfoPath := findNFO(query.FilePath)
+	if nfoPath == "" {
+		return nil, nil
+	}
+
+	movie, err := readNFO(nfoPath)
+	if errors.Is(err, errNotAMovie) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []registry.SearchResult{{
+		ID:    nfoPath,
+		Title: movie.Title,
+		Year:  getReleaseDate(movie).Year(),
+	}}, nil
+}
+
+func (p Provider) GetMetadata(id string, library database.Library) (*database.ItemMetadata, error) {
+	movie, err := readNFO(id)
+	if err != nil {
+		return nil, err
+	}
+
+	sortTitle := movie.SortTitle
+	if sortTitle == "" {
+		sortTitle = movie.Title
+	}
+
+	genres := make([]database.ItemTag, 0, len(movie.Genres))
+	for _, genre := range movie.Genres {
+		genres = append(genres, database.ItemTag{Tag: database.Tag{Name: genre}})
+	}
+
+	return &database.ItemMetadata{
+		Title:               movie.Title,
+		SortTitle:           utils.CleanSortTitle(sortTitle),
+		OriginalTitle:       movie.OriginalTitle,
+		Summary:             movie.Plot,
+		Tagline:             movie.Tagline,
+		ReleaseDate:         getReleaseDate(movie),
+		Duration:            (time.Duration(movie.Runtime) * time.Minute).Milliseconds(),
+		ExternalIdentifiers: getIdentifiers(movie),
+		Tags:                genres,
+		Ratings:             getRatings(movie),
+	}, nil
+}
+
+// Images aren't read from NFO files, which usually point to remote artwork other providers have already.
+func (p Provider) GetImages(id string, library database.Library) ([]registry.Image, error) {
+	return nil, nil
+}
+
+// Returns the path of the NFO file of a movie, or an empty string if it has none.
+func findNFO(filePath string) string {
+	if filePath == "" {
+		return ""
+	}
+
+	candidates := []string{
+		strings.TrimSuffix(filePath, filepath.Ext(filePath)) + ".nfo",
+		filepath.Join(filepath.Dir(filePath), "movie.nfo"),
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
+func readNFO(path string) (*nfoMovie, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read NFO file %s: %w", path, err)
+	}
+
+	var movie nfoMovie
+
+	// Some NFO files only hold a link to the movie page, which isn't XML
+	if err := xml.Unmarshal(data, &movie); err != nil {
+		return nil, fmt.Errorf("%w: %s", errNotAMovie, path)
+	}
+
+	return &movie, nil
+}
+
+func getReleaseDate(movie *nfoMovie) time.Time {
+	if releaseDate, err := time.Parse("2006-01-02", movie.Premiered); err == nil {
+		return releaseDate
+	}
+
+	if movie.Year != 0 {
+		return time.Date(movie.Year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	return time.Time{}
+}
+
+func getIdentifiers(movie *nfoMovie) []database.ExternalIdentifier {
+	var identifiers []database.ExternalIdentifier
+
+	identifierTypes := map[string]database.IdentifierType{
+		"imdb": database.ImdbIdentifier,
+		"tmdb": database.TmdbIdentifier,
+	}
+
+	for _, uniqueID := range movie.UniqueIDs {
+		identifierType, ok := identifierTypes[strings.ToLower(uniqueID.Type)]
+		if !ok || strings.TrimSpace(uniqueID.Value) == "" {
+			continue
+		}
+
+		identifiers = append(identifiers, database.ExternalIdentifier{
+			IdentifierType: identifierType,
+			Identifier:     strings.TrimSpace(uniqueID.Value),
+		})
+	}
+
+	return identifiers
+}
+
+// Returns the ratings of the movie, labelled with their source, keeping the first rating of each source.
+// Kodi names the Rotten Tomatoes critics score "tomatometerallcritics", and other sources keep their name.
+func getRatings(movie *nfoMovie) []database.CommunityRating {
+	ratings := make([]database.CommunityRating, 0, len(movie.Ratings))
+	sources := make(map[string]bool, len(movie.Ratings))
+
+	for _, rating := range movie.Ratings {
+		source := getRatingSource(rating.Name)
+		if source == "" || sources[source] || rating.Value <= 0 {
+			continue
+		}
+
+		sources[source] = true
+
+		maxValue := rating.Max
+		if maxValue <= 0 {
+			maxValue = defaultMaxRating
+		}
+
+		// Votes are sometimes written with thousands separators, like "2,000,000"
+		votes, _ := strconv.ParseInt(strings.ReplaceAll(strings.TrimSpace(rating.Votes), ",", ""), 10, 64) //nolint:gomnd
+
+		ratings = append(ratings, database.CommunityRating{
+			Source:   source,
+			Value:    rating.Value,
+			MaxValue: maxValue,
+			Votes:    votes,
+		})
+	}
+
+	return ratings
+}
+
+func getRatingSource(name string) string {
+	switch name = strings.ToLower(strings.TrimSpace(name)); name {
+	case "imdb":
+		return database.ImdbRatingSource
+	case "themoviedb", "tmdb":
+		return database.TmdbRatingSource
+	case "metacritic":
+		return database.MetacriticRatingSource
+	case "tomatometerallcritics", "rottentomatoes":
+		return database.RottenTomatoesRatingSource
+	}
+
+	return name
+}
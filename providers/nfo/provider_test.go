@@ -0,0 +1,109 @@
+package nfo_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/providers/nfo"
+	"github.com/meteorae/meteorae-server/providers/registry"
+)
+
+const movieNFO = `<?xml version="1.0" encoding="UTF-8" standalone="yes" ?>
+<movie>
+  <title>The Matrix</title>
+  <originaltitle>The Matrix</originaltitle>
+  <ratings>
+    <rating name="imdb" max="10" default="true">
+      <value>8.7</value>
+      <votes>2,000,000</votes>
+    </rating>
+    <rating name="tomatometerallcritics" max="100">
+      <value>88</value>
+      <votes>150</votes>
+    </rating>
+    <rating name="themoviedb">
+      <value>8.2</value>
+      <votes>24000</votes>
+    </rating>
+  </ratings>
+  <plot>A hacker learns the truth.</plot>
+  <tagline>Welcome to the Real World.</tagline>
+  <runtime>136</runtime>
+  <premiered>1999-03-30</premiered>
+  <uniqueid type="imdb" default="true">tt0133093</uniqueid>
+  <uniqueid type="tmdb">603</uniqueid>
+  <genre>Action</genre>
+</movie>`
+
+func TestProvider(t *testing.T) {
+	directory := t.TempDir()
+	moviePath := filepath.Join(directory, "The Matrix (1999).mkv")
+
+	if err := os.WriteFile(filepath.Join(directory, "movie.nfo"), []byte(movieNFO), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := nfo.Provider{}
+	library := database.Library{Type: database.MovieLibrary}
+
+	results, err := provider.Search(registry.SearchQuery{Title: "The Matrix", FilePath: moviePath}, library)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	if len(results) != 1 || results[0].Title != "The Matrix" || results[0].Year != 1999 {
+		t.Fatalf("Search() = %+v, want The Matrix from 1999", results)
+	}
+
+	metadata, err := provider.GetMetadata(results[0].ID, library)
+	if err != nil {
+		t.Fatalf("GetMetadata() error = %v", err)
+	}
+
+	if metadata.Title != "The Matrix" || metadata.Tagline != "Welcome to the Real World." ||
+		metadata.ReleaseDate.Format("2006-01-02") != "1999-03-30" {
+		t.Errorf("GetMetadata() = %+v, want The Matrix released on 1999-03-30 with its tagline", metadata)
+	}
+
+	wantIdentifiers := []database.ExternalIdentifier{
+		{IdentifierType: database.ImdbIdentifier, Identifier: "tt0133093"},
+		{IdentifierType: database.TmdbIdentifier, Identifier: "603"},
+	}
+
+	if !reflect.DeepEqual(metadata.ExternalIdentifiers, wantIdentifiers) {
+		t.Errorf("GetMetadata() identifiers = %+v, want %+v", metadata.ExternalIdentifiers, wantIdentifiers)
+	}
+
+	wantRatings := []database.CommunityRating{
+		{Source: database.ImdbRatingSource, Value: 8.7, MaxValue: 10, Votes: 2000000},
+		{Source: database.RottenTomatoesRatingSource, Value: 88, MaxValue: 100, Votes: 150},
+		{Source: database.TmdbRatingSource, Value: 8.2, MaxValue: 10, Votes: 24000},
+	}
+
+	if !reflect.DeepEqual(metadata.Ratings, wantRatings) {
+		t.Errorf("GetMetadata() ratings = %+v, want %+v", metadata.Ratings, wantRatings)
+	}
+}
+
+func TestSearchWithoutNFO(t *testing.T) {
+	directory := t.TempDir()
+
+	// NFO files holding only a link aren't read
+	err := os.WriteFile(filepath.Join(directory, "Movie.nfo"), []byte("https://www.imdb.com/title/tt0133093/"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider := nfo.Provider{}
+	library := database.Library{Type: database.MovieLibrary}
+
+	for _, filePath := range []string{filepath.Join(directory, "Movie.mkv"), filepath.Join(t.TempDir(), "Movie.mkv")} {
+		results, err := provider.Search(registry.SearchQuery{Title: "Movie", FilePath: filePath}, library)
+		if err != nil || len(results) != 0 {
+			t.Errorf("Search(%s) = %+v, %v, want no results", filePath, results, err)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+// Package opensubtitles searches and downloads subtitles from the OpenSubtitles REST API.
+// Videos are found from their OpenSubtitles hash first, which finds subtitles synchronized with the file.
+package opensubtitles
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/meteorae/meteorae-server/providers/httpclient"
+	"github.com/meteorae/meteorae-server/providers/subtitles"
+	"github.com/spf13/viper"
+	"golang.org/x/text/language"
+)
+
+// Login tokens are valid for 24 hours, so we log in again a bit before.
+const tokenLifetime = 23 * time.Hour
+
+var (
+	// Returned when using OpenSubtitles without an API key.
+	ErrMissingAPIKey = errors.New("OpenSubtitles needs an API key")
+	errInvalidID     = errors.New("invalid subtitle identifier")
+)
+
+func init() {
+	// The API allows 5 requests per second
+	httpclient.SetRateLimit("api.opensubtitles.com", 4) //nolint:gomnd
+
+	subtitles.Register(openSubtitlesProvider)
+}
+
+var openSubtitlesProvider subtitles.Provider = Provider{}
+
+// Fetches subtitles from OpenSubtitles. Results are identified by their file identifier.
+type Provider struct{}
+
+type searchResponse struct {
+	Data []struct {
+		Attributes struct {
+			Language       string `json:"language"`
+			DownloadCount  int    `json:"download_count"`
+			Release        string `json:"release"`
+			MovieHashMatch bool   `json:"moviehash_match"`
+			Files          []struct {
+				FileID int64 `json:"file_id"`
+			} `json:"files"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+type downloadResponse struct {
+	Link     string `json:"link"`
+	FileName string `json:"file_name"`
+}
+
+type loginResponse struct {
+	Token string `json:"token"`
+}
+
+// The login token of the configured account, shared by all downloads.
+var session struct {
+	mutex     sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func (p Provider) GetName() string {
+	return "OpenSubtitles"
+}
+
+func (p Provider) Search(query subtitles.SearchQuery) ([]subtitles.Result, error) {
+	parameters := url.Values{"languages": {getLanguageCode(query.Language)}}
+
+	if query.Hash != "" {
+		parameters.Set("moviehash", query.Hash)
+	}
+
+	if imdbID, err := strconv.ParseInt(strings.TrimPrefix(query.ImdbID, "tt"), 10, 64); err == nil { //nolint:gomnd
+		parameters.Set("imdb_id", strconv.FormatInt(imdbID, 10)) //nolint:gomnd
+	}
+
+	// Names are only worth searching for when nothing identifies the video
+	if query.Hash == "" && query.ImdbID == "" {
+		parameters.Set("query", query.Title)
+
+		if query.Year != 0 {
+			parameters.Set("year", strconv.Itoa(query.Year))
+		}
+	}
+
+	var response searchResponse
+
+	// Parameters are sorted, since the API redirects requests with unsorted ones
+	if err := call(http.MethodGet, "/subtitles?"+parameters.Encode(), nil, &response); err != nil {
+		return nil, fmt.Errorf("failed to search OpenSubtitles: %w", err)
+	}
+
+	results := make([]subtitles.Result, 0, len(response.Data))
+
+	for _, subtitle := range response.Data {
+		if len(subtitle.Attributes.Files) == 0 {
+			continue
+		}
+
+		results = append(results, subtitles.Result{
+			ID:        strconv.FormatInt(subtitle.Attributes.Files[0].FileID, 10), //nolint:gomnd
+			Language:  subtitle.Attributes.Language,
+			Release:   subtitle.Attributes.Release,
+			HashMatch: subtitle.Attributes.MovieHashMatch,
+			Downloads: subtitle.Attributes.DownloadCount,
+		})
+	}
+
+	return results, nil
+}
+
+func (p Provider) Download(id string) (*subtitles.Subtitle, error) {
+	fileID, err := strconv.ParseInt(id, 10, 64) //nolint:gomnd
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidID, id)
+	}
+
+	request, err := json.Marshal(map[string]interface{}{"file_id": fileID, "sub_format": "srt"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode OpenSubtitles request: %w", err)
+	}
+
+	var response downloadResponse
+	if err := call(http.MethodPost, "/download", request, &response); err != nil {
+		return nil, fmt.Errorf("failed to request OpenSubtitles download: %w", err)
+	}
+
+	data, err := httpclient.Download(response.Link)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download OpenSubtitles file: %w", err)
+	}
+
+	format := strings.TrimPrefix(filepath.Ext(response.FileName), ".")
+	if format == "" {
+		format = "srt"
+	}
+
+	return &subtitles.Subtitle{Data: data, Format: format}, nil
+}
+
+// Sends a request to the API, and decodes its JSON response into target.
+// Requests are authenticated with the configured account when one is set.
+func call(method, path string, body []byte, target interface{}) error {
+	apiKey := viper.GetString("providers.opensubtitles.api_key")
+	if apiKey == "" {
+		return ErrMissingAPIKey
+	}
+
+	header := newHeader(apiKey)
+
+	token, err := getToken(apiKey)
+	if err != nil {
+		return err
+	}
+
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+
+	responseBody, err := httpclient.Send(method, getBaseURL()+path, header, body)
+	if err != nil {
+		return fmt.Errorf("failed to call OpenSubtitles: %w", err)
+	}
+
+	if err := json.Unmarshal(responseBody, target); err != nil {
+		return fmt.Errorf("failed to decode OpenSubtitles response: %w", err)
+	}
+
+	return nil
+}
+
+// Returns the login token of the configured account, logging in when needed, or an empty token without account.
+func getToken(apiKey string) (string, error) {
+	username := viper.GetString("providers.opensubtitles.username")
+	if username == "" {
+		return "", nil
+	}
+
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	if session.token != "" && time.Now().Before(session.expiresAt) {
+		return session.token, nil
+	}
+
+	request, err := json.Marshal(map[string]string{
+		"username": username,
+		"password": viper.GetString("providers.opensubtitles.password"),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode OpenSubtitles login: %w", err)
+	}
+
+	responseBody, err := httpclient.Send(http.MethodPost, getBaseURL()+"/login", newHeader(apiKey), request)
+	if err != nil {
+		return "", fmt.Errorf("failed to log in to OpenSubtitles: %w", err)
+	}
+
+	var response loginResponse
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return "", fmt.Errorf("failed to decode OpenSubtitles login: %w", err)
+	}
+
+	session.token = response.Token
+	session.expiresAt = time.Now().Add(tokenLifetime)
+
+	return session.token, nil
+}
+
+func newHeader(apiKey string) http.Header {
+	return http.Header{
+		"Api-Key":      {apiKey},
+		"Accept":       {"application/json"},
+		"Content-Type": {"application/json"},
+	}
+}
+
+func getBaseURL() string {
+	return strings.TrimSuffix(viper.GetString("providers.opensubtitles.url"), "/")
+}
+
+// Converts a BCP 47 tag to the language codes of the API, which are ISO 639-1 codes,
+// except for Portuguese and Chinese which have a region, like "pt-br".
+func getLanguageCode(subtitleLanguage string) string {
+	tag, err := language.Parse(subtitleLanguage)
+	if err != nil {
+		return strings.ToLower(subtitleLanguage)
+	}
+
+	base, _ := tag.Base()
+
+	switch base.String() {
+	case "pt":
+		if region, confidence := tag.Region(); confidence == language.Exact && region.String() == "BR" {
+			return "pt-br"
+		}
+
+		return "pt-pt"
+	case "zh":
+		if script, _ := tag.Script(); script.String() == "Hant" {
+			return "zh-tw"
+		}
+
+		return "zh-cn"
+	}
+
+	return base.String()
+}
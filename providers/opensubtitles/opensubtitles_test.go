@@ -0,0 +1,117 @@
+package opensubtitles_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/meteorae/meteorae-server/providers/opensubtitles"
+	"github.com/meteorae/meteorae-server/providers/subtitles"
+	"github.com/spf13/viper"
+)
+
+// Serves canned OpenSubtitles responses, so we don't depend on the real API.
+func newFakeOpenSubtitles(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	var server *httptest.Server
+
+	server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/files/1234.srt" && request.Header.Get("Api-Key") != "secret" {
+			writer.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		switch request.URL.Path {
+		case "/api/v1/login":
+			var login map[string]string
+			if err := json.NewDecoder(request.Body).Decode(&login); err != nil || login["password"] != "hunter2" {
+				writer.WriteHeader(http.StatusUnauthorized)
+
+				return
+			}
+
+			fmt.Fprint(writer, `{"token": "token-1"}`)
+		case "/api/v1/subtitles":
+			query := request.URL.Query()
+			if query.Get("moviehash") != "8e245d9679d31e12" || query.Get("imdb_id") != "133093" ||
+				query.Get("languages") != "pt-br" || query.Has("query") {
+				fmt.Fprint(writer, `{"data": []}`)
+
+				return
+			}
+
+			fmt.Fprint(writer, `{"data": [
+				{"id": "1", "attributes": {"language": "pt-BR", "download_count": 10, "release": "The.Matrix.1999",
+				"moviehash_match": true, "files": [{"file_id": 1234}]}},
+				{"id": "2", "attributes": {"language": "pt-BR", "download_count": 5, "files": []}}]}`)
+		case "/api/v1/download":
+			var download map[string]interface{}
+			if err := json.NewDecoder(request.Body).Decode(&download); err != nil ||
+				download["file_id"] != float64(1234) || request.Header.Get("Authorization") != "Bearer token-1" {
+				writer.WriteHeader(http.StatusBadRequest)
+
+				return
+			}
+
+			fmt.Fprintf(writer, `{"link": "%s/files/1234.srt", "file_name": "The.Matrix.1999.srt"}`, server.URL)
+		case "/files/1234.srt":
+			fmt.Fprint(writer, "1\n00:00:01,000 --> 00:00:02,000\nOlá\n")
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	return server
+}
+
+func TestProvider(t *testing.T) {
+	server := newFakeOpenSubtitles(t)
+	defer server.Close()
+
+	viper.Set("providers.opensubtitles.url", server.URL+"/api/v1")
+	viper.Set("providers.opensubtitles.api_key", "secret")
+	viper.Set("providers.opensubtitles.username", "neo")
+	viper.Set("providers.opensubtitles.password", "hunter2")
+
+	provider := opensubtitles.Provider{}
+
+	results, err := provider.Search(subtitles.SearchQuery{
+		Hash:     "8e245d9679d31e12",
+		Size:     12909756,
+		ImdbID:   "tt0133093",
+		Title:    "The Matrix",
+		Language: "pt-BR",
+	})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	want := subtitles.Result{ID: "1234", Language: "pt-BR", Release: "The.Matrix.1999", HashMatch: true, Downloads: 10}
+
+	if len(results) != 1 || results[0] != want {
+		t.Fatalf("Search() = %+v, want only %+v, as the other subtitle has no file", results, want)
+	}
+
+	subtitle, err := provider.Download(results[0].ID)
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+
+	if subtitle.Format != "srt" || string(subtitle.Data) != "1\n00:00:01,000 --> 00:00:02,000\nOlá\n" {
+		t.Errorf("Download() = %q in %s, want the subtitle file in srt", subtitle.Data, subtitle.Format)
+	}
+}
+
+func TestMissingAPIKey(t *testing.T) {
+	viper.Set("providers.opensubtitles.api_key", "")
+
+	_, err := opensubtitles.Provider{}.Search(subtitles.SearchQuery{Title: "The Matrix", Language: "en"})
+	if !errors.Is(err, opensubtitles.ErrMissingAPIKey) {
+		t.Errorf("Search() error = %v, want %v", err, opensubtitles.ErrMissingAPIKey)
+	}
+}
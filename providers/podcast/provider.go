@@ -0,0 +1,43 @@
+package podcast
+
+import (
+	"fmt"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/filesystem/analyzer"
+	"github.com/meteorae/meteorae-server/utils"
+)
+
+// Gathers the tags, duration and chapters of a podcast episode.
+func GetInformation(item *database.ItemMetadata, library database.Library) error {
+	mediaInfo, err := analyzer.ProbeMediaInfo(item.MediaPart.FilePath)
+	if err != nil {
+		return fmt.Errorf("could not probe podcast episode %s: %w", item.MediaPart.FilePath, err)
+	}
+
+	if title := mediaInfo.Tag("title"); title != "" {
+		item.Title = title
+	}
+
+	item.SortTitle = utils.CleanSortTitle(item.Title)
+	item.Summary = mediaInfo.Tag("description", "comment", "synopsis")
+	item.Duration = mediaInfo.Duration
+	item.ReleaseDate = mediaInfo.GetReleaseDate()
+
+	chapters := mediaInfo.GetChapters(item.MediaPart.FilePath, 0)
+	for index := range chapters {
+		chapters[index].Index = int64(index)
+	}
+
+	err = database.SetChapters(item.ID, chapters)
+	if err != nil {
+		return fmt.Errorf("could not save chapters for podcast episode %s: %w", item.MediaPart.FilePath, err)
+	}
+
+	err = database.UpdatePodcast(item)
+	if err != nil {
+		return fmt.Errorf("could not get information for podcast episode %s: %w", item.MediaPart.FilePath, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,237 @@
+package refresher
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/providers/httpclient"
+	providers "github.com/meteorae/meteorae-server/providers/registry"
+	"github.com/meteorae/meteorae-server/providers/subtitles"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// Only items matched against remote providers benefit from refreshes.
+var refreshableItemTypes = []database.ItemType{database.MovieItem}
+
+// Holds the items waiting for a refresh, without duplicates.
+type refreshQueue struct {
+	mutex   sync.Mutex
+	pending []uint64
+	queued  map[uint64]bool
+	// Items whose refresh was requested by a user, which ignore the provider response cache.
+	requested map[uint64]bool
+	wake      chan struct{}
+}
+
+var queue = refreshQueue{
+	queued:    map[uint64]bool{},
+	requested: map[uint64]bool{},
+	wake:      make(chan struct{}, 1),
+}
+
+// Adds the given items to the refresh queue. Items already queued are skipped, but are still marked as
+// requested by a user when they are.
+func (q *refreshQueue) push(requested bool, ids ...uint64) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for _, id := range ids {
+		if requested {
+			q.requested[id] = true
+		}
+
+		if q.queued[id] {
+			continue
+		}
+
+		q.queued[id] = true
+		q.pending = append(q.pending, id)
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Removes the next item from the queue, if any, and returns whether its refresh was requested by a user.
+func (q *refreshQueue) pop() (id uint64, requested, ok bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(q.pending) == 0 {
+		return 0, false, false
+	}
+
+	id = q.pending[0]
+	q.pending = q.pending[1:]
+	requested = q.requested[id]
+
+	delete(q.queued, id)
+	delete(q.requested, id)
+
+	return id, requested, true
+}
+
+// Starts refreshing the queued items, and periodically queues the items with outdated metadata.
+// Refreshes are spaced by "providers.refresh.delay", to avoid hitting provider rate limits.
+// Stops when the context is canceled.
+func Start(ctx context.Context) {
+	go processQueue(ctx)
+	go checkPeriodically(ctx)
+}
+
+// Queues the given item for a refresh, requested by a user. Unless forced, items with up-to-date metadata
+// are skipped. Cached provider responses are ignored, see httpclient.BypassCache.
+func RefreshItem(itemID string, force bool) error {
+	item, err := database.GetItemByID(itemID)
+	if err != nil {
+		return fmt.Errorf("failed to get item %s: %w", itemID, err)
+	}
+
+	if !force && !needsRefresh(item, time.Now()) {
+		log.Debug().Msgf("Metadata for item %s is up to date, skipping refresh", itemID)
+
+		return nil
+	}
+
+	queue.push(true, item.ID)
+
+	return nil
+}
+
+// Queues the items of the given library for a refresh, requested by a user. Unless forced, items with
+// up-to-date metadata are skipped. Cached provider responses are ignored, see httpclient.BypassCache.
+func RefreshLibrary(libraryID string, force bool) error {
+	id, err := strconv.ParseUint(libraryID, 10, 64) //nolint:gomnd
+	if err != nil {
+		return fmt.Errorf("invalid library identifier %s: %w", libraryID, err)
+	}
+
+	return queueOutdatedItems(id, force, true)
+}
+
+// Queues the outdated items from the given library, or from all libraries when libraryID is 0.
+func queueOutdatedItems(libraryID uint64, force, requested bool) error {
+	now := time.Now()
+	staleBefore := now.Add(-viper.GetDuration("providers.refresh.max_age"))
+	missingBefore := now.Add(-viper.GetDuration("providers.refresh.missing_max_age"))
+
+	if force {
+		staleBefore, missingBefore = now, now
+	}
+
+	ids, err := database.GetItemIDsToRefresh(libraryID, refreshableItemTypes, staleBefore, missingBefore)
+	if err != nil {
+		return fmt.Errorf("failed to get items to refresh: %w", err)
+	}
+
+	if len(ids) > 0 {
+		log.Info().Msgf("Queuing %d items for a metadata refresh", len(ids))
+	}
+
+	queue.push(requested, ids...)
+
+	return nil
+}
+
+// Returns whether the metadata of an item is old enough to be refreshed.
+// Items missing a poster or summary are refreshed more often, in case providers have them now.
+func needsRefresh(item *database.ItemMetadata, now time.Time) bool {
+	if item.RefreshedAt.Before(now.Add(-viper.GetDuration("providers.refresh.max_age"))) {
+		return true
+	}
+
+	isMissingData := item.Thumb == "" || item.Summary == ""
+
+	return isMissingData && item.RefreshedAt.Before(now.Add(-viper.GetDuration("providers.refresh.missing_max_age")))
+}
+
+func checkPeriodically(ctx context.Context) {
+	ticker := time.NewTicker(viper.GetDuration("providers.refresh.check_interval"))
+	defer ticker.Stop()
+
+	for {
+		err := queueOutdatedItems(0, false, false)
+		if err != nil {
+			log.Err(err).Msg("Failed to queue outdated items for a refresh")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func processQueue(ctx context.Context) {
+	delay := time.NewTicker(viper.GetDuration("providers.refresh.delay"))
+	defer delay.Stop()
+
+	for {
+		id, requested, ok := queue.pop()
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-queue.wake:
+				continue
+			}
+		}
+
+		refresh(id, requested)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-delay.C:
+		}
+	}
+}
+
+// Fetches fresh metadata for an item, and saves it. Refreshes requested by users ignore cached responses.
+// Failed refreshes are still recorded, so the item isn't retried until it's outdated again.
+func refresh(id uint64, requested bool) {
+	item, err := database.GetItemByID(strconv.FormatUint(id, 10)) //nolint:gomnd
+	if err != nil {
+		log.Err(err).Msgf("Failed to get item %d to refresh", id)
+
+		return
+	}
+
+	if requested {
+		err = httpclient.BypassCache(func() error {
+			return providers.GetInformation(item, item.Library)
+		})
+	} else {
+		err = providers.GetInformation(item, item.Library)
+	}
+
+	if err != nil {
+		log.Err(err).Msgf("Failed to refresh metadata for item %d", id)
+
+		err = database.SetItemRefreshedAt(id, time.Now())
+		if err != nil {
+			log.Err(err).Msgf("Failed to record refresh for item %d", id)
+		}
+
+		return
+	}
+
+	err = database.UpdateItem(item)
+	if err != nil {
+		log.Err(err).Msgf("Failed to update item %d", id)
+	}
+
+	// Catch up on subtitle languages added to the library since the item was added
+	err = subtitles.DownloadMissing(item, item.Library)
+	if err != nil {
+		log.Err(err).Msgf("Failed to download subtitles for item %d", id)
+	}
+}
@@ -0,0 +1,694 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/helpers"
+	"github.com/meteorae/meteorae-server/providers/httpclient"
+	"github.com/meteorae/meteorae-server/utils"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+var (
+	errNoResultsFound  = errors.New("no results found")
+	errUnknownProvider = errors.New("unknown provider")
+)
+
+// How much the title counts in the score of a search result, compared to the year.
+const titleScoreWeight = 0.8
+
+// Describes the kind of image returned by a provider.
+type ImageType string
+
+const (
+	// Poster or cover art, used as the item thumbnail.
+	PosterImage ImageType = "poster"
+	// Backdrop or fan art, used as the item background.
+	ArtImage ImageType = "art"
+)
+
+// Describes what we know about an item when searching for it.
+type SearchQuery struct {
+	Title string
+	Year  int
+	// Path of the file or directory of the item, for providers reading local metadata.
+	FilePath string
+	// External identifiers already known for the item, like the recording identified from an audio fingerprint.
+	Identifiers []database.ExternalIdentifier
+	// The file of the item, with its size and hashes, for providers identifying files by hash.
+	MediaPart database.MediaPart
+}
+
+// Describes a single search result from a provider.
+type SearchResult struct {
+	// Identifier of the result, only meaningful to the provider that returned it.
+	ID    string
+	Title string
+	Year  int
+	// URL of the result's poster, if any.
+	Thumb string
+}
+
+// Describes an image available from a provider.
+// The URL is either a remote URL or a local file path.
+type Image struct {
+	Type ImageType
+	URL  string
+}
+
+// Describes the item a provider matched during a search.
+type match struct {
+	provider Provider
+	id       string
+}
+
+// Defines the structure of a metadata provider.
+type Provider interface {
+	// Returns the name of the provider.
+	GetName() string
+	// Returns whether the provider supports the given library type.
+	SupportsLibraryType(library database.Library) bool
+	// Searches for items matching the query, best matches first.
+	Search(query SearchQuery, library database.Library) ([]SearchResult, error)
+	// Returns the metadata for the item with the given identifier.
+	// Credited people only need a name, external identifiers, and the URL of their picture as Thumb.
+	// Genres are returned as tags with only a name, and are filed under the genres branch of the tag tree.
+	// Tags already saved by the provider, with a TagID, are assigned as is.
+	GetMetadata(id string, library database.Library) (*database.ItemMetadata, error)
+	// Returns the images for the item with the given identifier, best images first.
+	GetImages(id string, library database.Library) ([]Image, error)
+}
+
+var Registry []Provider
+
+// Registers a new metadata provider.
+func Register(provider Provider) {
+	Registry = append(Registry, provider)
+}
+
+// Returns the provider chain for the given library, in priority order.
+// The chain can be set per library type, like "providers.chain.movie", and defaults to every
+// provider supporting the library, in registration order.
+func GetProviders(library database.Library) []Provider {
+	var supported []Provider
+
+	for _, provider := range Registry {
+		if provider.SupportsLibraryType(library) {
+			supported = append(supported, provider)
+		}
+	}
+
+	chain := viper.GetStringSlice(fmt.Sprintf("providers.chain.%s", library.Type))
+	if len(chain) == 0 {
+		return supported
+	}
+
+	providers := make([]Provider, 0, len(chain))
+
+	for _, name := range chain {
+		for _, provider := range supported {
+			if strings.EqualFold(provider.GetName(), name) {
+				providers = append(providers, provider)
+			}
+		}
+	}
+
+	return providers
+}
+
+// Fetches the metadata and images of an item from the library's provider chain.
+// Items already matched keep their match, and are only searched for in the other providers.
+// Items unmatched by hand are left untouched, until their match is fixed.
+// Each provider only fills the fields left empty by the providers before it. Metadata is fetched
+// in the library's language first, then in the fallback language for the fields still missing.
+// The item is updated in place, and isn't saved to the database.
+func GetInformation(item *database.ItemMetadata, library database.Library) error {
+	if item.Unmatched {
+		log.Debug().Msgf("Skipping \"%s\", which was unmatched by hand", item.Title)
+
+		return nil
+	}
+
+	query := SearchQuery{
+		Title:       item.Title,
+		Year:        item.ReleaseDate.Year(),
+		FilePath:    item.MediaPart.FilePath,
+		Identifiers: item.ExternalIdentifiers,
+		MediaPart:   item.MediaPart,
+	}
+
+	if item.ReleaseDate.IsZero() {
+		query.Year = 0
+	}
+
+	var matches []match
+
+	for _, provider := range GetProviders(library) {
+		// Keep the item's current match, which may have been fixed by hand
+		if item.MatchID != "" && strings.EqualFold(provider.GetName(), item.MatchProvider) {
+			matches = append(matches, match{provider: provider, id: item.MatchID})
+
+			continue
+		}
+
+		results, err := provider.Search(query, library)
+		if err != nil {
+			log.Err(err).Msgf("Provider %s failed to search for \"%s\"", provider.GetName(), query.Title)
+
+			continue
+		}
+
+		if len(results) == 0 {
+			log.Debug().Msgf("Provider %s found no results for \"%s\"", provider.GetName(), query.Title)
+
+			continue
+		}
+
+		matches = append(matches, match{provider: provider, id: results[0].ID})
+	}
+
+	metadata, matched, err := fetchMetadata(matches, library)
+	if err != nil {
+		return fmt.Errorf("%w for \"%s\"", err, query.Title)
+	}
+
+	if len(matched) < len(matches) {
+		// Keep what we already know for the fields the failed providers could have filled
+		MergeMetadata(metadata, item)
+	} else {
+		// Fields cleared by the providers, like removed keywords or captions, stay cleared
+		mergeUnknownMetadata(metadata, item)
+	}
+
+	applyMetadata(item, metadata)
+
+	item.MatchProvider = matched[0].provider.GetName()
+	item.MatchID = matched[0].id
+	item.RefreshedAt = time.Now()
+
+	return nil
+}
+
+// Describes a possible match for an item, as returned by SearchMatches.
+type MatchCandidate struct {
+	Provider string
+	SearchResult
+	// How well the candidate matches the query, from 0 to 1.
+	Score float64
+}
+
+// Searches every provider of the library's chain for the given title, and returns
+// all the candidates, best matches first. The year is optional, and ignored when 0.
+func SearchMatches(title string, year int, filePath string, library database.Library) ([]MatchCandidate, error) {
+	query := SearchQuery{
+		Title:    title,
+		Year:     year,
+		FilePath: filePath,
+	}
+
+	var candidates []MatchCandidate
+
+	for _, provider := range GetProviders(library) {
+		results, err := provider.Search(query, library)
+		if err != nil {
+			log.Err(err).Msgf("Provider %s failed to search for \"%s\"", provider.GetName(), title)
+
+			continue
+		}
+
+		for _, result := range results {
+			candidates = append(candidates, MatchCandidate{
+				Provider:     provider.GetName(),
+				SearchResult: result,
+				Score:        scoreResult(query, result),
+			})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+
+	return candidates, nil
+}
+
+// Scores a search result against the query, mostly on its title, and on its year when known.
+func scoreResult(query SearchQuery, result SearchResult) float64 {
+	titleScore := utils.TitleSimilarity(query.Title, result.Title)
+
+	if query.Year == 0 || result.Year == 0 {
+		return titleScore
+	}
+
+	var yearScore float64
+
+	// Release dates often differ by a year between countries
+	switch difference := query.Year - result.Year; {
+	case difference == 0:
+		yearScore = 1
+	case difference == 1 || difference == -1:
+		yearScore = 0.5 //nolint:gomnd
+	}
+
+	return titleScoreWeight*titleScore + (1-titleScoreWeight)*yearScore
+}
+
+// Matches an item to the given result of one of the library's providers, replacing its metadata and images.
+// The item is updated in place, and isn't saved to the database.
+func FixMatch(item *database.ItemMetadata, library database.Library, providerName, id string) error {
+	var matched Provider
+
+	for _, provider := range GetProviders(library) {
+		if strings.EqualFold(provider.GetName(), providerName) {
+			matched = provider
+		}
+	}
+
+	if matched == nil {
+		return fmt.Errorf("%w: %s", errUnknownProvider, providerName)
+	}
+
+	var metadata *database.ItemMetadata
+
+	// Users fixing a match expect the provider's current metadata
+	err := httpclient.BypassCache(func() error {
+		var err error
+		metadata, _, err = fetchMetadata([]match{{provider: matched, id: id}}, library)
+
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get metadata for %s:%s: %w", providerName, id, err)
+	}
+
+	metadata.Title = mergeString(metadata.Title, item.Title)
+	metadata.SortTitle = mergeString(metadata.SortTitle, utils.CleanSortTitle(metadata.Title))
+
+	// Keep what was read from the file itself when the provider doesn't know better.
+	if metadata.Duration == 0 {
+		metadata.Duration = item.Duration
+	}
+
+	if len(metadata.ExtraInfo) == 0 {
+		metadata.ExtraInfo = item.ExtraInfo
+	}
+
+	applyMetadata(item, metadata)
+
+	item.MatchProvider = matched.GetName()
+	item.MatchID = id
+	item.Unmatched = false
+	item.RefreshedAt = time.Now()
+
+	return nil
+}
+
+// Removes all the provider metadata from an item, and resets its title to the given one.
+// What was read from the file itself, like its duration, EXIF data or faces, is kept.
+// The item is marked as unmatched, so it isn't matched again automatically.
+// The item is updated in place, and isn't saved to the database.
+func Unmatch(item *database.ItemMetadata, title string) {
+	item.Title = title
+	item.SortTitle = utils.CleanSortTitle(title)
+	item.OriginalTitle = ""
+	item.Tagline = ""
+	item.Summary = ""
+	item.OriginalLanguage = ""
+	item.Thumb = ""
+	item.Art = ""
+	item.ReleaseDate = time.Time{}
+	item.Popularity = 0
+	item.MatchProvider = ""
+	item.MatchID = ""
+	item.Unmatched = true
+	item.ExternalIdentifiers = []database.ExternalIdentifier{}
+	item.Credits = []database.Credit{}
+	item.Collections = []database.CollectionMember{}
+	item.Tags = []database.ItemTag{}
+	item.Ratings = []database.CommunityRating{}
+}
+
+// Fetches and merges the metadata and images of the given matches, in order.
+// Returns the merged metadata, and the matches metadata could be fetched for.
+func fetchMetadata(matches []match, library database.Library) (*database.ItemMetadata, []match, error) {
+	var (
+		metadata database.ItemMetadata
+		images   []Image
+		matched  []match
+	)
+
+	for _, match := range matches {
+		providerMetadata, err := match.provider.GetMetadata(match.id, library)
+		if err != nil {
+			log.Err(err).Msgf("Provider %s failed to get metadata for %s", match.provider.GetName(), match.id)
+
+			continue
+		}
+
+		matched = append(matched, match)
+
+		MergeMetadata(&metadata, providerMetadata)
+
+		providerImages, err := match.provider.GetImages(match.id, library)
+		if err != nil {
+			log.Err(err).Msgf("Provider %s failed to get images for %s", match.provider.GetName(), match.id)
+		}
+
+		images = append(images, providerImages...)
+	}
+
+	if len(matched) == 0 {
+		return nil, nil, errNoResultsFound
+	}
+
+	fallbackLanguage := viper.GetString("providers.fallback_language")
+
+	// Providers don't always have translations, so fill the gaps from the fallback language
+	if hasMissingText(&metadata) && fallbackLanguage != "" && !strings.EqualFold(fallbackLanguage, library.Language) {
+		fallbackLibrary := library
+		fallbackLibrary.Language = fallbackLanguage
+
+		for _, match := range matched {
+			providerMetadata, err := match.provider.GetMetadata(match.id, fallbackLibrary)
+			if err != nil {
+				log.Err(err).Msgf("Provider %s failed to get %s metadata for %s",
+					match.provider.GetName(), fallbackLanguage, match.id)
+
+				continue
+			}
+
+			MergeMetadata(&metadata, providerMetadata)
+		}
+	}
+
+	metadata.Thumb = saveFirstImage(images, PosterImage)
+	metadata.Art = saveFirstImage(images, ArtImage)
+
+	if metadata.Credits != nil {
+		metadata.Credits = resolveCredits(metadata.Credits)
+	}
+
+	if metadata.Collections != nil {
+		metadata.Collections = resolveCollections(metadata.Collections)
+	}
+
+	if metadata.Tags != nil {
+		metadata.Tags = resolveGenres(metadata.Tags)
+	}
+
+	return &metadata, matched, nil
+}
+
+// Links credits to existing people, creating the ones we don't know yet and caching their picture.
+// Credits for people that can't be saved are skipped.
+func resolveCredits(credits []database.Credit) []database.Credit {
+	resolved := make([]database.Credit, 0, len(credits))
+
+	for _, credit := range credits {
+		person, err := database.GetOrCreatePerson(&credit.Person)
+		if err != nil {
+			log.Err(err).Msgf("Failed to save person \"%s\"", credit.Person.Title)
+
+			continue
+		}
+
+		if person.Thumb == "" && credit.Person.Thumb != "" {
+			thumb := saveFirstImage([]Image{{Type: PosterImage, URL: credit.Person.Thumb}}, PosterImage)
+			if thumb != "" {
+				err = database.SetPersonThumb(person.ID, thumb)
+				if err != nil {
+					log.Err(err).Msgf("Failed to save picture for person \"%s\"", person.Title)
+				}
+			}
+		}
+
+		credit.PersonID = person.ID
+		credit.Person = database.ItemMetadata{}
+
+		resolved = append(resolved, credit)
+	}
+
+	return resolved
+}
+
+// Links collection memberships to existing collections, creating the ones we don't know yet and caching
+// their artwork. Collections that can't be saved are skipped.
+func resolveCollections(members []database.CollectionMember) []database.CollectionMember {
+	resolved := make([]database.CollectionMember, 0, len(members))
+
+	for _, member := range members {
+		collection := member.Collection
+		collection.Thumb = saveFirstImage([]Image{{Type: PosterImage, URL: collection.Thumb}}, PosterImage)
+		collection.Art = saveFirstImage([]Image{{Type: ArtImage, URL: collection.Art}}, ArtImage)
+
+		if collection.ExtraInfo == nil {
+			err := database.SetCollectionSortOrder(&collection, database.ReleaseDateSortOrder)
+			if err != nil {
+				log.Err(err).Msgf("Failed to set sort order for collection \"%s\"", collection.Title)
+			}
+		}
+
+		saved, err := database.GetOrCreateCollection(&collection)
+		if err != nil {
+			log.Err(err).Msgf("Failed to save collection \"%s\"", collection.Title)
+
+			continue
+		}
+
+		member.CollectionID = saved.ID
+		member.Collection = database.ItemMetadata{}
+
+		resolved = append(resolved, member)
+	}
+
+	return resolved
+}
+
+// Links genres to their tag in the genres branch of the tag tree, creating the ones we don't know yet.
+// Genres that can't be saved are skipped.
+func resolveGenres(itemTags []database.ItemTag) []database.ItemTag {
+	resolved := make([]database.ItemTag, 0, len(itemTags))
+
+	for _, itemTag := range itemTags {
+		if itemTag.TagID != 0 {
+			resolved = append(resolved, itemTag)
+
+			continue
+		}
+
+		tag, err := database.GetOrCreateGenreTag(itemTag.Tag.Name)
+		if err != nil {
+			log.Err(err).Msgf("Failed to save genre \"%s\"", itemTag.Tag.Name)
+
+			continue
+		}
+
+		itemTag.TagID = tag.ID
+		itemTag.Tag = database.Tag{}
+
+		resolved = append(resolved, itemTag)
+	}
+
+	return resolved
+}
+
+// Returns whether the main text fields of the metadata are missing.
+func hasMissingText(metadata *database.ItemMetadata) bool {
+	return metadata.Title == "" || metadata.Summary == ""
+}
+
+// Copies the fields of source into the empty fields of target.
+// Fields already set on target are left untouched. Associations source doesn't know about, which are nil,
+// don't replace the ones target knows are empty.
+func MergeMetadata(target, source *database.ItemMetadata) {
+	target.Title = mergeString(target.Title, source.Title)
+	target.SortTitle = mergeString(target.SortTitle, source.SortTitle)
+	target.OriginalTitle = mergeString(target.OriginalTitle, source.OriginalTitle)
+	target.Tagline = mergeString(target.Tagline, source.Tagline)
+	target.Summary = mergeString(target.Summary, source.Summary)
+	target.OriginalLanguage = mergeString(target.OriginalLanguage, source.OriginalLanguage)
+	target.Thumb = mergeString(target.Thumb, source.Thumb)
+	target.Art = mergeString(target.Art, source.Art)
+	target.PerceptualHash = mergeString(target.PerceptualHash, source.PerceptualHash)
+
+	if target.ReleaseDate.IsZero() {
+		target.ReleaseDate = source.ReleaseDate
+	}
+
+	if target.Popularity == 0 {
+		target.Popularity = source.Popularity
+	}
+
+	if target.Duration == 0 {
+		target.Duration = source.Duration
+	}
+
+	if len(target.ExtraInfo) == 0 {
+		target.ExtraInfo = source.ExtraInfo
+	}
+
+	if len(target.Credits) == 0 && source.Credits != nil {
+		target.Credits = source.Credits
+	}
+
+	if len(target.Collections) == 0 && source.Collections != nil {
+		target.Collections = source.Collections
+	}
+
+	if len(target.Tags) == 0 && source.Tags != nil {
+		target.Tags = source.Tags
+	}
+
+	if target.Exif == nil {
+		target.Exif = source.Exif
+	}
+
+	if len(target.FaceRegions) == 0 && source.FaceRegions != nil {
+		target.FaceRegions = source.FaceRegions
+	}
+
+	mergeExternalIdentifiers(target, source)
+
+	for _, rating := range source.Ratings {
+		if !hasRatingSource(target.Ratings, rating.Source) {
+			target.Ratings = append(target.Ratings, rating)
+		}
+	}
+}
+
+// Copies the fields of source that providers can't clear into the empty fields of target: the title read
+// from the file, artwork and perceptual hashes which failed to be saved or computed, what was read from
+// the file itself, and the external identifiers. Associations are only copied when no provider returned
+// them, as opposed to returning none.
+func mergeUnknownMetadata(target, source *database.ItemMetadata) {
+	target.Title = mergeString(target.Title, source.Title)
+	target.SortTitle = mergeString(target.SortTitle, source.SortTitle)
+	target.Thumb = mergeString(target.Thumb, source.Thumb)
+	target.Art = mergeString(target.Art, source.Art)
+	target.PerceptualHash = mergeString(target.PerceptualHash, source.PerceptualHash)
+
+	if target.Duration == 0 {
+		target.Duration = source.Duration
+	}
+
+	if len(target.ExtraInfo) == 0 {
+		target.ExtraInfo = source.ExtraInfo
+	}
+
+	if target.Credits == nil {
+		target.Credits = source.Credits
+	}
+
+	if target.Collections == nil {
+		target.Collections = source.Collections
+	}
+
+	if target.Tags == nil {
+		target.Tags = source.Tags
+	}
+
+	if target.Exif == nil {
+		target.Exif = source.Exif
+	}
+
+	if target.FaceRegions == nil {
+		target.FaceRegions = source.FaceRegions
+	}
+
+	mergeExternalIdentifiers(target, source)
+}
+
+// Adds the external identifiers of source to target, for the identifier types target doesn't have.
+func mergeExternalIdentifiers(target, source *database.ItemMetadata) {
+	for _, identifier := range source.ExternalIdentifiers {
+		if !hasIdentifierType(target.ExternalIdentifiers, identifier.IdentifierType) {
+			target.ExternalIdentifiers = append(target.ExternalIdentifiers, database.ExternalIdentifier{
+				IdentifierType: identifier.IdentifierType,
+				Identifier:     identifier.Identifier,
+			})
+		}
+	}
+}
+
+func hasIdentifierType(identifiers []database.ExternalIdentifier, identifierType database.IdentifierType) bool {
+	for _, identifier := range identifiers {
+		if identifier.IdentifierType == identifierType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasRatingSource(ratings []database.CommunityRating, source string) bool {
+	for _, rating := range ratings {
+		if rating.Source == source {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Overwrites the metadata fields of target with the ones from source.
+func applyMetadata(target, source *database.ItemMetadata) {
+	target.Title = source.Title
+	target.SortTitle = source.SortTitle
+	target.OriginalTitle = source.OriginalTitle
+	target.Tagline = source.Tagline
+	target.Summary = source.Summary
+	target.OriginalLanguage = source.OriginalLanguage
+	target.Thumb = source.Thumb
+	target.Art = source.Art
+	target.ReleaseDate = source.ReleaseDate
+	target.Popularity = source.Popularity
+	target.Duration = source.Duration
+	target.ExtraInfo = source.ExtraInfo
+	target.ExternalIdentifiers = source.ExternalIdentifiers
+	target.Credits = source.Credits
+	target.Collections = source.Collections
+	target.Tags = source.Tags
+	target.Exif = source.Exif
+	target.FaceRegions = source.FaceRegions
+	target.Ratings = source.Ratings
+	target.PerceptualHash = source.PerceptualHash
+}
+
+func mergeString(target, source string) string {
+	if target != "" {
+		return target
+	}
+
+	return source
+}
+
+// Saves the first image of the given type that can be downloaded to the cache.
+// Returns the hash of the cached image, or an empty string if none could be saved.
+func saveFirstImage(images []Image, imageType ImageType) string {
+	for _, image := range images {
+		if image.Type != imageType {
+			continue
+		}
+
+		if image.URL == "" {
+			continue
+		}
+
+		hash, err := helpers.SaveImageToCache(image.URL)
+		if err != nil {
+			log.Err(err).Msgf("Failed to save image %s to cache", image.URL)
+
+			continue
+		}
+
+		return hash
+	}
+
+	return ""
+}
@@ -0,0 +1,298 @@
+package registry_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/providers/registry"
+	"github.com/spf13/viper"
+)
+
+// Returns the same metadata for any item, without images.
+// English metadata is returned instead when the library language is "en-US".
+type fakeProvider struct {
+	name     string
+	metadata database.ItemMetadata
+	english  database.ItemMetadata
+}
+
+func (p fakeProvider) GetName() string {
+	return p.name
+}
+
+func (p fakeProvider) SupportsLibraryType(library database.Library) bool {
+	return library.Type == database.TVLibrary || library.Type == database.AnimeTVLibrary
+}
+
+func (p fakeProvider) Search(query registry.SearchQuery, library database.Library) ([]registry.SearchResult, error) {
+	if p.metadata.Title == "" && p.metadata.Summary == "" && p.metadata.Tagline == "" {
+		return nil, nil
+	}
+
+	return []registry.SearchResult{{ID: "1", Title: query.Title}}, nil
+}
+
+func (p fakeProvider) GetMetadata(id string, library database.Library) (*database.ItemMetadata, error) {
+	metadata := p.metadata
+	if library.Language == "en-US" {
+		metadata = p.english
+	}
+
+	return &metadata, nil
+}
+
+func (p fakeProvider) GetImages(id string, library database.Library) ([]registry.Image, error) {
+	return nil, nil
+}
+
+func TestGetInformation(t *testing.T) {
+	registry.Register(fakeProvider{
+		name:     "First",
+		metadata: database.ItemMetadata{Title: "First Title", Summary: "First summary"},
+	})
+	registry.Register(fakeProvider{
+		name:     "Second",
+		metadata: database.ItemMetadata{Summary: "Second summary", Tagline: "Second tagline"},
+	})
+	registry.Register(fakeProvider{name: "Empty"})
+
+	library := database.Library{Type: database.TVLibrary}
+
+	tests := []struct {
+		name  string
+		chain []string
+		want  database.ItemMetadata
+	}{
+		{
+			name:  "Later providers fill gaps",
+			chain: nil,
+			want: database.ItemMetadata{
+				Title:         "First Title",
+				Summary:       "First summary",
+				Tagline:       "Second tagline",
+				MatchProvider: "First",
+				MatchID:       "1",
+			},
+		},
+		{
+			name:  "Configured chain order",
+			chain: []string{"empty", "second", "first"},
+			want: database.ItemMetadata{
+				Title:         "First Title",
+				Summary:       "Second summary",
+				Tagline:       "Second tagline",
+				MatchProvider: "Second",
+				MatchID:       "1",
+			},
+		},
+		{
+			name:  "Unlisted providers are skipped",
+			chain: []string{"Second"},
+			want: database.ItemMetadata{
+				Title:         "Title from file",
+				Summary:       "Second summary",
+				Tagline:       "Second tagline",
+				MatchProvider: "Second",
+				MatchID:       "1",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		viper.Set("providers.chain.tv", tc.chain)
+
+		item := database.ItemMetadata{Title: "Title from file"}
+
+		err := registry.GetInformation(&item, library)
+		if err != nil {
+			t.Fatalf("%s: GetInformation() error = %v", tc.name, err)
+		}
+
+		if item.RefreshedAt.IsZero() {
+			t.Errorf("%s: GetInformation() should record the refresh time", tc.name)
+		}
+
+		item.RefreshedAt = time.Time{}
+
+		if !reflect.DeepEqual(item, tc.want) {
+			t.Errorf("%s: GetInformation() = %+v, want %+v", tc.name, item, tc.want)
+		}
+	}
+
+	viper.Set("providers.chain.tv", []string{"Empty"})
+
+	item := database.ItemMetadata{Title: "Title from file"}
+	if err := registry.GetInformation(&item, library); err == nil {
+		t.Errorf("GetInformation() without any result should fail")
+	}
+}
+
+func TestGetInformationFallbackLanguage(t *testing.T) {
+	registry.Register(fakeProvider{
+		name:     "Translated",
+		metadata: database.ItemMetadata{Title: "Le Titre"},
+		english:  database.ItemMetadata{Title: "The Title", Summary: "The summary"},
+	})
+
+	viper.Set("providers.chain.animeTV", []string{"Translated"})
+	viper.Set("providers.fallback_language", "en-US")
+
+	item := database.ItemMetadata{Title: "Title from file"}
+
+	err := registry.GetInformation(&item, database.Library{Type: database.AnimeTVLibrary, Language: "fr-FR"})
+	if err != nil {
+		t.Fatalf("GetInformation() error = %v", err)
+	}
+
+	item.RefreshedAt = time.Time{}
+
+	want := database.ItemMetadata{
+		Title:         "Le Titre",
+		Summary:       "The summary",
+		MatchProvider: "Translated",
+		MatchID:       "1",
+	}
+	if !reflect.DeepEqual(item, want) {
+		t.Errorf("GetInformation() = %+v, want %+v", item, want)
+	}
+}
+
+// Finds every item, but fails to get their metadata.
+type failingProvider struct {
+	fakeProvider
+}
+
+func (p failingProvider) Search(query registry.SearchQuery, library database.Library) ([]registry.SearchResult, error) {
+	return []registry.SearchResult{{ID: "1", Title: query.Title}}, nil
+}
+
+func (p failingProvider) GetMetadata(id string, library database.Library) (*database.ItemMetadata, error) {
+	return nil, errors.New("unavailable")
+}
+
+// Returns an item as saved after its keywords, caption and faces were synced from its file.
+func newSyncedItem() database.ItemMetadata {
+	return database.ItemMetadata{
+		Title:       "Beach",
+		Summary:     "Old caption",
+		Thumb:       "thumb",
+		Duration:    90,
+		Tags:        []database.ItemTag{{TagID: 1}},
+		FaceRegions: []database.FaceRegion{{X: 0.1, Y: 0.1, Width: 0.2, Height: 0.2}},
+	}
+}
+
+func TestGetInformationKeepsClearedFields(t *testing.T) {
+	registry.Register(fakeProvider{
+		name: "Cleared",
+		metadata: database.ItemMetadata{
+			Title:       "Beach",
+			Tags:        []database.ItemTag{},
+			FaceRegions: []database.FaceRegion{},
+		},
+	})
+	registry.Register(failingProvider{fakeProvider{name: "Failing"}})
+
+	library := database.Library{Type: database.TVLibrary}
+
+	viper.Set("providers.chain.tv", []string{"Cleared"})
+
+	item := newSyncedItem()
+	if err := registry.GetInformation(&item, library); err != nil {
+		t.Fatalf("GetInformation() error = %v", err)
+	}
+
+	if item.Summary != "" || item.Tags == nil || len(item.Tags) != 0 || item.FaceRegions == nil || len(item.FaceRegions) != 0 {
+		t.Errorf("GetInformation() should keep the keywords, caption and faces cleared, got %+v", item)
+	}
+
+	if item.Thumb != "thumb" || item.Duration != 90 {
+		t.Errorf("GetInformation() should keep what providers can't clear, got %+v", item)
+	}
+
+	// Nothing is known to be cleared when a provider fails
+	viper.Set("providers.chain.tv", []string{"Cleared", "Failing"})
+
+	item = newSyncedItem()
+	if err := registry.GetInformation(&item, library); err != nil {
+		t.Fatalf("GetInformation() error = %v", err)
+	}
+
+	if item.Summary != "Old caption" || len(item.Tags) != 1 || len(item.FaceRegions) != 1 {
+		t.Errorf("GetInformation() should keep the saved fields when a provider fails, got %+v", item)
+	}
+}
+
+func TestMergeMetadataRatings(t *testing.T) {
+	target := database.ItemMetadata{Ratings: []database.CommunityRating{
+		{Source: database.TmdbRatingSource, Value: 8.2, MaxValue: 10, Votes: 24000},
+	}}
+
+	registry.MergeMetadata(&target, &database.ItemMetadata{Ratings: []database.CommunityRating{
+		{Source: database.TmdbRatingSource, Value: 7, MaxValue: 10},
+		{Source: database.ImdbRatingSource, Value: 8.7, MaxValue: 10, Votes: 2000000},
+	}})
+
+	want := []database.CommunityRating{
+		{Source: database.TmdbRatingSource, Value: 8.2, MaxValue: 10, Votes: 24000},
+		{Source: database.ImdbRatingSource, Value: 8.7, MaxValue: 10, Votes: 2000000},
+	}
+
+	if !reflect.DeepEqual(target.Ratings, want) {
+		t.Errorf("MergeMetadata() ratings = %+v, want %+v, keeping the first rating of each source", target.Ratings, want)
+	}
+}
+
+func TestUnmatch(t *testing.T) {
+	registry.Register(fakeProvider{
+		name:     "Rematching",
+		metadata: database.ItemMetadata{Title: "Provider Title", Summary: "Provider summary"},
+	})
+
+	viper.Set("providers.chain.tv", []string{"Rematching"})
+
+	item := database.ItemMetadata{
+		Title:         "Provider Title",
+		Summary:       "Provider summary",
+		Thumb:         "thumb",
+		Duration:      90,
+		ExtraInfo:     []byte(`{"width":1920}`),
+		MatchProvider: "Rematching",
+		MatchID:       "1",
+	}
+
+	registry.Unmatch(&item, "Title from file")
+
+	if !item.Unmatched || item.MatchProvider != "" || item.MatchID != "" {
+		t.Errorf("Unmatch() should mark the item as unmatched, got %+v", item)
+	}
+
+	if item.Title != "Title from file" || item.Summary != "" || item.Thumb != "" {
+		t.Errorf("Unmatch() should clear provider fields, got %+v", item)
+	}
+
+	if item.Duration != 90 || string(item.ExtraInfo) != `{"width":1920}` {
+		t.Errorf("Unmatch() should keep file fields, got %+v", item)
+	}
+
+	err := registry.GetInformation(&item, database.Library{Type: database.TVLibrary})
+	if err != nil {
+		t.Fatalf("GetInformation() error = %v", err)
+	}
+
+	if item.Title != "Title from file" || item.MatchProvider != "" || !item.RefreshedAt.IsZero() {
+		t.Errorf("GetInformation() should not match an unmatched item again, got %+v", item)
+	}
+
+	err = registry.FixMatch(&item, database.Library{Type: database.TVLibrary}, "Rematching", "1")
+	if err != nil {
+		t.Fatalf("FixMatch() error = %v", err)
+	}
+
+	if item.Unmatched || item.Title != "Provider Title" || item.Duration != 90 {
+		t.Errorf("FixMatch() should match the item again, got %+v", item)
+	}
+}
@@ -0,0 +1,3 @@
+package subtitles
+
+var HasLanguage = hasLanguage
@@ -0,0 +1,373 @@
+// Package subtitles downloads subtitles for videos from pluggable subtitle providers, in the languages
+// chosen for their library, and stores them as external subtitle streams.
+package subtitles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/helpers"
+	"github.com/meteorae/meteorae-server/utils"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+	"golang.org/x/text/language"
+)
+
+const (
+	subtitleFileMode      = 0o644
+	subtitleDirectoryMode = 0o755
+	// Stores subtitles next to the media file, named after it, like "Movie.en.srt".
+	MediaStorage = "media"
+	// Stores subtitles in "subtitles.data_dir", for media on read-only storage.
+	DataStorage = "data"
+	// How often the data directory is checked for the subtitles of removed media.
+	dataPruneInterval = 24 * time.Hour
+)
+
+var errUnknownProvider = errors.New("unknown subtitle provider")
+
+// Describes the video subtitles are searched for. Providers use what they support, the hash first.
+type SearchQuery struct {
+	// The OpenSubtitles hash of the file, and its size, which find subtitles synchronized with it.
+	Hash string
+	Size int64
+	// The IMDb identifier of the movie, like "tt0133093".
+	ImdbID string
+	Title  string
+	Year   int
+	// The language of the subtitles, as a BCP 47 tag like "en" or "pt-BR".
+	Language string
+}
+
+// Describes a single subtitle found by a provider.
+type Result struct {
+	// Identifier of the subtitle, only meaningful to the provider that returned it.
+	ID       string
+	Language string
+	// The release the subtitle was made for, like "The.Matrix.1999.1080p.BluRay.x264".
+	Release string
+	// Whether the subtitle was found from the hash of the file, so it's synchronized with it.
+	HashMatch bool
+	Downloads int
+}
+
+// A downloaded subtitle file.
+type Subtitle struct {
+	Data []byte
+	// The format of the subtitle, as a file extension like "srt".
+	Format string
+}
+
+// Defines the structure of a subtitle provider.
+type Provider interface {
+	// Returns the name of the provider.
+	GetName() string
+	// Searches for subtitles matching the query.
+	Search(query SearchQuery) ([]Result, error)
+	// Downloads the subtitle with the given identifier.
+	Download(id string) (*Subtitle, error)
+}
+
+var Registry []Provider
+
+// Registers a new subtitle provider.
+func Register(provider Provider) {
+	Registry = append(Registry, provider)
+}
+
+// Describes a subtitle found by Search.
+type Candidate struct {
+	Provider string
+	Result
+}
+
+// Searches every provider for subtitles matching the query. Subtitles synchronized with the file come
+// first, followed by the most downloaded ones.
+func Search(query SearchQuery) []Candidate {
+	var candidates []Candidate
+
+	for _, provider := range Registry {
+		results, err := provider.Search(query)
+		if err != nil {
+			log.Err(err).Msgf("Subtitle provider %s failed to search for \"%s\"", provider.GetName(), query.Title)
+
+			continue
+		}
+
+		for _, result := range results {
+			candidates = append(candidates, Candidate{Provider: provider.GetName(), Result: result})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].HashMatch != candidates[j].HashMatch {
+			return candidates[i].HashMatch
+		}
+
+		return candidates[i].Downloads > candidates[j].Downloads
+	})
+
+	return candidates
+}
+
+// Downloads subtitles for a video in each of its library's languages it has no subtitles in yet,
+// either in the media file or downloaded before. Items that aren't videos are skipped.
+func DownloadMissing(item *database.ItemMetadata, library database.Library) error {
+	languages := library.GetSubtitleLanguages()
+	if len(languages) == 0 || item.MediaPart.ID == 0 ||
+		!utils.IsStringInSlice(filepath.Ext(item.MediaPart.FilePath), helpers.VideoFileExtensions) {
+		return nil
+	}
+
+	streams, err := database.GetSubtitleStreams(item.MediaPart.ID)
+	if err != nil {
+		return fmt.Errorf("could not get subtitles of %s: %w", item.MediaPart.FilePath, err)
+	}
+
+	for _, subtitleLanguage := range languages {
+		if hasLanguage(streams, subtitleLanguage) {
+			continue
+		}
+
+		candidates := Search(newQuery(item, subtitleLanguage))
+		if len(candidates) == 0 {
+			log.Debug().Msgf("No %s subtitles found for %s", subtitleLanguage, item.MediaPart.FilePath)
+
+			continue
+		}
+
+		if err := Download(item.MediaPart, candidates[0], subtitleLanguage); err != nil {
+			log.Err(err).Msgf("Failed to download %s subtitles for %s", subtitleLanguage, item.MediaPart.FilePath)
+		}
+	}
+
+	return nil
+}
+
+func newQuery(item *database.ItemMetadata, subtitleLanguage string) SearchQuery {
+	query := SearchQuery{
+		Hash:     item.MediaPart.OpenSubtitleHash,
+		Size:     item.MediaPart.Size,
+		Title:    item.Title,
+		Language: subtitleLanguage,
+	}
+
+	if !item.ReleaseDate.IsZero() {
+		query.Year = item.ReleaseDate.Year()
+	}
+
+	for _, identifier := range item.ExternalIdentifiers {
+		if identifier.IdentifierType == database.ImdbIdentifier {
+			query.ImdbID = identifier.Identifier
+		}
+	}
+
+	return query
+}
+
+// Returns whether one of the streams is in the given language. The region and script are only compared when
+// they are part of the language or the stream language, so "por" streams match "pt" but not "pt-BR", since
+// streams of media files are usually tagged with ISO 639-2 codes like "eng".
+func hasLanguage(streams []database.MediaStream, subtitleLanguage string) bool {
+	wanted, err := language.Parse(subtitleLanguage)
+	if err != nil {
+		return false
+	}
+
+	wantedParts := getExplicitParts(wanted)
+
+	for _, stream := range streams {
+		tag, err := language.Parse(stream.Language)
+		if err != nil {
+			continue
+		}
+
+		if getExplicitParts(tag) == wantedParts {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The parts of a language tag, leaving out the ones which are only guessed, like the region of "pt".
+type languageParts struct {
+	base   language.Base
+	script language.Script
+	region language.Region
+}
+
+func getExplicitParts(tag language.Tag) languageParts {
+	var parts languageParts
+
+	// Undetermined languages are guessed to be English
+	if base, confidence := tag.Base(); confidence == language.Exact {
+		parts.base = base
+	}
+
+	if script, confidence := tag.Script(); confidence == language.Exact {
+		parts.script = script
+	}
+
+	if region, confidence := tag.Region(); confidence == language.Exact {
+		parts.region = region
+	}
+
+	return parts
+}
+
+// Downloads a subtitle found by Search, stores it according to "subtitles.storage", and adds it to the
+// subtitle streams of the media part.
+func Download(mediaPart database.MediaPart, candidate Candidate, subtitleLanguage string) error {
+	var provider Provider
+
+	for _, registered := range Registry {
+		if strings.EqualFold(registered.GetName(), candidate.Provider) {
+			provider = registered
+		}
+	}
+
+	if provider == nil {
+		return fmt.Errorf("%w: %s", errUnknownProvider, candidate.Provider)
+	}
+
+	subtitle, err := provider.Download(candidate.ID)
+	if err != nil {
+		return fmt.Errorf("failed to download subtitle %s from %s: %w", candidate.ID, candidate.Provider, err)
+	}
+
+	filePath, err := saveSubtitle(mediaPart, subtitle, subtitleLanguage)
+	if err != nil {
+		return err
+	}
+
+	err = database.CreateExternalSubtitleStream(mediaPart.ID, candidate.Provider, subtitleLanguage, filePath)
+	if err != nil {
+		return fmt.Errorf("failed to save subtitle %s: %w", filePath, err)
+	}
+
+	log.Info().Msgf("Downloaded %s subtitles for %s from %s", subtitleLanguage, mediaPart.FilePath, candidate.Provider)
+
+	return nil
+}
+
+// Removes the subtitles stored in "subtitles.data_dir" every day for media parts which no longer exist.
+// Stops when the context is canceled.
+func StartDataPruning(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(dataPruneInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := PruneData(); err != nil {
+				log.Err(err).Msg("Failed to prune the subtitles of removed media")
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Removes the subtitles stored in "subtitles.data_dir" for media parts which no longer exist.
+// Subtitles stored next to media files go away with them.
+func PruneData() error {
+	dataDirectory := viper.GetString("subtitles.data_dir")
+
+	entries, err := os.ReadDir(dataDirectory)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to list subtitle directories: %w", err)
+	}
+
+	ids := make([]uint64, 0, len(entries))
+
+	for _, entry := range entries {
+		// Directories are named after their media part, anything else isn't ours
+		if id, err := strconv.ParseUint(entry.Name(), 10, 64); err == nil && entry.IsDir() { //nolint:gomnd
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	existing, err := database.GetExistingMediaPartIDs(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if existing[id] {
+			continue
+		}
+
+		directory := filepath.Join(dataDirectory, strconv.FormatUint(id, 10)) //nolint:gomnd
+		if err := os.RemoveAll(directory); err != nil {
+			log.Err(err).Msgf("Failed to remove the subtitles of removed media part %d", id)
+
+			continue
+		}
+
+		log.Debug().Msgf("Removed the subtitles of removed media part %d", id)
+	}
+
+	return nil
+}
+
+// Writes a subtitle next to the media file, or in the data directory when configured so, when the media
+// storage is read-only, or when a file with the same name already exists. Returns the path it was written to.
+func saveSubtitle(mediaPart database.MediaPart, subtitle *Subtitle, subtitleLanguage string) (string, error) {
+	mediaName := strings.TrimSuffix(filepath.Base(mediaPart.FilePath), filepath.Ext(mediaPart.FilePath))
+	fileName := fmt.Sprintf("%s.%s.%s", mediaName, subtitleLanguage, subtitle.Format)
+
+	if viper.GetString("subtitles.storage") == MediaStorage {
+		filePath := filepath.Join(filepath.Dir(mediaPart.FilePath), fileName)
+
+		file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, subtitleFileMode)
+		if err == nil {
+			_, err = file.Write(subtitle.Data)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+
+			if err == nil {
+				return filePath, nil
+			}
+
+			os.Remove(filePath)
+		}
+
+		log.Debug().Err(err).Msgf("Could not write subtitle next to %s, using the data directory", mediaPart.FilePath)
+	}
+
+	directory := filepath.Join(viper.GetString("subtitles.data_dir"), strconv.FormatUint(mediaPart.ID, 10)) //nolint:gomnd
+
+	if err := os.MkdirAll(directory, subtitleDirectoryMode); err != nil {
+		return "", fmt.Errorf("failed to create subtitle directory %s: %w", directory, err)
+	}
+
+	filePath := filepath.Join(directory, fileName)
+
+	if err := os.WriteFile(filePath, subtitle.Data, subtitleFileMode); err != nil {
+		return "", fmt.Errorf("failed to write subtitle %s: %w", filePath, err)
+	}
+
+	return filePath, nil
+}
@@ -0,0 +1,222 @@
+package subtitles_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/internal/databasetest"
+	"github.com/meteorae/meteorae-server/providers/subtitles"
+	"github.com/spf13/viper"
+)
+
+var errSearchFailed = errors.New("search failed")
+
+type fakeProvider struct {
+	name    string
+	results []subtitles.Result
+	err     error
+}
+
+func (p fakeProvider) GetName() string {
+	return p.name
+}
+
+func (p fakeProvider) Search(query subtitles.SearchQuery) ([]subtitles.Result, error) {
+	return p.results, p.err
+}
+
+func (p fakeProvider) Download(id string) (*subtitles.Subtitle, error) {
+	return &subtitles.Subtitle{Data: []byte(id), Format: "srt"}, nil
+}
+
+func TestSearch(t *testing.T) {
+	registry := subtitles.Registry
+	defer func() { subtitles.Registry = registry }()
+
+	subtitles.Registry = []subtitles.Provider{
+		fakeProvider{name: "Broken", err: errSearchFailed},
+		fakeProvider{name: "Popular", results: []subtitles.Result{
+			{ID: "popular", Downloads: 1000},
+			{ID: "unpopular", Downloads: 1},
+		}},
+		fakeProvider{name: "Synchronized", results: []subtitles.Result{
+			{ID: "synchronized", HashMatch: true, Downloads: 10},
+		}},
+	}
+
+	candidates := subtitles.Search(subtitles.SearchQuery{Title: "The Matrix", Language: "en"})
+
+	want := []string{"Synchronized/synchronized", "Popular/popular", "Popular/unpopular"}
+
+	if len(candidates) != len(want) {
+		t.Fatalf("Search() returned %d candidates, want %d", len(candidates), len(want))
+	}
+
+	for index, candidate := range candidates {
+		if got := candidate.Provider + "/" + candidate.ID; got != want[index] {
+			t.Errorf("Search()[%d] = %s, want %s", index, got, want[index])
+		}
+	}
+}
+
+func TestHasLanguage(t *testing.T) {
+	tests := []struct {
+		streamLanguage string
+		language       string
+		want           bool
+	}{
+		{"eng", "en", true},
+		{"en-US", "en", false},
+		{"por", "pt", true},
+		{"por", "pt-BR", false},
+		{"pt-BR", "pt-BR", true},
+		{"pt-PT", "pt-BR", false},
+		{"zh-Hant", "zh-Hant", true},
+		{"zh-Hans", "zh-Hant", false},
+		{"fre", "en", false},
+		{"und", "en", false},
+	}
+
+	for _, test := range tests {
+		streams := []database.MediaStream{{Language: test.streamLanguage}}
+
+		if got := subtitles.HasLanguage(streams, test.language); got != test.want {
+			t.Errorf("HasLanguage(%s, %s) = %v, want %v", test.streamLanguage, test.language, got, test.want)
+		}
+	}
+}
+
+func TestPruneData(t *testing.T) {
+	databasetest.Setup(t)
+
+	dataDirectory := t.TempDir()
+	viper.Set("subtitles.data_dir", dataDirectory)
+
+	defer viper.Set("subtitles.data_dir", nil)
+
+	movie := database.ItemMetadata{
+		Title:     "The Matrix",
+		Type:      database.MovieItem,
+		MediaPart: database.MediaPart{FilePath: "/movies/The Matrix.mkv", Hash: "matrix"},
+	}
+	if err := database.CreateMovie(&movie); err != nil {
+		t.Fatal(err)
+	}
+
+	kept := []string{strconv.FormatUint(movie.MediaPart.ID, 10), "notes"}
+	removed := strconv.FormatUint(movie.MediaPart.ID+1, 10)
+
+	for _, name := range append(kept, removed) {
+		if err := os.MkdirAll(filepath.Join(dataDirectory, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := subtitles.PruneData(); err != nil {
+		t.Fatalf("PruneData() error = %v", err)
+	}
+
+	for _, name := range kept {
+		if _, err := os.Stat(filepath.Join(dataDirectory, name)); err != nil {
+			t.Errorf("PruneData() removed %s: %v", name, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dataDirectory, removed)); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("PruneData() kept the subtitles of removed media part %s", removed)
+	}
+}
+
+func TestDownloadMissing(t *testing.T) {
+	databasetest.Setup(t)
+
+	registry := subtitles.Registry
+	defer func() { subtitles.Registry = registry }()
+
+	subtitles.Registry = []subtitles.Provider{
+		fakeProvider{name: "Fake", results: []subtitles.Result{{ID: "downloaded"}}},
+	}
+
+	mediaDirectory := t.TempDir()
+	dataDirectory := t.TempDir()
+
+	viper.Set("subtitles.storage", subtitles.MediaStorage)
+	viper.Set("subtitles.data_dir", dataDirectory)
+
+	defer viper.Set("subtitles.storage", nil)
+	defer viper.Set("subtitles.data_dir", nil)
+
+	library, _, err := database.CreateLibrary(
+		"Movies", "en", "movie", []string{mediaDirectory}, false, []string{"en", "fr-FR", "de"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	moviePath := filepath.Join(mediaDirectory, "The Matrix.mkv")
+
+	movie := database.ItemMetadata{
+		Title:     "The Matrix",
+		Type:      database.MovieItem,
+		LibraryID: library.ID,
+		MediaPart: database.MediaPart{FilePath: moviePath, Hash: "matrix"},
+	}
+	if err := database.CreateMovie(&movie); err != nil {
+		t.Fatal(err)
+	}
+
+	// German subtitles are in the media file already
+	if err := database.CreateMediaStream("", database.SubtitleStream, "ger", 2, nil, movie.MediaPart.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// A file users wrote themselves isn't overwritten
+	userSubtitle := filepath.Join(mediaDirectory, "The Matrix.fr-FR.srt")
+	if err := os.WriteFile(userSubtitle, []byte("user"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := subtitles.DownloadMissing(&movie, *library); err != nil {
+			t.Fatalf("DownloadMissing() error = %v", err)
+		}
+	}
+
+	streams, err := database.GetSubtitleStreams(movie.MediaPart.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"en":    filepath.Join(mediaDirectory, "The Matrix.en.srt"),
+		"fr-FR": filepath.Join(dataDirectory, strconv.FormatUint(movie.MediaPart.ID, 10), "The Matrix.fr-FR.srt"),
+	}
+
+	// Downloading again doesn't add the same languages twice
+	if len(streams) != len(want)+1 {
+		t.Fatalf("DownloadMissing() left %d subtitle streams, want %d: %+v", len(streams), len(want)+1, streams)
+	}
+
+	for _, stream := range streams {
+		filePath, ok := want[stream.Language]
+		if !ok {
+			continue
+		}
+
+		if stream.FilePath != filePath {
+			t.Errorf("%s subtitles saved to %s, want %s", stream.Language, stream.FilePath, filePath)
+		}
+
+		if data, err := os.ReadFile(stream.FilePath); err != nil || string(data) != "downloaded" {
+			t.Errorf("%s subtitles = %q, %v", stream.Language, data, err)
+		}
+	}
+
+	if data, err := os.ReadFile(userSubtitle); err != nil || string(data) != "user" {
+		t.Errorf("DownloadMissing() changed %s: %q, %v", userSubtitle, data, err)
+	}
+}
@@ -2,7 +2,12 @@ package all
 
 import (
 	// Import all resolvers to trigger their init() functions and register them.
+	_ "github.com/meteorae/meteorae-server/resolvers/anime"
+	_ "github.com/meteorae/meteorae-server/resolvers/audiobook"
 	_ "github.com/meteorae/meteorae-server/resolvers/image"
 	_ "github.com/meteorae/meteorae-server/resolvers/imageAlbum"
 	_ "github.com/meteorae/meteorae-server/resolvers/movie"
+	_ "github.com/meteorae/meteorae-server/resolvers/music"
+	_ "github.com/meteorae/meteorae-server/resolvers/musicVideo"
+	_ "github.com/meteorae/meteorae-server/resolvers/podcast"
 )
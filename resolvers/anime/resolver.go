@@ -0,0 +1,158 @@
+package anime
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/filesystem/analyzer"
+	"github.com/meteorae/meteorae-server/helpers"
+	providers "github.com/meteorae/meteorae-server/providers/registry"
+	"github.com/meteorae/meteorae-server/providers/subtitles"
+	"github.com/meteorae/meteorae-server/resolvers/registry"
+	"github.com/meteorae/meteorae-server/utils"
+	"github.com/panjf2000/ants/v2"
+	"github.com/rs/zerolog/log"
+)
+
+func init() {
+	registry.Register(animeResolver)
+}
+
+var animeResolver registry.Resolver = Resolver{}
+
+// Resolves anime movies, and anime episodes, which are grouped in shows by the title in their name.
+// Files are hashed so they can be identified on AniDB, and checked for corruption.
+type Resolver struct{}
+
+func (r Resolver) GetName() string {
+	return "Anime"
+}
+
+func (r Resolver) SupportsLibraryType(library database.Library) bool {
+	return library.Type == database.AnimeMovieLibrary || library.Type == database.AnimeTVLibrary
+}
+
+func (r Resolver) SupportsFileType(filePath string, isDir bool) bool {
+	if isDir {
+		return false
+	}
+
+	return utils.IsStringInSlice(filepath.Ext(filePath), helpers.VideoFileExtensions)
+}
+
+func (r Resolver) Resolve(mediaPart *database.MediaPart, library database.Library) error {
+	fileName := filepath.Base(mediaPart.FilePath)
+	parsed := utils.ParseAnimeFileName(fileName[:len(fileName)-len(filepath.Ext(fileName))])
+
+	// The title is the show's for episodes, which is what providers search for
+	item := database.ItemMetadata{
+		Title:     parsed.Title,
+		SortTitle: utils.CleanSortTitle(parsed.Title),
+		Type:      database.AnimeMovieItem,
+		LibraryID: library.ID,
+		Library:   library,
+		MediaPart: *mediaPart,
+	}
+
+	var show *database.ItemMetadata
+
+	if library.Type == database.AnimeTVLibrary {
+		var err error
+
+		show, err = database.GetOrCreateAnimeShow(library, parsed.Title, utils.CleanSortTitle(parsed.Title))
+		if err != nil {
+			return fmt.Errorf("could not resolve anime show for %s: %w", mediaPart.FilePath, err)
+		}
+
+		item.Type = database.AnimeEpisodeItem
+		item.ParentID = show.ID
+		item.Index = int64(parsed.Episode)
+	}
+
+	err := database.CreateAnime(&item)
+	if err != nil {
+		return fmt.Errorf("could not resolve anime metadata %s: %w", mediaPart.FilePath, err)
+	}
+
+	err = ants.Submit(func() {
+		// Copies are looked for once the episode is matched, even when no information was found
+		defer analyzer.QueueVideoFingerprint(item.MediaPart)
+
+		err := analyzer.AnalyzeVideo(item.MediaPart)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to analyze anime %s", mediaPart.FilePath)
+		}
+
+		err = analyzer.AnalyzeAnime(&item.MediaPart)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to hash anime %s", mediaPart.FilePath)
+		}
+
+		err = providers.GetInformation(&item, library)
+		if err != nil {
+			log.Err(err).Msgf("Failed to get anime information for %s", mediaPart.FilePath)
+
+			return
+		}
+
+		// Episodes identified by hash may be numbered differently than in their name
+		if _, episodeNumber, found := strings.Cut(item.MatchID, "/"); found {
+			if index, err := strconv.ParseInt(episodeNumber, 10, 64); err == nil { //nolint:gomnd
+				item.Index = index
+			}
+		}
+
+		err = database.UpdateAnime(&item)
+		if err != nil {
+			log.Err(err).Msgf("Failed to update anime \"%s\"", item.Title)
+		}
+
+		err = subtitles.DownloadMissing(&item, library)
+		if err != nil {
+			log.Err(err).Msgf("Failed to download subtitles for anime \"%s\"", item.Title)
+		}
+
+		if show != nil {
+			matchShow(show.ID, &item, library)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("could not schedule anime information job %s: %w", mediaPart.FilePath, err)
+	}
+
+	return nil
+}
+
+// Matches a show to the anime its episode was matched to, unless it's already matched.
+func matchShow(showID uint64, episode *database.ItemMetadata, library database.Library) {
+	animeID, _, found := strings.Cut(episode.MatchID, "/")
+	if !found {
+		return
+	}
+
+	show, err := database.GetItemByID(strconv.FormatUint(showID, 10)) //nolint:gomnd
+	if err != nil {
+		log.Err(err).Msgf("Failed to get the show of anime episode \"%s\"", episode.Title)
+
+		return
+	}
+
+	if show.MatchID != "" {
+		return
+	}
+
+	err = providers.FixMatch(show, library, episode.MatchProvider, animeID)
+	if err != nil {
+		log.Err(err).Msgf("Failed to get information for anime show \"%s\"", show.Title)
+
+		return
+	}
+
+	err = database.UpdateAnime(show)
+	if err != nil {
+		log.Err(err).Msgf("Failed to update anime show \"%s\"", show.Title)
+	}
+}
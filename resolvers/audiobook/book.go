@@ -0,0 +1,74 @@
+package audiobook
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/providers/audiobook"
+	"github.com/meteorae/meteorae-server/resolvers/registry"
+	"github.com/panjf2000/ants/v2"
+	"github.com/rs/zerolog/log"
+)
+
+func init() {
+	registry.Register(bookResolver)
+}
+
+var bookResolver registry.Resolver = BookResolver{}
+
+// Resolves audiobooks, which are either a single M4B file or a directory of numbered audio files.
+type BookResolver struct{}
+
+func (r BookResolver) GetName() string {
+	return "Audiobook"
+}
+
+func (r BookResolver) SupportsLibraryType(library database.Library) bool {
+	return library.Type == database.AudiobookLibrary
+}
+
+func (r BookResolver) SupportsFileType(filePath string, isDir bool) bool {
+	if !isDir {
+		return audiobook.IsBookFile(filePath)
+	}
+
+	files, err := audiobook.GetPartFiles(filePath)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to read directory %s", filePath)
+
+		return false
+	}
+
+	return len(files) > 0
+}
+
+func (r BookResolver) Resolve(mediaPart *database.MediaPart, library database.Library) error {
+	fileName := filepath.Base(mediaPart.FilePath)
+	fileName = fileName[:len(fileName)-len(filepath.Ext(fileName))]
+
+	item := database.ItemMetadata{
+		Title:     fileName,
+		Type:      database.AudiobookItem,
+		LibraryID: library.ID,
+		Library:   library,
+		MediaPart: *mediaPart,
+	}
+
+	err := database.CreateAudiobook(&item)
+	if err != nil {
+		return fmt.Errorf("could not resolve audiobook metadata %s: %w", mediaPart.FilePath, err)
+	}
+
+	err = ants.Submit(func() {
+		err := audiobook.GetInformation(&item, library)
+		if err != nil {
+			log.Err(err).Msgf("Failed to get audiobook information for %s", mediaPart.FilePath)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("could not schedule audiobook information job %s: %w", mediaPart.FilePath, err)
+	}
+
+	return nil
+}
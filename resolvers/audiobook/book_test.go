@@ -0,0 +1,62 @@
+package audiobook_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/resolvers/audiobook"
+)
+
+func TestSupportsFileType(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	bookDirectory := filepath.Join(root, "Dune")
+	emptyDirectory := filepath.Join(root, "Covers")
+
+	for _, directory := range []string{bookDirectory, emptyDirectory} {
+		if err := os.Mkdir(directory, 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(bookDirectory, "01.mp3"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(emptyDirectory, "cover.jpg"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		filePath string
+		isDir    bool
+		isBook   bool
+		isPart   bool
+	}{
+		{filepath.Join(root, "Dune.m4b"), false, true, false},
+		{bookDirectory, true, true, false},
+		{emptyDirectory, true, false, false},
+		{filepath.Join(bookDirectory, "01.mp3"), false, false, true},
+	}
+
+	library := database.Library{Type: database.AudiobookLibrary}
+
+	for _, tc := range tests {
+		bookResolver, partResolver := audiobook.BookResolver{}, audiobook.PartResolver{}
+
+		if !bookResolver.SupportsLibraryType(library) || !partResolver.SupportsLibraryType(library) {
+			t.Fatal("audiobook resolvers should support audiobook libraries")
+		}
+
+		if got := bookResolver.SupportsFileType(tc.filePath, tc.isDir); got != tc.isBook {
+			t.Errorf("BookResolver.SupportsFileType(%s) = %v, want %v", tc.filePath, got, tc.isBook)
+		}
+
+		if got := partResolver.SupportsFileType(tc.filePath, tc.isDir); got != tc.isPart {
+			t.Errorf("PartResolver.SupportsFileType(%s) = %v, want %v", tc.filePath, got, tc.isPart)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package audiobook
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/providers/audiobook"
+	"github.com/meteorae/meteorae-server/resolvers/registry"
+)
+
+func init() {
+	registry.Register(partResolver)
+}
+
+var partResolver registry.Resolver = PartResolver{}
+
+// Resolves the individual files of a directory audiobook, so they can be played back in order.
+type PartResolver struct{}
+
+func (r PartResolver) GetName() string {
+	return "Audiobook Part"
+}
+
+func (r PartResolver) SupportsLibraryType(library database.Library) bool {
+	return library.Type == database.AudiobookLibrary
+}
+
+func (r PartResolver) SupportsFileType(filePath string, isDir bool) bool {
+	if isDir {
+		return false
+	}
+
+	return audiobook.IsPartFile(filePath)
+}
+
+func (r PartResolver) Resolve(mediaPart *database.MediaPart, library database.Library) error {
+	fileName := filepath.Base(mediaPart.FilePath)
+	fileName = fileName[:len(fileName)-len(filepath.Ext(fileName))]
+
+	book, err := database.GetItemByMediaPartPath(filepath.Dir(mediaPart.FilePath))
+	if err != nil {
+		return fmt.Errorf("failed to get audiobook for path %s: %w", mediaPart.FilePath, err)
+	}
+
+	item := database.ItemMetadata{
+		Title:     fileName,
+		Type:      database.AudiobookPartItem,
+		LibraryID: library.ID,
+		Library:   library,
+		ParentID:  book.ID,
+		Index:     audiobook.GetPartIndex(mediaPart.FilePath),
+		MediaPart: *mediaPart,
+	}
+
+	err = database.CreateAudiobook(&item)
+	if err != nil {
+		return fmt.Errorf("could not resolve audiobook part metadata %s: %w", mediaPart.FilePath, err)
+	}
+
+	return nil
+}
@@ -2,10 +2,14 @@ package image
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/meteorae/meteorae-server/database"
-	"github.com/meteorae/meteorae-server/providers/image"
+	"github.com/meteorae/meteorae-server/duplicates"
+	"github.com/meteorae/meteorae-server/helpers"
+	providers "github.com/meteorae/meteorae-server/providers/registry"
 	"github.com/meteorae/meteorae-server/resolvers/registry"
 	"github.com/meteorae/meteorae-server/utils"
 	"github.com/panjf2000/ants/v2"
@@ -59,10 +63,53 @@ func (r Resolver) Resolve(mediaPart *database.MediaPart, library database.Librar
 		return fmt.Errorf("could not resolve image metadata %s: %w", mediaPart.FilePath, err)
 	}
 
-	err = ants.Submit(func() {
-		err := image.GetInformation(&item, library)
+	return scheduleInformationJob(&item, library)
+}
+
+// Refreshes the metadata of an image when the image or its XMP sidecars changed since the last refresh,
+// so edits made in other photo managers are picked up.
+func (r Resolver) Rescan(item *database.ItemMetadata, library database.Library) error {
+	if !isModifiedSince(item.MediaPart.FilePath, item.RefreshedAt) {
+		return nil
+	}
+
+	item.Library = library
+
+	return scheduleInformationJob(item, library)
+}
+
+func isModifiedSince(filePath string, since time.Time) bool {
+	for _, path := range append([]string{filePath}, helpers.GetSidecarPaths(filePath)...) {
+		if fileInfo, err := os.Stat(path); err == nil && fileInfo.ModTime().After(since) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func scheduleInformationJob(item *database.ItemMetadata, library database.Library) error {
+	mediaPart := item.MediaPart
+
+	err := ants.Submit(func() {
+		err := providers.GetInformation(item, library)
 		if err != nil {
 			log.Error().Err(err).Msgf("failed to get image information for %s", mediaPart.FilePath)
+
+			return
+		}
+
+		err = database.UpdateImage(item)
+		if err != nil {
+			log.Error().Err(err).Msgf("failed to update image %s", mediaPart.FilePath)
+
+			return
+		}
+
+		updateAlbumThumb(item)
+
+		if err := duplicates.FindImageDuplicates(item); err != nil {
+			log.Error().Err(err).Msgf("failed to find duplicates of image %s", mediaPart.FilePath)
 		}
 	})
 	if err != nil {
@@ -71,3 +118,22 @@ func (r Resolver) Resolve(mediaPart *database.MediaPart, library database.Librar
 
 	return nil
 }
+
+// Sets the thumbnail of the image's album to the image's, if the album doesn't have one yet.
+func updateAlbumThumb(item *database.ItemMetadata) {
+	parent, err := database.GetImageAlbum(item.ParentID)
+	if err != nil {
+		log.Err(err).Msgf("Failed to get image album for path %s: %s", item.MediaPart.FilePath, err)
+
+		return
+	}
+
+	if parent.Thumb == "" {
+		parent.Thumb = item.Thumb
+
+		err = database.UpdateImageAlbum(parent)
+		if err != nil {
+			log.Err(err).Msgf("Failed to update image album for path %s: %s", item.MediaPart.FilePath, err)
+		}
+	}
+}
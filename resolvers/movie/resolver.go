@@ -6,8 +6,10 @@ import (
 	"path/filepath"
 
 	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/filesystem/analyzer"
 	"github.com/meteorae/meteorae-server/helpers"
-	"github.com/meteorae/meteorae-server/providers/movie"
+	providers "github.com/meteorae/meteorae-server/providers/registry"
+	"github.com/meteorae/meteorae-server/providers/subtitles"
 	"github.com/meteorae/meteorae-server/resolvers/registry"
 	"github.com/meteorae/meteorae-server/utils"
 	PTN "github.com/middelink/go-parse-torrent-name"
@@ -68,9 +70,29 @@ func (r Resolver) Resolve(mediaPart *database.MediaPart, library database.Librar
 	}
 
 	err = ants.Submit(func() {
-		err := movie.GetInformation(&item, library)
+		// Sampling frames is slow, so copies are looked for once the metadata is saved, or failed to be
+		defer analyzer.QueueVideoFingerprint(item.MediaPart)
+
+		err := analyzer.AnalyzeVideo(item.MediaPart)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to analyze movie %s", mediaPart.FilePath)
+		}
+
+		err = providers.GetInformation(&item, library)
 		if err != nil {
 			log.Err(err).Msgf("Failed to get movie information for %s: %s", mediaPart.FilePath, err)
+
+			return
+		}
+
+		err = database.UpdateMovie(&item)
+		if err != nil {
+			log.Err(err).Msgf("Failed to update movie \"%s\"", item.Title)
+		}
+
+		err = subtitles.DownloadMissing(&item, library)
+		if err != nil {
+			log.Err(err).Msgf("Failed to download subtitles for movie \"%s\"", item.Title)
 		}
 	})
 	if err != nil {
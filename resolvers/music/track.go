@@ -0,0 +1,243 @@
+package music
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dhowden/tag"
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/filesystem/analyzer"
+	"github.com/meteorae/meteorae-server/providers/acoustid"
+	providers "github.com/meteorae/meteorae-server/providers/registry"
+	"github.com/meteorae/meteorae-server/resolvers/audio"
+	"github.com/meteorae/meteorae-server/resolvers/registry"
+	"github.com/meteorae/meteorae-server/utils"
+	"github.com/panjf2000/ants/v2"
+	"github.com/rs/zerolog/log"
+)
+
+func init() {
+	registry.Register(trackResolver)
+}
+
+var trackResolver registry.Resolver = TrackResolver{}
+
+// Resolves music tracks, which are audio files in music libraries. Tracks are identified from their
+// audio fingerprint, so that badly tagged files can be matched too.
+type TrackResolver struct{}
+
+func (r TrackResolver) GetName() string {
+	return "Music Track"
+}
+
+func (r TrackResolver) SupportsLibraryType(library database.Library) bool {
+	return library.Type == database.MusicLibrary
+}
+
+func (r TrackResolver) SupportsFileType(filePath string, isDir bool) bool {
+	if isDir {
+		return false
+	}
+
+	return audio.IsValidAudioFile(filePath)
+}
+
+func (r TrackResolver) Resolve(mediaPart *database.MediaPart, library database.Library) error {
+	fileName := filepath.Base(mediaPart.FilePath)
+	fileName = fileName[:len(fileName)-len(filepath.Ext(fileName))]
+
+	artists, title := utils.ParseArtistAndTitle(fileName)
+
+	item := database.ItemMetadata{
+		Type:      database.MusicTrackItem,
+		LibraryID: library.ID,
+		Library:   library,
+		MediaPart: *mediaPart,
+	}
+
+	// Embedded tags are more reliable than file names, so prefer them when present
+	if trackTags := readTags(mediaPart.FilePath); trackTags != nil {
+		if trackTags.Title() != "" {
+			title = trackTags.Title()
+		}
+
+		if trackTags.Artist() != "" {
+			artists = utils.ParseArtists(trackTags.Artist())
+		}
+
+		trackNumber, _ := trackTags.Track()
+		item.Index = int64(trackNumber)
+
+		album, err := resolveAlbum(trackTags, artists, library)
+		if err != nil {
+			return fmt.Errorf("could not resolve album of %s: %w", mediaPart.FilePath, err)
+		}
+
+		if album != nil {
+			item.ParentID = album.ID
+		}
+	}
+
+	item.Title = title
+	item.SortTitle = utils.CleanSortTitle(title)
+
+	err := database.CreateMusicTrack(&item)
+	if err != nil {
+		return fmt.Errorf("could not resolve music track metadata %s: %w", mediaPart.FilePath, err)
+	}
+
+	for index, name := range artists {
+		artist, err := database.GetOrCreateArtist(name, utils.CleanSortTitle(name))
+		if err != nil {
+			return fmt.Errorf("could not resolve artist \"%s\" for %s: %w", name, mediaPart.FilePath, err)
+		}
+
+		err = database.AddItemArtist(item.ID, artist.ID, index)
+		if err != nil {
+			return fmt.Errorf("could not link artist \"%s\" to %s: %w", name, mediaPart.FilePath, err)
+		}
+	}
+
+	err = ants.Submit(func() {
+		identifyTrack(&item)
+
+		err := providers.GetInformation(&item, library)
+		if err != nil {
+			log.Err(err).Msgf("Failed to get music track information for %s", mediaPart.FilePath)
+
+			return
+		}
+
+		err = database.UpdateMusicTrack(&item)
+		if err != nil {
+			log.Err(err).Msgf("Failed to update music track \"%s\"", item.Title)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("could not schedule music track analysis job %s: %w", mediaPart.FilePath, err)
+	}
+
+	return nil
+}
+
+// Returns the album of a track from its tags, creating it and linking it to its artists as needed.
+// The album artists default to the track artists. Returns nil for tracks without album.
+func resolveAlbum(trackTags tag.Metadata, trackArtists []string, library database.Library) (*database.ItemMetadata, error) {
+	title := strings.TrimSpace(trackTags.Album())
+	if title == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	artists := trackArtists
+	if trackTags.AlbumArtist() != "" {
+		artists = utils.ParseArtists(trackTags.AlbumArtist())
+	}
+
+	artistIDs := make([]uint64, 0, len(artists))
+
+	for _, name := range artists {
+		artist, err := database.GetOrCreateArtist(name, utils.CleanSortTitle(name))
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve album artist \"%s\": %w", name, err)
+		}
+
+		artistIDs = append(artistIDs, artist.ID)
+	}
+
+	var mainArtistID uint64
+	if len(artistIDs) > 0 {
+		mainArtistID = artistIDs[0]
+	}
+
+	album, err := database.GetOrCreateMusicAlbum(library, title, utils.CleanSortTitle(title), mainArtistID)
+	if err != nil {
+		return nil, err
+	}
+
+	for index, artistID := range artistIDs {
+		if err := database.AddItemArtist(album.ID, artistID, index); err != nil {
+			return nil, err
+		}
+	}
+
+	if album.ReleaseDate.IsZero() && trackTags.Year() != 0 {
+		album.ReleaseDate = time.Date(trackTags.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+
+		if err := database.UpdateItem(album); err != nil {
+			return nil, err
+		}
+	}
+
+	return album, nil
+}
+
+// Fingerprints a track, and links it to the MusicBrainz recording matching the fingerprint, if any.
+// The recording is added to the item's identifiers, so that metadata providers can use it.
+func identifyTrack(item *database.ItemMetadata) {
+	err := analyzer.AnalyzeAudio(&item.MediaPart)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to analyze music track %s", item.MediaPart.FilePath)
+
+		return
+	}
+
+	mediaInfo, err := analyzer.ProbeMediaInfo(item.MediaPart.FilePath)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to get the duration of music track %s", item.MediaPart.FilePath)
+
+		return
+	}
+
+	recordingID, err := acoustid.GetRecordingID(item.MediaPart.AcoustID, time.Duration(mediaInfo.Duration)*time.Millisecond)
+	if errors.Is(err, acoustid.ErrMissingAPIKey) {
+		log.Debug().Msgf("Skipping the identification of %s, as no AcoustID key is set", item.MediaPart.FilePath)
+
+		return
+	}
+
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to identify music track %s", item.MediaPart.FilePath)
+
+		return
+	}
+
+	if recordingID == "" {
+		log.Debug().Msgf("No recording matches the fingerprint of %s", item.MediaPart.FilePath)
+
+		return
+	}
+
+	err = database.AddExternalIdentifier(item.ID, database.MusicbrainzIdentifier, recordingID)
+	if err != nil {
+		log.Err(err).Msgf("Failed to save the recording of music track %s", item.MediaPart.FilePath)
+	}
+
+	item.ExternalIdentifiers = append(item.ExternalIdentifiers, database.ExternalIdentifier{
+		IdentifierType: database.MusicbrainzIdentifier,
+		Identifier:     recordingID,
+	})
+}
+
+// Reads the tags embedded in an audio file, or returns nil if it has none.
+func readTags(filePath string) tag.Metadata {
+	file, err := os.Open(filePath)
+	if err != nil {
+		log.Debug().Err(err).Msgf("Could not open %s to read tags", filePath)
+
+		return nil
+	}
+	defer file.Close()
+
+	trackTags, err := tag.ReadFrom(file)
+	if err != nil {
+		log.Debug().Err(err).Msgf("No embedded tags found in %s", filePath)
+
+		return nil
+	}
+
+	return trackTags
+}
@@ -0,0 +1,118 @@
+package musicvideo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dhowden/tag"
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/filesystem/analyzer"
+	"github.com/meteorae/meteorae-server/helpers"
+	"github.com/meteorae/meteorae-server/resolvers/registry"
+	"github.com/meteorae/meteorae-server/utils"
+	"github.com/panjf2000/ants/v2"
+	"github.com/rs/zerolog/log"
+)
+
+func init() {
+	registry.Register(musicVideoResolver)
+}
+
+var musicVideoResolver registry.Resolver = Resolver{}
+
+type Resolver struct{}
+
+func (r Resolver) GetName() string {
+	return "Music Video"
+}
+
+func (r Resolver) SupportsLibraryType(library database.Library) bool {
+	return library.Type == database.MusicVideoLibrary
+}
+
+func (r Resolver) SupportsFileType(filePath string, isDir bool) bool {
+	if isDir {
+		return false
+	}
+
+	return utils.IsStringInSlice(filepath.Ext(filePath), helpers.VideoFileExtensions)
+}
+
+func (r Resolver) Resolve(mediaPart *database.MediaPart, library database.Library) error {
+	fileName := filepath.Base(mediaPart.FilePath)
+	fileName = fileName[:len(fileName)-len(filepath.Ext(fileName))]
+
+	artists, title := utils.ParseArtistAndTitle(fileName)
+
+	// Embedded tags are more reliable than file names, so prefer them when present
+	tagArtist, tagTitle := readEmbeddedTags(mediaPart.FilePath)
+	if tagTitle != "" {
+		title = tagTitle
+	}
+
+	if tagArtist != "" {
+		artists = utils.ParseArtists(tagArtist)
+	}
+
+	item := database.ItemMetadata{
+		Title:     title,
+		SortTitle: utils.CleanSortTitle(title),
+		Type:      database.MusicVideoItem,
+		LibraryID: library.ID,
+		Library:   library,
+		MediaPart: *mediaPart,
+	}
+
+	err := database.CreateMusicVideo(&item)
+	if err != nil {
+		return fmt.Errorf("could not resolve music video metadata %s: %w", mediaPart.FilePath, err)
+	}
+
+	for index, name := range artists {
+		artist, err := database.GetOrCreateArtist(name, utils.CleanSortTitle(name))
+		if err != nil {
+			return fmt.Errorf("could not resolve artist \"%s\" for %s: %w", name, mediaPart.FilePath, err)
+		}
+
+		err = database.AddItemArtist(item.ID, artist.ID, index)
+		if err != nil {
+			return fmt.Errorf("could not link artist \"%s\" to %s: %w", name, mediaPart.FilePath, err)
+		}
+	}
+
+	err = ants.Submit(func() {
+		err := analyzer.AnalyzeVideo(item.MediaPart)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to analyze music video %s", mediaPart.FilePath)
+		}
+
+		analyzer.QueueVideoFingerprint(item.MediaPart)
+	})
+	if err != nil {
+		return fmt.Errorf("could not schedule music video analysis job %s: %w", mediaPart.FilePath, err)
+	}
+
+	return nil
+}
+
+// Reads the artist and title from the tags embedded in the file, if any.
+// Only some containers, like MP4, support those tags, so failures are expected.
+func readEmbeddedTags(filePath string) (string, string) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		log.Debug().Err(err).Msgf("Could not open %s to read tags", filePath)
+
+		return "", ""
+	}
+	defer file.Close()
+
+	tags, err := tag.ReadFrom(file)
+	if err != nil {
+		log.Debug().Err(err).Msgf("No embedded tags found in %s", filePath)
+
+		return "", ""
+	}
+
+	return tags.Artist(), tags.Title()
+}
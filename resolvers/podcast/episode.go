@@ -0,0 +1,73 @@
+package podcast
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/providers/podcast"
+	"github.com/meteorae/meteorae-server/resolvers/registry"
+	"github.com/panjf2000/ants/v2"
+	"github.com/rs/zerolog/log"
+)
+
+func init() {
+	registry.Register(episodeResolver)
+}
+
+var episodeResolver registry.Resolver = EpisodeResolver{}
+
+// Resolves podcast episodes, which are audio files inside a podcast directory.
+type EpisodeResolver struct{}
+
+func (r EpisodeResolver) GetName() string {
+	return "Podcast Episode"
+}
+
+func (r EpisodeResolver) SupportsLibraryType(library database.Library) bool {
+	return library.Type == database.PodcastLibrary
+}
+
+func (r EpisodeResolver) SupportsFileType(filePath string, isDir bool) bool {
+	if isDir {
+		return false
+	}
+
+	return isEpisodeFile(filePath)
+}
+
+func (r EpisodeResolver) Resolve(mediaPart *database.MediaPart, library database.Library) error {
+	fileName := filepath.Base(mediaPart.FilePath)
+	fileName = fileName[:len(fileName)-len(filepath.Ext(fileName))]
+
+	show, err := database.GetItemByMediaPartPath(filepath.Dir(mediaPart.FilePath))
+	if err != nil {
+		return fmt.Errorf("failed to get podcast for path %s: %w", mediaPart.FilePath, err)
+	}
+
+	item := database.ItemMetadata{
+		Title:     fileName,
+		Type:      database.PodcastEpisodeItem,
+		LibraryID: library.ID,
+		Library:   library,
+		ParentID:  show.ID,
+		MediaPart: *mediaPart,
+	}
+
+	err = database.CreatePodcast(&item)
+	if err != nil {
+		return fmt.Errorf("could not resolve podcast episode metadata %s: %w", mediaPart.FilePath, err)
+	}
+
+	err = ants.Submit(func() {
+		err := podcast.GetInformation(&item, library)
+		if err != nil {
+			log.Err(err).Msgf("Failed to get podcast episode information for %s", mediaPart.FilePath)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("could not schedule podcast episode information job %s: %w", mediaPart.FilePath, err)
+	}
+
+	return nil
+}
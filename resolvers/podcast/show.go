@@ -0,0 +1,75 @@
+package podcast
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/helpers"
+	"github.com/meteorae/meteorae-server/resolvers/registry"
+	"github.com/meteorae/meteorae-server/utils"
+	"github.com/rs/zerolog/log"
+)
+
+func init() {
+	registry.Register(showResolver)
+}
+
+var showResolver registry.Resolver = ShowResolver{}
+
+// Resolves podcasts, which are directories containing the episodes as audio files.
+type ShowResolver struct{}
+
+func (r ShowResolver) GetName() string {
+	return "Podcast"
+}
+
+func (r ShowResolver) SupportsLibraryType(library database.Library) bool {
+	return library.Type == database.PodcastLibrary
+}
+
+func (r ShowResolver) SupportsFileType(filePath string, isDir bool) bool {
+	if !isDir {
+		return false
+	}
+
+	entries, err := os.ReadDir(filePath)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to read directory %s", filePath)
+
+		return false
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() && isEpisodeFile(entry.Name()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r ShowResolver) Resolve(mediaPart *database.MediaPart, library database.Library) error {
+	title := filepath.Base(mediaPart.FilePath)
+
+	item := database.ItemMetadata{
+		Title:     title,
+		SortTitle: utils.CleanSortTitle(title),
+		Type:      database.PodcastItem,
+		LibraryID: library.ID,
+		Library:   library,
+		MediaPart: *mediaPart,
+	}
+
+	err := database.CreatePodcast(&item)
+	if err != nil {
+		return fmt.Errorf("could not resolve podcast metadata %s: %w", mediaPart.FilePath, err)
+	}
+
+	return nil
+}
+
+func isEpisodeFile(filePath string) bool {
+	return utils.IsStringInSlice(filepath.Ext(filePath), helpers.AudioFileExtensions)
+}
@@ -19,6 +19,12 @@ type Resolver interface {
 	Resolve(mediaPart *database.MediaPart, library database.Library) error
 }
 
+// Implemented by resolvers which update the items they resolved when their files are scanned again.
+type Rescanner interface {
+	// Rescans the given item, already resolved from a file the resolver supports.
+	Rescan(item *database.ItemMetadata, library database.Library) error
+}
+
 var Registry []Resolver
 
 // Registers a new file resolver.
@@ -45,3 +51,21 @@ func ResolveFile(mediaPart *database.MediaPart, library database.Library, isDir
 
 	return nil
 }
+
+// Schedules a rescan job for a file already in the library. Resolvers which don't support rescans are skipped.
+func RescanFile(item *database.ItemMetadata, library database.Library, isDir bool) error {
+	for _, resolver := range Registry {
+		rescanner, ok := resolver.(Rescanner)
+		if !ok || !resolver.SupportsLibraryType(library) || !resolver.SupportsFileType(item.MediaPart.FilePath, isDir) {
+			continue
+		}
+
+		log.Debug().Msgf("Rescanning file %s with resolver %s", item.MediaPart.FilePath, resolver.GetName())
+
+		if err := rescanner.Rescan(item, library); err != nil {
+			return fmt.Errorf("failed to rescan file: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package library
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/gorilla/mux"
+	"github.com/meteorae/meteorae-server/bundle"
+	"github.com/meteorae/meteorae-server/database"
+	"github.com/meteorae/meteorae-server/utils"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// Exports the metadata of a library, as a zip bundle with artwork, or as JSON with ?format=json.
+func ExportHTTPHandler(writer http.ResponseWriter, request *http.Request) {
+	if user := utils.GetUserFromContext(request.Context()); user == nil {
+		http.Error(writer, "Unauthorized", http.StatusUnauthorized)
+
+		return
+	}
+
+	format := bundle.ZipFormat
+
+	if formatParam := request.URL.Query().Get("format"); formatParam != "" {
+		if err := format.UnmarshalText([]byte(formatParam)); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	library, ok := getLibrary(writer, request)
+	if !ok {
+		return
+	}
+
+	libraryBundle, err := bundle.Export(library, format == bundle.ZipFormat)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to export library %d", library.ID)
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	contentType := "application/zip"
+	if format == bundle.JSONFormat {
+		contentType = "application/json"
+	}
+
+	writer.Header().Set("Content-Type", contentType)
+	writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"library-%d.%s\"", library.ID, format))
+
+	if err := bundle.Write(libraryBundle, format, writer); err != nil {
+		log.Error().Err(err).Msgf("Failed to write the bundle of library %d", library.ID)
+	}
+}
+
+// Imports a bundle, in any format, into a library.
+func ImportHTTPHandler(writer http.ResponseWriter, request *http.Request) {
+	if user := utils.GetUserFromContext(request.Context()); user == nil {
+		http.Error(writer, "Unauthorized", http.StatusUnauthorized)
+
+		return
+	}
+
+	library, ok := getLibrary(writer, request)
+	if !ok {
+		return
+	}
+
+	request.Body = http.MaxBytesReader(writer, request.Body, viper.GetInt64("bundles.max_import_size"))
+
+	// Zip archives are read from the end, so the body is spooled to disk instead of memory
+	file, err := os.CreateTemp("", "meteorae-bundle-*")
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create bundle file")
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	defer func() {
+		file.Close()
+
+		if err := os.Remove(file.Name()); err != nil {
+			log.Error().Err(err).Msgf("Failed to remove bundle file %s", file.Name())
+		}
+	}()
+
+	size, err := io.Copy(file, request.Body)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	libraryBundle, err := bundle.Read(file, size)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	result, err := bundle.Import(libraryBundle, library)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to import bundle into library %d", library.ID)
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	log.Info().Msgf("Imported %d items into library %d, %d unmatched", result.Matched, library.ID, len(result.Unmatched))
+
+	writer.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(writer).Encode(result); err != nil {
+		log.Error().Err(err).Msg("Failed to write import result")
+	}
+}
+
+func getLibrary(writer http.ResponseWriter, request *http.Request) (*database.Library, bool) {
+	library, err := database.GetLibraryWithLocations(mux.Vars(request)["library"])
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(writer, "Library not found", http.StatusNotFound)
+
+			return nil, false
+		}
+
+		log.Error().Err(err).Msg("Failed to get library")
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return nil, false
+	}
+
+	return library, true
+}
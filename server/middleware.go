@@ -150,6 +150,10 @@ func GetWebServer() (*http.Server, error) {
 	router.Handle("/query", loggingHandler.Then(queryHandler))
 	router.Handle("/playground", loggingHandler.Then(playground.Handler("GraphQL playground", "/query")))
 	router.Handle("/image/transcode", loggingHandler.Then(http.HandlerFunc(transcodeHandler.HTTPHandler)))
+	router.Handle("/library/{library}/export",
+		loggingHandler.Then(http.HandlerFunc(library.ExportHTTPHandler))).Methods("GET")
+	router.Handle("/library/{library}/import",
+		loggingHandler.Then(http.HandlerFunc(library.ImportHTTPHandler))).Methods("POST")
 	router.Handle("/library/{metadata}/{part}/file.{ext}",
 		loggingHandler.Then(http.HandlerFunc(library.MediaPartHTTPHandler)))
 	router.PathPrefix("/").Handler(loggingHandler.Then(spa))
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	animeGroupRegexp   = regexp.MustCompile(`^\s*\[([^\]]+)\]`)
+	animeCRCRegexp     = regexp.MustCompile(`[\[(]([0-9A-Fa-f]{8})[\])]`)
+	animeTagsRegexp    = regexp.MustCompile(`\[[^\]]*\]|\([^)]*\)`)
+	animeEpisodeRegexp = regexp.MustCompile(`(?i)(?:\s-\s|\s(?:e|ep|episode)\.?\s?)(\d{1,4})(?:v\d)?(?:\s|$)`)
+	animeSpacesRegexp  = regexp.MustCompile(`\s+`)
+)
+
+// Describes what the name of an anime release tells about it.
+type AnimeFileName struct {
+	// The fansub or release group, like "SubsPlease".
+	Group string
+	Title string
+	// The episode number, or 0 for movies and files without one.
+	Episode int
+	// The CRC32 of the file contents, in upper case, as fansub groups usually write it in the name.
+	CRC string
+}
+
+// Parses the name of an anime release following the usual fansub conventions,
+// like "[Group] Title - 01 (1080p) [ABCD1234]", without its extension.
+func ParseAnimeFileName(name string) AnimeFileName {
+	var parsed AnimeFileName
+
+	if match := animeGroupRegexp.FindStringSubmatch(name); match != nil {
+		parsed.Group = strings.TrimSpace(match[1])
+		name = name[len(match[0]):]
+	}
+
+	// The CRC is usually the last tag of the name
+	if matches := animeCRCRegexp.FindAllStringSubmatch(name, -1); len(matches) > 0 {
+		parsed.CRC = strings.ToUpper(matches[len(matches)-1][1])
+	}
+
+	name = animeTagsRegexp.ReplaceAllString(name, " ")
+
+	// Underscores often replace spaces, and dots when there are no spaces at all
+	name = strings.ReplaceAll(name, "_", " ")
+	if !strings.Contains(name, " ") {
+		name = strings.ReplaceAll(name, ".", " ")
+	}
+
+	name = animeSpacesRegexp.ReplaceAllString(name, " ") + " "
+
+	if location := animeEpisodeRegexp.FindStringSubmatchIndex(name); location != nil {
+		parsed.Episode, _ = strconv.Atoi(name[location[2]:location[3]])
+		name = name[:location[0]]
+	}
+
+	parsed.Title = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(name), "-"))
+
+	return parsed
+}
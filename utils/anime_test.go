@@ -0,0 +1,50 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/meteorae/meteorae-server/utils"
+)
+
+func TestParseAnimeFileName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		want  utils.AnimeFileName
+	}{
+		{
+			name:  "Fansub release",
+			input: "[SubsPlease] Sousou no Frieren - 05 (1080p) [A1B2C3D4]",
+			want:  utils.AnimeFileName{Group: "SubsPlease", Title: "Sousou no Frieren", Episode: 5, CRC: "A1B2C3D4"},
+		},
+		{
+			name:  "Underscores and version",
+			input: "[Coalgirls]_Clannad_-_12v2_(1920x1080_Blu-Ray_FLAC)_[deadbeef]",
+			want:  utils.AnimeFileName{Group: "Coalgirls", Title: "Clannad", Episode: 12, CRC: "DEADBEEF"},
+		},
+		{
+			name:  "Episode prefix",
+			input: "Cowboy Bebop Ep 3",
+			want:  utils.AnimeFileName{Title: "Cowboy Bebop", Episode: 3},
+		},
+		{
+			name:  "Movie",
+			input: "[Group] Kimi no Na wa (BD 1080p) [0123ABCD]",
+			want:  utils.AnimeFileName{Group: "Group", Title: "Kimi no Na wa", CRC: "0123ABCD"},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc // nolint:varnamelen
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := utils.ParseAnimeFileName(tc.input); got != tc.want {
+				t.Errorf("ParseAnimeFileName() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
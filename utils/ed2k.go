@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"hash"
+
+	"golang.org/x/crypto/md4" //nolint:staticcheck // ED2K is defined on top of MD4
+)
+
+// Size of the chunks ED2K hashes files by.
+const ed2kChunkSize = 9728000
+
+// Computes ED2K hashes, which identify files on AniDB along with their size.
+// Files are hashed by chunks of 9500 KiB with MD4, and the hash is the MD4 of the chunk hashes,
+// or the hash of the only chunk for small files.
+type ed2k struct {
+	chunk       hash.Hash
+	chunkLength int
+	chunkHashes []byte
+}
+
+// Returns a new hash computing ED2K hashes.
+func NewED2K() hash.Hash {
+	return &ed2k{chunk: md4.New()}
+}
+
+func (h *ed2k) Write(data []byte) (int, error) {
+	written := len(data)
+
+	for len(data) > 0 {
+		if h.chunkLength == ed2kChunkSize {
+			h.chunkHashes = h.chunk.Sum(h.chunkHashes)
+			h.chunk.Reset()
+			h.chunkLength = 0
+		}
+
+		length := len(data)
+		if remaining := ed2kChunkSize - h.chunkLength; length > remaining {
+			length = remaining
+		}
+
+		h.chunk.Write(data[:length])
+		h.chunkLength += length
+		data = data[length:]
+	}
+
+	return written, nil
+}
+
+func (h *ed2k) Sum(data []byte) []byte {
+	if len(h.chunkHashes) == 0 {
+		return h.chunk.Sum(data)
+	}
+
+	root := md4.New()
+	root.Write(h.chunkHashes)
+	root.Write(h.chunk.Sum(nil))
+
+	return root.Sum(data)
+}
+
+func (h *ed2k) Reset() {
+	h.chunk.Reset()
+	h.chunkLength = 0
+	h.chunkHashes = nil
+}
+
+func (h *ed2k) Size() int {
+	return md4.Size
+}
+
+func (h *ed2k) BlockSize() int {
+	return md4.BlockSize
+}
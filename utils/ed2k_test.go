@@ -0,0 +1,71 @@
+package utils_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/meteorae/meteorae-server/utils"
+	"golang.org/x/crypto/md4" //nolint:staticcheck
+)
+
+func TestED2K(t *testing.T) {
+	t.Parallel()
+
+	const chunkSize = 9728000
+
+	md4Sum := func(data []byte) []byte {
+		hash := md4.New()
+		hash.Write(data)
+
+		return hash.Sum(nil)
+	}
+
+	small := []byte("abc")
+	exact := bytes.Repeat([]byte{1}, chunkSize)
+	large := bytes.Repeat([]byte{2}, chunkSize+10)
+
+	tests := []struct {
+		name string
+		data []byte
+		want []byte
+	}{
+		{name: "Empty file", data: nil, want: md4Sum(nil)},
+		{name: "Single chunk", data: small, want: md4Sum(small)},
+		{name: "Exactly one chunk", data: exact, want: md4Sum(exact)},
+		{
+			name: "Several chunks",
+			data: large,
+			want: md4Sum(append(md4Sum(large[:chunkSize]), md4Sum(large[chunkSize:])...)),
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc // nolint:varnamelen
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			hash := utils.NewED2K()
+
+			// Write in uneven pieces, to check chunks are split in the right place
+			for data := tc.data; len(data) > 0; {
+				length := 1 << 20
+				if length > len(data) {
+					length = len(data)
+				}
+
+				hash.Write(data[:length])
+				data = data[length:]
+			}
+
+			if got := hash.Sum(nil); !bytes.Equal(got, tc.want) {
+				t.Errorf("ED2K = %s, want %s", hex.EncodeToString(got), hex.EncodeToString(tc.want))
+			}
+		})
+	}
+
+	if got := hex.EncodeToString(utils.NewED2K().Sum(nil)); got != "31d6cfe0d16ae931b73c59d7e0c089c0" {
+		t.Errorf("ED2K of an empty file = %s, want the MD4 of nothing", got)
+	}
+}
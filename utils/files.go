@@ -3,10 +3,9 @@ package utils
 import (
 	"crypto/sha256"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-
-	"github.com/rs/zerolog/log"
 )
 
 // Supported image formats for ingestion. Non-supported common formats needing support from libvips are commented out.
@@ -35,10 +34,12 @@ func IsImageFile(path string) bool {
 	return IsStringInSlice(ext, SupportedImageFormats)
 }
 
+// Returns the SHA-256 hash of the path of a file, which is cheap enough to compute on every scan.
+// The file must exist.
 func HashFilePath(path string) ([]byte, error) {
 	f, err := os.Open(path)
 	if err != nil {
-		log.Fatal().Err(err)
+		return nil, fmt.Errorf("could not open file: %w", err)
 	}
 	defer f.Close()
 
@@ -50,6 +51,23 @@ func HashFilePath(path string) ([]byte, error) {
 	return h.Sum(nil), nil
 }
 
+// Returns the SHA-256 hash of the contents of a file. This reads the whole file,
+// so it's only meant to recognize files after they were moved or renamed.
+func HashFileContents(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open file: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, fmt.Errorf("could not write to hash: %w", err)
+	}
+
+	return h.Sum(nil), nil
+}
+
 func HashFileBytes(fileBytes []byte) ([]byte, error) {
 	h := sha256.New()
 	if _, err := h.Write(fileBytes); err != nil {
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Size of the chunks hashed at the start and the end of files by the OpenSubtitles hash.
+const openSubtitlesChunkSize = 64 * 1024
+
+// Returned when hashing files too small to hold both chunks of the OpenSubtitles hash.
+var ErrFileTooSmall = errors.New("file is too small to be hashed")
+
+// Returns the OpenSubtitles hash of the file at the given path, as 16 hexadecimal characters.
+// It is the file size, plus the sum of the 64-bit little-endian words of its first and last 64 KiB,
+// and identifies a video on subtitle providers without reading the whole file.
+func OpenSubtitlesHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("could not open file: %w", err)
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("could not stat file: %w", err)
+	}
+
+	size := fileInfo.Size()
+	if size < 2*openSubtitlesChunkSize {
+		return "", fmt.Errorf("%w: %d bytes", ErrFileTooSmall, size)
+	}
+
+	hash := uint64(size)
+	buffer := make([]byte, openSubtitlesChunkSize)
+
+	for _, offset := range []int64{0, size - openSubtitlesChunkSize} {
+		if _, err := file.ReadAt(buffer, offset); err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("could not read file: %w", err)
+		}
+
+		// The sum overflows on purpose
+		for index := 0; index < openSubtitlesChunkSize; index += 8 {
+			hash += binary.LittleEndian.Uint64(buffer[index:])
+		}
+	}
+
+	return fmt.Sprintf("%016x", hash), nil
+}
@@ -0,0 +1,56 @@
+package utils_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/meteorae/meteorae-server/utils"
+)
+
+func TestOpenSubtitlesHash(t *testing.T) {
+	t.Parallel()
+
+	const chunkSize = 64 * 1024
+
+	zeros := make([]byte, 2*chunkSize)
+
+	overflowing := make([]byte, 3*chunkSize)
+	// The first word of the file, and the last word of the file, which wrap around when summed
+	copy(overflowing, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	copy(overflowing[len(overflowing)-8:], []byte{2})
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr error
+	}{
+		{name: "Only the size", data: zeros, want: "0000000000020000"},
+		{name: "Overflowing sum", data: overflowing, want: "0000000000030001"},
+		{name: "Small file", data: []byte("abc"), wantErr: utils.ErrFileTooSmall},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "video.mkv")
+			if err := os.WriteFile(path, tt.data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := utils.OpenSubtitlesHash(path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("OpenSubtitlesHash() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("OpenSubtitlesHash() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -23,3 +23,112 @@ func RemoveUnwantedCharacters(str string) string {
 
 	return strings.TrimSpace(res)
 }
+
+var (
+	featuringRegexp = regexp.MustCompile(`(?i)\s*[(\[]?\s*\b(?:feat\.?|ft\.?|featuring)\s+([^)\]]+)[)\]]?`)
+	featuringSplit  = regexp.MustCompile(`\s*(?:,|&| and )\s*`)
+)
+
+// Splits a music video name following the "Artist - Title" convention.
+// Featured artists, introduced by "feat.", "ft." or "featuring" on either side,
+// are returned after the main artist. If there is no separator, the whole name is the title.
+func ParseArtistAndTitle(name string) ([]string, string) {
+	artistPart, titlePart, found := strings.Cut(name, " - ")
+	if !found {
+		return nil, strings.TrimSpace(name)
+	}
+
+	title, featured := extractFeaturing(titlePart)
+
+	return append(ParseArtists(artistPart), featured...), title
+}
+
+// Splits an artist credit, such as "Artist feat. Other", into the main artist and featured artists.
+func ParseArtists(credit string) []string {
+	artist, featured := extractFeaturing(credit)
+	if artist == "" {
+		return featured
+	}
+
+	return append([]string{artist}, featured...)
+}
+
+// Removes the featured artists from the given string, and returns them separately.
+func extractFeaturing(str string) (string, []string) {
+	var featured []string
+
+	for _, match := range featuringRegexp.FindAllStringSubmatch(str, -1) {
+		for _, artist := range featuringSplit.Split(strings.TrimSpace(match[1]), -1) {
+			if artist != "" {
+				featured = append(featured, artist)
+			}
+		}
+	}
+
+	return strings.TrimSpace(featuringRegexp.ReplaceAllString(str, "")), featured
+}
+
+// Returns how similar two titles are, from 0 for completely different titles to 1 for identical ones.
+// Titles are compared case-insensitively, ignoring punctuation and leading articles.
+func TitleSimilarity(first, second string) float64 {
+	first = normalizeTitle(first)
+	second = normalizeTitle(second)
+
+	if first == second {
+		return 1
+	}
+
+	firstRunes, secondRunes := []rune(first), []rune(second)
+
+	longest := len(firstRunes)
+	if len(secondRunes) > longest {
+		longest = len(secondRunes)
+	}
+
+	return 1 - float64(levenshteinDistance(firstRunes, secondRunes))/float64(longest)
+}
+
+func normalizeTitle(title string) string {
+	title = strings.ToLower(RemoveUnwantedCharacters(title))
+
+	return strings.Join(strings.Fields(CleanSortTitle(title)), " ")
+}
+
+// Returns the number of single-character edits needed to turn one string into the other.
+func levenshteinDistance(first, second []rune) int {
+	previous := make([]int, len(second)+1)
+	current := make([]int, len(second)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(first); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(second); j++ {
+			cost := 1
+			if first[i-1] == second[j-1] {
+				cost = 0
+			}
+
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(second)]
+}
+
+func minInt(values ...int) int {
+	result := values[0]
+
+	for _, value := range values[1:] {
+		if value < result {
+			result = value
+		}
+	}
+
+	return result
+}
@@ -1,6 +1,12 @@
 package utils_test
 
-import "testing"
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/meteorae/meteorae-server/utils"
+)
 
 func TestCleanSortTitle(t *testing.T) {
 	t.Parallel()
@@ -11,3 +17,116 @@ func TestRemoveUnwantedCharacters(t *testing.T) {
 	t.Parallel()
 	t.Skip("TODO")
 }
+
+func TestParseArtistAndTitle(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		input       string
+		wantArtists []string
+		wantTitle   string
+	}{
+		{
+			name:        "Artist and title",
+			input:       "Daft Punk - Around the World",
+			wantArtists: []string{"Daft Punk"},
+			wantTitle:   "Around the World",
+		},
+		{
+			name:        "Featured artist in artist part",
+			input:       "Gorillaz feat. De La Soul - Feel Good Inc",
+			wantArtists: []string{"Gorillaz", "De La Soul"},
+			wantTitle:   "Feel Good Inc",
+		},
+		{
+			name:        "Featured artists in title part",
+			input:       "Major Lazer - Lean On (ft. MØ & DJ Snake)",
+			wantArtists: []string{"Major Lazer", "MØ", "DJ Snake"},
+			wantTitle:   "Lean On",
+		},
+		{
+			name:        "Only the first separator splits",
+			input:       "Blur - Song 2 - Live",
+			wantArtists: []string{"Blur"},
+			wantTitle:   "Song 2 - Live",
+		},
+		{
+			name:        "No separator",
+			input:       "Untitled",
+			wantArtists: nil,
+			wantTitle:   "Untitled",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc // nolint:varnamelen
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			artists, title := utils.ParseArtistAndTitle(tc.input)
+			if !reflect.DeepEqual(artists, tc.wantArtists) {
+				t.Errorf("ParseArtistAndTitle() artists = %v, want %v", artists, tc.wantArtists)
+			}
+
+			if title != tc.wantTitle {
+				t.Errorf("ParseArtistAndTitle() title = %v, want %v", title, tc.wantTitle)
+			}
+		})
+	}
+}
+
+func TestTitleSimilarity(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		first  string
+		second string
+		want   float64
+	}{
+		{
+			name:   "Identical titles",
+			first:  "The Matrix",
+			second: "The Matrix",
+			want:   1,
+		},
+		{
+			name:   "Case, punctuation and articles are ignored",
+			first:  "the.matrix",
+			second: "Matrix",
+			want:   1,
+		},
+		{
+			name:   "One typo",
+			first:  "Matrix",
+			second: "Matrux",
+			want:   1 - 1.0/6,
+		},
+		{
+			name:   "Completely different",
+			first:  "abc",
+			second: "xyz",
+			want:   0,
+		},
+		{
+			name:   "Empty title",
+			first:  "",
+			second: "Alien",
+			want:   0,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc // nolint:varnamelen
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := utils.TitleSimilarity(tc.first, tc.second); math.Abs(got-tc.want) > 1e-9 {
+				t.Errorf("TitleSimilarity() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}